@@ -0,0 +1,168 @@
+package grpcauth
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// ALBOIDCDataMetadataKey is the metadata field an AWS Application Load Balancer puts the authenticated user's
+	// claims in, as a JWT it signs. Pass it to WithMetadataKey when using ALB with an Authority.
+	ALBOIDCDataMetadataKey = "x-amzn-oidc-data"
+
+	// ALBOIDCAccessTokenMetadataKey is the metadata field ALB puts the identity provider's access token in.
+	ALBOIDCAccessTokenMetadataKey = "x-amzn-oidc-accesstoken"
+
+	// albKeysURL is where ALB publishes the ES256 keys it signs user claims with in each region.
+	albKeysURL = "https://public-keys.auth.elb.%s.amazonaws.com/"
+)
+
+// ALBOIDC authenticates requests from behind an AWS Application Load Balancer using OIDC authentication, by
+// verifying the user claims the load balancer signs and adds to every request.
+// The user's sub claim becomes AuthResult.ClientIdentifier.
+// ALB asserts identity in the x-amzn-oidc-data header instead of authorization, so use it with
+// WithMetadataKey(ALBOIDCDataMetadataKey).
+// See https://docs.aws.amazon.com/elasticloadbalancing/latest/application/listener-authenticate-users.html for more
+// details.
+type ALBOIDC struct {
+	// Region is the AWS region the load balancer is in, like us-east-1.
+	Region string
+	// LoadBalancerARN is the ARN of the load balancer that must have signed the claims.
+	LoadBalancerARN string
+	// Issuer, if set, is the iss claim of the identity provider ALB authenticates users with.
+	Issuer string
+	// Permissions maps the sub of each user allowed to call the server to their permissions.
+	// When it is nil every user ALB lets through is accepted with no permissions, unless AccessTokenAuthFunc is set.
+	Permissions map[string][]string
+	// AccessTokenAuthFunc, if set, authenticates the access token ALB forwards in x-amzn-oidc-accesstoken, and its
+	// permissions are used instead of Permissions, like the token's scopes.
+	AccessTokenAuthFunc AuthFunc
+	// KeysURL overrides where ALB's signing keys are fetched from, by key ID.
+	// It defaults to https://public-keys.auth.elb.REGION.amazonaws.com/.
+	KeysURL *url.URL
+	Client  *http.Client
+
+	mu   sync.Mutex
+	keys map[string]*ecdsa.PublicKey
+}
+
+// AuthFunc satisfies the AuthFunc interface so services behind ALB can trust the user it authenticated.
+func (a *ALBOIDC) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	values := md.Get(ALBOIDCDataMetadataKey)
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected JWT in '%s' metadata field", ALBOIDCDataMetadataKey)
+	}
+
+	token, err := jwt.Parse(values[0], func(token *jwt.Token) (interface{}, error) {
+		// ALB only signs user claims with ES256.
+		if token.Method != jwt.SigningMethodES256 {
+			return nil, fmt.Errorf("unexpected signing method: expected ES256, got %v", token.Header["alg"])
+		}
+
+		if signer, _ := token.Header["signer"].(string); signer != a.LoadBalancerARN {
+			return nil, fmt.Errorf("invalid signer, expected %s, got %v", a.LoadBalancerARN, token.Header["signer"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return a.key(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if a.Issuer != "" && !claims.VerifyIssuer(a.Issuer, true) {
+		return nil, fmt.Errorf("invalid issuer, expected %s, got %v", a.Issuer, claims["iss"])
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("ALB user claims have no sub claim")
+	}
+
+	var permissions []string
+	if a.AccessTokenAuthFunc != nil {
+		accessToken, err := FromMetadataKey(ALBOIDCAccessTokenMetadataKey, a.AccessTokenAuthFunc)(md)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = accessToken.Permissions
+	} else if a.Permissions != nil {
+		var ok bool
+		permissions, ok = a.Permissions[sub]
+		if !ok {
+			return nil, fmt.Errorf("%s is not allowed", sub)
+		}
+	}
+
+	return &AuthResult{
+		ClientIdentifier: sub,
+		Timestamp:        time.Now(),
+		Permissions:      permissions,
+		Claims:           claims,
+	}, nil
+}
+
+// key returns the public key with kid, fetching it from ALB the first time it is used.
+func (a *ALBOIDC) key(kid string) (*ecdsa.PublicKey, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if key, ok := a.keys[kid]; ok {
+		return key, nil
+	}
+
+	keysURL := a.KeysURL
+	if keysURL == nil {
+		var err error
+		keysURL, err = url.Parse(fmt.Sprintf(albKeysURL, a.Region))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Key IDs are looked up as a path, so make sure one can't escape the keys URL.
+	keyURL, err := keysURL.Parse(url.PathEscape(kid))
+	if err != nil {
+		return nil, err
+	}
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(keyURL.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unknown ALB key %s", kid)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := jwt.ParseECPublicKeyFromPEM(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.keys == nil {
+		a.keys = map[string]*ecdsa.PublicKey{}
+	}
+	a.keys[kid] = key
+	return key, nil
+}
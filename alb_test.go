@@ -0,0 +1,92 @@
+package grpcauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+const testLoadBalancerARN = "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/test/50dc6c495c0c9188"
+
+func TestALBOIDC(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/"+testKeyID {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		pem.Encode(w, &pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}))
+	defer server.Close()
+
+	keysURL, _ := url.Parse(server.URL + "/")
+	alb := &ALBOIDC{
+		LoadBalancerARN: testLoadBalancerARN,
+		Issuer:          "https://idp.example.com",
+		Permissions:     map[string][]string{testClientName: {targetMethodName}},
+		KeysURL:         keysURL,
+	}
+
+	sign := func(signer string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+		token.Header["kid"] = testKeyID
+		token.Header["signer"] = signer
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	claims := func(sub string) jwt.MapClaims {
+		return jwt.MapClaims{"sub": sub, "iss": "https://idp.example.com", "exp": time.Now().Add(time.Minute).Unix()}
+	}
+
+	for i := 0; i < 2; i++ {
+		authResult, err := alb.AuthFunc(metadata.Pairs(ALBOIDCDataMetadataKey, sign(testLoadBalancerARN, claims(testClientName))))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if authResult.ClientIdentifier != testClientName || len(authResult.Permissions) != 1 {
+			t.Fatalf("unexpected AuthResult %+v", authResult)
+		}
+	}
+
+	if requests != 1 {
+		t.Fatalf("expected ALB key to be cached, got %d requests", requests)
+	}
+
+	tests := []string{
+		sign("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/other/1", claims(testClientName)),
+		sign(testLoadBalancerARN, claims("otherClient")),
+		sign(testLoadBalancerARN, jwt.MapClaims{"sub": testClientName, "iss": "https://other.example.com"}),
+	}
+	for _, token := range tests {
+		_, err := alb.AuthFunc(metadata.Pairs(ALBOIDCDataMetadataKey, token))
+		if err == nil {
+			t.Fatalf("expected %s to be rejected", token)
+		}
+	}
+}
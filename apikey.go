@@ -0,0 +1,295 @@
+package grpcauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// apiKeyScheme is the authorization scheme clients send API keys with.
+	apiKeyScheme = "apikey "
+
+	// apiKeyIDSize and apiKeySecretSize are how many random bytes are in the ID and secret of keys from
+	// GenerateAPIKey.
+	apiKeyIDSize     = 8
+	apiKeySecretSize = 32
+)
+
+var (
+	// ErrKeyNotFound is returned from a KeyStore when no API key matches the hash it was given.
+	ErrKeyNotFound = errors.New("api key not found")
+)
+
+// APIKey is an API key as it is stored at rest.
+// Only a hash of the key is stored so a leaked KeyStore can't be used to authenticate.
+type APIKey struct {
+	// Hash is the SHA-256 hash of the key. See HashAPIKey.
+	Hash []byte
+	// ID is the ID of a key from GenerateAPIKey, for KeyIDStores.
+	ID string
+	// SecretHash is an argon2id or bcrypt hash of a key from GenerateAPIKey, checked instead of Hash when the key is
+	// looked up by ID. See HashAPIKeyArgon2id.
+	SecretHash       string
+	ClientIdentifier string
+	Permissions      []string
+}
+
+// KeyStore looks up API keys by their hash.
+// Implementations should return ErrKeyNotFound if no key matches.
+type KeyStore interface {
+	Key(hash []byte) (*APIKey, error)
+}
+
+// KeyIDStore is a KeyStore that can also look up keys from GenerateAPIKey by their ID, so they can be stored with a
+// slow hash like argon2id, which can't be looked up, instead of SHA-256.
+// Implementations should return ErrKeyNotFound if no key has the ID.
+type KeyIDStore interface {
+	KeyStore
+	KeyByID(id string) (*APIKey, error)
+}
+
+// HashAPIKey returns the hash of an API key that should be stored in a KeyStore.
+func HashAPIKey(key string) []byte {
+	hash := sha256.Sum256([]byte(key))
+	return hash[:]
+}
+
+// HashAPIKeyArgon2id hashes a key from GenerateAPIKey with argon2id for APIKey.SecretHash, so keys leaked from a
+// KeyIDStore are expensive to brute force even if they are weak.
+// Checking argon2id hashes is deliberately slow, so it adds latency to every request that isn't cached.
+func HashAPIKeyArgon2id(key string) (string, error) {
+	return HashPasswordArgon2id(key)
+}
+
+// GenerateAPIKey returns a new random API key like `<prefix>_<id>_<secret>`, and its ID.
+// The prefix lets people and secret scanners recognize leaked keys, and the ID lets a KeyIDStore find the key
+// without hashing it first. Only a hash of the key should be stored.
+func GenerateAPIKey(prefix string) (key, id string, err error) {
+	b := make([]byte, apiKeyIDSize+apiKeySecretSize)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", "", err
+	}
+
+	id = hex.EncodeToString(b[:apiKeyIDSize])
+	secret := hex.EncodeToString(b[apiKeyIDSize:])
+	return prefix + "_" + id + "_" + secret, id, nil
+}
+
+// ParseAPIKey splits a key from GenerateAPIKey into its prefix, ID and secret.
+func ParseAPIKey(key string) (prefix, id, secret string, err error) {
+	parts := strings.Split(key, "_")
+	if len(parts) < 3 {
+		return "", "", "", fmt.Errorf("malformed API key")
+	}
+
+	// Prefixes can contain underscores, but IDs and secrets are hex.
+	n := len(parts)
+	prefix, id, secret = strings.Join(parts[:n-2], "_"), parts[n-2], parts[n-1]
+	if len(id) != 2*apiKeyIDSize || len(secret) != 2*apiKeySecretSize {
+		return "", "", "", fmt.Errorf("malformed API key")
+	}
+
+	return prefix, id, secret, nil
+}
+
+// APIKeys authenticates clients presenting an API key in the authorization metadata field, such as
+// `authorization: ApiKey <key>`.
+// The key is hashed before looking it up in the KeyStore, which returns its ClientIdentifier and permissions.
+// Keys from GenerateAPIKey are looked up by ID first if the KeyStore is a KeyIDStore.
+type APIKeys struct {
+	Store KeyStore
+	// Prefix, if set, is the prefix keys from GenerateAPIKey must have. Other keys are rejected without looking them
+	// up in the KeyStore.
+	Prefix string
+}
+
+// AuthFunc satisfies the AuthFunc interface so clients can use API keys with a gRPC server.
+func (a *APIKeys) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	key, err := apiKeyFromMetadata(md)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, id, _, parseErr := ParseAPIKey(key)
+	if a.Prefix != "" && (parseErr != nil || prefix != a.Prefix) {
+		return nil, ErrKeyNotFound
+	}
+
+	if idStore, ok := a.Store.(KeyIDStore); ok && parseErr == nil {
+		authResult, err := a.authenticateByID(idStore, id, key)
+		// Keys from GenerateAPIKey may have been stored by Hash only, so look them up by hash too.
+		if !errors.Is(err, ErrKeyNotFound) {
+			return authResult, err
+		}
+	}
+
+	hash := HashAPIKey(key)
+	apiKey, err := a.Store.Key(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't trust the KeyStore to have compared the hash in constant time.
+	if subtle.ConstantTimeCompare(apiKey.Hash, hash) != 1 {
+		return nil, ErrKeyNotFound
+	}
+
+	return &AuthResult{
+		ClientIdentifier: apiKey.ClientIdentifier,
+		Timestamp:        time.Now(),
+		Permissions:      apiKey.Permissions,
+	}, nil
+}
+
+// authenticateByID looks up a key from GenerateAPIKey by its ID, and checks it against the stored hash.
+func (a *APIKeys) authenticateByID(store KeyIDStore, id, key string) (*AuthResult, error) {
+	apiKey, err := store.KeyByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey.SecretHash != "" {
+		if verifyPasswordHash(apiKey.SecretHash, key) != nil {
+			return nil, ErrKeyNotFound
+		}
+	} else if subtle.ConstantTimeCompare(apiKey.Hash, HashAPIKey(key)) != 1 {
+		return nil, ErrKeyNotFound
+	}
+
+	return &AuthResult{
+		ClientIdentifier: apiKey.ClientIdentifier,
+		Timestamp:        time.Now(),
+		Permissions:      apiKey.Permissions,
+	}, nil
+}
+
+// apiKeyFromMetadata extracts the key from an authorization metadata field using the ApiKey scheme.
+func apiKeyFromMetadata(md metadata.MD) (string, error) {
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return "", fmt.Errorf("expected API key in 'authorization' metadata field")
+	}
+
+	value := values[0]
+	if len(value) <= len(apiKeyScheme) || !strings.EqualFold(value[:len(apiKeyScheme)], apiKeyScheme) {
+		return "", fmt.Errorf("expected ApiKey authorization scheme")
+	}
+
+	return value[len(apiKeyScheme):], nil
+}
+
+// InMemoryKeyStore is a KeyIDStore that keeps API key hashes in memory.
+// It is safe for concurrent use.
+type InMemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*APIKey
+	ids  map[string]*APIKey
+}
+
+// NewInMemoryKeyStore returns an InMemoryKeyStore containing keys.
+func NewInMemoryKeyStore(keys ...*APIKey) *InMemoryKeyStore {
+	store := &InMemoryKeyStore{keys: map[string]*APIKey{}, ids: map[string]*APIKey{}}
+	for _, key := range keys {
+		store.Add(key)
+	}
+	return store
+}
+
+// Add stores an API key, replacing any key with the same hash or ID.
+func (s *InMemoryKeyStore) Add(key *APIKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(key.Hash) > 0 {
+		s.keys[hex.EncodeToString(key.Hash)] = key
+	}
+
+	if key.ID != "" {
+		s.ids[key.ID] = key
+	}
+}
+
+// Remove deletes the API key with hash.
+func (s *InMemoryKeyStore) Remove(hash []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[hex.EncodeToString(hash)]
+	if ok && key.ID != "" {
+		delete(s.ids, key.ID)
+	}
+	delete(s.keys, hex.EncodeToString(hash))
+}
+
+// RemoveID deletes the API key with id.
+func (s *InMemoryKeyStore) RemoveID(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.ids[id]
+	if ok && len(key.Hash) > 0 {
+		delete(s.keys, hex.EncodeToString(key.Hash))
+	}
+	delete(s.ids, id)
+}
+
+// Key satisfies the KeyStore interface.
+func (s *InMemoryKeyStore) Key(hash []byte) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[hex.EncodeToString(hash)]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// KeyByID satisfies the KeyIDStore interface.
+func (s *InMemoryKeyStore) KeyByID(id string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.ids[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// SQLKeyStore is a KeyStore backed by a SQL database.
+// Query must select the client identifier and space separated permissions of the key whose hash matches its only
+// parameter, such as:
+//
+//	SELECT client_identifier, permissions FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL
+//
+// The hash is passed as []byte.
+type SQLKeyStore struct {
+	DB    *sql.DB
+	Query string
+}
+
+// Key satisfies the KeyStore interface.
+func (s *SQLKeyStore) Key(hash []byte) (*APIKey, error) {
+	var clientIdentifier, permissions string
+	err := s.DB.QueryRow(s.Query, hash).Scan(&clientIdentifier, &permissions)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &APIKey{
+		Hash:             hash,
+		ClientIdentifier: clientIdentifier,
+		Permissions:      strings.Fields(permissions),
+	}, nil
+}
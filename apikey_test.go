@@ -0,0 +1,129 @@
+package grpcauth
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const testAPIKey = "3x4mpl3-4p1-k3y"
+
+func TestAPIKeysAuthenticatesStoredKeys(t *testing.T) {
+	store := NewInMemoryKeyStore(&APIKey{
+		Hash:             HashAPIKey(testAPIKey),
+		ClientIdentifier: testClientName,
+		Permissions:      []string{targetMethodName},
+	})
+	apiKeys := &APIKeys{Store: store}
+
+	authResult, err := apiKeys.AuthFunc(metadata.Pairs("authorization", "ApiKey "+testAPIKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected %v, got %v", testClientName, authResult.ClientIdentifier)
+	}
+
+	if !reflect.DeepEqual(authResult.Permissions, []string{targetMethodName}) {
+		t.Fatalf("expected %v, got %v", []string{targetMethodName}, authResult.Permissions)
+	}
+
+	store.Remove(HashAPIKey(testAPIKey))
+	_, err = apiKeys.AuthFunc(metadata.Pairs("authorization", "ApiKey "+testAPIKey))
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound after removing key, got %v", err)
+	}
+}
+
+func TestAPIKeysRequiresScheme(t *testing.T) {
+	apiKeys := &APIKeys{Store: NewInMemoryKeyStore()}
+	for _, value := range []string{testAPIKey, "Bearer " + testAPIKey, "ApiKey "} {
+		_, err := apiKeys.AuthFunc(metadata.Pairs("authorization", value))
+		if err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, id, err := GenerateAPIKey("my_service")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefix, parsedID, secret, err := ParseAPIKey(key)
+	if err != nil || prefix != "my_service" || parsedID != id || len(secret) != 64 {
+		t.Fatalf("unexpected key %s: %s %s %s %v", key, prefix, parsedID, secret, err)
+	}
+
+	other, _, _ := GenerateAPIKey("my_service")
+	if other == key {
+		t.Fatalf("expected keys to be random")
+	}
+
+	_, _, _, err = ParseAPIKey(testAPIKey)
+	if err == nil {
+		t.Fatalf("expected keys that weren't generated to be rejected")
+	}
+}
+
+func TestAPIKeysLooksUpKeysByID(t *testing.T) {
+	key, id, err := GenerateAPIKey("gk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secretHash, err := HashAPIKeyArgon2id(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewInMemoryKeyStore(&APIKey{ID: id, SecretHash: secretHash, ClientIdentifier: testClientName})
+	apiKeys := &APIKeys{Store: store, Prefix: "gk"}
+	authResult, err := apiKeys.AuthFunc(metadata.Pairs("authorization", "ApiKey "+key))
+	if err != nil || authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected the key to be found by its ID, got %v %v", authResult, err)
+	}
+
+	forged := key[:len(key)-1] + "0"
+	if forged == key {
+		forged = key[:len(key)-1] + "1"
+	}
+	_, err = apiKeys.AuthFunc(metadata.Pairs("authorization", "ApiKey "+forged))
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected a key with the right ID and the wrong secret to be rejected, got %v", err)
+	}
+
+	otherKey, _, _ := GenerateAPIKey("other")
+	_, err = apiKeys.AuthFunc(metadata.Pairs("authorization", "ApiKey "+otherKey))
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected keys with the wrong prefix to be rejected, got %v", err)
+	}
+
+	store.RemoveID(id)
+	_, err = apiKeys.AuthFunc(metadata.Pairs("authorization", "ApiKey "+key))
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected removed keys to be rejected, got %v", err)
+	}
+}
+
+func TestAPIKeysFallsBackToHashLookup(t *testing.T) {
+	key, _, err := GenerateAPIKey("gk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewInMemoryKeyStore(&APIKey{Hash: HashAPIKey(key), ClientIdentifier: testClientName})
+	apiKeys := &APIKeys{Store: store, Prefix: "gk"}
+	authResult, err := apiKeys.AuthFunc(metadata.Pairs("authorization", "ApiKey "+key))
+	if err != nil {
+		t.Fatalf("expected keys stored by hash only to be accepted, got %v", err)
+	}
+
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected %v, got %v", testClientName, authResult.ClientIdentifier)
+	}
+}
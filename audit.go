@@ -0,0 +1,239 @@
+package grpcauth
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultAuditQueueSize is how many events an AuditWriter holds while its sink catches up.
+	DefaultAuditQueueSize = 1024
+
+	// DefaultAuditBatchSize is the most events an AuditWriter sends to its sink at once.
+	DefaultAuditBatchSize = 100
+
+	// DefaultAuditFlushInterval is how long an AuditWriter waits for a batch to fill before sending it anyway.
+	DefaultAuditFlushInterval = time.Second
+)
+
+// AuditEvent is an AuthEvent with credentials redacted from its errors, in a form audit sinks can serialize.
+type AuditEvent struct {
+	Time    time.Time `json:"time"`
+	Method  string    `json:"method"`
+	Client  string    `json:"client,omitempty"`
+	Outcome string    `json:"outcome"`
+	// Latency is how long authenticating and authorizing the request took, in nanoseconds when serialized.
+	Latency       time.Duration `json:"latency"`
+	Error         string        `json:"error,omitempty"`
+	Cause         string        `json:"cause,omitempty"`
+	CorrelationID string        `json:"correlationId,omitempty"`
+}
+
+// NewAuditEvent returns an AuditEvent for event that happened at t.
+func NewAuditEvent(event *AuthEvent, t time.Time) *AuditEvent {
+	return &AuditEvent{
+		Time:          t,
+		Method:        event.MethodName,
+		Client:        event.ClientIdentifier(),
+		Outcome:       event.Outcome(),
+		Latency:       event.Latency,
+		Error:         event.RedactedError(),
+		Cause:         event.RedactedCause(),
+		CorrelationID: event.CorrelationID,
+	}
+}
+
+// AuditSink stores batches of AuditEvents, such as in a SIEM.
+type AuditSink interface {
+	WriteAudit(ctx context.Context, events []*AuditEvent) error
+}
+
+// AuditSinkFunc satisfies the AuditSink interface with a function.
+type AuditSinkFunc func(ctx context.Context, events []*AuditEvent) error
+
+// WriteAudit satisfies the AuditSink interface.
+func (f AuditSinkFunc) WriteAudit(ctx context.Context, events []*AuditEvent) error {
+	return f(ctx, events)
+}
+
+// AuditBackpressure is what an AuditWriter does with events when its queue is full.
+type AuditBackpressure int
+
+const (
+	// AuditDrop drops events when the queue is full, so a stalled sink never slows down requests.
+	AuditDrop AuditBackpressure = iota
+	// AuditBlock makes requests wait for room in the queue, or until they are cancelled, so events are only lost
+	// when the client gives up.
+	AuditBlock
+)
+
+// AuditWriterConfig configures an AuditWriter. The zero value is a usable configuration.
+type AuditWriterConfig struct {
+	// QueueSize is how many events are held while the sink catches up. It defaults to DefaultAuditQueueSize.
+	QueueSize int
+	// BatchSize is the most events sent to the sink at once. It defaults to DefaultAuditBatchSize.
+	BatchSize int
+	// FlushInterval is how long to wait for a batch to fill before sending it anyway.
+	// It defaults to DefaultAuditFlushInterval.
+	FlushInterval time.Duration
+	// Backpressure is what happens to events when the queue is full. It defaults to AuditDrop.
+	Backpressure AuditBackpressure
+	// OnError is called with batches the sink failed to store. They are dropped if it is nil.
+	OnError func(err error, events []*AuditEvent)
+}
+
+// AuditWriter is a Logger that queues events and sends them to an AuditSink in batches from a background goroutine,
+// so audit logging doesn't add latency to requests or take the service down when the sink stalls.
+// Pass it to an Authority with WithLogger, and Close it on shutdown to flush queued events.
+type AuditWriter struct {
+	sink          AuditSink
+	batchSize     int
+	flushInterval time.Duration
+	backpressure  AuditBackpressure
+	onError       func(err error, events []*AuditEvent)
+
+	queue   chan *AuditEvent
+	dropped atomic.Uint64
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// NewAuditWriter returns an AuditWriter sending events to sink, and starts its background goroutine.
+func NewAuditWriter(sink AuditSink, config AuditWriterConfig) *AuditWriter {
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultAuditQueueSize
+	}
+
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultAuditBatchSize
+	}
+
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultAuditFlushInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &AuditWriter{
+		sink:          sink,
+		batchSize:     config.BatchSize,
+		flushInterval: config.FlushInterval,
+		backpressure:  config.Backpressure,
+		onError:       config.OnError,
+		queue:         make(chan *AuditEvent, config.QueueSize),
+		ctx:           ctx,
+		cancel:        cancel,
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// LogAuth satisfies the Logger interface by queueing event.
+// Events logged after Close are dropped.
+func (w *AuditWriter) LogAuth(ctx context.Context, event *AuthEvent) {
+	auditEvent := NewAuditEvent(event, time.Now())
+	select {
+	case <-w.done:
+		w.dropped.Add(1)
+		return
+	default:
+	}
+
+	select {
+	case w.queue <- auditEvent:
+		return
+	default:
+	}
+
+	if w.backpressure != AuditBlock {
+		w.dropped.Add(1)
+		return
+	}
+
+	select {
+	case w.queue <- auditEvent:
+	case <-ctx.Done():
+		w.dropped.Add(1)
+	case <-w.done:
+		w.dropped.Add(1)
+	}
+}
+
+// Dropped returns how many events have been dropped because the queue was full or the AuditWriter was closed.
+// Batches the sink failed to store aren't counted.
+func (w *AuditWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close stops accepting events and sends queued events to the sink, waiting until it is done or ctx is done.
+// If ctx is done first, the sink's context is cancelled and ctx's error is returned.
+func (w *AuditWriter) Close(ctx context.Context) error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+
+	select {
+	case <-w.stopped:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
+
+// run sends batches to the sink when they are full or FlushInterval has passed, until the AuditWriter is closed.
+func (w *AuditWriter) run() {
+	defer close(w.stopped)
+	defer w.cancel()
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*AuditEvent, 0, w.batchSize)
+	for {
+		select {
+		case event := <-w.queue:
+			batch = append(batch, event)
+			if len(batch) >= w.batchSize {
+				batch = w.flush(batch)
+			}
+		case <-ticker.C:
+			batch = w.flush(batch)
+		case <-w.done:
+			for {
+				select {
+				case event := <-w.queue:
+					batch = append(batch, event)
+					if len(batch) >= w.batchSize {
+						batch = w.flush(batch)
+					}
+				default:
+					w.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush sends batch to the sink and returns an empty batch to fill next.
+// Sinks may keep batch, so it isn't reused.
+func (w *AuditWriter) flush(batch []*AuditEvent) []*AuditEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	err := w.sink.WriteAudit(w.ctx, batch)
+	if err != nil && w.onError != nil {
+		w.onError(err, batch)
+	}
+
+	return make([]*AuditEvent, 0, w.batchSize)
+}
@@ -0,0 +1,157 @@
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingAuditSink stores the batches written to it.
+type recordingAuditSink struct {
+	mu      sync.Mutex
+	batches [][]*AuditEvent
+}
+
+func (s *recordingAuditSink) WriteAudit(ctx context.Context, events []*AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *recordingAuditSink) events() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, batch := range s.batches {
+		count += len(batch)
+	}
+	return count
+}
+
+func testAuthEvent() *AuthEvent {
+	return &AuthEvent{
+		MethodName: targetMethodName,
+		AuthResult: &AuthResult{ClientIdentifier: testClientName},
+		Err:        errUnauthorized,
+		Cause:      errors.New("invalid token bearer abc"),
+	}
+}
+
+func TestAuditWriterBatches(t *testing.T) {
+	sink := &recordingAuditSink{}
+	writer := NewAuditWriter(sink, AuditWriterConfig{BatchSize: 2, FlushInterval: time.Hour})
+	for i := 0; i < 5; i++ {
+		writer.LogAuth(context.Background(), testAuthEvent())
+	}
+
+	err := writer.Close(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.batches) != 3 || len(sink.batches[0]) != 2 || len(sink.batches[2]) != 1 {
+		t.Fatalf("expected batches of 2, 2 and 1 events, got %v", sink.batches)
+	}
+
+	event := sink.batches[0][0]
+	if event.Client != testClientName || event.Method != targetMethodName || event.Outcome != OutcomeUnauthenticated {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	if event.Cause != "invalid token bearer "+redactedToken {
+		t.Fatalf("expected the cause to be redacted, got %s", event.Cause)
+	}
+}
+
+func TestAuditWriterFlushesOnInterval(t *testing.T) {
+	sink := &recordingAuditSink{}
+	writer := NewAuditWriter(sink, AuditWriterConfig{FlushInterval: 10 * time.Millisecond})
+	defer writer.Close(context.Background())
+
+	writer.LogAuth(context.Background(), testAuthEvent())
+	deadline := time.Now().Add(time.Second)
+	for sink.events() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a partial batch to be flushed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAuditWriterBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	stalled := AuditSinkFunc(func(ctx context.Context, events []*AuditEvent) error {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+
+	dropping := NewAuditWriter(stalled, AuditWriterConfig{QueueSize: 1, BatchSize: 1})
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		dropping.LogAuth(context.Background(), testAuthEvent())
+	}
+
+	if time.Since(start) > time.Second {
+		t.Fatalf("expected a stalled sink not to block requests")
+	}
+
+	if dropping.Dropped() < 8 {
+		t.Fatalf("expected events to be dropped, got %d", dropping.Dropped())
+	}
+
+	blocking := NewAuditWriter(stalled, AuditWriterConfig{QueueSize: 1, BatchSize: 1, Backpressure: AuditBlock})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		blocking.LogAuth(ctx, testAuthEvent())
+	}
+
+	if ctx.Err() == nil {
+		t.Fatalf("expected a stalled sink to block requests until they are cancelled")
+	}
+
+	if blocking.Dropped() != 1 {
+		t.Fatalf("expected the cancelled request's event to be dropped, got %d", blocking.Dropped())
+	}
+
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer closeCancel()
+	if err := dropping.Close(closeCtx); err != context.DeadlineExceeded {
+		t.Fatalf("expected Close to give up on a stalled sink, got %v", err)
+	}
+
+	close(release)
+	if err := blocking.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditWriterReportsErrors(t *testing.T) {
+	var failed []*AuditEvent
+	failing := AuditSinkFunc(func(ctx context.Context, events []*AuditEvent) error {
+		return errors.New("sink unavailable")
+	})
+
+	writer := NewAuditWriter(failing, AuditWriterConfig{OnError: func(err error, events []*AuditEvent) {
+		failed = append(failed, events...)
+	}})
+	writer.LogAuth(context.Background(), testAuthEvent())
+	if err := writer.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(failed) != 1 {
+		t.Fatalf("expected the failed batch to be reported, got %v", failed)
+	}
+
+	writer.LogAuth(context.Background(), testAuthEvent())
+	if writer.Dropped() != 1 {
+		t.Fatalf("expected events logged after Close to be dropped")
+	}
+}
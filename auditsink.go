@@ -0,0 +1,93 @@
+package grpcauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// WebhookAuditSink is an AuditSink that POSTs each batch of events to an HTTP endpoint as a JSON array, such as a
+// SIEM's HTTP event collector.
+type WebhookAuditSink struct {
+	// URL is where batches are POSTed.
+	URL string
+	// Header is added to every request, such as an Authorization header for the collector.
+	Header http.Header
+	// Client makes the requests. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// WriteAudit satisfies the AuditSink interface.
+// Responses other than 2xx are errors, so the AuditWriter can report the batch as failed.
+func (s *WebhookAuditSink) WriteAudit(ctx context.Context, events []*AuditEvent) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for key, values := range s.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+// KafkaWriter publishes messages to Kafka. It is satisfied by *kafka.Writer.
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// KafkaAuditSink is an AuditSink that publishes each event to Kafka as a JSON message keyed by the client's
+// identifier, so a client's events stay in order on one partition.
+// The topic is set on Writer.
+type KafkaAuditSink struct {
+	Writer KafkaWriter
+}
+
+// WriteAudit satisfies the AuditSink interface.
+func (s *KafkaAuditSink) WriteAudit(ctx context.Context, events []*AuditEvent) error {
+	messages := make([]kafka.Message, len(events))
+	for i, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		messages[i] = kafka.Message{
+			Key:   []byte(event.Client),
+			Value: value,
+			Time:  event.Time,
+		}
+	}
+
+	return s.Writer.WriteMessages(ctx, messages...)
+}
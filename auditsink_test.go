@@ -0,0 +1,80 @@
+package grpcauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestWebhookAuditSink(t *testing.T) {
+	var received []*AuditEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Splunk token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	events := []*AuditEvent{NewAuditEvent(testAuthEvent(), time.Now())}
+	sink := &WebhookAuditSink{URL: server.URL, Header: http.Header{"Authorization": {"Splunk token"}}}
+	err := sink.WriteAudit(context.Background(), events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received) != 1 || received[0].Client != testClientName || received[0].Outcome != OutcomeUnauthenticated {
+		t.Fatalf("unexpected events %v", received)
+	}
+
+	unauthorized := &WebhookAuditSink{URL: server.URL}
+	err = unauthorized.WriteAudit(context.Background(), events)
+	if err == nil {
+		t.Fatalf("expected an error when the webhook rejects the batch")
+	}
+}
+
+type fakeKafkaWriter struct {
+	messages []kafka.Message
+}
+
+func (w *fakeKafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func TestKafkaAuditSink(t *testing.T) {
+	writer := &fakeKafkaWriter{}
+	sink := &KafkaAuditSink{Writer: writer}
+	events := []*AuditEvent{
+		NewAuditEvent(testAuthEvent(), time.Now()),
+		NewAuditEvent(&AuthEvent{MethodName: targetMethodName}, time.Now()),
+	}
+
+	err := sink.WriteAudit(context.Background(), events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(writer.messages) != 2 || string(writer.messages[0].Key) != testClientName {
+		t.Fatalf("expected a message per event keyed by client, got %v", writer.messages)
+	}
+
+	var event AuditEvent
+	if err := json.Unmarshal(writer.messages[1].Value, &event); err != nil {
+		t.Fatal(err)
+	}
+
+	if event.Outcome != OutcomeAllowed || event.Method != targetMethodName {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
@@ -2,17 +2,13 @@ package grpcauth
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
-	"strings"
-	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/oauth"
 	"google.golang.org/grpc/metadata"
 )
@@ -21,6 +17,19 @@ import (
 // It is meant to be used with auth0's machine to machine OAuth2.
 // It optionally allows a client to specify a subset of scopes to limit privileges.
 func Auth0M2MClientCredentials(ctx context.Context, clientID, clientSecret, tokenURL, audience string, scopes ...string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(Auth0M2MCredentials(ctx, clientID, clientSecret, tokenURL, audience, scopes...))
+}
+
+// Auth0M2MCredentials returns credentials.PerRPCCredentials that attach an access token from auth0's client
+// credentials flow to every call, for clients that build their own dial options.
+func Auth0M2MCredentials(ctx context.Context, clientID, clientSecret, tokenURL, audience string, scopes ...string) credentials.PerRPCCredentials {
+	return oauth.TokenSource{TokenSource: Auth0M2MTokenSource(ctx, clientID, clientSecret, tokenURL, audience, scopes...)}
+}
+
+// Auth0M2MTokenSource returns an oauth2.TokenSource that gets access tokens for audience from auth0's client
+// credentials flow, caching each token and refreshing it before it expires.
+// Use it with TokenUnaryClientInterceptor and TokenStreamClientInterceptor to set credentials per call.
+func Auth0M2MTokenSource(ctx context.Context, clientID, clientSecret, tokenURL, audience string, scopes ...string) oauth2.TokenSource {
 	params := url.Values{}
 	params.Add("audience", audience)
 	config := &clientcredentials.Config{
@@ -30,72 +39,27 @@ func Auth0M2MClientCredentials(ctx context.Context, clientID, clientSecret, toke
 		EndpointParams: params,
 		Scopes:         scopes,
 	}
-	return grpc.WithPerRPCCredentials(oauth.TokenSource{TokenSource: config.TokenSource(ctx)})
-}
-
-const (
-	// JWTs must be signed with RS256.
-	signingMethod = "RS256"
-)
-
-// auth0JWKEndpoint is a list of auth0JWK from auth0.
-type auth0JWKEndpoint struct {
-	Keys []auth0JWK `json:"keys"`
-}
-
-// auth0JWK is a single JWK from auth0 that the auth0 JWT will be signed with.
-type auth0JWK struct {
-	Kty string   `json:"kty"`
-	Kid string   `json:"kid"`
-	Use string   `json:"use"`
-	N   string   `json:"n"`
-	E   string   `json:"e"`
-	X5c []string `json:"x5c"`
+	return clientCredentialsTokenSource(ctx, config)
 }
 
 // Auth0M2M uses auth0's Machine to Machine authentication to secure a gRPC server.
-// It validates a client's temporary access token using a user-supplied auth0 public key.
+// It validates a client's temporary access token offline using auth0's cached public keys.
 // See https://auth0.com/machine-to-machine for more details.
 type Auth0M2M struct {
 	Domain        *url.URL
 	APIIdentifier string
 	JWKSURL       *url.URL
+
+	JWTValidation
 }
 
 // AuthFunc satisfies the AuthFunc interface so clients can use auth0 M2M with a gRPC server.
 func (a *Auth0M2M) AuthFunc(md metadata.MD) (*AuthResult, error) {
-	if len(md["authorization"]) != 1 {
-		return nil, fmt.Errorf("expected JWT in 'authorization' metadata field")
-	}
-
-	tokenString := md["authorization"][0]
-	tokenString = strings.Replace(tokenString, "Bearer ", "", 1)
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok && token.Header["alg"] != signingMethod {
-			return nil, fmt.Errorf("unexpected signing method: expected %s, got %v", signingMethod, token.Header["alg"])
-		}
-
-		cert, err := a.getPemCert(token)
-		if err != nil {
-			return nil, err
-		}
-
-		return jwt.ParseRSAPublicKeyFromPEM([]byte(cert))
-	})
-
+	claims, err := verifyBearerToken(md, a.JWKSURL, a.APIIdentifier, a.JWTValidation)
 	if err != nil {
 		return nil, err
 	}
 
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
-	}
-
-	claims := token.Claims.(jwt.MapClaims)
-	checkAud := claims.VerifyAudience(a.APIIdentifier, false)
-	if !checkAud {
-		return nil, fmt.Errorf("invalid audience, expected %s, got %v", a.APIIdentifier, claims["aud"])
-	}
 	// Verify 'iss' claim
 	checkIss := claims.VerifyIssuer(a.Domain.String(), false)
 	if !checkIss {
@@ -103,41 +67,5 @@ func (a *Auth0M2M) AuthFunc(md metadata.MD) (*AuthResult, error) {
 	}
 
 	// auth0 puts the client's OAuth2 client ID in the sub field.
-	clientIdentifier := claims["sub"].(string)
-
-	scopes, _ := claims["scope"].(string)
-	permissions := strings.Split(scopes, " ")
-	return &AuthResult{
-		ClientIdentifier: clientIdentifier,
-		Timestamp:        time.Now(),
-		Permissions:      permissions,
-	}, nil
-}
-
-func (a *Auth0M2M) getPemCert(token *jwt.Token) (string, error) {
-	var cert string
-	resp, err := http.Get(a.JWKSURL.String())
-	if err != nil {
-		return cert, err
-	}
-	defer resp.Body.Close()
-
-	var jwks = auth0JWKEndpoint{}
-	err = json.NewDecoder(resp.Body).Decode(&jwks)
-
-	if err != nil {
-		return cert, err
-	}
-
-	for k := range jwks.Keys {
-		if token.Header["kid"] == jwks.Keys[k].Kid {
-			cert = "-----BEGIN CERTIFICATE-----\n" + jwks.Keys[k].X5c[0] + "\n-----END CERTIFICATE-----"
-		}
-	}
-
-	if cert == "" {
-		return cert, errors.New("unable to find appropriate keys")
-	}
-
-	return cert, nil
+	return authResultFromClaims(claims, a.Leeway)
 }
@@ -1 +1,57 @@
 package grpcauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuth0M2MAcceptsValidToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/.well-known/jwks.json"
+	auth0 := &Auth0M2M{
+		Domain:        issuer.url(t),
+		APIIdentifier: testAudience,
+		JWKSURL:       jwksURL,
+	}
+
+	authResult, err := auth0.AuthFunc(bearerMetadata(issuer.sign(t, issuer.claims())))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected %v, got %v", testClientName, authResult.ClientIdentifier)
+	}
+
+	wrongAudience := issuer.claims()
+	wrongAudience["aud"] = "https://other.example.com"
+	_, err = auth0.AuthFunc(bearerMetadata(issuer.sign(t, wrongAudience)))
+	if err == nil {
+		t.Fatalf("expected error with wrong audience")
+	}
+}
+
+func TestAuth0M2MTokenSourceRequestsAudience(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("audience") != testAudience || r.FormValue("grant_type") != "client_credentials" {
+			http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"words","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	token, err := Auth0M2MTokenSource(context.Background(), "client", "secret", server.URL, testAudience).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken != "words" {
+		t.Fatalf("expected access token from auth0, got %v", token.AccessToken)
+	}
+}
@@ -0,0 +1,214 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// authCacheEntry is a cached AuthResult, or the error the credentials were rejected with.
+type authCacheEntry struct {
+	authResult *AuthResult
+	err        error
+	expiry     time.Time
+}
+
+// AuthCache caches the AuthResults of successful authentications by a hash of the client's credentials, so a client
+// sending the same token on every request doesn't have its signature verified, or an identity provider called, every
+// time. Results are never cached past their Expiry or the exp claim of the token they came from.
+// Failures can be cached too, so a client retrying a bad token in a tight loop can't make the server fetch keys or
+// call an introspection endpoint on every attempt.
+// Only authenticators whose result depends on nothing but the credentials in MetadataKey should be cached: DPoP
+// proofs, signed requests and client certificates must be checked on every request.
+// Cached results aren't checked again, so a token revoked in a JWTValidation's Revocation, or at an introspection
+// endpoint, is accepted until its result expires from the cache. Keep TTL short if revocation has to take effect
+// quickly.
+// It is safe for concurrent use.
+type AuthCache struct {
+	// TTL is the longest a result is cached for.
+	TTL time.Duration
+	// FailureTTL is how long failures are cached for. Failures aren't cached when it is 0.
+	// It should be short, since failures caused by an identity provider being down are cached too.
+	FailureTTL time.Duration
+	// MetadataKey is the metadata field holding the credentials. It defaults to authorization.
+	MetadataKey string
+
+	mu        sync.Mutex
+	entries   map[string]*authCacheEntry
+	lastSweep time.Time
+}
+
+// AuthFunc returns an AuthFunc that calls authFunc when the client's credentials aren't cached.
+func (c *AuthCache) AuthFunc(authFunc AuthFunc) AuthFunc {
+	cached := c.ContextAuthFunc(func(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+		return authFunc(md)
+	})
+
+	return func(md metadata.MD) (*AuthResult, error) {
+		return cached(context.Background(), md)
+	}
+}
+
+// ContextAuthFunc returns a ContextAuthFunc that calls authFunc when the client's credentials aren't cached.
+func (c *AuthCache) ContextAuthFunc(authFunc ContextAuthFunc) ContextAuthFunc {
+	return func(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+		key, ok := c.key(md)
+		if !ok {
+			return authFunc(ctx, md)
+		}
+
+		if entry, ok := c.cached(key); ok {
+			return entry.authResult, entry.err
+		}
+
+		authResult, err := authFunc(ctx, md)
+		now := time.Now()
+		if err != nil {
+			// Requests that were cancelled say nothing about the credentials.
+			if c.FailureTTL > 0 && ctx.Err() == nil {
+				c.store(key, &authCacheEntry{err: err, expiry: now.Add(c.FailureTTL)}, now)
+			}
+			return nil, err
+		}
+
+		expiry := now.Add(c.TTL)
+		if exp, ok := authResult.expiry(); ok && exp.Before(expiry) {
+			expiry = exp
+		}
+
+		if now.Before(expiry) {
+			c.store(key, &authCacheEntry{authResult: authResult.clone(), expiry: expiry}, now)
+		}
+
+		return authResult, nil
+	}
+}
+
+// key returns a hash of the client's credentials, and false if it didn't send any.
+func (c *AuthCache) key(md metadata.MD) (string, bool) {
+	return credentialsKey(md, c.MetadataKey)
+}
+
+// credentialsKey returns a hash of the credentials in metadataKey, or authorization if it is empty, and false if
+// there aren't any.
+func credentialsKey(md metadata.MD, metadataKey string) (string, bool) {
+	if metadataKey == "" {
+		metadataKey = defaultMetadataKey
+	}
+
+	values := md.Get(metadataKey)
+	if len(values) == 0 {
+		return "", false
+	}
+
+	// Credentials can't contain newlines, so joining them can't make two sets of values look the same.
+	hash := sha256.Sum256([]byte(strings.Join(values, "\n")))
+	return hex.EncodeToString(hash[:]), true
+}
+
+// cached returns the cached entry for key, with a copy of its AuthResult that handlers can't change the cache with.
+func (c *AuthCache) cached(key string) (*authCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !time.Now().Before(entry.expiry) {
+		return nil, false
+	}
+
+	if entry.err != nil {
+		return entry, true
+	}
+
+	authResult := entry.authResult.clone()
+	authResult.Timestamp = time.Now()
+	return &authCacheEntry{authResult: authResult, expiry: entry.expiry}, true
+}
+
+func (c *AuthCache) store(key string, entry *authCacheEntry, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+	c.entries[key] = entry
+}
+
+// sweep forgets entries that have expired, at most once per TTL or FailureTTL, whichever is longer.
+// It must be called with c.mu held.
+func (c *AuthCache) sweep(now time.Time) {
+	if c.entries == nil {
+		c.entries = map[string]*authCacheEntry{}
+	}
+
+	window := c.TTL
+	if c.FailureTTL > window {
+		window = c.FailureTTL
+	}
+
+	if now.Sub(c.lastSweep) < window {
+		return
+	}
+
+	c.lastSweep = now
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiry) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// clone returns a copy of r that shares no claims or permissions with it, so handlers can't change cached results.
+func (r *AuthResult) clone() *AuthResult {
+	clone := *r
+	if r.Permissions != nil {
+		clone.Permissions = append([]string(nil), r.Permissions...)
+	}
+
+	if r.Claims != nil {
+		clone.Claims = cloneValue(r.Claims).(map[string]interface{})
+	}
+
+	return &clone
+}
+
+// cloneValue copies the maps and slices in a value decoded from JSON.
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		clone := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			clone[key] = cloneValue(value)
+		}
+		return clone
+	case []interface{}:
+		clone := make([]interface{}, len(v))
+		for i, value := range v {
+			clone[i] = cloneValue(value)
+		}
+		return clone
+	case []string:
+		return append([]string(nil), v...)
+	}
+
+	return value
+}
+
+// timeClaim returns the time in a NumericDate claim like exp or iat, and false if there isn't one.
+func timeClaim(claims map[string]interface{}, name string) (time.Time, bool) {
+	switch seconds := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(seconds), 0), true
+	case int64:
+		return time.Unix(seconds, 0), true
+	case json.Number:
+		n, err := seconds.Int64()
+		return time.Unix(n, 0), err == nil
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,135 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAuthCache(t *testing.T) {
+	calls := 0
+	exp := float64(time.Now().Add(time.Hour).Unix())
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		calls++
+		if len(md.Get("authorization")) > 0 && md.Get("authorization")[0] == "bearer expiring" {
+			return &AuthResult{ClientIdentifier: testClientName, Claims: map[string]interface{}{"exp": float64(time.Now().Unix())}}, nil
+		}
+		return &AuthResult{ClientIdentifier: testClientName, Claims: map[string]interface{}{"exp": exp}}, nil
+	}
+
+	cache := &AuthCache{TTL: time.Minute}
+	cached := cache.AuthFunc(authFunc)
+	for i := 0; i < 3; i++ {
+		authResult, err := cached(metadata.Pairs("authorization", "bearer words"))
+		if err != nil || authResult.ClientIdentifier != testClientName {
+			t.Fatalf("unexpected result %v %v", authResult, err)
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected the token to be validated once, got %d calls", calls)
+	}
+
+	cached(metadata.Pairs("authorization", "bearer other"))
+	if calls != 2 {
+		t.Fatalf("expected other tokens to be validated, got %d calls", calls)
+	}
+
+	cached(metadata.Pairs("authorization", "bearer expiring"))
+	cached(metadata.Pairs("authorization", "bearer expiring"))
+	if calls != 4 {
+		t.Fatalf("expected results not to be cached past their exp claim, got %d calls", calls)
+	}
+
+	cached(metadata.MD{})
+	if calls != 5 {
+		t.Fatalf("expected requests without credentials not to be cached, got %d calls", calls)
+	}
+}
+
+func TestAuthCacheDoesntCacheFailures(t *testing.T) {
+	calls := 0
+	cached := (&AuthCache{TTL: time.Minute}).AuthFunc(func(md metadata.MD) (*AuthResult, error) {
+		calls++
+		return nil, ErrCredentialNotFound
+	})
+
+	cached(metadata.Pairs("authorization", "bearer words"))
+	_, err := cached(metadata.Pairs("authorization", "bearer words"))
+	if err == nil || calls != 2 {
+		t.Fatalf("expected failures not to be cached, got %v after %d calls", err, calls)
+	}
+}
+
+func TestAuthCacheCachesFailures(t *testing.T) {
+	calls := 0
+	cache := &AuthCache{TTL: time.Minute, FailureTTL: time.Minute}
+	cached := cache.ContextAuthFunc(func(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+		calls++
+		return nil, ErrCredentialNotFound
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := cached(ctx, metadata.Pairs("authorization", "bearer bad"))
+		if err != ErrCredentialNotFound {
+			t.Fatalf("expected the cached failure, got %v", err)
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected the bad token to be checked once, got %d calls", calls)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	cached(cancelled, metadata.Pairs("authorization", "bearer cancelled"))
+	cached(ctx, metadata.Pairs("authorization", "bearer cancelled"))
+	if calls != 3 {
+		t.Fatalf("expected failures from cancelled requests not to be cached, got %d calls", calls)
+	}
+}
+
+func TestAuthCacheCopiesResults(t *testing.T) {
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		return &AuthResult{
+			ClientIdentifier: testClientName,
+			Permissions:      []string{targetMethodName},
+			Claims:           map[string]interface{}{"roles": []interface{}{"reader"}},
+		}, nil
+	}
+
+	cached := (&AuthCache{TTL: time.Minute}).AuthFunc(authFunc)
+	for i := 0; i < 2; i++ {
+		authResult, err := cached(metadata.Pairs("authorization", "bearer words"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if authResult.Permissions[0] != targetMethodName || authResult.Claims["roles"].([]interface{})[0] != "reader" {
+			t.Fatalf("expected handlers not to change cached results, got %v", authResult)
+		}
+
+		authResult.Permissions[0] = "/server.ServiceName/OtherMethod"
+		authResult.Claims["roles"].([]interface{})[0] = "admin"
+		authResult.Claims["tenant_id"] = "other"
+	}
+}
+
+func TestAuthCacheSweepsOncePerTTL(t *testing.T) {
+	cache := &AuthCache{TTL: time.Minute}
+	now := time.Now()
+	cache.store("words", &authCacheEntry{authResult: &AuthResult{}, expiry: now.Add(time.Second)}, now)
+
+	cache.store("other", &authCacheEntry{authResult: &AuthResult{}, expiry: now.Add(time.Minute)}, now.Add(2*time.Second))
+	if _, ok := cache.entries["words"]; !ok {
+		t.Fatalf("expected expired entries to be kept until the next sweep")
+	}
+
+	cache.store("other", &authCacheEntry{authResult: &AuthResult{}, expiry: now.Add(time.Minute)}, now.Add(time.Minute))
+	if _, ok := cache.entries["words"]; ok {
+		t.Fatalf("expected expired entries to be forgotten once TTL has passed")
+	}
+}
@@ -2,11 +2,14 @@ package grpcauth
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
-	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -15,15 +18,27 @@ import (
 
 const (
 	authKeyName = "auth"
+
+	// defaultMetadataKey is the metadata field clients send their credentials in by default.
+	defaultMetadataKey = "authorization"
 )
 
 var (
-	errUnauthorized = status.Errorf(codes.Unauthenticated, UnauthenticatedError)
+	errUnauthorized              = withErrorInfo(status.New(codes.Unauthenticated, UnauthenticatedError), ReasonUnauthenticated, nil).Err()
+	errAuthenticationUnavailable = status.Error(codes.Unavailable, ErrAuthenticationUnavailable.Error())
 )
 
 var (
 	// ErrUnauthenticatedContext is returned from GetAuthResult when it is called with an unauthenticated context.
 	ErrUnauthenticatedContext = fmt.Errorf("cannot get AuthResult from unauthenticated context")
+
+	// ErrAuthorizationUnavailable can be wrapped by CheckedAuthorizationFuncs when the policy couldn't be evaluated
+	// because a dependency is down, so clients get Unavailable and know to retry.
+	ErrAuthorizationUnavailable = fmt.Errorf("authorization is unavailable")
+
+	// ErrAuthenticationUnavailable is wrapped by authenticators when credentials couldn't be checked because an
+	// identity provider is down, so clients get Unavailable and know to retry, and CircuitBreakers know to trip.
+	ErrAuthenticationUnavailable = fmt.Errorf("authentication is unavailable")
 )
 
 // GetAuthResult is a helper function that returns the AuthResult attached to a context and returns ErrUnauthenticatedContext if none exists.
@@ -34,16 +49,39 @@ func GetAuthResult(ctx context.Context) (*AuthResult, error) {
 		return nil, ErrUnauthenticatedContext
 	}
 
-	// Callers cannot put the auth value into the context themselves, so it's safe to panic here.
+	// Callers can only put an *AuthResult into the context themselves, so it's safe to panic here.
 	return v.(*AuthResult), nil
 }
 
+// MustGetAuthResult returns the AuthResult attached to a context, and panics if none exists.
+// It is meant for handlers on methods that can't be called without authenticating.
+func MustGetAuthResult(ctx context.Context) *AuthResult {
+	authResult, err := GetAuthResult(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	return authResult
+}
+
+// ContextWithAuthResult returns a copy of ctx carrying authResult, as if an Authority had authenticated the client.
+// It is meant for unit testing handlers that call GetAuthResult without going through the interceptors, and
+// shouldn't be used to authenticate clients in production.
+func ContextWithAuthResult(ctx context.Context, authResult *AuthResult) context.Context {
+	return context.WithValue(ctx, authContextKey(authKeyName), authResult)
+}
+
 // AuthFunc validates a gRPC request's metadata based on some arbitrary criteria.
 // It's meant to allow integration with a custom auth scheme.
 // Implementations should return error if authentication failed.
 // See auth0.go and cognito.go.
 type AuthFunc func(md metadata.MD) (*AuthResult, error)
 
+// ContextAuthFunc is an AuthFunc that can also see the request's context.
+// It gives authenticators access to the gRPC peer, such as the client's TLS certificate, and not just the metadata.
+// See mtls.go.
+type ContextAuthFunc func(ctx context.Context, md metadata.MD) (*AuthResult, error)
+
 // authContextKey is a key for values injected into the context by an Authority's UnaryInterceptor.
 type authContextKey string
 
@@ -51,10 +89,37 @@ type authContextKey string
 // AuthFuncs should put an identifier, timestamp when the client authenticated
 // and its permissions when returning an AuthResult.
 // When authenticating with OAuth2 providers, Permissions should be a list of the client's scopes.
+// Authenticators that validate tokens put the token's claims in Claims, so handlers can read claims like a
+// tenant ID or email without parsing the token again.
 type AuthResult struct {
 	ClientIdentifier string
 	Timestamp        time.Time
 	Permissions      []string
+	Claims           map[string]interface{}
+	// Expiry is when the client's credentials expire. It is zero if they don't, or the AuthFunc doesn't know.
+	// Calls are rejected, and streams are closed, with Unauthenticated once it passes.
+	Expiry time.Time
+}
+
+// expiry returns when the client's credentials expire from Expiry, or the exp claim if it isn't set, and false if
+// it isn't known.
+func (r *AuthResult) expiry() (time.Time, bool) {
+	if !r.Expiry.IsZero() {
+		return r.Expiry, true
+	}
+	return timeClaim(r.Claims, "exp")
+}
+
+// Claim returns the named claim, and false if the client's credentials didn't have it.
+func (r *AuthResult) Claim(name string) (interface{}, bool) {
+	value, ok := r.Claims[name]
+	return value, ok
+}
+
+// StringClaim returns the named claim if it is a string, and an empty string otherwise.
+func (r *AuthResult) StringClaim(name string) string {
+	value, _ := r.Claims[name].(string)
+	return value
 }
 
 // Authority allows a gRPC server to determine who is sending a request and check with an AuthFunc and an
@@ -76,83 +141,663 @@ type Authority interface {
 // By default, the Authority will take the method names as permission strings in the AuthResult.
 // See cognito.go for an example.
 // If you wish to use the default permission behaviour, pass a nil permissionFunc.
-func NewAuthority(authFunc AuthFunc, permissionFunc PermissionFunc) Authority {
+// Options can be passed to change the Authority's default behaviour.
+func NewAuthority(authFunc AuthFunc, permissionFunc PermissionFunc, options ...Option) Authority {
 	if authFunc == nil {
 		panic("authFunc cannot be nil")
 	}
 
+	return newAuthority(&authority{IsAuthenticated: authFunc}, permissionFunc, options)
+}
+
+// NewContextAuthority returns an Authority provisioned with a ContextAuthFunc and optionally a permissionFunc.
+// It behaves like NewAuthority, but the authFunc can see the request's context as well as its metadata.
+// Authenticators that don't use metadata, like MutualTLS, should be used with WithoutMetadataKey.
+func NewContextAuthority(authFunc ContextAuthFunc, permissionFunc PermissionFunc, options ...Option) Authority {
+	if authFunc == nil {
+		panic("authFunc cannot be nil")
+	}
+
+	return newAuthority(&authority{IsAuthenticatedContext: authFunc}, permissionFunc, options)
+}
+
+func newAuthority(a *authority, permissionFunc PermissionFunc, options []Option) *authority {
 	if permissionFunc == nil {
 		permissionFunc = defaultHasPermissions
 	}
 
-	return &authority{
-		IsAuthenticated: authFunc,
-		HasPermissions:  permissionFunc,
+	a.HasPermissions = permissionFunc
+	for _, option := range options {
+		option(a)
+	}
+	return a
+}
+
+// Option configures optional behaviour on an Authority created with NewAuthority.
+type Option func(a *authority)
+
+// WithMetadataKey sets the metadata field the Authority requires clients to send their credentials in.
+// It defaults to authorization, but some proxies like GCP's Identity-Aware Proxy assert identity in another field.
+func WithMetadataKey(key string) Option {
+	return func(a *authority) {
+		a.MetadataKey = strings.ToLower(key)
+	}
+}
+
+// WithoutMetadataKey stops the Authority from requiring credentials in the metadata.
+// It is meant for ContextAuthFuncs that authenticate clients some other way, like with their TLS certificate.
+func WithoutMetadataKey() Option {
+	return func(a *authority) {
+		a.SkipMetadataKey = true
+	}
+}
+
+// WithAuthorizationFunc makes the Authority authorize requests with authorizationFunc instead of its PermissionFunc.
+func WithAuthorizationFunc(authorizationFunc AuthorizationFunc) Option {
+	return WithCheckedAuthorizationFunc(func(ctx context.Context, authResult *AuthResult, methodName string) (bool, error) {
+		return authorizationFunc(ctx, authResult, methodName), nil
+	})
+}
+
+// WithCheckedAuthorizationFunc makes the Authority authorize requests with authorizationFunc instead of its
+// PermissionFunc, returning Internal or Unavailable when it fails instead of PermissionDenied.
+func WithCheckedAuthorizationFunc(authorizationFunc CheckedAuthorizationFunc) Option {
+	return func(a *authority) {
+		a.Authorize = authorizationFunc
+	}
+}
+
+// WithAuthResultPermissionFunc makes the Authority authorize clients with permissionFunc instead of its
+// PermissionFunc.
+func WithAuthResultPermissionFunc(permissionFunc AuthResultPermissionFunc) Option {
+	return WithAuthorizationFunc(func(ctx context.Context, authResult *AuthResult, methodName string) bool {
+		return permissionFunc(authResult, methodName)
+	})
+}
+
+// WithRequestAuthorizationFunc makes the Authority check every request message with requestAuthorizationFunc after
+// the client is authorized to call the method.
+func WithRequestAuthorizationFunc(requestAuthorizationFunc RequestAuthorizationFunc) Option {
+	return func(a *authority) {
+		a.AuthorizeRequest = requestAuthorizationFunc
+	}
+}
+
+// WithUnauthenticatedMethods lets clients call methods without authenticating, like health checks, reflection or a
+// public Ping. Methods can be full gRPC method names or patterns like `/grpc.health.v1.Health/*`, matched with
+// MatchMethod. Handlers for these methods won't have an AuthResult in their context.
+func WithUnauthenticatedMethods(methods ...string) Option {
+	return func(a *authority) {
+		a.UnauthenticatedMethods = append(a.UnauthenticatedMethods, methods...)
+	}
+}
+
+// WithMetadataLimits makes the Authority reject requests with more than maxValues values for a metadata key, or a
+// metadata value longer than maxValueSize bytes, before calling its AuthFunc.
+// This stops oversized credentials from wasting CPU on parsing and quota on calls to identity providers.
+// A limit of 0 leaves that dimension unlimited.
+func WithMetadataLimits(maxValues, maxValueSize int) Option {
+	return func(a *authority) {
+		a.MaxMetadataValues = maxValues
+		a.MaxMetadataValueSize = maxValueSize
+	}
+}
+
+// ShadowDenialFunc is called by an Authority in shadow mode with the error a request would have been rejected with.
+// ctx has the client's AuthResult if it authenticated.
+type ShadowDenialFunc func(ctx context.Context, methodName string, err error)
+
+// WithShadowMode makes the Authority call onDenied instead of rejecting requests it would deny, and invoke the
+// handler anyway. It lets grpcauth be rolled out to a production service without breaking clients, by logging or
+// counting the requests that would fail before enforcing.
+func WithShadowMode(onDenied ShadowDenialFunc) Option {
+	return func(a *authority) {
+		a.OnShadowDenial = onDenied
+	}
+}
+
+// PolicyMismatchFunc is called when a candidate PermissionFunc makes a different decision to the Authority's.
+// active is the decision the Authority enforced and candidate is the decision the candidate would have made.
+type PolicyMismatchFunc func(ctx context.Context, authResult *AuthResult, methodName string, active, candidate bool)
+
+// WithCandidatePermissionFunc makes the Authority also check every authenticated client with candidate and call
+// onMismatch when it disagrees with the decision being enforced. It lets a policy migration be validated against
+// live traffic before cutover. The candidate's decisions are never enforced.
+func WithCandidatePermissionFunc(candidate PermissionFunc, onMismatch PolicyMismatchFunc) Option {
+	return func(a *authority) {
+		a.CandidatePermissions = candidate
+		a.OnPolicyMismatch = onMismatch
+	}
+}
+
+// WithKillSwitch makes the Authority deny requests with Unavailable while killSwitch is enabled, before
+// authenticating them. Methods skipped with WithUnauthenticatedMethods are denied too unless killSwitch allows them.
+func WithKillSwitch(killSwitch *KillSwitch) Option {
+	return func(a *authority) {
+		a.KillSwitch = killSwitch
+	}
+}
+
+// WithLogger makes the Authority report every attempt to authenticate and authorize a request to logger, including
+// why clients failed to authenticate, which isn't sent to them.
+// It can be passed more than once to report to several Loggers, such as a log and OTelMetrics.
+func WithLogger(logger Logger) Option {
+	return func(a *authority) {
+		a.Loggers = append(a.Loggers, logger)
+	}
+}
+
+// WithRateLimiter makes the Authority limit how many requests each client can make with limiter, after it
+// authenticates and before it is authorized. Clients over their limit get ResourceExhausted with RetryInfo.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(a *authority) {
+		a.RateLimiter = limiter
+	}
+}
+
+// WithQuota makes the Authority count every call a client is authorized to make against quota, and reject calls
+// from clients over it with ResourceExhausted.
+func WithQuota(quota Quota) Option {
+	return func(a *authority) {
+		a.Quota = quota
+	}
+}
+
+// WithStreamLimiter makes the Authority limit how many streams each client can have open at once with limiter,
+// after it is authorized. Clients over their limit get ResourceExhausted.
+func WithStreamLimiter(limiter *StreamLimiter) Option {
+	return func(a *authority) {
+		a.StreamLimiter = limiter
+	}
+}
+
+// WithBruteForceProtection makes the Authority block clients that repeatedly fail to authenticate with protection.
+func WithBruteForceProtection(protection *BruteForceProtection) Option {
+	return func(a *authority) {
+		a.BruteForce = protection
+	}
+}
+
+// WithDenylist makes the Authority reject peers and clients on denylist, and report failures to it so peers that
+// keep failing to authenticate and clients that keep being denied can be added automatically.
+func WithDenylist(denylist *Denylist) Option {
+	return func(a *authority) {
+		a.Denylist = denylist
+	}
+}
+
+// WithClientNetworks makes the Authority reject clients calling from outside the networks clientNetworks returns
+// for them with PermissionDenied, so stolen credentials can't be used from unexpected networks.
+// It checks the gRPC peer's address, so servers behind a proxy see the proxy's address.
+func WithClientNetworks(clientNetworks ClientNetworksFunc) Option {
+	return func(a *authority) {
+		a.ClientNetworks = clientNetworks
+	}
+}
+
+// WithPseudonymizer makes the Authority replace client identifiers and string claims with pseudonyms from
+// pseudonymizer in the events it gives Loggers and the attributes it adds to spans, so logs, metrics and audit
+// events don't hold personal data but events from the same client can still be correlated.
+// Handlers still get the client's real AuthResult.
+func WithPseudonymizer(pseudonymizer Pseudonymizer) Option {
+	return func(a *authority) {
+		a.Pseudonymizer = pseudonymizer
+	}
+}
+
+// WithErrorVerbosity sets how much the Authority tells clients about why they were denied permission.
+// Loggers still get the full error, so servers can use MinimalErrors or OpaqueErrors without losing detail.
+func WithErrorVerbosity(verbosity ErrorVerbosity) Option {
+	return func(a *authority) {
+		a.ErrorVerbosity = verbosity
+	}
+}
+
+// WithHiddenMethods makes the Authority reject clients that fail to authenticate or are denied permission to call
+// one of methods with the error gRPC returns for methods that don't exist, so probing clients can't find admin RPCs.
+// code must be codes.Unimplemented, which servers return for unknown methods, or codes.NotFound.
+// Methods can be patterns like `/server.AdminService/*`. Loggers still get the real error.
+func WithHiddenMethods(code codes.Code, methods ...string) Option {
+	if code != codes.Unimplemented && code != codes.NotFound {
+		panic("hidden methods must be Unimplemented or NotFound")
+	}
+
+	return func(a *authority) {
+		a.HiddenMethods = methods
+		a.HiddenMethodCode = code
+	}
+}
+
+// WithStreamReauthentication makes the Authority authenticate and authorize streams again with the metadata they
+// were opened with every interval, or every messages messages the client sends, so long-lived streams are cut off
+// with Unauthenticated when their credentials expire or are revoked, and PermissionDenied when their permissions are.
+// Streams are checked when the handler sends or receives a message. An interval or messages of 0 disables that
+// trigger.
+func WithStreamReauthentication(interval time.Duration, messages int) Option {
+	return func(a *authority) {
+		a.ReauthInterval = interval
+		a.ReauthMessages = messages
+	}
+}
+
+// WithMaxTokenAge makes the Authority reject tokens issued more than maxAge ago by their iat claim, however far
+// off their exp claim is, so a stolen long-lived token is only useful for maxAge. Tokens without an iat claim are
+// rejected, and streams are closed when their token reaches maxAge.
+func WithMaxTokenAge(maxAge time.Duration) Option {
+	return func(a *authority) {
+		a.MaxTokenAge = maxAge
+	}
+}
+
+// WithChallenge makes the Authority send challenge in the www-authenticate trailer of calls from clients that fail
+// to authenticate, and HTTPMiddleware send it as WWW-Authenticate headers.
+func WithChallenge(challenge *Challenge) Option {
+	return func(a *authority) {
+		a.Challenge = challenge
 	}
 }
 
 type authority struct {
-	IsAuthenticated func(md metadata.MD) (*AuthResult, error)
-	HasPermissions  func(permissions []string, methodName string) bool
+	IsAuthenticated        func(md metadata.MD) (*AuthResult, error)
+	IsAuthenticatedContext func(ctx context.Context, md metadata.MD) (*AuthResult, error)
+	HasPermissions         func(permissions []string, methodName string) bool
+	Authorize              func(ctx context.Context, authResult *AuthResult, methodName string) (bool, error)
+	AuthorizeRequest       func(ctx context.Context, authResult *AuthResult, methodName string, req interface{}) bool
+	MetadataKey            string
+	SkipMetadataKey        bool
+	UnauthenticatedMethods []string
+	MaxMetadataValues      int
+	MaxMetadataValueSize   int
+	OnShadowDenial         ShadowDenialFunc
+	CandidatePermissions   PermissionFunc
+	OnPolicyMismatch       PolicyMismatchFunc
+	KillSwitch             *KillSwitch
+	Principal              func(ctx context.Context, authResult *AuthResult) (interface{}, error)
+	Loggers                []Logger
+	Tracer                 trace.Tracer
+	Pseudonymizer          Pseudonymizer
+	RateLimiter            *RateLimiter
+	BruteForce             *BruteForceProtection
+	Denylist               *Denylist
+	ClientNetworks         ClientNetworksFunc
+	ErrorVerbosity         ErrorVerbosity
+	HiddenMethods          []string
+	HiddenMethodCode       codes.Code
+	Challenge              *Challenge
+	ReauthInterval         time.Duration
+	ReauthMessages         int
+	MaxTokenAge            time.Duration
+	StreamLimiter          *StreamLimiter
+	Quota                  Quota
 }
 
 // UnaryServerInterceptor ensures a request is authenticated based on its metadata before invoking the server handler.
 func (a *authority) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	ctx, err := a.authenticateAndAuthorizeContext(ctx, info.FullMethod)
+	ctx, err := a.authenticateMethod(ctx, info.FullMethod)
 	if err != nil {
 		return nil, err
 	}
 
+	if a.isUnauthenticatedMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	err = a.authorizeRequest(ctx, info.FullMethod, req)
+	if err == nil {
+		err = a.useQuota(ctx, info.FullMethod)
+	}
+
+	if err != nil && !a.shadowDenied(ctx, info.FullMethod, err) {
+		return nil, err
+	}
+
 	return handler(ctx, req)
 }
 
 // StreamServerInterceptor authenticates stream requests.
+// Streams are closed with Unauthenticated when the client's credentials expire.
 func (a *authority) StreamServerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-	ctx, err := a.authenticateAndAuthorizeContext(stream.Context(), info.FullMethod)
+	if a.isUnauthenticatedMethod(info.FullMethod) {
+		_, err := a.authenticateMethod(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+
+	// The handler's context is cancelled if the stream is closed because its credentials expired.
+	streamCtx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	ctx, err := a.authenticateMethod(streamCtx, info.FullMethod)
 	if err != nil {
 		return err
 	}
 
-	wrapped := grpc_middleware.WrapServerStream(stream)
-	wrapped.WrappedContext = ctx
-	return handler(srv, wrapped)
+	authResult, _ := GetAuthResult(ctx)
+	err = a.StreamLimiter.acquire(authResult)
+	if err == nil {
+		defer a.StreamLimiter.release(authResult)
+	} else if !a.shadowDenied(ctx, info.FullMethod, err) {
+		return err
+	}
+
+	err = a.useQuota(ctx, info.FullMethod)
+	if err != nil && !a.shadowDenied(ctx, info.FullMethod, err) {
+		return err
+	}
+
+	if a.AuthorizeRequest == nil && a.ReauthInterval == 0 && a.ReauthMessages == 0 && !a.expires(authResult) {
+		wrapped := middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+
+	authorizing := newAuthorizingServerStream(a, stream, streamCtx, cancel, ctx, info.FullMethod)
+	defer authorizing.stop()
+	err = handler(srv, authorizing)
+	if closedErr := authorizing.closed(); closedErr != nil {
+		return closedErr
+	}
+
+	return err
+}
+
+// MiddlewareAuthFunc adapts an Authority to go-grpc-middleware v2's auth.AuthFunc, so it can be composed with the
+// interceptors from github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth.
+// The method being called is taken from the context with grpc.Method.
+// RequestAuthorizationFuncs need the request message, so they only run with the Authority's own interceptors.
+func MiddlewareAuthFunc(a Authority) auth.AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		methodName, _ := grpc.Method(ctx)
+		return authenticateWith(a, ctx, methodName)
+	}
+}
+
+// authenticateMethod checks the Authority's KillSwitch, then returns a context with the client's AuthResult if it
+// may call methodName. Methods that don't require authentication get ctx back.
+// Clients that fail to authenticate get the Authority's Challenge in their trailers.
+func (a *authority) authenticateMethod(ctx context.Context, methodName string) (context.Context, error) {
+	if a.KillSwitch != nil {
+		err := a.KillSwitch.check(methodName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if a.isUnauthenticatedMethod(methodName) {
+		return ctx, nil
+	}
+
+	// Authorization checks and the handler share the request's relationship checks.
+	ctx = withRelationshipCache(ctx)
+	authCtx, err := a.authenticateAndAuthorizeContext(ctx, methodName)
+	if status.Code(err) == codes.Unauthenticated {
+		a.Challenge.setTrailer(ctx)
+	}
+
+	return authCtx, err
 }
 
+// authenticateAndAuthorizeContext returns a context with the client's AuthResult if it may call methodName.
+// In shadow mode, it reports denials and returns the context as far as it got instead of an error.
 func (a *authority) authenticateAndAuthorizeContext(ctx context.Context, methodName string) (context.Context, error) {
+	start := time.Now()
+	ctx, span := a.startSpan(ctx, methodName)
+	authCtx, err := a.authenticateAndAuthorize(ctx, methodName)
+	if authCtx == nil {
+		authCtx = ctx
+	}
+
+	err = a.logAuth(authCtx, methodName, start, err)
+	authCtx = span.end(authCtx, err)
+	if err == nil {
+		return authCtx, nil
+	}
+
+	if !a.shadowDenied(authCtx, methodName, err) {
+		return nil, err
+	}
+
+	return authCtx, nil
+}
+
+// authenticateAndAuthorize returns a context with the client's AuthResult, along with an error if it isn't allowed
+// to call methodName. The context is nil if the client didn't authenticate.
+// Authentication failures are returned as an authenticationError with the reason, which logAuth replaces with
+// errUnauthorized.
+func (a *authority) authenticateAndAuthorize(ctx context.Context, methodName string) (context.Context, error) {
+	// Formatting the peer's address allocates, so only do it once, and only if a check needs it.
+	var peerIP string
+	if a.Denylist != nil || a.BruteForce != nil {
+		peerIP = PeerIP(ctx)
+	}
+
+	if err := a.Denylist.check(DenylistPeer, peerIP); err != nil {
+		return nil, err
+	}
+
+	bruteForceKey := a.BruteForce.key(ctx, peerIP)
+	if err := a.BruteForce.check(bruteForceKey); err != nil {
+		return nil, err
+	}
+
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, errUnauthorized
+		return nil, &authenticationError{cause: fmt.Errorf("no metadata in context")}
+	}
+
+	if !a.withinMetadataLimits(md) {
+		return nil, &authenticationError{cause: fmt.Errorf("metadata exceeds limits")}
 	}
 
-	if !validateIncomingMetadata(md) {
-		return nil, errUnauthorized
+	if !a.SkipMetadataKey && !validateIncomingMetadata(md, a.metadataKey()) {
+		return nil, &authenticationError{cause: fmt.Errorf("expected credentials in '%s' metadata field", a.metadataKey())}
 	}
 
-	authResult, err := a.IsAuthenticated(md)
+	authResult, err := a.authenticate(ctx, md)
+	if err == nil {
+		err = a.checkExpiry(authResult, time.Now())
+	}
 	if err != nil {
-		return nil, errUnauthorized
+		// Clients shouldn't be locked out because an identity provider is down.
+		if !errors.Is(err, ErrAuthenticationUnavailable) {
+			a.BruteForce.recordFailure(bruteForceKey)
+			a.Denylist.recordFailure(DenylistPeer, peerIP)
+		}
+		return nil, &authenticationError{cause: err}
 	}
+	a.BruteForce.recordSuccess(bruteForceKey)
 
-	if !a.HasPermissions(authResult.Permissions, methodName) {
-		permissionDenied := &PermissionDeniedError{
-			ClientIdentifier:    authResult.ClientIdentifier,
-			PermissionRequested: methodName,
-			ClientPermissions:   authResult.Permissions,
+	// Insert auth result into the context so handlers can determine which client is performing an action.
+	ctx = ContextWithAuthResult(ctx, authResult)
+	if err := a.Denylist.check(DenylistClient, authResult.ClientIdentifier); err != nil {
+		return ctx, err
+	}
+
+	if err := a.checkNetwork(ctx, authResult); err != nil {
+		return ctx, err
+	}
+
+	if a.Principal != nil {
+		principal, err := a.Principal(ctx, authResult)
+		if err != nil {
+			return nil, &authenticationError{cause: err}
 		}
 
-		b, _ := json.Marshal(permissionDenied)
-		permissionDeniedJSON := string(b)
-		return nil, status.Errorf(codes.PermissionDenied, permissionDeniedJSON)
+		ctx = context.WithValue(ctx, authContextKey(principalKeyName), principal)
+	}
+
+	if a.RateLimiter != nil {
+		err := a.RateLimiter.Allow(ctx, authResult)
+		if err != nil {
+			return ctx, err
+		}
+	}
+
+	authorized, err := a.authorize(ctx, authResult, methodName)
+	if err != nil {
+		return ctx, authorizationErrorStatus(err)
+	}
+
+	if !authorized {
+		a.Denylist.recordFailure(DenylistClient, authResult.ClientIdentifier)
+		return ctx, permissionDeniedStatus(authResult, methodName)
 	}
 
-	// Insert auth result into the context so handlers can determine which client is performing an action.
-	authKey := authContextKey(authKeyName)
-	ctx = context.WithValue(ctx, authKey, authResult)
 	return ctx, nil
 }
 
-func validateIncomingMetadata(md metadata.MD) bool {
-	if len(md.Get("authorization")) != 1 {
+// shadowDenied reports err to the Authority's ShadowDenialFunc and returns true if it is in shadow mode.
+func (a *authority) shadowDenied(ctx context.Context, methodName string, err error) bool {
+	if a.OnShadowDenial == nil {
+		return false
+	}
+
+	a.OnShadowDenial(ctx, methodName, err)
+	return true
+}
+
+// useQuota counts a call by the client in ctx against the Authority's Quota, if it has one. It is called once every
+// authorization check has passed, so calls the client isn't allowed to make don't use its quota.
+func (a *authority) useQuota(ctx context.Context, methodName string) error {
+	if a.Quota == nil {
+		return nil
+	}
+
+	authResult, err := GetAuthResult(ctx)
+	if err != nil {
+		// Clients that didn't authenticate are only served in shadow mode, which already reported them.
+		return nil
+	}
+
+	err = a.Quota.Use(ctx, authResult, methodName)
+	if err != nil {
+		return quotaErrorStatus(err)
+	}
+
+	return nil
+}
+
+// authorizeRequest checks a request message from an authenticated client with the Authority's
+// RequestAuthorizationFunc, if it has one.
+func (a *authority) authorizeRequest(ctx context.Context, methodName string, req interface{}) error {
+	if a.AuthorizeRequest == nil {
+		return nil
+	}
+
+	authResult, err := GetAuthResult(ctx)
+	if err != nil {
+		// Shadow mode already reported that the client didn't authenticate.
+		if a.OnShadowDenial != nil {
+			return nil
+		}
+
+		return errUnauthorized
+	}
+
+	if !a.AuthorizeRequest(ctx, authResult, methodName, req) {
+		err, _ := a.ErrorVerbosity.clientError(permissionDeniedStatus(authResult, methodName), methodName)
+		return a.hideMethod(err, methodName)
+	}
+
+	return nil
+}
+
+// permissionDeniedStatus returns a PermissionDenied status with a PermissionDeniedError to help the client debug.
+func permissionDeniedStatus(authResult *AuthResult, methodName string) error {
+	return denials.permissionDenied(authResult, methodName)
+}
+
+// authenticate calls the Authority's ContextAuthFunc if it has one, and its AuthFunc otherwise.
+func (a *authority) authenticate(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+	if a.IsAuthenticatedContext != nil {
+		return a.IsAuthenticatedContext(ctx, md)
+	}
+
+	return a.IsAuthenticated(md)
+}
+
+// authorize calls the Authority's AuthorizationFunc if it has one, and its PermissionFunc otherwise.
+// The decision is compared with the candidate PermissionFunc's if the Authority has one.
+func (a *authority) authorize(ctx context.Context, authResult *AuthResult, methodName string) (bool, error) {
+	var authorized bool
+	if a.Authorize != nil {
+		var err error
+		authorized, err = a.Authorize(ctx, authResult, methodName)
+		if err != nil {
+			return false, err
+		}
+	} else {
+		authorized = a.HasPermissions(authResult.Permissions, methodName)
+	}
+
+	if a.CandidatePermissions != nil {
+		candidate := a.CandidatePermissions(authResult.Permissions, methodName)
+		if candidate != authorized && a.OnPolicyMismatch != nil {
+			a.OnPolicyMismatch(ctx, authResult, methodName, authorized, candidate)
+		}
+	}
+
+	return authorized, nil
+}
+
+// authorizationErrorStatus converts an error from a CheckedAuthorizationFunc to a gRPC status.
+// The error's details aren't sent to the client since they may describe the server's internals.
+func authorizationErrorStatus(err error) error {
+	if st, ok := status.FromError(err); ok {
+		return st.Err()
+	}
+
+	if errors.Is(err, ErrAuthorizationUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.Unavailable, ErrAuthorizationUnavailable.Error())
+	}
+
+	return status.Error(codes.Internal, "authorization failed")
+}
+
+// isUnauthenticatedMethod reports whether clients can call methodName without authenticating.
+func (a *authority) isUnauthenticatedMethod(methodName string) bool {
+	for _, pattern := range a.UnauthenticatedMethods {
+		if MatchMethod(pattern, methodName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withinMetadataLimits reports whether md is within the Authority's metadata limits.
+func (a *authority) withinMetadataLimits(md metadata.MD) bool {
+	for _, values := range md {
+		if a.MaxMetadataValues > 0 && len(values) > a.MaxMetadataValues {
+			return false
+		}
+
+		if a.MaxMetadataValueSize <= 0 {
+			continue
+		}
+
+		for _, value := range values {
+			if len(value) > a.MaxMetadataValueSize {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// metadataKey returns the metadata field that must carry the client's credentials.
+func (a *authority) metadataKey() string {
+	if a.MetadataKey == "" {
+		return defaultMetadataKey
+	}
+
+	return a.MetadataKey
+}
+
+func validateIncomingMetadata(md metadata.MD, key string) bool {
+	if len(md.Get(key)) != 1 {
 		return false
 	}
 
@@ -0,0 +1,98 @@
+package grpcauth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2/clientcredentials"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/oauth"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// azureADDefaultAuthorityHost is the Microsoft identity platform's authority host in the public cloud.
+	azureADDefaultAuthorityHost = "https://login.microsoftonline.com"
+
+	// azureADTokenVersion is the only access token version AzureAD accepts.
+	azureADTokenVersion = "2.0"
+)
+
+// AzureADClientCredentials returns a grpc.DialOption that uses the client credentials flow with Azure AD (Microsoft Entra ID).
+// The token will be requested for the resource's .default scope, so the client gets every app role it has been assigned.
+func AzureADClientCredentials(ctx context.Context, clientID, clientSecret, tenantID, resource string) grpc.DialOption {
+	config := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     fmt.Sprintf("%s/%s/oauth2/v2.0/token", azureADDefaultAuthorityHost, tenantID),
+		Scopes:       []string{strings.TrimSuffix(resource, "/") + "/.default"},
+	}
+	return grpc.WithPerRPCCredentials(oauth.TokenSource{TokenSource: clientCredentialsTokenSource(ctx, config)})
+}
+
+// AzureAD authenticates incoming gRPC requests carrying v2.0 client credentials access tokens issued by an Azure AD tenant.
+// The app roles assigned to the client application in the roles claim become AuthResult.Permissions.
+// See https://learn.microsoft.com/en-us/entra/identity-platform/access-tokens for more details.
+type AzureAD struct {
+	TenantID string
+	// Audience is the application ID URI or client ID of the API the tokens are issued for.
+	Audience string
+	// AuthorityHost is the Microsoft identity platform host, for national clouds.
+	// It defaults to https://login.microsoftonline.com.
+	AuthorityHost *url.URL
+
+	JWTValidation
+}
+
+// AuthFunc satisfies the AuthFunc interface so clients can use Azure AD with a gRPC server.
+func (a *AzureAD) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	authorityHost := azureADDefaultAuthorityHost
+	if a.AuthorityHost != nil {
+		authorityHost = strings.TrimSuffix(a.AuthorityHost.String(), "/")
+	}
+
+	issuer, err := url.Parse(fmt.Sprintf("%s/%s/v2.0", authorityHost, a.TenantID))
+	if err != nil {
+		return nil, err
+	}
+
+	jwksURL, err := url.Parse(fmt.Sprintf("%s/%s/discovery/v2.0/keys", authorityHost, a.TenantID))
+	if err != nil {
+		return nil, err
+	}
+
+	oidc := &OIDC{
+		Issuer:        issuer,
+		Audience:      a.Audience,
+		JWKSURL:       jwksURL,
+		JWTValidation: a.JWTValidation,
+	}
+	claims, err := oidc.verify(md)
+	if err != nil {
+		return nil, err
+	}
+
+	if version := claims["ver"]; version != azureADTokenVersion {
+		return nil, fmt.Errorf("token version must be %s, got %v", azureADTokenVersion, version)
+	}
+
+	if tenantID := claims["tid"]; tenantID != a.TenantID {
+		return nil, fmt.Errorf("invalid tenant, expected %s, got %v", a.TenantID, tenantID)
+	}
+
+	authResult, err := authResultFromClaims(claims, a.Leeway)
+	if err != nil {
+		return nil, err
+	}
+
+	// Azure AD puts the client application's ID in the azp claim in v2.0 tokens.
+	// The sub claim is the ID of the client's service principal.
+	if azp, ok := claims["azp"].(string); ok && azp != "" {
+		authResult.ClientIdentifier = azp
+	}
+
+	authResult.Permissions = stringsFromClaim(claims["roles"])
+	return authResult, nil
+}
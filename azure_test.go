@@ -0,0 +1,44 @@
+package grpcauth
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testTenantID = "72f988bf-86f1-41af-91ab-2d7cd011db47"
+
+func TestAzureADMapsRolesToPermissions(t *testing.T) {
+	issuer := newTestIssuer(t)
+	azure := &AzureAD{
+		TenantID:      testTenantID,
+		Audience:      testAudience,
+		AuthorityHost: issuer.url(t),
+	}
+
+	claims := issuer.claims()
+	delete(claims, "scope")
+	claims["iss"] = issuer.server.URL + "/" + testTenantID + "/v2.0"
+	claims["ver"] = "2.0"
+	claims["tid"] = testTenantID
+	claims["azp"] = testClientName
+	claims["roles"] = []string{targetMethodName}
+
+	authResult, err := azure.AuthFunc(bearerMetadata(issuer.sign(t, claims)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected %v, got %v", testClientName, authResult.ClientIdentifier)
+	}
+
+	if !reflect.DeepEqual(authResult.Permissions, []string{targetMethodName}) {
+		t.Fatalf("expected %v, got %v", []string{targetMethodName}, authResult.Permissions)
+	}
+
+	claims["ver"] = "1.0"
+	_, err = azure.AuthFunc(bearerMetadata(issuer.sign(t, claims)))
+	if err == nil {
+		t.Fatalf("expected error with v1.0 token")
+	}
+}
@@ -0,0 +1,237 @@
+package grpcauth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// basicScheme is the authorization scheme for HTTP Basic authentication.
+	basicScheme = "basic "
+
+	// argon2idPrefix is the prefix of PHC formatted argon2id hashes.
+	argon2idPrefix = "$argon2id$"
+
+	// Argon2id parameters from the OWASP Password Storage Cheat Sheet.
+	argon2idMemory  = 19 * 1024
+	argon2idTime    = 2
+	argon2idThreads = 1
+	argon2idKeyLen  = 32
+	argon2idSaltLen = 16
+)
+
+var (
+	// ErrCredentialNotFound is returned from a CredentialStore when it has no credential for a username.
+	ErrCredentialNotFound = errors.New("credential not found")
+
+	// errInvalidPassword is returned when a password doesn't match its hash.
+	errInvalidPassword = errors.New("invalid username or password")
+
+	// dummyPasswordHash is compared against when a username doesn't exist, so a failed login takes as long for
+	// unknown users as it does for known users with the wrong password.
+	dummyPasswordHash, _ = HashPasswordArgon2id("grpcauth")
+)
+
+// BasicCredential is a username and password hash as it is stored at rest.
+// PasswordHash can be a bcrypt hash or a PHC formatted argon2id hash. See HashPasswordBcrypt and HashPasswordArgon2id.
+type BasicCredential struct {
+	Username     string
+	PasswordHash string
+	Permissions  []string
+}
+
+// CredentialStore looks up credentials by username.
+// Implementations should return ErrCredentialNotFound if the username doesn't exist.
+type CredentialStore interface {
+	Credential(username string) (*BasicCredential, error)
+}
+
+// Basic authenticates clients using HTTP Basic authentication, for internal tools and legacy clients that only speak
+// username and password.
+// Clients send `authorization: Basic base64(username:password)` and the username becomes AuthResult.ClientIdentifier.
+// Since Basic authentication sends the password on every request, it should only be used over TLS.
+type Basic struct {
+	Store CredentialStore
+}
+
+// AuthFunc satisfies the AuthFunc interface so clients can use a username and password with a gRPC server.
+func (b *Basic) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	username, password, err := basicCredentialsFromMetadata(md)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := b.Store.Credential(username)
+	if errors.Is(err, ErrCredentialNotFound) {
+		verifyPasswordHash(dummyPasswordHash, password)
+		return nil, errInvalidPassword
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifyPasswordHash(credential.PasswordHash, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResult{
+		ClientIdentifier: credential.Username,
+		Timestamp:        time.Now(),
+		Permissions:      credential.Permissions,
+	}, nil
+}
+
+// basicCredentialsFromMetadata decodes the username and password from an authorization metadata field using the Basic scheme.
+func basicCredentialsFromMetadata(md metadata.MD) (string, string, error) {
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return "", "", fmt.Errorf("expected credentials in 'authorization' metadata field")
+	}
+
+	value := values[0]
+	if len(value) <= len(basicScheme) || !strings.EqualFold(value[:len(basicScheme)], basicScheme) {
+		return "", "", fmt.Errorf("expected Basic authorization scheme")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(value[len(basicScheme):])
+	if err != nil {
+		return "", "", err
+	}
+
+	credentials := strings.SplitN(string(decoded), ":", 2)
+	if len(credentials) != 2 || credentials[0] == "" {
+		return "", "", fmt.Errorf("invalid Basic credentials")
+	}
+
+	return credentials[0], credentials[1], nil
+}
+
+// HashPasswordBcrypt hashes a password with bcrypt for storage in a CredentialStore.
+func HashPasswordBcrypt(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	return string(hash), err
+}
+
+// HashPasswordArgon2id hashes a password with argon2id for storage in a CredentialStore.
+// The hash is returned in the PHC string format so its parameters are stored alongside it.
+func HashPasswordArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLen)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argon2idTime, argon2idMemory, argon2idThreads, argon2idKeyLen)
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		argon2idMemory,
+		argon2idTime,
+		argon2idThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// verifyPasswordHash checks password against a bcrypt or argon2id hash.
+func verifyPasswordHash(hash, password string) error {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			return errInvalidPassword
+		}
+		return nil
+	}
+
+	var version int
+	var memory, iterations uint32
+	var threads uint8
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return fmt.Errorf("invalid argon2id hash")
+	}
+
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil {
+		return err
+	}
+
+	if version != argon2.Version {
+		return fmt.Errorf("unsupported argon2 version: %d", version)
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads)
+	if err != nil {
+		return err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return err
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(expected)))
+	if subtle.ConstantTimeCompare(key, expected) != 1 {
+		return errInvalidPassword
+	}
+
+	return nil
+}
+
+// InMemoryCredentialStore is a CredentialStore that keeps password hashes in memory.
+// It is safe for concurrent use.
+type InMemoryCredentialStore struct {
+	mu          sync.RWMutex
+	credentials map[string]*BasicCredential
+}
+
+// NewInMemoryCredentialStore returns an InMemoryCredentialStore containing credentials.
+func NewInMemoryCredentialStore(credentials ...*BasicCredential) *InMemoryCredentialStore {
+	store := &InMemoryCredentialStore{credentials: map[string]*BasicCredential{}}
+	for _, credential := range credentials {
+		store.Add(credential)
+	}
+	return store
+}
+
+// Add stores a credential, replacing any credential with the same username.
+func (s *InMemoryCredentialStore) Add(credential *BasicCredential) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credentials[credential.Username] = credential
+}
+
+// Remove deletes the credential for username.
+func (s *InMemoryCredentialStore) Remove(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.credentials, username)
+}
+
+// Credential satisfies the CredentialStore interface.
+func (s *InMemoryCredentialStore) Credential(username string) (*BasicCredential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	credential, ok := s.credentials[username]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	return credential, nil
+}
@@ -0,0 +1,50 @@
+package grpcauth
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/metadata"
+)
+
+func basicMetadata(username, password string) metadata.MD {
+	return metadata.Pairs("authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+}
+
+func TestBasicVerifiesPasswordHashes(t *testing.T) {
+	bcryptHash, err := HashPasswordBcrypt("hunter2", bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	argon2idHash, err := HashPasswordArgon2id("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	basic := &Basic{
+		Store: NewInMemoryCredentialStore(
+			&BasicCredential{Username: "legacy", PasswordHash: bcryptHash, Permissions: []string{targetMethodName}},
+			&BasicCredential{Username: "tool", PasswordHash: argon2idHash},
+		),
+	}
+
+	for username, password := range map[string]string{"legacy": "hunter2", "tool": "correct horse battery staple"} {
+		authResult, err := basic.AuthFunc(basicMetadata(username, password))
+		if err != nil {
+			t.Fatalf("expected %s to authenticate: %v", username, err)
+		}
+
+		if authResult.ClientIdentifier != username {
+			t.Fatalf("expected %v, got %v", username, authResult.ClientIdentifier)
+		}
+	}
+
+	for username, password := range map[string]string{"legacy": "hunter3", "tool": "", "unknown": "hunter2"} {
+		_, err := basic.AuthFunc(basicMetadata(username, password))
+		if err != errInvalidPassword {
+			t.Fatalf("expected errInvalidPassword for %s, got %v", username, err)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package grpcauth
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// DefaultBruteForceThreshold is how many failed attempts a client can make before it has to back off.
+	DefaultBruteForceThreshold = 5
+
+	// DefaultBruteForceBaseDelay is how long a client has to back off after reaching the threshold.
+	DefaultBruteForceBaseDelay = time.Second
+
+	// DefaultBruteForceMaxDelay is the longest a client has to back off before it is locked out.
+	DefaultBruteForceMaxDelay = time.Minute
+
+	// DefaultBruteForceLockoutDuration is how long a client is locked out for.
+	DefaultBruteForceLockoutDuration = 15 * time.Minute
+
+	// DefaultBruteForceWindow is how long failed attempts are remembered.
+	DefaultBruteForceWindow = 15 * time.Minute
+)
+
+// BruteForceEvent reports that a client crossed a BruteForceProtection threshold.
+type BruteForceEvent struct {
+	// Key identifies the client, by default its IP address.
+	Key string
+	// Failures is how many failed attempts it has made within the window.
+	Failures int
+	// BlockedUntil is when it can try again.
+	BlockedUntil time.Time
+	// Lockout is true if the client was locked out, and false if it has started to back off.
+	Lockout bool
+}
+
+// BruteForceProtection slows down credential stuffing by making clients that repeatedly fail to authenticate wait
+// before trying again, then locking them out. Requests from a blocked client are rejected with ResourceExhausted and
+// RetryInfo before its credentials are checked.
+// Successful authentications don't reset a client's failures unless ResetOnSuccess is set, so a client with one valid
+// credential, or sharing an address with one, can't keep guessing by authenticating between attempts.
+// Use it with WithBruteForceProtection. The zero value uses the defaults, without a lockout.
+type BruteForceProtection struct {
+	// Threshold is how many failed attempts a client can make before it has to back off.
+	// It defaults to DefaultBruteForceThreshold.
+	Threshold int
+	// BaseDelay is how long a client backs off when it reaches Threshold, doubling with each further failure.
+	// It defaults to DefaultBruteForceBaseDelay.
+	BaseDelay time.Duration
+	// MaxDelay caps the back off. It defaults to DefaultBruteForceMaxDelay.
+	MaxDelay time.Duration
+	// LockoutThreshold is how many failed attempts lock a client out for LockoutDuration.
+	// Clients aren't locked out if it is zero.
+	LockoutThreshold int
+	// LockoutDuration defaults to DefaultBruteForceLockoutDuration.
+	LockoutDuration time.Duration
+	// Window is how long failed attempts are remembered. It defaults to DefaultBruteForceWindow.
+	Window time.Duration
+	// KeyFunc identifies the client making a request. Requests it returns an empty string for aren't limited.
+	// It defaults to PeerIP.
+	KeyFunc func(ctx context.Context) string
+	// ResetOnSuccess forgets a client's failures when it authenticates. Only set it with a KeyFunc that identifies the
+	// credential being presented, like a username, since a key shared by several clients, like PeerIP, would let one
+	// client's success clear another's failures.
+	ResetOnSuccess bool
+	// OnThreshold is called when a client starts to back off and when it is locked out, such as to alert on it.
+	OnThreshold func(event *BruteForceEvent)
+
+	mu        sync.Mutex
+	attempts  map[string]*failedAttempts
+	lastSweep time.Time
+}
+
+// failedAttempts are a client's recent failures to authenticate.
+type failedAttempts struct {
+	failures     int
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// PeerIP returns the IP address of the client calling the gRPC server, or an empty string if it isn't known.
+func PeerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	// Most peers are TCP, and formatting their IP directly saves formatting and splitting the whole address.
+	if tcp, ok := p.Addr.(*net.TCPAddr); ok && tcp.IP != nil {
+		if tcp.Zone != "" {
+			return tcp.IP.String() + "%" + tcp.Zone
+		}
+		return tcp.IP.String()
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+// Blocked returns when the client with key can try to authenticate again, and false if it isn't blocked.
+func (b *BruteForceProtection) Blocked(key string) (time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	attempts, ok := b.attempts[key]
+	if !ok || !time.Now().Before(attempts.blockedUntil) {
+		return time.Time{}, false
+	}
+
+	return attempts.blockedUntil, true
+}
+
+// Reset forgets the failed attempts of the client with key, unblocking it.
+func (b *BruteForceProtection) Reset(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.attempts, key)
+}
+
+// key identifies the client making the request in ctx, which has the IP address peerIP.
+func (b *BruteForceProtection) key(ctx context.Context, peerIP string) string {
+	if b == nil {
+		return ""
+	}
+
+	if b.KeyFunc != nil {
+		return b.KeyFunc(ctx)
+	}
+
+	return peerIP
+}
+
+// check returns a ResourceExhausted status if the client with key is blocked.
+func (b *BruteForceProtection) check(key string) error {
+	if b == nil || key == "" {
+		return nil
+	}
+
+	blockedUntil, blocked := b.Blocked(key)
+	if !blocked {
+		return nil
+	}
+
+	return resourceExhaustedStatus("too many failed authentication attempts", time.Until(blockedUntil))
+}
+
+// recordFailure counts a failed attempt by the client with key, and blocks it once it reaches a threshold.
+func (b *BruteForceProtection) recordFailure(key string) {
+	if b == nil || key == "" {
+		return
+	}
+
+	event := b.fail(key, time.Now())
+	if event != nil && b.OnThreshold != nil {
+		b.OnThreshold(event)
+	}
+}
+
+// fail counts a failed attempt and returns an event if the client crossed a threshold.
+func (b *BruteForceProtection) fail(key string, now time.Time) *BruteForceEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sweep(now)
+	attempts, ok := b.attempts[key]
+	if !ok || now.Sub(attempts.last) > b.window() {
+		attempts = &failedAttempts{}
+		b.attempts[key] = attempts
+	}
+
+	attempts.failures++
+	attempts.last = now
+
+	threshold := b.Threshold
+	if threshold <= 0 {
+		threshold = DefaultBruteForceThreshold
+	}
+
+	if b.LockoutThreshold > 0 && attempts.failures >= b.LockoutThreshold {
+		lockout := b.LockoutDuration
+		if lockout <= 0 {
+			lockout = DefaultBruteForceLockoutDuration
+		}
+
+		attempts.blockedUntil = now.Add(lockout)
+		if attempts.failures == b.LockoutThreshold {
+			return &BruteForceEvent{Key: key, Failures: attempts.failures, BlockedUntil: attempts.blockedUntil, Lockout: true}
+		}
+		return nil
+	}
+
+	if attempts.failures < threshold {
+		return nil
+	}
+
+	attempts.blockedUntil = now.Add(b.delay(attempts.failures - threshold))
+	if attempts.failures == threshold {
+		return &BruteForceEvent{Key: key, Failures: attempts.failures, BlockedUntil: attempts.blockedUntil}
+	}
+	return nil
+}
+
+// delay returns how long a client backs off after failing more times than the threshold.
+func (b *BruteForceProtection) delay(over int) time.Duration {
+	delay := b.BaseDelay
+	if delay <= 0 {
+		delay = DefaultBruteForceBaseDelay
+	}
+
+	maxDelay := b.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultBruteForceMaxDelay
+	}
+
+	for i := 0; i < over && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// recordSuccess forgets the failed attempts of a client that authenticated if ResetOnSuccess is set.
+func (b *BruteForceProtection) recordSuccess(key string) {
+	if b == nil || key == "" || !b.ResetOnSuccess {
+		return
+	}
+
+	b.Reset(key)
+}
+
+// window returns how long failed attempts are remembered.
+func (b *BruteForceProtection) window() time.Duration {
+	if b.Window <= 0 {
+		return DefaultBruteForceWindow
+	}
+	return b.Window
+}
+
+// sweep forgets clients that are no longer blocked and haven't failed within the window, at most once per window.
+// It must be called with b.mu held.
+func (b *BruteForceProtection) sweep(now time.Time) {
+	if b.attempts == nil {
+		b.attempts = map[string]*failedAttempts{}
+	}
+
+	if now.Sub(b.lastSweep) < b.window() {
+		return
+	}
+
+	b.lastSweep = now
+	for key, attempts := range b.attempts {
+		if now.Sub(attempts.last) > b.window() && now.After(attempts.blockedUntil) {
+			delete(b.attempts, key)
+		}
+	}
+}
+
+// String describes the event for logs.
+func (e *BruteForceEvent) String() string {
+	action := "backing off"
+	if e.Lockout {
+		action = "locked out"
+	}
+
+	return fmt.Sprintf("%s %s after %d failed attempts until %s", e.Key, action, e.Failures, e.BlockedUntil.Format(time.RFC3339))
+}
@@ -0,0 +1,153 @@
+package grpcauth
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func peerContext(ip string) context.Context {
+	md := metadata.Pairs("authorization", "bearer words")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 50051}})
+}
+
+func TestPeerIP(t *testing.T) {
+	if ip := PeerIP(peerContext("192.0.2.1")); ip != "192.0.2.1" {
+		t.Fatalf("expected the peer's IP address, got %s", ip)
+	}
+
+	if ip := PeerIP(context.Background()); ip != "" {
+		t.Fatalf("expected no IP address without a peer, got %s", ip)
+	}
+}
+
+func TestBruteForceProtectionBacksOff(t *testing.T) {
+	var events []*BruteForceEvent
+	protection := &BruteForceProtection{Threshold: 2, BaseDelay: time.Second, MaxDelay: 3 * time.Second}
+	protection.OnThreshold = func(event *BruteForceEvent) {
+		events = append(events, event)
+	}
+
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		protection.fail("192.0.2.1", now)
+	}
+
+	blockedUntil, blocked := protection.Blocked("192.0.2.1")
+	if !blocked || blockedUntil.Sub(now) != 3*time.Second {
+		t.Fatalf("expected the back off to double up to MaxDelay, got %v", blockedUntil.Sub(now))
+	}
+
+	if _, blocked := protection.Blocked("192.0.2.2"); blocked {
+		t.Fatalf("expected other clients not to be blocked")
+	}
+
+	protection.recordFailure("192.0.2.2")
+	protection.recordFailure("192.0.2.2")
+	if len(events) != 1 || events[0].Key != "192.0.2.2" || events[0].Lockout {
+		t.Fatalf("expected an event when the client started to back off, got %v", events)
+	}
+
+	protection.recordSuccess("192.0.2.2")
+	if _, blocked := protection.Blocked("192.0.2.2"); !blocked {
+		t.Fatalf("expected authenticating not to reset the client by default")
+	}
+
+	protection.ResetOnSuccess = true
+	protection.recordSuccess("192.0.2.2")
+	if _, blocked := protection.Blocked("192.0.2.2"); blocked {
+		t.Fatalf("expected authenticating to reset the client with ResetOnSuccess")
+	}
+}
+
+func TestAuthorityLocksOutInterleavedGuesses(t *testing.T) {
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		if md.Get("authorization")[0] != "bearer words" {
+			return nil, ErrCredentialNotFound
+		}
+		return alwaysAuthenticatedAllPermissions(md)
+	}
+
+	protection := &BruteForceProtection{LockoutThreshold: 3}
+	a := NewAuthority(authFunc, nil, WithBruteForceProtection(protection)).(*authority)
+	valid := peerContext("192.0.2.1")
+	guess := metadata.NewIncomingContext(valid, metadata.Pairs("authorization", "bearer guess"))
+	for i := 0; i < 3; i++ {
+		a.authenticateAndAuthorizeContext(valid, targetMethodName)
+		a.authenticateAndAuthorizeContext(guess, targetMethodName)
+	}
+
+	_, err := a.authenticateAndAuthorizeContext(guess, targetMethodName)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected guesses between valid logins to lock the client out, got %v", err)
+	}
+}
+
+func TestBruteForceProtectionLocksOut(t *testing.T) {
+	var events []*BruteForceEvent
+	protection := &BruteForceProtection{LockoutThreshold: 3, LockoutDuration: time.Hour, Window: time.Minute}
+	protection.OnThreshold = func(event *BruteForceEvent) {
+		events = append(events, event)
+	}
+
+	now := time.Now()
+	protection.fail("192.0.2.1", now.Add(-2*time.Minute))
+	protection.fail("192.0.2.1", now.Add(-2*time.Minute))
+	if event := protection.fail("192.0.2.1", now); event != nil {
+		t.Fatalf("expected failures outside the window to be forgotten, got %v", event)
+	}
+
+	protection.fail("192.0.2.1", now)
+	event := protection.fail("192.0.2.1", now)
+	if event == nil || !event.Lockout || event.BlockedUntil.Sub(now) != time.Hour {
+		t.Fatalf("expected the client to be locked out, got %v", event)
+	}
+}
+
+func TestAuthorityBlocksBruteForce(t *testing.T) {
+	calls := 0
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		calls++
+		return alwaysUnauthenticated(md)
+	}
+
+	protection := &BruteForceProtection{Threshold: 2}
+	a := NewAuthority(authFunc, nil, WithBruteForceProtection(protection)).(*authority)
+	ctx := peerContext("192.0.2.1")
+	for i := 0; i < 2; i++ {
+		_, err := a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+		if err != errUnauthorized {
+			t.Fatalf("expected Unauthenticated, got %v", err)
+		}
+	}
+
+	_, err := a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+
+	if delay, ok := RetryDelay(err); !ok || delay <= 0 || delay > DefaultBruteForceBaseDelay {
+		t.Fatalf("expected a retry delay, got %v", delay)
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected blocked requests not to reach the AuthFunc, got %d calls", calls)
+	}
+
+	explanation := a.Explain(ctx, metadata.Pairs("authorization", "bearer words"), targetMethodName)
+	if explanation.Steps[0].Check != CheckBruteForce || explanation.Outcome != OutcomeRateLimited {
+		t.Fatalf("expected the block to be explained, got %s", explanation)
+	}
+
+	_, err = a.authenticateAndAuthorizeContext(peerContext("192.0.2.2"), targetMethodName)
+	if err != errUnauthorized {
+		t.Fatalf("expected other clients not to be blocked, got %v", err)
+	}
+}
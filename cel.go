@@ -0,0 +1,102 @@
+package grpcauth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"google.golang.org/grpc/peer"
+)
+
+// CELPolicy authorizes requests with a CEL expression, so rules like
+// `method.startsWith("/server.ServiceName/") && "admin" in permissions && claims.tenant_id == "acme"` don't need
+// Go code.
+// The expression is compiled once by NewCELPolicy and can use these variables:
+//
+//	client_identifier  string               AuthResult.ClientIdentifier
+//	permissions        list(string)         AuthResult.Permissions
+//	claims             map(string, dyn)     AuthResult.Claims
+//	method             string               the full gRPC method name
+//	peer               map(string, string)  the client's "address" and "tls_common_name" if it used a certificate
+//
+// Use it with WithAuthorizationFunc. See https://github.com/google/cel-spec for the language.
+type CELPolicy struct {
+	program cel.Program
+}
+
+// NewCELPolicy compiles expression, which must evaluate to a bool.
+func NewCELPolicy(expression string) (*CELPolicy, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("client_identifier", cel.StringType),
+		cel.Variable("permissions", cel.ListType(cel.StringType)),
+		cel.Variable("claims", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("method", cel.StringType),
+		cel.Variable("peer", cel.MapType(cel.StringType, cel.StringType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("CEL expression must evaluate to a bool, got %v", ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CELPolicy{program: program}, nil
+}
+
+// AuthorizationFunc satisfies the AuthorizationFunc interface by evaluating the expression for the request.
+// Evaluation errors deny the request.
+func (p *CELPolicy) AuthorizationFunc(ctx context.Context, authResult *AuthResult, methodName string) bool {
+	permissions := authResult.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	claims := authResult.Claims
+	if claims == nil {
+		claims = map[string]interface{}{}
+	}
+
+	out, _, err := p.program.Eval(map[string]interface{}{
+		"client_identifier": authResult.ClientIdentifier,
+		"permissions":       permissions,
+		"claims":            claims,
+		"method":            methodName,
+		"peer":              celPeer(ctx),
+	})
+	if err != nil {
+		return false
+	}
+
+	allowed, ok := out.Value().(bool)
+	return ok && allowed
+}
+
+// celPeer describes the client for CEL expressions.
+func celPeer(ctx context.Context) map[string]string {
+	attributes := map[string]string{}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return attributes
+	}
+
+	if p.Addr != nil {
+		attributes["address"] = p.Addr.String()
+	}
+
+	if cert, err := verifiedPeerCertificate(ctx); err == nil {
+		attributes["tls_common_name"] = cert.Subject.CommonName
+	}
+
+	return attributes
+}
@@ -0,0 +1,59 @@
+package grpcauth
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/peer"
+)
+
+func TestCELPolicyEvaluatesExpression(t *testing.T) {
+	policy, err := NewCELPolicy(`method.startsWith("/server.ServiceName/") && ("admin" in permissions || client_identifier == "testClient") && peer.address.startsWith("10.")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 443}})
+	if !policy.AuthorizationFunc(ctx, testUnpermissionedAuthResult, targetMethodName) {
+		t.Fatalf("expected expression to allow %s", targetMethodName)
+	}
+
+	if policy.AuthorizationFunc(ctx, testUnpermissionedAuthResult, "/server.OtherService/MethodName") {
+		t.Fatalf("expected expression to deny other services")
+	}
+
+	// The peer has no address without a peer in the context, so evaluation fails and the request is denied.
+	if policy.AuthorizationFunc(context.Background(), testUnpermissionedAuthResult, targetMethodName) {
+		t.Fatalf("expected evaluation errors to deny the request")
+	}
+
+	_, err = NewCELPolicy(`client_identifier`)
+	if err == nil {
+		t.Fatalf("expected error with non-bool expression")
+	}
+}
+
+func TestCELPolicyUsesClaims(t *testing.T) {
+	policy, err := NewCELPolicy(`"tenant_id" in claims && claims.tenant_id == "acme" && "admin" in claims.roles`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authResult := &AuthResult{
+		ClientIdentifier: testClientName,
+		Claims:           map[string]interface{}{"tenant_id": "acme", "roles": []interface{}{"admin"}},
+	}
+	if !policy.AuthorizationFunc(context.Background(), authResult, targetMethodName) {
+		t.Fatalf("expected expression to allow clients with matching claims")
+	}
+
+	authResult.Claims["tenant_id"] = "other"
+	if policy.AuthorizationFunc(context.Background(), authResult, targetMethodName) {
+		t.Fatalf("expected expression to deny clients from other tenants")
+	}
+
+	if policy.AuthorizationFunc(context.Background(), testUnpermissionedAuthResult, targetMethodName) {
+		t.Fatalf("expected expression to deny clients without claims")
+	}
+}
@@ -0,0 +1,65 @@
+package grpcauth
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// challengeMetadataKey is the trailer Challenges are sent in, named after the HTTP header they imitate.
+const challengeMetadataKey = "www-authenticate"
+
+// Challenge tells clients that failed to authenticate how they can, like HTTP's WWW-Authenticate header, so
+// well-behaved clients can fetch a token for the right audience and retry.
+// Use it with WithChallenge.
+type Challenge struct {
+	// Schemes are the authorization schemes the server accepts, like Bearer or DPoP.
+	Schemes []string
+	Realm   string
+	// Audience and Issuer are the audience and issuer tokens must have.
+	Audience string
+	Issuer   string
+	// TokenEndpoint is where clients can get a token, such as with the client credentials grant.
+	TokenEndpoint string
+}
+
+// Values returns the challenge for each scheme, formatted like WWW-Authenticate headers:
+//
+//	Bearer realm="api", audience="https://api.example.com", issuer="https://idp.example.com/", token_endpoint="https://idp.example.com/oauth2/token"
+func (c *Challenge) Values() []string {
+	var params []string
+	for _, param := range []struct{ name, value string }{
+		{"realm", c.Realm},
+		{"audience", c.Audience},
+		{"issuer", c.Issuer},
+		{"token_endpoint", c.TokenEndpoint},
+	} {
+		if param.value != "" {
+			params = append(params, param.name+"="+strconv.Quote(param.value))
+		}
+	}
+
+	values := make([]string, 0, len(c.Schemes))
+	for _, scheme := range c.Schemes {
+		if len(params) == 0 {
+			values = append(values, scheme)
+			continue
+		}
+		values = append(values, scheme+" "+strings.Join(params, ", "))
+	}
+
+	return values
+}
+
+// setTrailer sends the challenge in the trailers of the call in ctx.
+func (c *Challenge) setTrailer(ctx context.Context) {
+	if c == nil {
+		return
+	}
+
+	// There is no call to set trailers on for HTTP requests, which get a WWW-Authenticate header instead.
+	grpc.SetTrailer(ctx, metadata.MD{challengeMetadataKey: c.Values()})
+}
@@ -0,0 +1,62 @@
+package grpcauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestChallengeValues(t *testing.T) {
+	challenge := &Challenge{Schemes: []string{"Bearer", "DPoP"}, Audience: "https://api.example.com", TokenEndpoint: "https://idp.example.com/token"}
+	expected := []string{
+		`Bearer audience="https://api.example.com", token_endpoint="https://idp.example.com/token"`,
+		`DPoP audience="https://api.example.com", token_endpoint="https://idp.example.com/token"`,
+	}
+	if values := challenge.Values(); !reflect.DeepEqual(values, expected) {
+		t.Fatalf("expected %v, got %v", expected, values)
+	}
+
+	if values := (&Challenge{Schemes: []string{"Basic"}}).Values(); !reflect.DeepEqual(values, []string{"Basic"}) {
+		t.Fatalf("expected schemes without params, got %v", values)
+	}
+}
+
+func TestAuthoritySendsChallenge(t *testing.T) {
+	challenge := &Challenge{Schemes: []string{"Bearer"}, Issuer: "https://idp.example.com/"}
+	a := NewAuthority(alwaysUnauthenticated, nil, WithChallenge(challenge)).(*authority)
+	stream := &testServerTransportStream{method: targetMethodName}
+	md := metadata.Pairs("authorization", "bearer words")
+	ctx := metadata.NewIncomingContext(grpc.NewContextWithServerTransportStream(context.Background(), stream), md)
+	_, err := a.authenticateMethod(ctx, targetMethodName)
+	if err == nil {
+		t.Fatalf("expected the client to fail to authenticate")
+	}
+
+	if values := stream.trailer.Get("www-authenticate"); !reflect.DeepEqual(values, challenge.Values()) {
+		t.Fatalf("expected the challenge in the trailer, got %v", values)
+	}
+
+	stream = &testServerTransportStream{method: targetMethodName}
+	a = NewAuthority(alwaysAuthenticatedNoPermissions, nil, WithChallenge(challenge)).(*authority)
+	a.authenticateMethod(metadata.NewIncomingContext(grpc.NewContextWithServerTransportStream(context.Background(), stream), md), targetMethodName)
+	if len(stream.trailer) != 0 {
+		t.Fatalf("expected authenticated clients not to be challenged, got %v", stream.trailer)
+	}
+}
+
+func TestHTTPMiddlewareSendsChallenge(t *testing.T) {
+	authority := NewAuthority(alwaysUnauthenticated, nil, WithChallenge(&Challenge{Schemes: []string{"Bearer"}, Realm: "api"}))
+	handler := HTTPMiddleware(authority, RouteMethods(map[string]string{"GET /": targetMethodName}))(http.NotFoundHandler())
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer words")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != `Bearer realm="api"` {
+		t.Fatalf("expected a WWW-Authenticate header, got %d %v", rec.Code, rec.Header())
+	}
+}
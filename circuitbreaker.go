@@ -0,0 +1,229 @@
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	defaultCircuitBreakerThreshold    = 5
+	defaultCircuitBreakerOpenDuration = 30 * time.Second
+)
+
+// errCircuitOpen is returned by a CircuitBreaker without calling the identity provider.
+var errCircuitOpen = fmt.Errorf("%w: circuit breaker is open", ErrAuthenticationUnavailable)
+
+// CircuitBreaker stops calling an identity provider after it fails Threshold times in a row, so while it is down
+// requests fail fast with Unavailable instead of each waiting for a timeout. After OpenDuration, one request is let
+// through to see if it has recovered.
+// While the identity provider is down, clients it recently authenticated can keep being served their last
+// successful AuthResult for StaleFor, so an outage doesn't take down every backend. Results are never served past
+// their Expiry or the exp claim of the token they came from.
+// Only failures wrapping ErrAuthenticationUnavailable, like TokenIntrospection and JWKSCache's network errors and 5xx
+// responses, trip the breaker: rejected credentials mean the identity provider is up.
+// It is safe for concurrent use.
+type CircuitBreaker struct {
+	// Threshold is how many failures in a row open the breaker. It defaults to 5.
+	Threshold int
+	// OpenDuration is how long the breaker stays open before trying the identity provider again.
+	// It defaults to 30 seconds.
+	OpenDuration time.Duration
+	// StaleFor is how long after a client last authenticated its result can be served while the identity provider
+	// is down. Stale results aren't served when it is 0.
+	StaleFor time.Duration
+	// MetadataKey is the metadata field holding the credentials. It defaults to authorization.
+	MetadataKey string
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+	recent    map[string]*authCacheEntry
+	lastSweep time.Time
+}
+
+// AuthFunc returns an AuthFunc that calls authFunc unless the breaker is open.
+func (b *CircuitBreaker) AuthFunc(authFunc AuthFunc) AuthFunc {
+	guarded := b.ContextAuthFunc(func(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+		return authFunc(md)
+	})
+
+	return func(md metadata.MD) (*AuthResult, error) {
+		return guarded(context.Background(), md)
+	}
+}
+
+// ContextAuthFunc returns a ContextAuthFunc that calls authFunc unless the breaker is open.
+func (b *CircuitBreaker) ContextAuthFunc(authFunc ContextAuthFunc) ContextAuthFunc {
+	return func(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+		if !b.allow(time.Now()) {
+			return b.stale(md, errCircuitOpen)
+		}
+
+		authResult, err := authFunc(ctx, md)
+		now := time.Now()
+		switch {
+		case err != nil && errors.Is(err, ErrAuthenticationUnavailable) && ctx.Err() == nil:
+			b.recordFailure(now)
+			return b.stale(md, err)
+		case err != nil && ctx.Err() != nil:
+			// Requests that were cancelled say nothing about the identity provider.
+			b.release()
+			return nil, err
+		case err != nil:
+			b.recordSuccess()
+			return nil, err
+		}
+
+		b.recordSuccess()
+		b.remember(md, authResult, now)
+		return authResult, nil
+	}
+}
+
+// Open reports whether the breaker is failing requests without calling the identity provider.
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.openUntil.IsZero()
+}
+
+// allow reports whether the identity provider should be called, letting one request through at a time once the
+// breaker has been open for OpenDuration.
+func (b *CircuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return true
+	}
+
+	if now.Before(b.openUntil) || b.probing {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+func (b *CircuitBreaker) recordFailure(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	b.failures++
+	if b.failures >= b.threshold() {
+		b.openUntil = now.Add(b.openDuration())
+	}
+}
+
+func (b *CircuitBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+// release lets another request try the identity provider if this one was the probe.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// remember keeps authResult so it can be served while the identity provider is down.
+func (b *CircuitBreaker) remember(md metadata.MD, authResult *AuthResult, now time.Time) {
+	if b.StaleFor <= 0 {
+		return
+	}
+
+	key, ok := credentialsKey(md, b.MetadataKey)
+	if !ok {
+		return
+	}
+
+	expiry := now.Add(b.StaleFor)
+	if exp, ok := authResult.expiry(); ok && exp.Before(expiry) {
+		expiry = exp
+	}
+
+	if !now.Before(expiry) {
+		return
+	}
+
+	cached := authResult.clone()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sweep(now)
+	b.recent[key] = &authCacheEntry{authResult: cached, expiry: expiry}
+}
+
+// sweep forgets results that can no longer be served, at most once per StaleFor.
+// It must be called with b.mu held.
+func (b *CircuitBreaker) sweep(now time.Time) {
+	if b.recent == nil {
+		b.recent = map[string]*authCacheEntry{}
+	}
+
+	if now.Sub(b.lastSweep) < b.StaleFor {
+		return
+	}
+
+	b.lastSweep = now
+	for key, entry := range b.recent {
+		if !now.Before(entry.expiry) {
+			delete(b.recent, key)
+		}
+	}
+}
+
+// stale returns the client's last successful AuthResult if it is recent enough, and err otherwise.
+func (b *CircuitBreaker) stale(md metadata.MD, err error) (*AuthResult, error) {
+	key, ok := credentialsKey(md, b.MetadataKey)
+	if !ok {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.recent[key]
+	if !ok || !time.Now().Before(entry.expiry) {
+		return nil, err
+	}
+
+	authResult := entry.authResult.clone()
+	authResult.Timestamp = time.Now()
+	return authResult, nil
+}
+
+func (b *CircuitBreaker) threshold() int {
+	if b.Threshold <= 0 {
+		return defaultCircuitBreakerThreshold
+	}
+	return b.Threshold
+}
+
+func (b *CircuitBreaker) openDuration() time.Duration {
+	if b.OpenDuration <= 0 {
+		return defaultCircuitBreakerOpenDuration
+	}
+	return b.OpenDuration
+}
+
+// providerError is the error for an identity provider's non-200 response, which wraps ErrAuthenticationUnavailable
+// if the identity provider is down or overloaded.
+func providerError(statusCode int, body string) error {
+	if statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %d %s", ErrAuthenticationUnavailable, statusCode, body)
+	}
+	return errors.New(body)
+}
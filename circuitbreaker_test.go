@@ -0,0 +1,152 @@
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	calls := 0
+	down := false
+	breaker := &CircuitBreaker{Threshold: 2, OpenDuration: 10 * time.Millisecond}
+	guarded := breaker.AuthFunc(func(md metadata.MD) (*AuthResult, error) {
+		calls++
+		if down {
+			return nil, fmt.Errorf("%w: connection refused", ErrAuthenticationUnavailable)
+		}
+		if md.Get("authorization")[0] != "bearer words" {
+			return nil, ErrCredentialNotFound
+		}
+		return &AuthResult{ClientIdentifier: testClientName}, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		guarded(metadata.Pairs("authorization", "bearer bad"))
+	}
+	if breaker.Open() {
+		t.Fatalf("expected rejected credentials not to open the breaker")
+	}
+
+	down = true
+	guarded(metadata.Pairs("authorization", "bearer words"))
+	guarded(metadata.Pairs("authorization", "bearer words"))
+	_, err := guarded(metadata.Pairs("authorization", "bearer words"))
+	if !breaker.Open() || calls != 5 || err != errCircuitOpen {
+		t.Fatalf("expected the breaker to open and fail fast, got %v after %d calls", err, calls)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	down = false
+	authResult, err := guarded(metadata.Pairs("authorization", "bearer words"))
+	if err != nil || authResult.ClientIdentifier != testClientName || breaker.Open() {
+		t.Fatalf("expected the breaker to close once the identity provider recovers, got %v %v", authResult, err)
+	}
+}
+
+func TestCircuitBreakerProbesOnce(t *testing.T) {
+	breaker := &CircuitBreaker{Threshold: 1, OpenDuration: time.Millisecond}
+	breaker.recordFailure(time.Now())
+
+	now := time.Now().Add(time.Second)
+	if !breaker.allow(now) {
+		t.Fatalf("expected a probe to be allowed once the breaker has been open for OpenDuration")
+	}
+	if breaker.allow(now) {
+		t.Fatalf("expected only one probe at a time")
+	}
+
+	breaker.release()
+	if !breaker.allow(now) {
+		t.Fatalf("expected another probe once a cancelled probe is released")
+	}
+}
+
+func TestCircuitBreakerServesStaleResults(t *testing.T) {
+	down := false
+	breaker := &CircuitBreaker{Threshold: 1, OpenDuration: time.Hour, StaleFor: time.Minute}
+	guarded := breaker.ContextAuthFunc(func(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+		if down {
+			return nil, fmt.Errorf("%w: 503", ErrAuthenticationUnavailable)
+		}
+		if md.Get("authorization")[0] == "bearer expired" {
+			return &AuthResult{ClientIdentifier: testClientName, Claims: map[string]interface{}{"exp": float64(time.Now().Unix())}}, nil
+		}
+		return &AuthResult{ClientIdentifier: testClientName}, nil
+	})
+
+	ctx := context.Background()
+	guarded(ctx, metadata.Pairs("authorization", "bearer words"))
+	guarded(ctx, metadata.Pairs("authorization", "bearer expired"))
+
+	down = true
+	for i := 0; i < 2; i++ {
+		authResult, err := guarded(ctx, metadata.Pairs("authorization", "bearer words"))
+		if err != nil || authResult.ClientIdentifier != testClientName {
+			t.Fatalf("expected the stale result while the identity provider is down, got %v %v", authResult, err)
+		}
+	}
+
+	_, err := guarded(ctx, metadata.Pairs("authorization", "bearer other"))
+	if !errors.Is(err, ErrAuthenticationUnavailable) {
+		t.Fatalf("expected clients that haven't authenticated recently to be rejected, got %v", err)
+	}
+
+	_, err = guarded(ctx, metadata.Pairs("authorization", "bearer expired"))
+	if !errors.Is(err, ErrAuthenticationUnavailable) {
+		t.Fatalf("expected stale results not to be served past their exp claim, got %v", err)
+	}
+}
+
+func TestIntrospectionOutagesAreUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	introspection := &TokenIntrospection{URL: u}
+	breaker := &CircuitBreaker{}
+	a := NewContextAuthority(breaker.ContextAuthFunc(introspection.ContextAuthFunc), nil,
+		WithBruteForceProtection(&BruteForceProtection{Threshold: 1, BaseDelay: time.Minute}),
+	).(*authority)
+
+	for i := 0; i < 2; i++ {
+		_, err := a.authenticateAndAuthorizeContext(peerContext("192.0.2.1"), targetMethodName)
+		if status.Code(err) != codes.Unavailable {
+			t.Fatalf("expected Unavailable without locking the client out, got %v", err)
+		}
+	}
+}
+
+func TestCircuitBreakerCopiesStaleResults(t *testing.T) {
+	down := false
+	breaker := &CircuitBreaker{Threshold: 1, OpenDuration: time.Hour, StaleFor: time.Minute}
+	guarded := breaker.AuthFunc(func(md metadata.MD) (*AuthResult, error) {
+		if down {
+			return nil, fmt.Errorf("%w: 503", ErrAuthenticationUnavailable)
+		}
+		return &AuthResult{ClientIdentifier: testClientName, Permissions: []string{targetMethodName}, Claims: map[string]interface{}{"tenant_id": "acme"}}, nil
+	})
+
+	authResult, _ := guarded(metadata.Pairs("authorization", "bearer words"))
+	authResult.Permissions[0] = "/server.ServiceName/OtherMethod"
+
+	down = true
+	for i := 0; i < 2; i++ {
+		authResult, err := guarded(metadata.Pairs("authorization", "bearer words"))
+		if err != nil || authResult.Permissions[0] != targetMethodName || authResult.StringClaim("tenant_id") != "acme" {
+			t.Fatalf("expected handlers not to change stale results, got %v %v", authResult, err)
+		}
+		authResult.Claims["tenant_id"] = "other"
+	}
+}
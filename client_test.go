@@ -0,0 +1,56 @@
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type failingTokenSource struct{}
+
+func (failingTokenSource) Token() (*oauth2.Token, error) {
+	return nil, errors.New("token endpoint unavailable")
+}
+
+func TestTokenClientInterceptors(t *testing.T) {
+	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "words", TokenType: "Bearer"})
+	var authorization []string
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		authorization = md.Get("authorization")
+		return nil
+	}
+
+	err := TokenUnaryClientInterceptor(tokenSource)(context.Background(), targetMethodName, nil, nil, nil, invoker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(authorization) != 1 || authorization[0] != "Bearer words" {
+		t.Fatalf("expected bearer token in metadata, got %v", authorization)
+	}
+
+	authorization = nil
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return nil, invoker(ctx, method, nil, nil, cc, opts...)
+	}
+	_, err = TokenStreamClientInterceptor(tokenSource)(context.Background(), &grpc.StreamDesc{}, nil, targetMethodName, streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(authorization) != 1 || authorization[0] != "Bearer words" {
+		t.Fatalf("expected bearer token in stream metadata, got %v", authorization)
+	}
+
+	err = TokenUnaryClientInterceptor(failingTokenSource{})(context.Background(), targetMethodName, nil, nil, nil, invoker)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated when no token can be fetched, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// ClientAssertionType is the client_assertion_type for JWT client assertions.
+	ClientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	// clientAssertionLifetime is how long the client assertions PrivateKeyJWT signs are valid for.
+	clientAssertionLifetime = time.Minute
+
+	// defaultClientAssertionMaxLifetime is the longest lifetime ClientAssertion accepts by default.
+	defaultClientAssertionMaxLifetime = 5 * time.Minute
+)
+
+// PrivateKeyJWT authenticates a client with JWT client assertions signed by its private key instead of a client
+// secret, for identity providers that support the private_key_jwt client authentication method.
+// See https://datatracker.ietf.org/doc/html/rfc7523 for more details.
+type PrivateKeyJWT struct {
+	ClientID string
+	// Key is the client's *rsa.PrivateKey or *ecdsa.PrivateKey.
+	// Assertions are signed with RS256, or the ECDSA algorithm for the key's curve.
+	Key interface{}
+	// KeyID identifies Key in the JWKS registered for the client.
+	KeyID string
+}
+
+// Assertion returns a short-lived client assertion for audience, such as a token endpoint's URL.
+func (p *PrivateKeyJWT) Assertion(audience string) (string, error) {
+	var method jwt.SigningMethod
+	switch key := p.Key.(type) {
+	case *rsa.PrivateKey:
+		method = jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			method = jwt.SigningMethodES256
+		case 384:
+			method = jwt.SigningMethodES384
+		case 521:
+			method = jwt.SigningMethodES512
+		default:
+			return "", fmt.Errorf("unsupported curve: %v", key.Curve.Params().Name)
+		}
+	default:
+		return "", fmt.Errorf("unsupported key type: %T", p.Key)
+	}
+
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(method, jwt.MapClaims{
+		"iss": p.ClientID,
+		"sub": p.ClientID,
+		"aud": audience,
+		"jti": hex.EncodeToString(b),
+		"iat": now.Unix(),
+		"exp": now.Add(clientAssertionLifetime).Unix(),
+	})
+	if p.KeyID != "" {
+		token.Header["kid"] = p.KeyID
+	}
+
+	return token.SignedString(p.Key)
+}
+
+// TokenSource returns an oauth2.TokenSource that uses the client credentials flow at tokenURL, authenticating with a
+// client assertion instead of a client secret. Tokens are cached and refreshed before they expire.
+func (p *PrivateKeyJWT) TokenSource(ctx context.Context, tokenURL string, endpointParams url.Values, scopes ...string) oauth2.TokenSource {
+	return RefreshingTokenSource(tokenSourceFunc(func() (*oauth2.Token, error) {
+		// Assertions can only be used once, so every request needs a new one.
+		assertion, err := p.Assertion(tokenURL)
+		if err != nil {
+			return nil, err
+		}
+
+		params := url.Values{}
+		for key, values := range endpointParams {
+			params[key] = values
+		}
+		params.Set("client_assertion_type", ClientAssertionType)
+		params.Set("client_assertion", assertion)
+		config := &clientcredentials.Config{
+			ClientID:       p.ClientID,
+			TokenURL:       tokenURL,
+			Scopes:         scopes,
+			EndpointParams: params,
+			AuthStyle:      oauth2.AuthStyleInParams,
+		}
+		return config.Token(ctx)
+	}), DefaultTokenRefreshWindow)
+}
+
+// Credentials returns credentials.PerRPCCredentials that send a new client assertion for audience with every call,
+// for servers that accept them directly with ClientAssertion.
+// Use it with grpc.WithPerRPCCredentials.
+func (p *PrivateKeyJWT) Credentials(audience string) credentials.PerRPCCredentials {
+	return &privateKeyJWTCredentials{privateKeyJWT: p, audience: audience}
+}
+
+type privateKeyJWTCredentials struct {
+	privateKeyJWT *PrivateKeyJWT
+	audience      string
+}
+
+// GetRequestMetadata signs a client assertion for the call.
+func (c *privateKeyJWTCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	assertion, err := c.privateKeyJWT.Assertion(c.audience)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{"authorization": "Bearer " + assertion}, nil
+}
+
+// RequireTransportSecurity is true since anyone who sees an assertion can use it until it expires.
+func (c *privateKeyJWTCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// AssertionClient is a client that authenticates with ClientAssertion.
+type AssertionClient struct {
+	// Keys is the JWKS the client signs its assertions with.
+	Keys        *JWKSCache
+	Permissions []string
+}
+
+// ClientAssertion authenticates clients that send a JWT client assertion signed by their private key as a bearer
+// token, so the same keys clients use with private_key_jwt at their identity provider can authenticate them directly.
+// The iss and sub claims must both be the client's ID, aud must contain Audience, and each jti can only be used once
+// while the assertion is valid. See PrivateKeyJWT.Credentials for the client side.
+type ClientAssertion struct {
+	// Audience must be in the assertion's aud claim, such as the server's URL.
+	Audience string
+	// Clients maps client IDs to the keys they sign assertions with.
+	Clients map[string]*AssertionClient
+	// MaxLifetime is the longest time between an assertion's iat and exp claims that is accepted, limiting how long
+	// a leaked assertion can be used.
+	// It defaults to 5 minutes.
+	MaxLifetime time.Duration
+
+	// Replay remembers assertions' jti claims. It defaults to an InMemoryReplayStore, so use a shared store such as
+	// RedisReplayStore to reject assertions replayed to other servers.
+	Replay ReplayStore
+
+	JWTValidation
+
+	replay InMemoryReplayStore
+}
+
+// AuthFunc satisfies the AuthFunc interface so clients can authenticate with signed client assertions.
+func (c *ClientAssertion) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	tokenString, err := bearerToken(md)
+	if err != nil {
+		return nil, err
+	}
+
+	var client *AssertionClient
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if err := c.verifySigningMethod(token); err != nil {
+			return nil, err
+		}
+
+		// The key is chosen by the unverified iss claim, which is checked against sub once the signature is verified.
+		claims := token.Claims.(jwt.MapClaims)
+		clientID, _ := claims["iss"].(string)
+		var ok bool
+		client, ok = c.Clients[clientID]
+		if !ok {
+			return nil, fmt.Errorf("unknown client: %v", clientID)
+		}
+
+		return keyFromJWKS(client.Keys, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	clientID, _ := claims["iss"].(string)
+	if claims["sub"] != clientID {
+		return nil, fmt.Errorf("client assertion sub must match iss, got %v", claims["sub"])
+	}
+
+	if !verifyAudience(claims, c.Audience) {
+		return nil, fmt.Errorf("invalid audience, expected %s, got %v", c.Audience, claims["aud"])
+	}
+
+	now := time.Now()
+	err = c.verifyTimes(claims, now)
+	if err != nil {
+		return nil, err
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("client assertion has no exp claim")
+	}
+
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("client assertion has no iat claim")
+	}
+
+	expiry := time.Unix(int64(exp), 0)
+	if expiry.Sub(time.Unix(int64(iat), 0)) > c.maxLifetime() {
+		return nil, fmt.Errorf("client assertion is valid for longer than %v", c.maxLifetime())
+	}
+
+	err = c.verifyClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return nil, fmt.Errorf("client assertion has no jti claim")
+	}
+
+	// The assertion is accepted for Leeway after it expires, so its jti has to be remembered for as long.
+	expiry = c.tokenExpiry(claims)
+	fresh, err := c.replayStore().Use(context.Background(), clientID+":"+jti, expiry)
+	if err != nil {
+		return nil, fmt.Errorf("checking client assertion jti: %v", err)
+	}
+
+	if !fresh {
+		return nil, fmt.Errorf("client assertion has already been used")
+	}
+
+	return &AuthResult{
+		ClientIdentifier: clientID,
+		Timestamp:        now,
+		Permissions:      client.Permissions,
+		Claims:           claims,
+		Expiry:           expiry,
+	}, nil
+}
+
+func (c *ClientAssertion) maxLifetime() time.Duration {
+	if c.MaxLifetime == 0 {
+		return defaultClientAssertionMaxLifetime
+	}
+	return c.MaxLifetime
+}
+
+// replayStore returns the ClientAssertion's ReplayStore, or its own InMemoryReplayStore if it doesn't have one.
+func (c *ClientAssertion) replayStore() ReplayStore {
+	if c.Replay != nil {
+		return c.Replay
+	}
+	return &c.replay
+}
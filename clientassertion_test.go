@@ -0,0 +1,116 @@
+package grpcauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+func newTestClientAssertion(t *testing.T, issuer *testIssuer, audience string) *ClientAssertion {
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/jwks"
+	return &ClientAssertion{
+		Audience: audience,
+		Clients: map[string]*AssertionClient{
+			testClientName: {Keys: &JWKSCache{URL: jwksURL}, Permissions: []string{targetMethodName}},
+		},
+	}
+}
+
+func TestClientAssertionAcceptsPrivateKeyJWTCredentials(t *testing.T) {
+	issuer := newTestIssuer(t)
+	clientAssertion := newTestClientAssertion(t, issuer, testAudience)
+	privateKeyJWT := &PrivateKeyJWT{ClientID: testClientName, Key: issuer.key, KeyID: testKeyID}
+
+	requestMetadata, err := privateKeyJWT.Credentials(testAudience).GetRequestMetadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	md := metadata.New(requestMetadata)
+	authResult, err := clientAssertion.AuthFunc(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName || len(authResult.Permissions) != 1 {
+		t.Fatalf("expected %v with its permissions, got %v", testClientName, authResult)
+	}
+
+	_, err = clientAssertion.AuthFunc(md)
+	if err == nil {
+		t.Fatalf("expected replayed assertion to be rejected")
+	}
+}
+
+func TestClientAssertionRejectsInvalidAssertions(t *testing.T) {
+	issuer := newTestIssuer(t)
+	clientAssertion := newTestClientAssertion(t, issuer, testAudience)
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": testClientName,
+			"sub": testClientName,
+			"aud": testAudience,
+			"jti": now.String(),
+			"iat": now.Unix(),
+			"exp": now.Add(time.Minute).Unix(),
+		}
+	}
+
+	tests := map[string]func(claims jwt.MapClaims){
+		"wrong subject":  func(claims jwt.MapClaims) { claims["sub"] = "other" },
+		"unknown client": func(claims jwt.MapClaims) { claims["iss"], claims["sub"] = "other", "other" },
+		"wrong audience": func(claims jwt.MapClaims) { claims["aud"] = "https://other.example.com" },
+		"no jti":         func(claims jwt.MapClaims) { delete(claims, "jti") },
+		"no exp":         func(claims jwt.MapClaims) { delete(claims, "exp") },
+		"long lifetime":  func(claims jwt.MapClaims) { claims["exp"] = now.Add(time.Hour).Unix() },
+		"expired":        func(claims jwt.MapClaims) { claims["exp"] = now.Add(-time.Minute).Unix() },
+	}
+
+	for name, modify := range tests {
+		claims := valid()
+		modify(claims)
+		_, err := clientAssertion.AuthFunc(bearerMetadata(issuer.sign(t, claims)))
+		if err == nil {
+			t.Fatalf("%s: expected assertion to be rejected", name)
+		}
+	}
+}
+
+func TestPrivateKeyJWTTokenSource(t *testing.T) {
+	issuer := newTestIssuer(t)
+	var clientAssertion *ClientAssertion
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_assertion_type") != ClientAssertionType || r.FormValue("client_secret") != "" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+
+		_, err := clientAssertion.AuthFunc(bearerMetadata(r.FormValue("client_assertion")))
+		if err != nil {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"words","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+	clientAssertion = newTestClientAssertion(t, issuer, server.URL)
+
+	privateKeyJWT := &PrivateKeyJWT{ClientID: testClientName, Key: issuer.key, KeyID: testKeyID}
+	token, err := privateKeyJWT.TokenSource(context.Background(), server.URL, nil).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken != "words" {
+		t.Fatalf("expected access token, got %v", token.AccessToken)
+	}
+}
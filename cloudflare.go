@@ -0,0 +1,85 @@
+package grpcauth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// CloudflareAccessMetadataKey is the metadata field Cloudflare Access puts its signed assertion in.
+	// Pass it to WithMetadataKey when using Cloudflare Access with an Authority.
+	CloudflareAccessMetadataKey = "cf-access-jwt-assertion"
+)
+
+// CloudflareAccess authenticates requests arriving through Cloudflare Access, such as gRPC servers fronted
+// by a Cloudflare Tunnel, by verifying the JWT Access adds to each request against the team domain's certs.
+// Users are identified by their email and service tokens by their client ID.
+// Cloudflare Access asserts identity in the cf-access-jwt-assertion header instead of authorization, so use it with
+// WithMetadataKey(CloudflareAccessMetadataKey).
+// See https://developers.cloudflare.com/cloudflare-one/identity/authorization-cookie/validating-json/ for more details.
+type CloudflareAccess struct {
+	// TeamDomain is the Zero Trust team domain, such as https://example.cloudflareaccess.com.
+	TeamDomain *url.URL
+	// Audience is the Application Audience (AUD) tag of the Access application.
+	Audience string
+	// Permissions maps the email or service token client ID of each identity allowed to call the server to its permissions.
+	// Cloudflare Access does its own authorization, so when it is nil every identity it lets through is accepted
+	// with no permissions.
+	Permissions map[string][]string
+
+	JWTValidation
+}
+
+// AuthFunc satisfies the AuthFunc interface so services behind Cloudflare Access can trust the asserted identity.
+func (c *CloudflareAccess) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	assertions := md.Get(CloudflareAccessMetadataKey)
+	if len(assertions) != 1 {
+		return nil, fmt.Errorf("expected JWT in '%s' metadata field", CloudflareAccessMetadataKey)
+	}
+
+	teamDomain := strings.TrimSuffix(c.TeamDomain.String(), "/")
+	certsURL, err := url.Parse(teamDomain + "/cdn-cgi/access/certs")
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := verifyJWT(assertions[0], certsURL, c.Audience, c.JWTValidation)
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyIssuer(teamDomain, true) {
+		return nil, fmt.Errorf("invalid issuer, expected %s, got %v", teamDomain, claims["iss"])
+	}
+
+	// Service tokens have no email, so they're identified by their client ID in the common_name claim.
+	identity, _ := claims["email"].(string)
+	if identity == "" {
+		identity, _ = claims["common_name"].(string)
+	}
+
+	if identity == "" {
+		return nil, fmt.Errorf("Cloudflare Access token has no email or common_name claim")
+	}
+
+	var permissions []string
+	if c.Permissions != nil {
+		var ok bool
+		permissions, ok = c.Permissions[identity]
+		if !ok {
+			return nil, fmt.Errorf("%s is not allowed", identity)
+		}
+	}
+
+	return &AuthResult{
+		ClientIdentifier: identity,
+		Timestamp:        time.Now(),
+		Permissions:      permissions,
+		Claims:           claims,
+		Expiry:           c.tokenExpiry(claims),
+	}, nil
+}
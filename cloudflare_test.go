@@ -0,0 +1,40 @@
+package grpcauth
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestCloudflareAccessIdentifiesServiceTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	cloudflare := &CloudflareAccess{
+		TeamDomain:  issuer.url(t),
+		Audience:    testAudience,
+		Permissions: map[string][]string{"88bf3b6d86161464f6509f7219099e57.access": {targetMethodName}},
+	}
+
+	claims := issuer.claims()
+	claims["sub"] = ""
+	claims["common_name"] = "88bf3b6d86161464f6509f7219099e57.access"
+	authResult, err := cloudflare.AuthFunc(metadata.Pairs(CloudflareAccessMetadataKey, issuer.sign(t, claims)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != "88bf3b6d86161464f6509f7219099e57.access" {
+		t.Fatalf("expected service token client ID, got %v", authResult.ClientIdentifier)
+	}
+
+	if !reflect.DeepEqual(authResult.Permissions, []string{targetMethodName}) {
+		t.Fatalf("expected %v, got %v", []string{targetMethodName}, authResult.Permissions)
+	}
+
+	claims["common_name"] = ""
+	claims["email"] = "intruder@example.com"
+	_, err = cloudflare.AuthFunc(metadata.Pairs(CloudflareAccessMetadataKey, issuer.sign(t, claims)))
+	if err == nil {
+		t.Fatalf("expected error with unknown identity")
+	}
+}
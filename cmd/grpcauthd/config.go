@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+
+	"github.com/joncooperworks/grpcauth"
+	"gopkg.in/yaml.v3"
+)
+
+// config is grpcauthd's configuration file.
+//
+//	listen: ":9191"
+//	policy: /etc/grpcauthd/policy.yaml
+//	provider:
+//	  type: oidc
+//	  issuer: https://auth.example.com/
+//	  audience: https://api.example.com
+type config struct {
+	// Listen is the address the Check RPC is served on.
+	Listen string `yaml:"listen"`
+	// Policy is the path of a grpcauth policy file, which is reloaded on SIGHUP.
+	Policy string `yaml:"policy"`
+	// ClientIdentifierHeader is added to allowed requests with the client's identifier.
+	ClientIdentifierHeader string         `yaml:"client_identifier_header"`
+	Provider               providerConfig `yaml:"provider"`
+}
+
+// providerConfig configures the identity provider clients authenticate with.
+type providerConfig struct {
+	// Type is oidc, to discover the provider's keys from its issuer, or jwt, to use jwks_url directly.
+	Type     string `yaml:"type"`
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	JWKSURL  string `yaml:"jwks_url"`
+}
+
+// loadConfig reads the config file at path.
+func loadConfig(path string) (*config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config{Listen: ":9191"}
+	err = yaml.Unmarshal(b, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	if cfg.Policy == "" {
+		return nil, fmt.Errorf("config file %s has no policy", path)
+	}
+
+	return cfg, nil
+}
+
+// authFunc returns an AuthFunc for the configured identity provider.
+func (p *providerConfig) authFunc(ctx context.Context) (grpcauth.AuthFunc, error) {
+	switch p.Type {
+	case "oidc":
+		issuer, err := url.Parse(p.Issuer)
+		if err != nil {
+			return nil, err
+		}
+
+		oidc, err := grpcauth.NewOIDC(ctx, issuer, p.Audience)
+		if err != nil {
+			return nil, err
+		}
+
+		return oidc.AuthFunc, nil
+	case "jwt":
+		jwksURL, err := url.Parse(p.JWKSURL)
+		if err != nil {
+			return nil, err
+		}
+
+		return grpcauth.NewJWTValidator(jwksURL, p.Issuer, p.Audience).AuthFunc, nil
+	default:
+		return nil, fmt.Errorf("unknown provider type %q, expected oidc or jwt", p.Type)
+	}
+}
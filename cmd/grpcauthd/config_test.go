@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpcauthd.yaml")
+	err := ioutil.WriteFile(path, []byte(`
+policy: policy.yaml
+provider:
+  type: jwt
+  issuer: https://auth.example.com/
+  audience: https://api.example.com
+  jwks_url: https://auth.example.com/.well-known/jwks.json
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Listen != ":9191" {
+		t.Fatalf("expected default listen address, got %s", cfg.Listen)
+	}
+
+	_, err = cfg.Provider.authFunc(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Provider.Type = "saml"
+	_, err = cfg.Provider.authFunc(context.Background())
+	if err == nil {
+		t.Fatalf("expected unknown provider type to be rejected")
+	}
+}
@@ -0,0 +1,61 @@
+// Command grpcauthd is an authorization sidecar that makes grpcauth decisions for services in any language.
+// It authenticates clients with an identity provider, authorizes them with a grpcauth policy file and serves the
+// decisions over Envoy's external authorization Check RPC, which Envoy or any gRPC client can call.
+//
+//	grpcauthd -config /etc/grpcauthd/config.yaml
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"syscall"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/joncooperworks/grpcauth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func main() {
+	configPath := flag.String("config", "grpcauthd.yaml", "path to the config file")
+	flag.Parse()
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	authFunc, err := cfg.Provider.authFunc(ctx)
+	if err != nil {
+		log.Fatalf("error configuring provider: %v", err)
+	}
+
+	policyFile, err := grpcauth.LoadPolicyFile(cfg.Policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	policyFile.OnReloadError = func(err error) {
+		log.Printf("error reloading policy: %v", err)
+	}
+	go policyFile.ReloadOnSignal(ctx, syscall.SIGHUP)
+
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := grpc.NewServer()
+	authv3.RegisterAuthorizationServer(server, &grpcauth.EnvoyAuthorizer{
+		Authority:              grpcauth.NewAuthority(authFunc, policyFile.PermissionFunc),
+		ClientIdentifierHeader: cfg.ClientIdentifierHeader,
+	})
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	log.Printf("grpcauthd listening on %s", listener.Addr())
+	log.Fatal(server.Serve(listener))
+}
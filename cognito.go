@@ -1,25 +1,15 @@
 package grpcauth
 
 import (
-	"bytes"
 	"context"
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/base64"
-	"encoding/json"
-	"encoding/pem"
-	"errors"
 	"fmt"
-	"io/ioutil"
-	"math/big"
-	"net/http"
 	"net/url"
 	"strings"
-	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/oauth"
 	"google.golang.org/grpc/metadata"
 )
@@ -37,22 +27,30 @@ func AWSCognitoAppClientCredentials(ctx context.Context, clientID, clientSecret,
 		TokenURL:     tokenURL,
 		Scopes:       scopes,
 	}
-	return grpc.WithPerRPCCredentials(oauth.TokenSource{TokenSource: config.TokenSource(ctx)})
+	return grpc.WithPerRPCCredentials(oauth.TokenSource{TokenSource: clientCredentialsTokenSource(ctx, config)})
 }
 
-// awsJWKEndpoint is a list of auth0JWK from AWS Congnito.
-type awsJWKEndpoint struct {
-	Keys []awsJWK `json:"keys"`
+// AWSCognitoCredentials returns credentials.PerRPCCredentials that attach an access token for an AWS Cognito App
+// client to every call, for clients that build their own dial options.
+func AWSCognitoCredentials(ctx context.Context, userPoolDomain, clientID, clientSecret string, scopes ...string) credentials.PerRPCCredentials {
+	return oauth.TokenSource{TokenSource: AWSCognitoTokenSource(ctx, userPoolDomain, clientID, clientSecret, scopes...)}
 }
 
-// JSONWebKeys is a single JWK from AWS Cognito that the AWS Cognito JWT will be signed with.
-type awsJWK struct {
-	Kty string `json:"kty"`
-	Kid string `json:"kid"`
-	Use string `json:"use"`
-	N   string `json:"n"`
-	E   string `json:"e"`
-	Alg string `json:"alg"`
+// AWSCognitoTokenSource returns an oauth2.TokenSource that exchanges an AWS Cognito App client's ID and secret for
+// access tokens at the user pool's domain, such as https://example.auth.us-east-1.amazoncognito.com, caching each token
+// and refreshing it before it expires.
+// Use it with TokenUnaryClientInterceptor and TokenStreamClientInterceptor to set credentials per call.
+// See https://docs.aws.amazon.com/cognito/latest/developerguide/token-endpoint.html
+func AWSCognitoTokenSource(ctx context.Context, userPoolDomain, clientID, clientSecret string, scopes ...string) oauth2.TokenSource {
+	config := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     strings.TrimSuffix(userPoolDomain, "/") + "/oauth2/token",
+		Scopes:       scopes,
+		// Cognito only accepts the client's credentials in the Authorization header.
+		AuthStyle: oauth2.AuthStyleInHeader,
+	}
+	return clientCredentialsTokenSource(ctx, config)
 }
 
 // AWSCognitoM2M authenticates incoming gRPC requests from AWS Cognito App clients.
@@ -60,43 +58,18 @@ type AWSCognitoM2M struct {
 	Domain        *url.URL
 	APIIdentifier string
 	JWKSURL       *url.URL
+
+	JWTValidation
 }
 
 // AuthFunc satisfies the AuthFunc interface so clients can use AWS Cognito App clients with a gRPC Server.
 // See https://docs.aws.amazon.com/cognito/latest/developerguide/amazon-cognito-user-pools-using-tokens-verifying-a-jwt.html
 func (a *AWSCognitoM2M) AuthFunc(md metadata.MD) (*AuthResult, error) {
-	if len(md["authorization"]) != 1 {
-		return nil, fmt.Errorf("expected JWT in 'authorization' metadata field")
-	}
-
-	tokenString := md["authorization"][0]
-	tokenString = strings.Replace(tokenString, "Bearer ", "", 1)
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok && token.Header["alg"] != signingMethod {
-			return nil, fmt.Errorf("unexpected signing method: expected %s, got %v", signingMethod, token.Header["alg"])
-		}
-
-		cert, err := a.getPemCert(token)
-		if err != nil {
-			return nil, err
-		}
-
-		return jwt.ParseRSAPublicKeyFromPEM([]byte(cert))
-	})
-
+	claims, err := verifyBearerToken(md, a.JWKSURL, a.APIIdentifier, a.JWTValidation)
 	if err != nil {
 		return nil, err
 	}
 
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
-	}
-
-	claims := token.Claims.(jwt.MapClaims)
-	checkAud := claims.VerifyAudience(a.APIIdentifier, false)
-	if !checkAud {
-		return nil, fmt.Errorf("invalid audience, expected %s, got %v", a.APIIdentifier, claims["aud"])
-	}
 	// Verify 'iss' claim
 	checkIss := claims.VerifyIssuer(a.Domain.String(), false)
 	if !checkIss {
@@ -109,86 +82,6 @@ func (a *AWSCognitoM2M) AuthFunc(md metadata.MD) (*AuthResult, error) {
 		return nil, fmt.Errorf("token_use claim must be 'access', got %s", tokenUse)
 	}
 
-	// auth0 puts the client's OAuth2 client ID in the sub field.
-	clientIdentifier := claims["sub"].(string)
-
-	scopes, _ := claims["scope"].(string)
-	permissions := strings.Split(scopes, " ")
-	return &AuthResult{
-		ClientIdentifier: clientIdentifier,
-		Timestamp:        time.Now(),
-		Permissions:      permissions,
-	}, nil
-}
-
-func (a *AWSCognitoM2M) getPemCert(token *jwt.Token) (string, error) {
-	var cert string
-	resp, err := http.Get(a.JWKSURL.String())
-	if err != nil {
-		return cert, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		b, _ := ioutil.ReadAll(resp.Body)
-		return cert, errors.New(string(b))
-	}
-
-	var jwks = awsJWKEndpoint{}
-	err = json.NewDecoder(resp.Body).Decode(&jwks)
-
-	if err != nil {
-		return cert, err
-	}
-
-	for k := range jwks.Keys {
-		if token.Header["kid"] == jwks.Keys[k].Kid {
-			cert, err = pemFromJWK(&jwks.Keys[k])
-			if err != nil {
-				return cert, err
-			}
-		}
-	}
-
-	if cert == "" {
-		return cert, fmt.Errorf("key not found: %v", token.Header["kid"])
-	}
-
-	return cert, nil
-}
-
-func pemFromJWK(jwk *awsJWK) (string, error) {
-	var cert string
-	nb, err := base64.RawURLEncoding.DecodeString(jwk.N)
-	if err != nil {
-		return cert, err
-	}
-
-	var e int
-	if jwk.E == "AQAB" || jwk.E == "AAEAAQ" {
-		e = 65537
-	} else {
-		// need to decode "e" as a big-endian int
-		return cert, fmt.Errorf("need to deocde e: %v", jwk.E)
-	}
-
-	n := new(big.Int).SetBytes(nb)
-	pk := &rsa.PublicKey{
-		N: n,
-		E: e,
-	}
-	der, err := x509.MarshalPKIXPublicKey(pk)
-	if err != nil {
-		return cert, err
-	}
-
-	block := &pem.Block{
-		Type:  "BEGIN CERTIFICATE",
-		Bytes: der,
-	}
-
-	var out bytes.Buffer
-	err = pem.Encode(&out, block)
-	cert = out.String()
-	return cert, err
+	// AWS Cognito puts the app client's ID in the sub field.
+	return authResultFromClaims(claims, a.Leeway)
 }
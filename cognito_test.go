@@ -1 +1,36 @@
 package grpcauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAWSCognitoTokenSourceUsesUserPoolDomain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if r.URL.Path != "/oauth2/token" || !ok || clientID != "client" || clientSecret != "secret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+
+		if r.FormValue("scope") != "api/read" {
+			http.Error(w, `{"error":"invalid_scope"}`, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"words","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	token, err := AWSCognitoTokenSource(context.Background(), server.URL+"/", "client", "secret", "api/read").Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken != "words" {
+		t.Fatalf("expected access token from Cognito, got %v", token.AccessToken)
+	}
+}
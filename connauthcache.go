@@ -0,0 +1,55 @@
+package grpcauth
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+// connAuthCacheKey is the context key for a connection's AuthCache.
+type connAuthCacheKey struct{}
+
+// ConnectionAuthCache caches AuthResults for each gRPC connection by a hash of the client's credentials, so clients
+// on long-lived channels are authenticated once per connection instead of on every RPC.
+// It is a stats.Handler that gives every connection its own AuthCache, which is dropped when the connection closes,
+// so a token is never trusted on a connection it wasn't presented on.
+// Only credentials in MetadataKey are cached, so requests without them, like ones authenticated by their client
+// certificate alone, are passed to the ContextAuthFunc every time.
+// Install it with grpc.StatsHandler and wrap a ContextAuthFunc with ContextAuthFunc.
+type ConnectionAuthCache struct {
+	// TTL is the longest a result is cached for. Results are never cached past the token's exp claim.
+	TTL time.Duration
+	// MetadataKey is the metadata field holding the credentials. It defaults to authorization.
+	MetadataKey string
+}
+
+// ContextAuthFunc returns a ContextAuthFunc that calls authFunc when the client's credentials aren't cached for its
+// connection. Requests on servers without the ConnectionAuthCache installed are always passed to authFunc.
+func (c *ConnectionAuthCache) ContextAuthFunc(authFunc ContextAuthFunc) ContextAuthFunc {
+	return func(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+		cache, ok := ctx.Value(connAuthCacheKey{}).(*AuthCache)
+		if !ok {
+			return authFunc(ctx, md)
+		}
+
+		return cache.ContextAuthFunc(authFunc)(ctx, md)
+	}
+}
+
+// TagConn satisfies the stats.Handler interface by giving the connection its own AuthCache.
+func (c *ConnectionAuthCache) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return context.WithValue(ctx, connAuthCacheKey{}, &AuthCache{TTL: c.TTL, MetadataKey: c.MetadataKey})
+}
+
+// HandleConn satisfies the stats.Handler interface.
+func (c *ConnectionAuthCache) HandleConn(ctx context.Context, s stats.ConnStats) {}
+
+// TagRPC satisfies the stats.Handler interface.
+func (c *ConnectionAuthCache) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+// HandleRPC satisfies the stats.Handler interface.
+func (c *ConnectionAuthCache) HandleRPC(ctx context.Context, s stats.RPCStats) {}
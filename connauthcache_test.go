@@ -0,0 +1,73 @@
+package grpcauth
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestConnectionAuthCache(t *testing.T) {
+	var calls int32
+	cache := &ConnectionAuthCache{TTL: time.Minute}
+	authFunc := cache.ContextAuthFunc(func(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+		atomic.AddInt32(&calls, 1)
+		return &AuthResult{ClientIdentifier: testClientName, Permissions: []string{"/grpc.health.v1.Health/Check"}}, nil
+	})
+
+	authority := NewContextAuthority(authFunc, nil)
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(append(ServerOptions(authority, nil, nil), grpc.StatsHandler(cache))...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+
+	dial := func() healthpb.HealthClient {
+		conn, err := grpc.Dial("bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return healthpb.NewHealthClient(conn)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "bearer words")
+	first := dial()
+	for i := 0; i < 3; i++ {
+		_, err := first.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected the client to be authenticated once on its connection, got %d calls", calls)
+	}
+
+	_, err := dial().Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected the token to be authenticated again on a new connection, got %d calls", calls)
+	}
+
+	_, err = authFunc(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	if err != nil || calls != 3 {
+		t.Fatalf("expected requests without a connection to be authenticated, got %v after %d calls", err, calls)
+	}
+}
@@ -0,0 +1,79 @@
+package grpcauth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// CookieMetadataKey is the metadata field browsers send cookies in through grpc-web proxies.
+	// HTTP/2 clients can split cookies across several fields, so use CookieCredentials with WithoutMetadataKey.
+	CookieMetadataKey = "cookie"
+
+	defaultCSRFCookieName = "csrf_token"
+	defaultCSRFHeader     = "x-csrf-token"
+)
+
+// CookieCredentials authenticates browser clients using grpc-web, which can't set arbitrary authorization headers,
+// with a token stored in a cookie.
+// Since browsers send cookies with cross-site requests, it requires the double submit CSRF defence: the client must
+// copy the value of a CSRF cookie into a header, which other sites can't read or set.
+// The token is passed to TokenAuthFunc as a bearer token in the authorization metadata field.
+// See https://cheatsheetseries.owasp.org/cheatsheets/Cross-Site_Request_Forgery_Prevention_Cheat_Sheet.html for
+// more details.
+type CookieCredentials struct {
+	// CookieName is the cookie the client's token is stored in.
+	CookieName string
+	// CSRFCookieName is the cookie holding the CSRF token. It defaults to csrf_token.
+	CSRFCookieName string
+	// CSRFHeader is the metadata field the client must copy the CSRF token into. It defaults to x-csrf-token.
+	CSRFHeader string
+	// TokenAuthFunc authenticates the token, like JWTValidator.AuthFunc.
+	TokenAuthFunc AuthFunc
+}
+
+// AuthFunc satisfies the AuthFunc interface so browsers can authenticate with a cookie over grpc-web.
+func (c *CookieCredentials) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	r := &http.Request{Header: http.Header{"Cookie": md.Get(CookieMetadataKey)}}
+	token, err := r.Cookie(c.CookieName)
+	if err != nil || token.Value == "" {
+		return nil, fmt.Errorf("expected token in %s cookie", c.CookieName)
+	}
+
+	err = c.verifyCSRF(r, md)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenMetadata := md.Copy()
+	tokenMetadata.Set("authorization", "Bearer "+token.Value)
+	return c.TokenAuthFunc(tokenMetadata)
+}
+
+// verifyCSRF checks that the CSRF header matches the CSRF cookie.
+func (c *CookieCredentials) verifyCSRF(r *http.Request, md metadata.MD) error {
+	csrfCookieName := c.CSRFCookieName
+	if csrfCookieName == "" {
+		csrfCookieName = defaultCSRFCookieName
+	}
+
+	csrfHeader := c.CSRFHeader
+	if csrfHeader == "" {
+		csrfHeader = defaultCSRFHeader
+	}
+
+	csrfCookie, err := r.Cookie(csrfCookieName)
+	if err != nil || csrfCookie.Value == "" {
+		return fmt.Errorf("expected CSRF token in %s cookie", csrfCookieName)
+	}
+
+	values := md.Get(csrfHeader)
+	if len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), []byte(csrfCookie.Value)) != 1 {
+		return fmt.Errorf("%s doesn't match the CSRF cookie", csrfHeader)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package grpcauth
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestCookieCredentials(t *testing.T) {
+	credentials := &CookieCredentials{
+		CookieName: "session",
+		TokenAuthFunc: func(md metadata.MD) (*AuthResult, error) {
+			token, err := bearerToken(md)
+			if err != nil || token != "words" {
+				return nil, ErrCredentialNotFound
+			}
+
+			return testPermissionedAuthResult, nil
+		},
+	}
+
+	tests := []struct {
+		md       metadata.MD
+		expected bool
+	}{
+		{metadata.Pairs("cookie", "session=words; csrf_token=nonce", "x-csrf-token", "nonce"), true},
+		{metadata.Pairs("cookie", "session=words", "cookie", "csrf_token=nonce", "x-csrf-token", "nonce"), true},
+		{metadata.Pairs("cookie", "session=words; csrf_token=nonce"), false},
+		{metadata.Pairs("cookie", "session=words; csrf_token=nonce", "x-csrf-token", "forged"), false},
+		{metadata.Pairs("cookie", "session=words", "x-csrf-token", ""), false},
+		{metadata.Pairs("cookie", "session=other; csrf_token=nonce", "x-csrf-token", "nonce"), false},
+		{metadata.Pairs("authorization", "Bearer words"), false},
+	}
+
+	for _, test := range tests {
+		_, err := credentials.AuthFunc(test.md)
+		if (err == nil) != test.expected {
+			t.Fatalf("expected %v to be accepted: %v, got %v", test.md, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package grpcauth
+
+import (
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxCachedDenials is how many denial errors are kept before the cache is cleared, so clients can't grow it forever
+// by calling with ever changing identifiers.
+const maxCachedDenials = 1024
+
+// denials keeps the errors clients are denied permission with, which only depend on the client, its permissions and
+// the method, so a denial storm, like a fleet retrying with a token that lacks a scope, doesn't build and encode an
+// error with ErrorInfo details on every request.
+var denials = &denialCache{}
+
+// denialKey identifies the shape of a denial. Minimal denials only have a method.
+type denialKey struct {
+	minimal          bool
+	clientIdentifier string
+	methodName       string
+	permissions      string
+}
+
+// denialCache is a bounded cache of denial errors. Status errors are immutable, so they can be shared between
+// requests.
+type denialCache struct {
+	mu      sync.RWMutex
+	entries map[denialKey]error
+}
+
+// permissionDenied returns the PermissionDenied status with a PermissionDeniedError for authResult and methodName.
+func (c *denialCache) permissionDenied(authResult *AuthResult, methodName string) error {
+	key := denialKey{
+		clientIdentifier: authResult.ClientIdentifier,
+		methodName:       methodName,
+		// Permissions can't contain spaces, so joining them can't make two sets look the same.
+		permissions: strings.Join(authResult.Permissions, " "),
+	}
+
+	return c.get(key, func() error {
+		permissionDenied := &PermissionDeniedError{
+			ClientIdentifier:    authResult.ClientIdentifier,
+			PermissionRequested: methodName,
+			ClientPermissions:   authResult.Permissions,
+		}
+		return permissionDenied.GRPCStatus().Err()
+	})
+}
+
+// minimal returns the PermissionDenied status MinimalErrors sends for methodName.
+func (c *denialCache) minimal(methodName string) error {
+	return c.get(denialKey{minimal: true, methodName: methodName}, func() error {
+		st := status.Newf(codes.PermissionDenied, "permission denied for %s", methodName)
+		return withErrorInfo(st, ReasonPermissionDenied, map[string]string{"permissionRequested": methodName}).Err()
+	})
+}
+
+// get returns the cached error for key, building and caching it with build if it isn't cached.
+func (c *denialCache) get(key denialKey, build func() error) error {
+	c.mu.RLock()
+	err, ok := c.entries[key]
+	c.mu.RUnlock()
+	if ok {
+		return err
+	}
+
+	err = build()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil || len(c.entries) >= maxCachedDenials {
+		c.entries = make(map[denialKey]error)
+	}
+	c.entries[key] = err
+	return err
+}
@@ -0,0 +1,33 @@
+package grpcauth
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDenialCache(t *testing.T) {
+	cache := &denialCache{}
+	authResult := &AuthResult{ClientIdentifier: testClientName, Permissions: []string{"a", "b"}}
+	first := cache.permissionDenied(authResult, targetMethodName)
+	if cache.permissionDenied(authResult, targetMethodName) != first {
+		t.Fatalf("expected the same denial to be reused")
+	}
+
+	other := cache.permissionDenied(&AuthResult{ClientIdentifier: testClientName, Permissions: []string{"a"}}, targetMethodName)
+	details, _ := PermissionDeniedDetails(other)
+	if other == first || len(details.ClientPermissions) != 1 {
+		t.Fatalf("expected clients with other permissions to get their own denial, got %v", details)
+	}
+
+	if cache.minimal(targetMethodName) != cache.minimal(targetMethodName) {
+		t.Fatalf("expected minimal denials to be reused")
+	}
+
+	for i := 0; i < 2*maxCachedDenials; i++ {
+		cache.permissionDenied(&AuthResult{ClientIdentifier: fmt.Sprint(i)}, targetMethodName)
+	}
+
+	if len(cache.entries) > maxCachedDenials {
+		t.Fatalf("expected the cache to be bounded, got %d entries", len(cache.entries))
+	}
+}
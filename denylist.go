@@ -0,0 +1,311 @@
+package grpcauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The kinds of DenylistEntry.
+const (
+	// DenylistPeer entries deny an IP address, as returned by PeerIP.
+	DenylistPeer = "peer"
+	// DenylistClient entries deny a client identifier.
+	DenylistClient = "client"
+)
+
+const (
+	// DefaultDenylistFindTime is how long a Denylist counts failures for before forgetting them.
+	DefaultDenylistFindTime = 10 * time.Minute
+
+	// DefaultDenylistBanTime is how long a Denylist denies peers and clients that failed too many times.
+	DefaultDenylistBanTime = time.Hour
+)
+
+// errDenylisted is returned to peers and clients on a Denylist.
+var errDenylisted = status.Error(codes.PermissionDenied, "denied")
+
+// DenylistEntry is a peer or client on a Denylist.
+type DenylistEntry struct {
+	// Kind is DenylistPeer or DenylistClient.
+	Kind string `json:"kind"`
+	// Value is the IP address or client identifier.
+	Value  string `json:"value"`
+	Reason string `json:"reason,omitempty"`
+	// Expires is when the entry is removed, or zero if it is never removed.
+	Expires time.Time `json:"expires"`
+}
+
+// expired reports whether the entry has been removed as of now.
+func (e *DenylistEntry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires)
+}
+
+// denylistKey identifies a peer or client on a Denylist.
+type denylistKey struct {
+	kind  string
+	value string
+}
+
+// denylistFailures are a peer or client's recent failures.
+type denylistFailures struct {
+	count int
+	first time.Time
+}
+
+// Denylist rejects peers and clients at runtime, like fail2ban. Denied peers are rejected before their credentials are
+// checked, and denied clients are rejected before they are authorized.
+// Entries can be added and removed by hand, and peers that fail to authenticate or clients that are denied
+// MaxFailures times within FindTime are added for BanTime.
+// Use it with WithDenylist, and manage it over HTTP with its ServeHTTP method. It is safe for concurrent use.
+type Denylist struct {
+	// MaxFailures is how many failures within FindTime add a peer or client to the Denylist.
+	// Nothing is added automatically if it is zero.
+	MaxFailures int
+	// FindTime defaults to DefaultDenylistFindTime.
+	FindTime time.Duration
+	// BanTime is how long peers and clients are denied when they fail too many times.
+	// It defaults to DefaultDenylistBanTime.
+	BanTime time.Duration
+	// OnBan is called when a peer or client is added because it failed too many times.
+	OnBan func(entry DenylistEntry)
+
+	mu        sync.Mutex
+	entries   map[denylistKey]*DenylistEntry
+	failures  map[denylistKey]*denylistFailures
+	lastSweep time.Time
+}
+
+// Add denies the peer or client of kind with value for ttl, or forever if ttl is zero.
+func (d *Denylist) Add(kind, value, reason string, ttl time.Duration) {
+	entry := &DenylistEntry{Kind: kind, Value: value, Reason: reason}
+	if ttl > 0 {
+		entry.Expires = time.Now().Add(ttl)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.entries == nil {
+		d.entries = map[denylistKey]*DenylistEntry{}
+	}
+	d.entries[denylistKey{kind, value}] = entry
+}
+
+// Remove removes the peer or client of kind with value, and its failures, and reports whether it was denied.
+func (d *Denylist) Remove(kind, value string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := denylistKey{kind, value}
+	entry, ok := d.entries[key]
+	delete(d.entries, key)
+	delete(d.failures, key)
+	return ok && !entry.expired(time.Now())
+}
+
+// Get returns the entry for the peer or client of kind with value, and false if it isn't denied.
+func (d *Denylist) Get(kind, value string) (DenylistEntry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := denylistKey{kind, value}
+	entry, ok := d.entries[key]
+	if !ok {
+		return DenylistEntry{}, false
+	}
+
+	if entry.expired(time.Now()) {
+		delete(d.entries, key)
+		return DenylistEntry{}, false
+	}
+
+	return *entry, true
+}
+
+// Entries returns the peers and clients that are denied, sorted by kind and value.
+func (d *Denylist) Entries() []DenylistEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	entries := make([]DenylistEntry, 0, len(d.entries))
+	for key, entry := range d.entries {
+		if entry.expired(now) {
+			delete(d.entries, key)
+			continue
+		}
+
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Kind != entries[j].Kind {
+			return entries[i].Kind < entries[j].Kind
+		}
+		return entries[i].Value < entries[j].Value
+	})
+	return entries
+}
+
+// check returns errDenylisted if the peer or client of kind with value is denied.
+func (d *Denylist) check(kind, value string) error {
+	if d == nil || value == "" {
+		return nil
+	}
+
+	if _, denied := d.Get(kind, value); denied {
+		return errDenylisted
+	}
+
+	return nil
+}
+
+// checkPeer returns errDenylisted if the peer making the request in ctx is denied.
+func (d *Denylist) checkPeer(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	return d.check(DenylistPeer, PeerIP(ctx))
+}
+
+// recordFailure counts a failure by the peer or client of kind with value, and denies it once it has failed
+// MaxFailures times within FindTime.
+func (d *Denylist) recordFailure(kind, value string) {
+	if d == nil || value == "" || d.MaxFailures <= 0 {
+		return
+	}
+
+	entry, banned := d.fail(denylistKey{kind, value}, time.Now())
+	if banned && d.OnBan != nil {
+		d.OnBan(entry)
+	}
+}
+
+// fail counts a failure and returns the entry added if it was denied.
+func (d *Denylist) fail(key denylistKey, now time.Time) (DenylistEntry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sweep(now)
+	failures, ok := d.failures[key]
+	if !ok || now.Sub(failures.first) > d.findTime() {
+		failures = &denylistFailures{first: now}
+		d.failures[key] = failures
+	}
+
+	failures.count++
+	if failures.count < d.MaxFailures {
+		return DenylistEntry{}, false
+	}
+
+	banTime := d.BanTime
+	if banTime <= 0 {
+		banTime = DefaultDenylistBanTime
+	}
+
+	delete(d.failures, key)
+	entry := &DenylistEntry{Kind: key.kind, Value: key.value, Reason: "too many failures", Expires: now.Add(banTime)}
+	if d.entries == nil {
+		d.entries = map[denylistKey]*DenylistEntry{}
+	}
+	d.entries[key] = entry
+	return *entry, true
+}
+
+// findTime returns how long failures are counted for.
+func (d *Denylist) findTime() time.Duration {
+	if d.FindTime <= 0 {
+		return DefaultDenylistFindTime
+	}
+	return d.FindTime
+}
+
+// sweep forgets expired entries and failures outside FindTime, at most once per FindTime.
+// It must be called with d.mu held.
+func (d *Denylist) sweep(now time.Time) {
+	if d.failures == nil {
+		d.failures = map[denylistKey]*denylistFailures{}
+	}
+
+	if now.Sub(d.lastSweep) < d.findTime() {
+		return
+	}
+
+	d.lastSweep = now
+	for key, failures := range d.failures {
+		if now.Sub(failures.first) > d.findTime() {
+			delete(d.failures, key)
+		}
+	}
+
+	for key, entry := range d.entries {
+		if entry.expired(now) {
+			delete(d.entries, key)
+		}
+	}
+}
+
+// denylistRequest is the body of a request to add an entry to a Denylist over HTTP.
+type denylistRequest struct {
+	Kind   string `json:"kind"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+	// TTL is a duration like 1h, or empty to deny forever.
+	TTL string `json:"ttl"`
+}
+
+// ServeHTTP lets operators manage the Denylist over HTTP:
+//
+//	GET lists the entries as JSON.
+//	POST adds an entry from a JSON body like {"kind": "peer", "value": "192.0.2.1", "reason": "abuse", "ttl": "1h"}.
+//	DELETE removes the entry in the kind and value query parameters.
+//
+// It doesn't authenticate requests, so it should only be served on an admin port or behind HTTPMiddleware.
+func (d *Denylist) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d.Entries())
+	case http.MethodPost:
+		var req denylistRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if (req.Kind != DenylistPeer && req.Kind != DenylistClient) || req.Value == "" {
+			http.Error(w, "kind must be peer or client and value must be set", http.StatusBadRequest)
+			return
+		}
+
+		var ttl time.Duration
+		if req.TTL != "" {
+			var err error
+			ttl, err = time.ParseDuration(req.TTL)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		d.Add(req.Kind, req.Value, req.Reason, ttl)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if !d.Remove(r.URL.Query().Get("kind"), r.URL.Query().Get("value")) {
+			http.Error(w, "not denied", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,152 @@
+package grpcauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestDenylistEntries(t *testing.T) {
+	denylist := &Denylist{}
+	denylist.Add(DenylistPeer, "192.0.2.1", "abuse", time.Hour)
+	denylist.Add(DenylistClient, testClientName, "", 0)
+	denylist.Add(DenylistPeer, "192.0.2.2", "", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	entries := denylist.Entries()
+	if len(entries) != 2 || entries[0].Kind != DenylistClient || entries[1].Reason != "abuse" {
+		t.Fatalf("expected expired entries to be removed, got %v", entries)
+	}
+
+	if _, denied := denylist.Get(DenylistClient, testClientName); !denied {
+		t.Fatalf("expected entries without a TTL not to expire")
+	}
+
+	if !denylist.Remove(DenylistClient, testClientName) || denylist.Remove(DenylistClient, testClientName) {
+		t.Fatalf("expected Remove to report whether the client was denied")
+	}
+}
+
+func TestDenylistBansOnFailures(t *testing.T) {
+	var banned []DenylistEntry
+	denylist := &Denylist{MaxFailures: 2, FindTime: time.Minute, BanTime: time.Hour}
+	denylist.OnBan = func(entry DenylistEntry) {
+		banned = append(banned, entry)
+	}
+
+	now := time.Now()
+	key := denylistKey{DenylistPeer, "192.0.2.1"}
+	denylist.fail(key, now.Add(-2*time.Minute))
+	if _, ok := denylist.fail(key, now); ok {
+		t.Fatalf("expected failures outside FindTime to be forgotten")
+	}
+
+	denylist.recordFailure(DenylistPeer, "192.0.2.1")
+	if len(banned) != 1 || banned[0].Value != "192.0.2.1" {
+		t.Fatalf("expected the peer to be banned, got %v", banned)
+	}
+
+	entry, denied := denylist.Get(DenylistPeer, "192.0.2.1")
+	if !denied || time.Until(entry.Expires) <= 59*time.Minute {
+		t.Fatalf("expected the peer to be denied for BanTime, got %v", entry)
+	}
+}
+
+func TestAuthorityDenylist(t *testing.T) {
+	calls := 0
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		calls++
+		if md.Get("authorization")[0] == "bearer words" {
+			return &AuthResult{ClientIdentifier: testClientName}, nil
+		}
+		return nil, ErrCredentialNotFound
+	}
+
+	denylist := &Denylist{MaxFailures: 2}
+	a := NewAuthority(authFunc, nil, WithDenylist(denylist)).(*authority)
+	bad := metadata.NewIncomingContext(peerContext("192.0.2.1"), metadata.Pairs("authorization", "bearer bad"))
+	for i := 0; i < 2; i++ {
+		a.authenticateAndAuthorizeContext(bad, targetMethodName)
+	}
+
+	_, err := a.authenticateAndAuthorizeContext(peerContext("192.0.2.1"), targetMethodName)
+	if err != errDenylisted || calls != 2 {
+		t.Fatalf("expected the peer to be denied before authenticating, got %v after %d calls", err, calls)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err = a.authenticateAndAuthorizeContext(peerContext("192.0.2.2"), targetMethodName)
+		if status.Code(err) != codes.PermissionDenied || err == errDenylisted {
+			t.Fatalf("expected the client to be denied by its permissions, got %v", err)
+		}
+	}
+
+	_, err = a.authenticateAndAuthorizeContext(peerContext("192.0.2.3"), targetMethodName)
+	if err != errDenylisted {
+		t.Fatalf("expected the client to be denied from any peer, got %v", err)
+	}
+
+	explanation := a.Explain(context.Background(), metadata.Pairs("authorization", "bearer words"), targetMethodName)
+	if last := explanation.Steps[len(explanation.Steps)-1]; last.Check != CheckDenylist || last.Passed {
+		t.Fatalf("expected the denylist to be explained, got %s", explanation)
+	}
+}
+
+func TestDenylistServeHTTP(t *testing.T) {
+	denylist := &Denylist{}
+	server := httptest.NewServer(denylist)
+	defer server.Close()
+
+	body := `{"kind": "peer", "value": "192.0.2.1", "reason": "abuse", "ttl": "1h"}`
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected the entry to be added, got %s", resp.Status)
+	}
+
+	resp, err = http.Post(server.URL, "application/json", strings.NewReader(`{"kind": "host", "value": "a"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected unknown kinds to be rejected, got %s", resp.Status)
+	}
+
+	resp, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []DenylistEntry
+	err = json.NewDecoder(resp.Body).Decode(&entries)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].Value != "192.0.2.1" || entries[0].Reason != "abuse" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"?kind=peer&value=192.0.2.1", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || len(denylist.Entries()) != 0 {
+		t.Fatalf("expected the entry to be removed, got %s", resp.Status)
+	}
+}
@@ -0,0 +1,236 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// dpopScheme is the authorization scheme for DPoP bound access tokens.
+	dpopScheme = "dpop "
+
+	// dpopMetadataKey is the metadata field clients send DPoP proofs in.
+	dpopMetadataKey = "dpop"
+
+	// dpopProofType is the typ header DPoP proofs must have.
+	dpopProofType = "dpop+jwt"
+
+	// dpopHTTPMethod is the htm claim DPoP proofs for gRPC calls must have, since gRPC always uses POST.
+	dpopHTTPMethod = "POST"
+
+	// defaultDPoPWindow is how far a proof's iat can be from the server's clock by default.
+	defaultDPoPWindow = time.Minute
+)
+
+// DPoP authenticates clients presenting DPoP bound access tokens, so stolen tokens can't be replayed without the
+// client's private key.
+// Clients send the access token as `authorization: DPoP <token>` and a proof JWT signed with their key in the dpop
+// metadata field. The htu claim's path must be the gRPC method, since servers often don't know the URL clients
+// dialed, and htm must be POST.
+// The proof's key must match the access token's cnf.jkt thumbprint, its ath the hash of the access token, and its jti
+// must not have been seen within the freshness window.
+// DPoP needs the method name, so it must be used with NewContextAuthority.
+// See https://datatracker.ietf.org/doc/html/rfc9449 for more details.
+type DPoP struct {
+	// Validator verifies the access token.
+	Validator *JWTValidator
+	// Window is how far a proof's iat may be from the server's clock.
+	// It defaults to 1 minute.
+	Window time.Duration
+	// Replay remembers proofs' jti claims. It defaults to an InMemoryReplayStore, so use a shared store such as
+	// RedisReplayStore to reject proofs replayed to other servers.
+	Replay ReplayStore
+
+	replay InMemoryReplayStore
+}
+
+// AuthFunc satisfies the ContextAuthFunc interface so clients can use DPoP bound access tokens with a gRPC server.
+func (d *DPoP) AuthFunc(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+	method, ok := grpc.Method(ctx)
+	if !ok {
+		return nil, fmt.Errorf("no gRPC method in context")
+	}
+
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected JWT in 'authorization' metadata field")
+	}
+
+	value := values[0]
+	if len(value) <= len(dpopScheme) || !strings.EqualFold(value[:len(dpopScheme)], dpopScheme) {
+		return nil, fmt.Errorf("expected DPoP authorization scheme")
+	}
+	accessToken := value[len(dpopScheme):]
+
+	proofs := md.Get(dpopMetadataKey)
+	if len(proofs) != 1 {
+		return nil, fmt.Errorf("expected proof in '%s' metadata field", dpopMetadataKey)
+	}
+
+	claims, err := d.Validator.Validate(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	cnf, _ := claims["cnf"].(map[string]interface{})
+	jkt, _ := cnf["jkt"].(string)
+	if jkt == "" {
+		return nil, fmt.Errorf("access token is not DPoP bound")
+	}
+
+	err = d.verifyProof(ctx, proofs[0], accessToken, method, jkt)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Validator.AuthResult(claims)
+}
+
+// verifyProof checks a DPoP proof is signed by the key the access token is bound to, for this call.
+func (d *DPoP) verifyProof(ctx context.Context, proof, accessToken, method, jkt string) error {
+	var thumbprint string
+	token, err := jwt.Parse(proof, func(token *jwt.Token) (interface{}, error) {
+		if err := verifyAsymmetricSigningMethod(token); err != nil {
+			return nil, err
+		}
+
+		if token.Header["typ"] != dpopProofType {
+			return nil, fmt.Errorf("DPoP proof typ must be %s, got %v", dpopProofType, token.Header["typ"])
+		}
+
+		jwk, err := dpopJWK(token.Header["jwk"])
+		if err != nil {
+			return nil, err
+		}
+
+		thumbprint, err = jwkThumbprint(jwk)
+		if err != nil {
+			return nil, err
+		}
+
+		return jwk.publicKey()
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if !token.Valid {
+		return fmt.Errorf("invalid DPoP proof")
+	}
+
+	if thumbprint != jkt {
+		return fmt.Errorf("DPoP proof key does not match the access token")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if claims["htm"] != dpopHTTPMethod {
+		return fmt.Errorf("DPoP proof htm must be %s, got %v", dpopHTTPMethod, claims["htm"])
+	}
+
+	htu, _ := claims["htu"].(string)
+	u, err := url.Parse(htu)
+	if err != nil || u.Path != method {
+		return fmt.Errorf("DPoP proof htu must be for %s, got %v", method, claims["htu"])
+	}
+
+	hash := sha256.Sum256([]byte(accessToken))
+	if claims["ath"] != base64.RawURLEncoding.EncodeToString(hash[:]) {
+		return fmt.Errorf("DPoP proof ath does not match the access token")
+	}
+
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return fmt.Errorf("DPoP proof has no iat claim")
+	}
+
+	window := d.window()
+	issuedAt := time.Unix(int64(iat), 0)
+	now := time.Now()
+	if issuedAt.Before(now.Add(-window)) || issuedAt.After(now.Add(window)) {
+		return fmt.Errorf("DPoP proof iat %v is outside the %v window", issuedAt, window)
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return fmt.Errorf("DPoP proof has no jti claim")
+	}
+
+	// Proofs only need to be remembered while they are fresh.
+	fresh, err := d.replayStore().Use(ctx, jkt+":"+jti, issuedAt.Add(window))
+	if err != nil {
+		return fmt.Errorf("checking DPoP proof jti: %v", err)
+	}
+
+	if !fresh {
+		return fmt.Errorf("DPoP proof has already been used")
+	}
+
+	return nil
+}
+
+func (d *DPoP) window() time.Duration {
+	if d.Window == 0 {
+		return defaultDPoPWindow
+	}
+	return d.Window
+}
+
+// replayStore returns the DPoP's ReplayStore, or its own InMemoryReplayStore if it doesn't have one.
+func (d *DPoP) replayStore() ReplayStore {
+	if d.Replay != nil {
+		return d.Replay
+	}
+	return &d.replay
+}
+
+// dpopJWK decodes the public key from a DPoP proof's jwk header.
+func dpopJWK(header interface{}) (*jsonWebKey, error) {
+	fields, ok := header.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("DPoP proof has no jwk header")
+	}
+
+	if _, ok := fields["d"]; ok {
+		return nil, fmt.Errorf("DPoP proof jwk must not contain a private key")
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwk jsonWebKey
+	err = json.Unmarshal(b, &jwk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwk, nil
+}
+
+// jwkThumbprint computes the RFC 7638 SHA-256 thumbprint of a public key.
+func jwkThumbprint(jwk *jsonWebKey) (string, error) {
+	var members string
+	switch jwk.Kty {
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, jwk.Crv, jwk.X, jwk.Y)
+	default:
+		return "", fmt.Errorf("unsupported key type: %v", jwk.Kty)
+	}
+
+	hash := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
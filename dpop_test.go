@@ -0,0 +1,110 @@
+package grpcauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+// testDPoPKey is a client's DPoP key pair.
+type testDPoPKey struct {
+	key *ecdsa.PrivateKey
+	jwk map[string]interface{}
+}
+
+func newTestDPoPKey(t *testing.T) *testDPoPKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testDPoPKey{
+		key: key,
+		jwk: map[string]interface{}{
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		},
+	}
+}
+
+func (k *testDPoPKey) thumbprint(t *testing.T) string {
+	jwk, err := dpopJWK(k.jwk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thumbprint, err := jwkThumbprint(jwk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return thumbprint
+}
+
+func (k *testDPoPKey) proof(t *testing.T, accessToken, method, jti string) string {
+	hash := sha256.Sum256([]byte(accessToken))
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"jti": jti,
+		"htm": "POST",
+		"htu": "https://api.example.com" + method,
+		"iat": time.Now().Unix(),
+		"ath": base64.RawURLEncoding.EncodeToString(hash[:]),
+	})
+	token.Header["typ"] = "dpop+jwt"
+	token.Header["jwk"] = k.jwk
+	signed, err := token.SignedString(k.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func dpopMetadata(accessToken, proof string) metadata.MD {
+	return metadata.Pairs("authorization", "DPoP "+accessToken, "dpop", proof)
+}
+
+func TestDPoPVerifiesProofs(t *testing.T) {
+	issuer := newTestIssuer(t)
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/jwks"
+	dpop := &DPoP{Validator: NewJWTValidator(jwksURL, issuer.server.URL, testAudience)}
+	ctx := methodContext(targetMethodName)
+
+	clientKey, attackerKey := newTestDPoPKey(t), newTestDPoPKey(t)
+	claims := issuer.claims()
+	claims["cnf"] = map[string]interface{}{"jkt": clientKey.thumbprint(t)}
+	accessToken := issuer.sign(t, claims)
+
+	authResult, err := dpop.AuthFunc(ctx, dpopMetadata(accessToken, clientKey.proof(t, accessToken, targetMethodName, "proof-1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected %v, got %v", testClientName, authResult.ClientIdentifier)
+	}
+
+	unbound := issuer.sign(t, issuer.claims())
+	tests := map[string]metadata.MD{
+		"replayed proof":   dpopMetadata(accessToken, clientKey.proof(t, accessToken, targetMethodName, "proof-1")),
+		"attacker key":     dpopMetadata(accessToken, attackerKey.proof(t, accessToken, targetMethodName, "proof-2")),
+		"different method": dpopMetadata(accessToken, clientKey.proof(t, accessToken, "/server.ServiceName/OtherMethod", "proof-3")),
+		"other token":      dpopMetadata(accessToken, clientKey.proof(t, unbound, targetMethodName, "proof-4")),
+		"unbound token":    dpopMetadata(unbound, clientKey.proof(t, unbound, targetMethodName, "proof-5")),
+		"bearer scheme":    metadata.Pairs("authorization", "Bearer "+accessToken, "dpop", clientKey.proof(t, accessToken, targetMethodName, "proof-6")),
+	}
+	for name, md := range tests {
+		_, err := dpop.AuthFunc(ctx, md)
+		if err == nil {
+			t.Fatalf("expected error with %s", name)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// ed25519Scheme is the authorization scheme for Ed25519 signed requests.
+	ed25519Scheme = "Ed25519"
+
+	// defaultEd25519Window is how far a signed request's timestamp can be from the server's clock by default.
+	defaultEd25519Window = time.Minute
+)
+
+// Ed25519Key is a public key registered for a client.
+type Ed25519Key struct {
+	PublicKey        ed25519.PublicKey
+	ClientIdentifier string
+	Permissions      []string
+}
+
+// Ed25519 authenticates clients that sign the method name and a timestamp with an Ed25519 private key, for
+// embedded and IoT clients that can't use OAuth2 or keep a shared secret on the server.
+// Clients send the signature in the authorization metadata field:
+//
+//	authorization: Ed25519 Credential=<key id>, Timestamp=<unix seconds>, Signature=<base64url>
+//
+// Signatures are only accepted within the freshness window. Unlike HMAC there's no nonce, so a captured signature
+// can be replayed to the same method until it expires; keep the window short and use TLS.
+// Ed25519 needs the method name, so it must be used with NewContextAuthority. See Ed25519Credentials for the client side.
+type Ed25519 struct {
+	// Keys maps key IDs to the public keys clients sign requests with.
+	Keys map[string]*Ed25519Key
+	// Window is how far a request's timestamp may be from the server's clock.
+	// It defaults to 1 minute.
+	Window time.Duration
+}
+
+// AuthFunc satisfies the ContextAuthFunc interface so clients can sign requests with an Ed25519 key.
+func (e *Ed25519) AuthFunc(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+	method, ok := grpc.Method(ctx)
+	if !ok {
+		return nil, fmt.Errorf("no gRPC method in context")
+	}
+
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected signature in 'authorization' metadata field")
+	}
+
+	params, err := parseSignatureParams(values[0], ed25519Scheme, "Credential", "Timestamp", "Signature")
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := e.Keys[params["Credential"]]
+	if !ok {
+		return nil, fmt.Errorf("unknown key: %s", params["Credential"])
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(params["Signature"])
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := params["Timestamp"]
+	if !ed25519.Verify(key.PublicKey, ed25519Message(method, timestamp), signature) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	window := e.window()
+	signedAt := time.Unix(seconds, 0)
+	now := time.Now()
+	if signedAt.Before(now.Add(-window)) || signedAt.After(now.Add(window)) {
+		return nil, fmt.Errorf("request timestamp %v is outside the %v window", signedAt, window)
+	}
+
+	return &AuthResult{
+		ClientIdentifier: key.ClientIdentifier,
+		Timestamp:        now,
+		Permissions:      key.Permissions,
+	}, nil
+}
+
+func (e *Ed25519) window() time.Duration {
+	if e.Window == 0 {
+		return defaultEd25519Window
+	}
+	return e.Window
+}
+
+// ed25519Message is the message clients sign for a request.
+func ed25519Message(method, timestamp string) []byte {
+	return []byte(method + "\n" + timestamp)
+}
+
+// Ed25519Credentials returns credentials.PerRPCCredentials that sign every request with an Ed25519 private key for
+// servers using Ed25519.
+// Use it with grpc.WithPerRPCCredentials.
+func Ed25519Credentials(keyID string, privateKey ed25519.PrivateKey) credentials.PerRPCCredentials {
+	return &ed25519Credentials{keyID: keyID, privateKey: privateKey}
+}
+
+type ed25519Credentials struct {
+	keyID      string
+	privateKey ed25519.PrivateKey
+}
+
+// GetRequestMetadata signs the method being called.
+func (e *ed25519Credentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	info, ok := credentials.RequestInfoFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("no request info in context")
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := ed25519.Sign(e.privateKey, ed25519Message(info.Method, timestamp))
+	return map[string]string{
+		"authorization": fmt.Sprintf("%s Credential=%s, Timestamp=%s, Signature=%s", ed25519Scheme, e.keyID, timestamp, base64.RawURLEncoding.EncodeToString(signature)),
+	}, nil
+}
+
+// RequireTransportSecurity is true so signatures can't be captured and replayed within the freshness window.
+func (e *ed25519Credentials) RequireTransportSecurity() bool {
+	return true
+}
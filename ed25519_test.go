@@ -0,0 +1,59 @@
+package grpcauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func ed25519Metadata(privateKey ed25519.PrivateKey, method string, signedAt time.Time) metadata.MD {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	signature := base64.RawURLEncoding.EncodeToString(ed25519.Sign(privateKey, ed25519Message(method, timestamp)))
+	return metadata.Pairs("authorization", fmt.Sprintf("Ed25519 Credential=device-1, Timestamp=%s, Signature=%s", timestamp, signature))
+}
+
+func TestEd25519VerifiesSignedRequests(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &Ed25519{
+		Keys: map[string]*Ed25519Key{
+			"device-1": {PublicKey: publicKey, ClientIdentifier: testClientName, Permissions: []string{targetMethodName}},
+		},
+	}
+	ctx := methodContext(targetMethodName)
+
+	authResult, err := e.AuthFunc(ctx, ed25519Metadata(privateKey, targetMethodName, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected %v, got %v", testClientName, authResult.ClientIdentifier)
+	}
+
+	tests := map[string]metadata.MD{
+		"stale timestamp":  ed25519Metadata(privateKey, targetMethodName, time.Now().Add(-time.Hour)),
+		"different method": ed25519Metadata(privateKey, "/server.ServiceName/OtherMethod", time.Now()),
+		"wrong key":        ed25519Metadata(otherKey, targetMethodName, time.Now()),
+	}
+	for name, md := range tests {
+		_, err := e.AuthFunc(ctx, md)
+		if err == nil {
+			t.Fatalf("expected error with %s", name)
+		}
+	}
+}
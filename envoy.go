@@ -0,0 +1,101 @@
+package grpcauth
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// EnvoyAuthorizer implements Envoy's external authorization gRPC service with an Authority, so the same AuthFunc and
+// permission policy can authorize traffic at the proxy for services that aren't written in Go.
+// Register it on a gRPC server with authv3.RegisterAuthorizationServer and point Envoy's ext_authz filter at it.
+// See https://www.envoyproxy.io/docs/envoy/latest/intro/arch_overview/security/ext_authz_filter for more details.
+type EnvoyAuthorizer struct {
+	Authority Authority
+	// MethodFunc maps the request Envoy is checking to the gRPC method it is authorized as.
+	// It defaults to the request's path, which is the full method name for gRPC traffic.
+	MethodFunc HTTPMethodFunc
+	// ClientIdentifierHeader, if set, is a header Envoy adds to allowed requests with the client's
+	// AuthResult.ClientIdentifier, so upstream services know who is calling them.
+	ClientIdentifierHeader string
+}
+
+// Check satisfies authv3.AuthorizationServer by authenticating and authorizing the request Envoy is checking.
+// Denials are returned as a CheckResponse, not an error, so Envoy can send them on to the client.
+// The request is authenticated as coming from the downstream client's address, not Envoy's, so BruteForceProtection,
+// Denylist and WithClientNetworks apply to the client.
+func (e *EnvoyAuthorizer) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	httpRequest := req.GetAttributes().GetRequest().GetHttp()
+	// Envoy's path is the request target, including the query string.
+	u, err := url.ParseRequestURI(httpRequest.GetPath())
+	if err != nil {
+		return envoyDeniedResponse(status.New(codes.InvalidArgument, "invalid path")), nil
+	}
+
+	r := &http.Request{Method: httpRequest.GetMethod(), URL: u, Header: http.Header{}}
+	methodName := r.URL.Path
+	if e.MethodFunc != nil {
+		methodName = e.MethodFunc(r)
+	}
+
+	if methodName == "" {
+		return envoyDeniedResponse(status.New(codes.PermissionDenied, "no gRPC method for route")), nil
+	}
+
+	// Envoy sends header names in lowercase, like gRPC metadata.
+	md := metadata.New(httpRequest.GetHeaders())
+	// The peer calling Check is Envoy, so peer-keyed checks use the downstream client's address instead.
+	ctx = peer.NewContext(ctx, envoyPeer(req.GetAttributes().GetSource()))
+	authCtx, err := authenticateWith(e.Authority, metadata.NewIncomingContext(ctx, md), methodName)
+	if err != nil {
+		return envoyDeniedResponse(status.Convert(err)), nil
+	}
+
+	okResponse := &authv3.OkHttpResponse{}
+	authResult, err := GetAuthResult(authCtx)
+	if err == nil && e.ClientIdentifierHeader != "" {
+		okResponse.Headers = append(okResponse.Headers, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{Key: e.ClientIdentifierHeader, Value: authResult.ClientIdentifier},
+		})
+	}
+
+	return &authv3.CheckResponse{
+		Status:       &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: okResponse},
+	}, nil
+}
+
+// envoyPeer returns the downstream client Envoy is checking as a gRPC peer. It has no address if Envoy didn't send a
+// socket address for the client.
+func envoyPeer(source *authv3.AttributeContext_Peer) *peer.Peer {
+	socketAddress := source.GetAddress().GetSocketAddress()
+	ip := net.ParseIP(socketAddress.GetAddress())
+	if ip == nil {
+		return &peer.Peer{}
+	}
+
+	return &peer.Peer{Addr: &net.TCPAddr{IP: ip, Port: int(socketAddress.GetPortValue())}}
+}
+
+// envoyDeniedResponse converts a denial from an Authority to a CheckResponse Envoy sends to the client.
+func envoyDeniedResponse(st *status.Status) *authv3.CheckResponse {
+	return &authv3.CheckResponse{
+		Status: st.Proto(),
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status: &typev3.HttpStatus{Code: typev3.StatusCode(httpStatusFromCode(st.Code()))},
+				Body:   st.Message(),
+			},
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package grpcauth
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func envoyCheckRequest(path string, headers map[string]string) *authv3.CheckRequest {
+	return &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{Method: "POST", Path: path, Headers: headers},
+			},
+		},
+	}
+}
+
+func TestEnvoyAuthorizer(t *testing.T) {
+	authorizer := &EnvoyAuthorizer{
+		Authority:              NewAuthority(alwaysAuthenticatedAllPermissions, nil),
+		ClientIdentifierHeader: "x-client-id",
+	}
+
+	headers := map[string]string{"authorization": "bearer words"}
+	response, err := authorizer.Check(context.Background(), envoyCheckRequest(targetMethodName, headers))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if codes.Code(response.GetStatus().GetCode()) != codes.OK {
+		t.Fatalf("expected OK, got %v", response.GetStatus())
+	}
+
+	responseHeaders := response.GetOkResponse().GetHeaders()
+	if len(responseHeaders) != 1 || responseHeaders[0].GetHeader().GetValue() != testClientName {
+		t.Fatalf("expected client identifier header, got %v", responseHeaders)
+	}
+
+	response, err = authorizer.Check(context.Background(), envoyCheckRequest("/server.ServiceName/OtherMethod", headers))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if codes.Code(response.GetStatus().GetCode()) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", response.GetStatus())
+	}
+
+	if response.GetDeniedResponse().GetStatus().GetCode() != typev3.StatusCode_Forbidden {
+		t.Fatalf("expected 403, got %v", response.GetDeniedResponse().GetStatus())
+	}
+
+	response, err = authorizer.Check(context.Background(), envoyCheckRequest(targetMethodName, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.GetDeniedResponse().GetStatus().GetCode() != typev3.StatusCode_Unauthorized {
+		t.Fatalf("expected 401, got %v", response.GetDeniedResponse().GetStatus())
+	}
+}
+
+func TestEnvoyAuthorizerUsesDownstreamPeer(t *testing.T) {
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		if len(md.Get("authorization")) == 0 || md.Get("authorization")[0] != "bearer words" {
+			return nil, ErrCredentialNotFound
+		}
+		return alwaysAuthenticatedAllPermissions(md)
+	}
+	authorizer := &EnvoyAuthorizer{
+		Authority: NewAuthority(authFunc, nil, WithBruteForceProtection(&BruteForceProtection{Threshold: 1, BaseDelay: time.Minute})),
+	}
+	check := func(address string, headers map[string]string) codes.Code {
+		req := envoyCheckRequest(targetMethodName, headers)
+		req.Attributes.Source = &authv3.AttributeContext_Peer{
+			Address: &corev3.Address{Address: &corev3.Address_SocketAddress{
+				SocketAddress: &corev3.SocketAddress{Address: address, PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: 50051}},
+			}},
+		}
+
+		// Every check comes from Envoy's own address.
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 443}})
+		response, err := authorizer.Check(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return codes.Code(response.GetStatus().GetCode())
+	}
+
+	valid := map[string]string{"authorization": "bearer words"}
+	check("192.0.2.1", map[string]string{"authorization": "bearer wrong"})
+	if code := check("192.0.2.1", valid); code == codes.OK {
+		t.Fatalf("expected the client that failed to be locked out")
+	}
+
+	if code := check("192.0.2.2", valid); code != codes.OK {
+		t.Fatalf("expected other clients behind the same proxy not to be locked out, got %v", code)
+	}
+}
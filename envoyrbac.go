@@ -0,0 +1,122 @@
+package grpcauth
+
+import (
+	"sort"
+	"strings"
+
+	rbacv3 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+)
+
+// EnvoyPrincipalFunc returns the Envoy RBAC principal that matches clients with role.
+// An empty role must match any authenticated client, and is used for a Policy's exempt methods.
+type EnvoyPrincipalFunc func(role string) *rbacv3.Principal
+
+// JWTClaimPrincipals matches roles against claim in the JWT payload that Envoy's jwt_authn filter stores in its dynamic
+// metadata under payloadKey, set with payload_in_metadata in the filter's provider config.
+// The claim can be a single role or a list of roles.
+func JWTClaimPrincipals(payloadKey, claim string) EnvoyPrincipalFunc {
+	return func(role string) *rbacv3.Principal {
+		if role == "" {
+			return envoyMetadataPrincipal([]string{payloadKey}, &matcherv3.ValueMatcher{
+				MatchPattern: &matcherv3.ValueMatcher_PresentMatch{PresentMatch: true},
+			})
+		}
+
+		roleMatcher := &matcherv3.ValueMatcher{
+			MatchPattern: &matcherv3.ValueMatcher_StringMatch{StringMatch: &matcherv3.StringMatcher{
+				MatchPattern: &matcherv3.StringMatcher_Exact{Exact: role},
+			}},
+		}
+		path := []string{payloadKey, claim}
+		return &rbacv3.Principal{
+			Identifier: &rbacv3.Principal_OrIds{OrIds: &rbacv3.Principal_Set{Ids: []*rbacv3.Principal{
+				envoyMetadataPrincipal(path, roleMatcher),
+				envoyMetadataPrincipal(path, &matcherv3.ValueMatcher{
+					MatchPattern: &matcherv3.ValueMatcher_ListMatch{ListMatch: &matcherv3.ListMatcher{
+						MatchPattern: &matcherv3.ListMatcher_OneOf{OneOf: roleMatcher},
+					}},
+				}),
+			}}},
+		}
+	}
+}
+
+// EnvoyRBAC returns Envoy RBAC filter rules that allow each role to call the methods it grants, so an edge proxy can
+// reject requests before they reach the server with the same rules grpcauth enforces in-process.
+// Roles that grant no methods are left out, since Envoy requires every policy to have a permission.
+// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/rbac/v3/rbac.proto for more details.
+func (r *RBAC) EnvoyRBAC(principalFunc EnvoyPrincipalFunc) *rbacv3.RBAC {
+	rules := &rbacv3.RBAC{
+		Action:   rbacv3.RBAC_ALLOW,
+		Policies: map[string]*rbacv3.Policy{},
+	}
+	for role, methods := range r.Roles {
+		if len(methods) == 0 {
+			continue
+		}
+
+		rules.Policies["role:"+role] = &rbacv3.Policy{
+			Permissions: envoyMethodPermissions(methods),
+			Principals:  []*rbacv3.Principal{principalFunc(role)},
+		}
+	}
+
+	return rules
+}
+
+// EnvoyRBAC returns Envoy RBAC filter rules for the policy's roles, as RBAC.EnvoyRBAC does, and a policy named exempt
+// that lets any authenticated client call the exempt methods.
+func (p *Policy) EnvoyRBAC(principalFunc EnvoyPrincipalFunc) *rbacv3.RBAC {
+	rbac := &RBAC{Roles: p.Roles}
+	rules := rbac.EnvoyRBAC(principalFunc)
+	if len(p.Exempt) > 0 {
+		rules.Policies["exempt"] = &rbacv3.Policy{
+			Permissions: envoyMethodPermissions(p.Exempt),
+			Principals:  []*rbacv3.Principal{principalFunc("")},
+		}
+	}
+
+	return rules
+}
+
+// envoyMethodPermissions matches the request path against method patterns the way MatchMethod does.
+func envoyMethodPermissions(patterns []string) []*rbacv3.Permission {
+	// Sort the patterns so the generated config is stable and diffs cleanly.
+	patterns = append([]string(nil), patterns...)
+	sort.Strings(patterns)
+
+	permissions := make([]*rbacv3.Permission, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			// Envoy doesn't allow empty prefixes.
+			permissions = append(permissions, &rbacv3.Permission{Rule: &rbacv3.Permission_Any{Any: true}})
+			continue
+		}
+
+		matcher := &matcherv3.StringMatcher{MatchPattern: &matcherv3.StringMatcher_Exact{Exact: pattern}}
+		if strings.HasSuffix(pattern, "*") {
+			matcher.MatchPattern = &matcherv3.StringMatcher_Prefix{Prefix: strings.TrimSuffix(pattern, "*")}
+		}
+		permissions = append(permissions, &rbacv3.Permission{Rule: &rbacv3.Permission_UrlPath{UrlPath: &matcherv3.PathMatcher{
+			Rule: &matcherv3.PathMatcher_Path{Path: matcher},
+		}}})
+	}
+
+	return permissions
+}
+
+func envoyMetadataPrincipal(path []string, value *matcherv3.ValueMatcher) *rbacv3.Principal {
+	segments := make([]*matcherv3.MetadataMatcher_PathSegment, 0, len(path))
+	for _, key := range path {
+		segments = append(segments, &matcherv3.MetadataMatcher_PathSegment{
+			Segment: &matcherv3.MetadataMatcher_PathSegment_Key{Key: key},
+		})
+	}
+
+	return &rbacv3.Principal{Identifier: &rbacv3.Principal_Metadata{Metadata: &matcherv3.MetadataMatcher{
+		Filter: "envoy.filters.http.jwt_authn",
+		Path:   segments,
+		Value:  value,
+	}}}
+}
@@ -0,0 +1,74 @@
+package grpcauth
+
+import (
+	"testing"
+
+	rbacv3 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+)
+
+func TestPolicyEnvoyRBAC(t *testing.T) {
+	policy := &Policy{
+		Roles: map[string][]string{
+			"admin":  {"/server.ServiceName/*"},
+			"reader": {targetMethodName},
+			"none":   {},
+		},
+		Exempt: []string{"/grpc.health.v1.Health/*"},
+	}
+
+	rules := policy.EnvoyRBAC(JWTClaimPrincipals("jwt_payload", "roles"))
+	err := rules.ValidateAll()
+	if err != nil {
+		t.Fatalf("expected valid Envoy RBAC config, got %v", err)
+	}
+
+	if rules.Action != rbacv3.RBAC_ALLOW {
+		t.Fatalf("expected ALLOW action, got %v", rules.Action)
+	}
+
+	if len(rules.Policies) != 3 {
+		t.Fatalf("expected 3 policies, got %d", len(rules.Policies))
+	}
+
+	admin := rules.Policies["role:admin"].Permissions[0].GetUrlPath().GetPath()
+	if admin.GetPrefix() != "/server.ServiceName/" {
+		t.Fatalf("expected admin prefix match, got %v", admin)
+	}
+
+	reader := rules.Policies["role:reader"]
+	if reader.Permissions[0].GetUrlPath().GetPath().GetExact() != targetMethodName {
+		t.Fatalf("expected reader exact match, got %v", reader.Permissions[0])
+	}
+
+	ids := reader.Principals[0].GetOrIds().GetIds()
+	if len(ids) != 2 {
+		t.Fatalf("expected the role to match single and list claims, got %v", reader.Principals[0])
+	}
+
+	path := ids[1].GetMetadata().GetPath()
+	if path[0].GetKey() != "jwt_payload" || path[1].GetKey() != "roles" {
+		t.Fatalf("expected role to be read from the JWT payload, got %v", path)
+	}
+
+	if ids[1].GetMetadata().GetValue().GetListMatch().GetOneOf().GetStringMatch().GetExact() != "reader" {
+		t.Fatalf("expected reader role to be matched, got %v", ids[1])
+	}
+
+	exempt := rules.Policies["exempt"]
+	if !exempt.Principals[0].GetMetadata().GetValue().GetPresentMatch() {
+		t.Fatalf("expected exempt methods to require a JWT payload, got %v", exempt.Principals[0])
+	}
+}
+
+func TestRBACEnvoyRBACMatchesAnyMethod(t *testing.T) {
+	rbac := &RBAC{Roles: map[string][]string{"admin": {"*"}}}
+	rules := rbac.EnvoyRBAC(JWTClaimPrincipals("jwt_payload", "roles"))
+	err := rules.ValidateAll()
+	if err != nil {
+		t.Fatalf("expected valid Envoy RBAC config, got %v", err)
+	}
+
+	if !rules.Policies["role:admin"].Permissions[0].GetAny() {
+		t.Fatalf("expected * to match any method, got %v", rules.Policies["role:admin"].Permissions[0])
+	}
+}
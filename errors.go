@@ -1,13 +1,119 @@
 package grpcauth
 
-// PermissionDeniedError is a JSON object containing the error details to help a client debug permission errors.
-// This is included in the gRPC error response.
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the ErrorInfo details grpcauth attaches to the errors it returns.
+const ErrorDomain = "grpcauth"
+
+// The reasons in the ErrorInfo details grpcauth attaches to the errors it returns, so clients can tell errors apart
+// without parsing their messages.
+const (
+	ReasonUnauthenticated  = "UNAUTHENTICATED"
+	ReasonPermissionDenied = "PERMISSION_DENIED"
+	ReasonMaintenance      = "MAINTENANCE"
+	// ReasonCredentialsExpired is the reason streams are closed with when the client's credentials expire, so
+	// clients know to open them again with fresh credentials.
+	ReasonCredentialsExpired = "CREDENTIALS_EXPIRED"
+	// ReasonQuotaExceeded is the reason calls are rejected with when the client is over its Quota.
+	ReasonQuotaExceeded = "QUOTA_EXCEEDED"
+)
+
+// PermissionDeniedError contains the error details to help a client debug permission errors.
+// It is sent to the client as the metadata of an ErrorInfo detail, and clients can get it back with
+// PermissionDeniedDetails.
 type PermissionDeniedError struct {
 	ClientIdentifier    string   `json:"clientIdentifier"`
 	PermissionRequested string   `json:"permissionRequested"`
 	ClientPermissions   []string `json:"clientPermissions"`
 }
 
-// UnauthenticatedError is a JSON object returned when a gRPC client attempts to access the server without authenticating.
-// Since the user hasn't authenticated, don't even marshal a struct: just return this const string.
-const UnauthenticatedError = `{"error": "no valid authorzation metadata field"}`
+// GRPCStatus returns the PermissionDenied status sent to the client.
+func (e *PermissionDeniedError) GRPCStatus() *status.Status {
+	st := status.New(codes.PermissionDenied, fmt.Sprintf("%s is not allowed to call %s", e.ClientIdentifier, e.PermissionRequested))
+	return withErrorInfo(st, ReasonPermissionDenied, map[string]string{
+		"clientIdentifier":    e.ClientIdentifier,
+		"permissionRequested": e.PermissionRequested,
+		// Permissions can't contain spaces, so they are joined like OAuth2 scopes.
+		"clientPermissions": strings.Join(e.ClientPermissions, " "),
+	})
+}
+
+// PermissionDeniedDetails returns the PermissionDeniedError in the details of err, and false if it doesn't have one.
+func PermissionDeniedDetails(err error) (*PermissionDeniedError, bool) {
+	info, ok := errorInfo(err, ReasonPermissionDenied)
+	if !ok {
+		return nil, false
+	}
+
+	permissionDenied := &PermissionDeniedError{
+		ClientIdentifier:    info.Metadata["clientIdentifier"],
+		PermissionRequested: info.Metadata["permissionRequested"],
+	}
+	if permissions := info.Metadata["clientPermissions"]; permissions != "" {
+		permissionDenied.ClientPermissions = strings.Split(permissions, " ")
+	}
+
+	return permissionDenied, true
+}
+
+// UnauthenticatedError is the message returned when a gRPC client attempts to access the server without
+// authenticating. The error has an ErrorInfo detail with ReasonUnauthenticated, and nothing else, since the reason
+// a client failed to authenticate can describe the server's configuration.
+const UnauthenticatedError = "no valid authorization metadata"
+
+// MaintenanceError is returned when a KillSwitch denies a request, telling the client why.
+// It is sent to the client as the metadata of an ErrorInfo detail, and clients can get it back with
+// MaintenanceDetails.
+type MaintenanceError struct {
+	Reason     string `json:"reason"`
+	MethodName string `json:"methodName"`
+}
+
+// GRPCStatus returns the Unavailable status sent to the client.
+func (e *MaintenanceError) GRPCStatus() *status.Status {
+	st := status.New(codes.Unavailable, fmt.Sprintf("%s is unavailable: %s", e.MethodName, e.Reason))
+	return withErrorInfo(st, ReasonMaintenance, map[string]string{
+		"reason":     e.Reason,
+		"methodName": e.MethodName,
+	})
+}
+
+// MaintenanceDetails returns the MaintenanceError in the details of err, and false if it doesn't have one.
+func MaintenanceDetails(err error) (*MaintenanceError, bool) {
+	info, ok := errorInfo(err, ReasonMaintenance)
+	if !ok {
+		return nil, false
+	}
+
+	return &MaintenanceError{Reason: info.Metadata["reason"], MethodName: info.Metadata["methodName"]}, true
+}
+
+// withErrorInfo returns st with an ErrorInfo detail in ErrorDomain.
+func withErrorInfo(st *status.Status, reason string, metadata map[string]string) *status.Status {
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain, Metadata: metadata})
+	if err != nil {
+		// ErrorInfo always marshals, so this can't happen.
+		return st
+	}
+
+	return detailed
+}
+
+// errorInfo returns the ErrorInfo detail in err from ErrorDomain with reason.
+func errorInfo(err error, reason string) (*errdetails.ErrorInfo, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if ok && info.Domain == ErrorDomain && info.Reason == reason {
+			return info, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,53 @@
+package grpcauth
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorDetails(t *testing.T) {
+	permissionDenied := &PermissionDeniedError{
+		ClientIdentifier:    testClientName,
+		PermissionRequested: targetMethodName,
+		ClientPermissions:   []string{"a", "b"},
+	}
+	err := permissionDenied.GRPCStatus().Err()
+	details, ok := PermissionDeniedDetails(err)
+	if status.Code(err) != codes.PermissionDenied || !ok || !reflect.DeepEqual(details, permissionDenied) {
+		t.Fatalf("expected %v to round trip, got %v", permissionDenied, details)
+	}
+
+	if _, ok := MaintenanceDetails(err); ok {
+		t.Fatalf("expected details to be matched by reason")
+	}
+
+	maintenance := &MaintenanceError{Reason: "upgrade", MethodName: targetMethodName}
+	details2, ok := MaintenanceDetails(maintenance.GRPCStatus().Err())
+	if !ok || *details2 != *maintenance {
+		t.Fatalf("expected %v to round trip, got %v", maintenance, details2)
+	}
+
+	info, ok := errorInfo(errUnauthorized, ReasonUnauthenticated)
+	if !ok || info.Domain != ErrorDomain {
+		t.Fatalf("expected Unauthenticated errors to have an ErrorInfo, got %v", info)
+	}
+}
+
+func TestOpaqueErrorsHaveRequestInfo(t *testing.T) {
+	err, correlationID := OpaqueErrors.clientError(permissionDeniedStatus(&AuthResult{ClientIdentifier: testClientName}, targetMethodName), targetMethodName)
+	if _, ok := PermissionDeniedDetails(err); !ok {
+		t.Fatalf("expected opaque errors to keep their reason, got %v", err)
+	}
+
+	for _, detail := range status.Convert(err).Details() {
+		if requestInfo, ok := detail.(*errdetails.RequestInfo); ok && requestInfo.RequestId == correlationID {
+			return
+		}
+	}
+
+	t.Fatalf("expected the correlation ID in a RequestInfo, got %v", status.Convert(err).Details())
+}
@@ -0,0 +1,51 @@
+package grpcauth
+
+import (
+	"fmt"
+	"time"
+)
+
+// checkExpiry returns an error if the Authority no longer accepts authResult's credentials at now.
+func (a *authority) checkExpiry(authResult *AuthResult, now time.Time) error {
+	expiry, ok, err := a.credentialsExpiry(authResult)
+	if err != nil {
+		return err
+	}
+
+	if ok && !now.Before(expiry) {
+		return fmt.Errorf("credentials expired at %v", expiry)
+	}
+
+	return nil
+}
+
+// credentialsExpiry returns when the Authority stops accepting authResult's credentials: their Expiry or exp claim,
+// or when their token reaches MaxTokenAge if that is sooner. It returns false if they don't expire.
+func (a *authority) credentialsExpiry(authResult *AuthResult) (time.Time, bool, error) {
+	expiry, ok := authResult.expiry()
+	if a.MaxTokenAge <= 0 {
+		return expiry, ok, nil
+	}
+
+	issuedAt, found := timeClaim(authResult.Claims, "iat")
+	if !found {
+		return time.Time{}, false, fmt.Errorf("token has no iat claim")
+	}
+
+	if maxExpiry := issuedAt.Add(a.MaxTokenAge); !ok || maxExpiry.Before(expiry) {
+		return maxExpiry, true, nil
+	}
+
+	return expiry, true, nil
+}
+
+// expires reports whether the Authority stops accepting authResult's credentials at some point.
+// It is false for nil AuthResults.
+func (a *authority) expires(authResult *AuthResult) bool {
+	if authResult == nil {
+		return false
+	}
+
+	_, ok, _ := a.credentialsExpiry(authResult)
+	return ok
+}
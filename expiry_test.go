@@ -0,0 +1,234 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthorityRejectsExpiredCredentials(t *testing.T) {
+	expiry := time.Now().Add(-time.Second)
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		return &AuthResult{ClientIdentifier: testClientName, Permissions: []string{targetMethodName}, Expiry: expiry}, nil
+	}
+	a := NewAuthority(authFunc, nil)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}
+	_, err := a.UnaryServerInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: targetMethodName}, handler)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected expired credentials to be rejected, got %v", err)
+	}
+
+	expiry = time.Now().Add(time.Hour)
+	_, err = a.UnaryServerInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: targetMethodName}, handler)
+	if err != nil {
+		t.Fatalf("expected credentials that haven't expired to be accepted, got %v", err)
+	}
+}
+
+func TestAuthorityAllowsLeeway(t *testing.T) {
+	issuer := newTestIssuer(t)
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/jwks"
+	validator := NewJWTValidator(jwksURL, "", testAudience)
+	validator.PermissionsClaim = "permissions"
+	validator.Leeway = time.Minute
+
+	claims := issuer.claims()
+	claims["exp"] = time.Now().Add(-10 * time.Second).Unix()
+	claims["permissions"] = []interface{}{targetMethodName}
+	token := issuer.sign(t, claims)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&introspectionResponse{
+			Active:   true,
+			ClientID: testClientName,
+			Scope:    targetMethodName,
+			Exp:      time.Now().Add(-10 * time.Second).Unix(),
+		})
+	}))
+	defer server.Close()
+	introspectionURL, _ := url.Parse(server.URL)
+	introspection := &TokenIntrospection{URL: introspectionURL, Leeway: time.Minute}
+
+	authFuncs := map[string]AuthFunc{
+		"jwt":           validator.AuthFunc,
+		"introspection": introspection.AuthFunc,
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer "+token))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}
+	for name, authFunc := range authFuncs {
+		a := NewAuthority(authFunc, nil)
+		_, err := a.UnaryServerInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: targetMethodName}, handler)
+		if err != nil {
+			t.Fatalf("expected %s token within its leeway to be accepted, got %v", name, err)
+		}
+	}
+}
+
+func TestAuthorityAllowsProviderLeeway(t *testing.T) {
+	issuer := newTestIssuer(t)
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/jwks"
+	leeway := JWTValidation{Leeway: time.Minute}
+	expired := func(claims jwt.MapClaims) jwt.MapClaims {
+		claims["exp"] = time.Now().Add(-10 * time.Second).Unix()
+		claims["iat"] = time.Now().Add(-time.Minute).Unix()
+		return claims
+	}
+
+	azureClaims := expired(issuer.claims())
+	azureClaims["iss"] = issuer.server.URL + "/" + testTenantID + "/v2.0"
+	azureClaims["ver"] = "2.0"
+	azureClaims["tid"] = testTenantID
+	azureClaims["azp"] = testClientName
+
+	googleClaims := expired(issuer.claims())
+	googleClaims["iss"] = "https://accounts.google.com"
+	googleClaims["email"] = testServiceAccount
+	googleClaims["email_verified"] = true
+
+	keycloakClaims := expired(issuer.claims())
+	keycloakClaims["iss"] = issuer.server.URL + "/realms/services"
+
+	oktaClaims := expired(issuer.claims())
+	oktaClaims["iss"] = oktaIssuer(issuer.url(t), OktaDefaultAuthorizationServer)
+	oktaClaims["cid"] = testClientName
+
+	cognitoClaims := expired(issuer.claims())
+	cognitoClaims["token_use"] = claimsUseAccess
+
+	cloudflareClaims := expired(issuer.claims())
+	cloudflareClaims["email"] = "user@example.com"
+
+	kubernetesClaims := expired(issuer.claims())
+	kubernetesClaims["sub"] = "system:serviceaccount:payments:api"
+
+	assertionClaims := expired(issuer.claims())
+	assertionClaims["iss"] = testClientName
+	assertionClaims["jti"] = "assertion-1"
+	clientAssertion := newTestClientAssertion(t, issuer, testAudience)
+	clientAssertion.JWTValidation = leeway
+
+	iapKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iapServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&jsonWebKeySet{Keys: []jsonWebKey{{
+			Kty: "EC",
+			Kid: testKeyID,
+			Alg: "ES256",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(iapKey.X.Bytes()),
+			Y:   base64.RawURLEncoding.EncodeToString(iapKey.Y.Bytes()),
+		}}})
+	}))
+	defer iapServer.Close()
+	iapJWKSURL, _ := url.Parse(iapServer.URL)
+	iapToken := jwt.NewWithClaims(jwt.SigningMethodES256, expired(jwt.MapClaims{
+		"iss":   iapIssuer,
+		"aud":   testIAPAudience,
+		"sub":   "accounts.google.com:1234",
+		"email": "user@example.com",
+	}))
+	iapToken.Header["kid"] = testKeyID
+	iapAssertion, err := iapToken.SignedString(iapKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		authFunc AuthFunc
+		md       metadata.MD
+		options  []Option
+	}{
+		{"JWTValidator", (&JWTValidator{Keys: &JWKSCache{URL: jwksURL}, Audience: testAudience, JWTValidation: leeway}).AuthFunc, bearerMetadata(issuer.sign(t, expired(issuer.claims()))), nil},
+		{"OIDC", (&OIDC{Issuer: issuer.url(t), Audience: testAudience, JWKSURL: jwksURL, JWTValidation: leeway}).AuthFunc, bearerMetadata(issuer.sign(t, expired(issuer.claims()))), nil},
+		{"Auth0M2M", (&Auth0M2M{Domain: issuer.url(t), APIIdentifier: testAudience, JWKSURL: jwksURL, JWTValidation: leeway}).AuthFunc, bearerMetadata(issuer.sign(t, expired(issuer.claims()))), nil},
+		{"AWSCognitoM2M", (&AWSCognitoM2M{Domain: issuer.url(t), APIIdentifier: testAudience, JWKSURL: jwksURL, JWTValidation: leeway}).AuthFunc, bearerMetadata(issuer.sign(t, cognitoClaims)), nil},
+		{"AzureAD", (&AzureAD{TenantID: testTenantID, Audience: testAudience, AuthorityHost: issuer.url(t), JWTValidation: leeway}).AuthFunc, bearerMetadata(issuer.sign(t, azureClaims)), nil},
+		{"GoogleServiceAccount", (&GoogleServiceAccount{Audience: testAudience, JWKSURL: issuer.url(t), JWTValidation: leeway}).AuthFunc, bearerMetadata(issuer.sign(t, googleClaims)), nil},
+		{"Keycloak", (&Keycloak{URL: issuer.url(t), Realm: "services", Audience: testAudience, JWTValidation: leeway}).AuthFunc, bearerMetadata(issuer.sign(t, keycloakClaims)), nil},
+		{"Okta", (&Okta{OrgURL: issuer.url(t), Audience: testAudience, JWTValidation: leeway}).AuthFunc, bearerMetadata(issuer.sign(t, oktaClaims)), nil},
+		{"CloudflareAccess", (&CloudflareAccess{TeamDomain: issuer.url(t), Audience: testAudience, JWTValidation: leeway}).AuthFunc, metadata.Pairs(CloudflareAccessMetadataKey, issuer.sign(t, cloudflareClaims)), []Option{WithMetadataKey(CloudflareAccessMetadataKey)}},
+		{"IAP", (&IAP{Audience: testIAPAudience, JWKSURL: iapJWKSURL, JWTValidation: leeway}).AuthFunc, metadata.Pairs(IAPMetadataKey, iapAssertion), []Option{WithMetadataKey(IAPMetadataKey)}},
+		{"KubernetesServiceAccount", (&KubernetesServiceAccount{Issuer: issuer.url(t), Audience: testAudience, JWTValidation: leeway}).AuthFunc, bearerMetadata(issuer.sign(t, kubernetesClaims)), nil},
+		{"ClientAssertion", clientAssertion.AuthFunc, bearerMetadata(issuer.sign(t, assertionClaims)), nil},
+	}
+
+	allowAll := func(permissions []string, methodName string) bool {
+		return true
+	}
+	for _, test := range tests {
+		a := NewAuthority(test.authFunc, allowAll, test.options...).(*authority)
+		_, err = a.authenticateAndAuthorizeContext(metadata.NewIncomingContext(context.Background(), test.md), targetMethodName)
+		if err != nil {
+			t.Fatalf("expected the Authority to accept a %s token within its leeway, got %v", test.name, err)
+		}
+	}
+}
+
+func TestAuthorityEnforcesMaxTokenAge(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   map[string]interface{}
+		accepted bool
+	}{
+		{"fresh", map[string]interface{}{"iat": float64(time.Now().Unix())}, true},
+		{"old", map[string]interface{}{"iat": float64(time.Now().Add(-2 * time.Hour).Unix()), "exp": float64(time.Now().Add(time.Hour).Unix())}, false},
+		{"no iat", map[string]interface{}{}, false},
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	for _, test := range tests {
+		authFunc := func(md metadata.MD) (*AuthResult, error) {
+			return &AuthResult{ClientIdentifier: testClientName, Permissions: []string{targetMethodName}, Claims: test.claims}, nil
+		}
+		a := NewAuthority(authFunc, nil, WithMaxTokenAge(time.Hour)).(*authority)
+		_, err := a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+		if (err == nil) != test.accepted {
+			t.Fatalf("expected %s token to be accepted=%t, got %v", test.name, test.accepted, err)
+		}
+	}
+}
+
+func TestCredentialsExpiry(t *testing.T) {
+	issuedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	a := &authority{MaxTokenAge: time.Hour}
+
+	expiry, ok, err := a.credentialsExpiry(&AuthResult{Claims: map[string]interface{}{"iat": float64(issuedAt.Unix())}})
+	if err != nil || !ok || !expiry.Equal(issuedAt.Add(time.Hour)) {
+		t.Fatalf("expected tokens without an expiry to expire at their max age, got %v %v %v", expiry, ok, err)
+	}
+
+	sooner := issuedAt.Add(time.Minute)
+	expiry, _, _ = a.credentialsExpiry(&AuthResult{Expiry: sooner, Claims: map[string]interface{}{"iat": float64(issuedAt.Unix())}})
+	if !expiry.Equal(sooner) {
+		t.Fatalf("expected the sooner of Expiry and max age, got %v", expiry)
+	}
+
+	if a.expires(nil) || (&authority{}).expires(&AuthResult{}) {
+		t.Fatalf("expected credentials without an expiry not to expire")
+	}
+}
@@ -0,0 +1,233 @@
+package grpcauth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// The checks an Authority makes, in the order it makes them, as named in an Explanation.
+const (
+	CheckKillSwitch            = "kill_switch"
+	CheckUnauthenticatedMethod = "unauthenticated_method"
+	CheckDenylist              = "denylist"
+	CheckBruteForce            = "brute_force"
+	CheckMetadata              = "metadata"
+	CheckAuthentication        = "authentication"
+	CheckNetwork               = "network"
+	CheckPrincipal             = "principal"
+	CheckAuthorization         = "authorization"
+	CheckCandidatePolicy       = "candidate_policy"
+)
+
+// ExplanationStep is one check an Authority made while deciding a request.
+type ExplanationStep struct {
+	Check  string `json:"check"`
+	Passed bool   `json:"passed"`
+	// Detail says why the check passed or failed, with credentials redacted.
+	Detail string `json:"detail,omitempty"`
+}
+
+// Explanation is why an Authority would allow or reject a request, for debugging reports of clients being denied.
+type Explanation struct {
+	MethodName string `json:"method"`
+	// Outcome is OutcomeAllowed, OutcomeUnauthenticated, OutcomeDenied, OutcomeRateLimited or OutcomeError.
+	Outcome string `json:"outcome"`
+	// Client is the client's identifier, or empty if it didn't authenticate.
+	Client string `json:"client,omitempty"`
+	// Permissions are the client's permissions.
+	Permissions []string `json:"permissions,omitempty"`
+	// MatchedPermissions are the client's permissions that each allow it to call the method on their own.
+	// They are only found when the Authority decides with a PermissionFunc.
+	MatchedPermissions []string `json:"matched_permissions,omitempty"`
+	// ShadowMode is true if the Authority only reports denials, so the request would be let through anyway.
+	ShadowMode bool `json:"shadow_mode,omitempty"`
+	// Steps are the checks that were made, in order. The last one failed if the request was rejected.
+	Steps []ExplanationStep `json:"steps"`
+	// Err is the error the client would get, or nil if the request would be allowed.
+	Err error `json:"-"`
+}
+
+// Allowed reports whether the request would be allowed.
+func (e *Explanation) Allowed() bool {
+	return e.Err == nil
+}
+
+// String returns the explanation as lines of text, one per step.
+func (e *Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", e.MethodName, e.Outcome)
+	if e.ShadowMode && !e.Allowed() {
+		b.WriteString(" (shadow mode)")
+	}
+
+	for _, step := range e.Steps {
+		result := "passed"
+		if !step.Passed {
+			result = "failed"
+		}
+
+		fmt.Fprintf(&b, "\n  %s %s", step.Check, result)
+		if step.Detail != "" {
+			fmt.Fprintf(&b, ": %s", step.Detail)
+		}
+	}
+
+	return b.String()
+}
+
+// Explainer explains why it would allow or reject a request. Authorities from NewAuthority and NewContextAuthority
+// are Explainers.
+type Explainer interface {
+	// Explain decides a request with metadata md to methodName the way the Authority's interceptors would, and
+	// returns each check it made.
+	// It calls the Authority's AuthFunc and policies, but doesn't report to its Loggers, ShadowDenialFunc or
+	// PolicyMismatchFunc, or take a token from its RateLimiter. RequestAuthorizationFuncs aren't checked, since they
+	// need the request message.
+	Explain(ctx context.Context, md metadata.MD, methodName string) *Explanation
+}
+
+// Explain satisfies the Explainer interface.
+func (a *authority) Explain(ctx context.Context, md metadata.MD, methodName string) *Explanation {
+	e := &Explanation{MethodName: methodName, ShadowMode: a.OnShadowDenial != nil}
+	if a.KillSwitch != nil {
+		if err := a.KillSwitch.check(methodName); err != nil {
+			return e.fail(CheckKillSwitch, "the kill switch is enabled", err)
+		}
+
+		e.pass(CheckKillSwitch, "")
+	}
+
+	if a.isUnauthenticatedMethod(methodName) {
+		return e.pass(CheckUnauthenticatedMethod, "the method doesn't require authentication")
+	}
+
+	if a.Denylist != nil {
+		if err := a.Denylist.checkPeer(ctx); err != nil {
+			return e.fail(CheckDenylist, "the peer is on the denylist", err)
+		}
+
+		e.pass(CheckDenylist, "")
+	}
+
+	if a.BruteForce != nil {
+		if err := a.BruteForce.check(a.BruteForce.key(ctx, PeerIP(ctx))); err != nil {
+			return e.fail(CheckBruteForce, "the client has failed to authenticate too many times", err)
+		}
+
+		e.pass(CheckBruteForce, "")
+	}
+
+	if !a.withinMetadataLimits(md) {
+		return e.fail(CheckMetadata, "metadata exceeds limits", errUnauthorized)
+	}
+
+	if !a.SkipMetadataKey && !validateIncomingMetadata(md, a.metadataKey()) {
+		return e.fail(CheckMetadata, fmt.Sprintf("expected credentials in '%s' metadata field", a.metadataKey()), errUnauthorized)
+	}
+	e.pass(CheckMetadata, "")
+
+	ctx = metadata.NewIncomingContext(ctx, md)
+	authResult, err := a.authenticate(ctx, md)
+	if err == nil {
+		err = a.checkExpiry(authResult, time.Now())
+	}
+	if err != nil {
+		return e.fail(CheckAuthentication, redactError(err), errUnauthorized)
+	}
+
+	e.Client = authResult.ClientIdentifier
+	e.Permissions = authResult.Permissions
+	e.pass(CheckAuthentication, "authenticated as "+authResult.ClientIdentifier)
+
+	if a.Denylist != nil {
+		if err := a.Denylist.check(DenylistClient, authResult.ClientIdentifier); err != nil {
+			return e.fail(CheckDenylist, "the client is on the denylist", err)
+		}
+
+		e.pass(CheckDenylist, "")
+	}
+
+	if a.ClientNetworks != nil {
+		if err := a.checkNetwork(ctx, authResult); err != nil {
+			return e.fail(CheckNetwork, fmt.Sprintf("the peer address %q isn't in the client's networks", PeerIP(ctx)), err)
+		}
+
+		e.pass(CheckNetwork, "")
+	}
+
+	ctx = ContextWithAuthResult(ctx, authResult)
+	if a.Principal != nil {
+		principal, err := a.Principal(ctx, authResult)
+		if err != nil {
+			return e.fail(CheckPrincipal, redactError(err), errUnauthorized)
+		}
+
+		ctx = context.WithValue(ctx, authContextKey(principalKeyName), principal)
+		e.pass(CheckPrincipal, "")
+	}
+
+	var authorized bool
+	var detail string
+	if a.Authorize != nil {
+		authorized, err = a.Authorize(ctx, authResult, methodName)
+		if err != nil {
+			return e.fail(CheckAuthorization, redactError(err), authorizationErrorStatus(err))
+		}
+
+		detail = "decided by the AuthorizationFunc"
+	} else {
+		authorized = a.HasPermissions(authResult.Permissions, methodName)
+		e.MatchedPermissions = a.matchingPermissions(authResult.Permissions, methodName)
+		detail = "no permission allows the method"
+		if len(e.MatchedPermissions) > 0 {
+			detail = "allowed by " + strings.Join(e.MatchedPermissions, ", ")
+		}
+	}
+
+	if a.CandidatePermissions != nil {
+		candidate := a.CandidatePermissions(authResult.Permissions, methodName)
+		e.Steps = append(e.Steps, ExplanationStep{
+			Check:  CheckCandidatePolicy,
+			Passed: candidate == authorized,
+			Detail: fmt.Sprintf("the candidate policy would decide allowed=%t", candidate),
+		})
+	}
+
+	if !authorized {
+		return e.fail(CheckAuthorization, detail, permissionDeniedStatus(authResult, methodName))
+	}
+
+	return e.pass(CheckAuthorization, detail)
+}
+
+// matchingPermissions returns each of permissions that allows methodName on its own with the Authority's
+// PermissionFunc.
+func (a *authority) matchingPermissions(permissions []string, methodName string) []string {
+	var matched []string
+	for _, permission := range permissions {
+		if a.HasPermissions([]string{permission}, methodName) {
+			matched = append(matched, permission)
+		}
+	}
+
+	return matched
+}
+
+// pass records a passed check and returns e with the request allowed so far.
+func (e *Explanation) pass(check, detail string) *Explanation {
+	e.Steps = append(e.Steps, ExplanationStep{Check: check, Passed: true, Detail: detail})
+	e.Outcome = OutcomeAllowed
+	return e
+}
+
+// fail records a failed check and returns e with the request rejected with err.
+func (e *Explanation) fail(check, detail string, err error) *Explanation {
+	e.Steps = append(e.Steps, ExplanationStep{Check: check, Detail: detail})
+	e.Err = err
+	e.Outcome = (&AuthEvent{Err: err}).Outcome()
+	return e
+}
@@ -0,0 +1,98 @@
+package grpcauth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestExplain(t *testing.T) {
+	md := metadata.Pairs("authorization", "bearer words")
+	logged := false
+	logger := LoggerFunc(func(ctx context.Context, event *AuthEvent) {
+		logged = true
+	})
+
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		return &AuthResult{ClientIdentifier: testClientName, Permissions: []string{"/other.Service/*", "/server.*"}}, nil
+	}
+
+	allowed := NewAuthority(authFunc, WildcardPermissions, WithLogger(logger)).(Explainer)
+	explanation := allowed.Explain(context.Background(), md, targetMethodName)
+	if !explanation.Allowed() || explanation.Outcome != OutcomeAllowed || explanation.Client != testClientName {
+		t.Fatalf("expected the request to be allowed, got %s", explanation)
+	}
+
+	if len(explanation.MatchedPermissions) != 1 || explanation.MatchedPermissions[0] != "/server.*" {
+		t.Fatalf("expected the permission that allowed the request, got %v", explanation.MatchedPermissions)
+	}
+
+	if logged {
+		t.Fatalf("expected explaining not to report to Loggers")
+	}
+
+	denied := NewAuthority(alwaysAuthenticatedNoPermissions, nil).(Explainer)
+	explanation = denied.Explain(context.Background(), md, targetMethodName)
+	last := explanation.Steps[len(explanation.Steps)-1]
+	if explanation.Outcome != OutcomeDenied || last.Check != CheckAuthorization || last.Passed {
+		t.Fatalf("expected authorization to fail, got %s", explanation)
+	}
+
+	if status.Code(explanation.Err) != codes.PermissionDenied {
+		t.Fatalf("expected the client to get PermissionDenied, got %v", explanation.Err)
+	}
+
+	unauthenticated := NewAuthority(alwaysUnauthenticated, nil).(Explainer)
+	explanation = unauthenticated.Explain(context.Background(), md, targetMethodName)
+	last = explanation.Steps[len(explanation.Steps)-1]
+	if explanation.Err != errUnauthorized || last.Check != CheckAuthentication || last.Detail != "unauthenticated" {
+		t.Fatalf("expected authentication to fail with its cause, got %s", explanation)
+	}
+
+	explanation = unauthenticated.Explain(context.Background(), metadata.MD{}, targetMethodName)
+	if explanation.Steps[0].Check != CheckMetadata || explanation.Outcome != OutcomeUnauthenticated {
+		t.Fatalf("expected the metadata check to fail, got %s", explanation)
+	}
+}
+
+func TestExplainKillSwitchAndUnauthenticatedMethods(t *testing.T) {
+	killSwitch := &KillSwitch{}
+	killSwitch.Enable("maintenance", "/grpc.health.v1.Health/*")
+	a := NewAuthority(alwaysUnauthenticated, nil,
+		WithKillSwitch(killSwitch),
+		WithUnauthenticatedMethods("/grpc.health.v1.Health/Check"),
+	).(Explainer)
+
+	explanation := a.Explain(context.Background(), metadata.MD{}, targetMethodName)
+	if explanation.Outcome != OutcomeError || explanation.Steps[0].Check != CheckKillSwitch {
+		t.Fatalf("expected the kill switch to reject the request, got %s", explanation)
+	}
+
+	explanation = a.Explain(context.Background(), metadata.MD{}, "/grpc.health.v1.Health/Check")
+	if !explanation.Allowed() || explanation.Steps[1].Check != CheckUnauthenticatedMethod {
+		t.Fatalf("expected the method not to need authentication, got %s", explanation)
+	}
+}
+
+func TestExplainCandidatePolicy(t *testing.T) {
+	mismatches := 0
+	a := NewAuthority(alwaysAuthenticatedAllPermissions, nil, WithCandidatePermissionFunc(
+		func(permissions []string, methodName string) bool { return false },
+		func(ctx context.Context, authResult *AuthResult, methodName string, current, candidate bool) {
+			mismatches++
+		},
+	)).(Explainer)
+
+	explanation := a.Explain(context.Background(), metadata.Pairs("authorization", "bearer words"), targetMethodName)
+	if !explanation.Allowed() {
+		t.Fatalf("expected the current policy to decide, got %s", explanation)
+	}
+
+	if !strings.Contains(explanation.String(), CheckCandidatePolicy+" failed") || mismatches != 0 {
+		t.Fatalf("expected the disagreement to be explained but not reported, got %s", explanation)
+	}
+}
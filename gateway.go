@@ -0,0 +1,122 @@
+package grpcauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// ForwardedAuthorizationMetadataKey is the metadata field gateways like ESPv2 and API Gateway move the client's
+	// original authorization header to when they replace it with their own credentials.
+	ForwardedAuthorizationMetadataKey = "x-forwarded-authorization"
+
+	// EndpointsUserInfoMetadataKey is the metadata field ESPv2 and Cloud Endpoints put the claims of the JWT they
+	// validated in, as base64 encoded JSON.
+	EndpointsUserInfoMetadataKey = "x-endpoint-api-userinfo"
+)
+
+// FromMetadataKey returns an AuthFunc that calls authFunc with the credentials in key as the authorization metadata
+// field, for deployments where a gateway forwards the client's token in another field like
+// ForwardedAuthorizationMetadataKey. Use it with WithMetadataKey(key).
+func FromMetadataKey(key string, authFunc AuthFunc) AuthFunc {
+	key = strings.ToLower(key)
+	return func(md metadata.MD) (*AuthResult, error) {
+		values := md.Get(key)
+		if len(values) != 1 {
+			return nil, fmt.Errorf("expected credentials in '%s' metadata field", key)
+		}
+
+		forwarded := md.Copy()
+		forwarded.Set("authorization", values[0])
+		return authFunc(forwarded)
+	}
+}
+
+// GatewayClaims authenticates clients with claims a gateway has already validated, like the ones ESPv2 puts in
+// EndpointsUserInfoMetadataKey, without verifying a signature.
+// It must only be used when the gateway is the only way to reach the server and it strips the field from client
+// requests, since anyone who can reach the server directly can assert any claims.
+// See https://cloud.google.com/endpoints/docs/openapi/authenticating-users-custom for more details.
+type GatewayClaims struct {
+	// MetadataKey is the metadata field holding the claims. It defaults to EndpointsUserInfoMetadataKey.
+	// Use the same field with WithMetadataKey.
+	MetadataKey string
+	// ClientIdentifierClaim is the claim used as AuthResult.ClientIdentifier. It defaults to sub.
+	ClientIdentifierClaim string
+	// PermissionsClaim is the claim used as AuthResult.Permissions. It defaults to the scope claim.
+	PermissionsClaim string
+}
+
+// AuthFunc satisfies the AuthFunc interface so servers behind a gateway can trust the claims it forwards.
+func (g *GatewayClaims) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	key := g.MetadataKey
+	if key == "" {
+		key = EndpointsUserInfoMetadataKey
+	}
+
+	values := md.Get(key)
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected claims in '%s' metadata field", key)
+	}
+
+	// Gateways differ on whether they use the URL safe alphabet and padding.
+	encoded := strings.TrimRight(values[0], "=")
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		b, err = base64.RawStdEncoding.DecodeString(encoded)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid '%s' metadata field: %v", key, err)
+	}
+
+	var claims jwt.MapClaims
+	err = json.Unmarshal(b, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("invalid '%s' metadata field: %v", key, err)
+	}
+
+	validator := &JWTValidator{ClientIdentifierClaim: g.ClientIdentifierClaim, PermissionsClaim: g.PermissionsClaim}
+	return validator.AuthResult(claims)
+}
+
+// TrustedProxyClaims authenticates clients with the claims of a JWT a proxy, like an Istio or Envoy sidecar, has
+// already validated and forwarded in a header, without verifying the JWT's signature again.
+// It only accepts the claims from peers that connect over mutual TLS with one of TrustedPeers as a SAN, so the
+// gRPC server must verify client certificates and TrustedProxyClaims must be used with NewContextAuthority.
+type TrustedProxyClaims struct {
+	// TrustedPeers are the URI, DNS or email SANs, like SPIFFE IDs, of the proxies allowed to forward claims.
+	TrustedPeers []string
+	// Claims reads the forwarded claims. Set its MetadataKey to the header the proxy forwards them in, such as
+	// the one configured with Istio's outputPayloadToHeader, and use the same key with WithMetadataKey.
+	Claims GatewayClaims
+}
+
+// AuthFunc satisfies the ContextAuthFunc interface so servers behind a trusted proxy can use the claims it forwards.
+func (p *TrustedProxyClaims) AuthFunc(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+	cert, err := verifiedPeerCertificate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	trusted := false
+	for _, san := range certificateSANs(cert) {
+		for _, peer := range p.TrustedPeers {
+			if san == peer {
+				trusted = true
+			}
+		}
+	}
+
+	if !trusted {
+		return nil, fmt.Errorf("peer is not a trusted proxy")
+	}
+
+	return p.Claims.AuthFunc(md)
+}
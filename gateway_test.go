@@ -0,0 +1,91 @@
+package grpcauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFromMetadataKey(t *testing.T) {
+	issuer := newTestIssuer(t)
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/jwks"
+	validator := NewJWTValidator(jwksURL, issuer.server.URL, testAudience)
+	authFunc := FromMetadataKey(ForwardedAuthorizationMetadataKey, validator.AuthFunc)
+
+	token := issuer.sign(t, issuer.claims())
+	md := metadata.Pairs(ForwardedAuthorizationMetadataKey, "Bearer "+token, "authorization", "Bearer gateway-token")
+	authResult, err := authFunc(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected %s, got %s", testClientName, authResult.ClientIdentifier)
+	}
+
+	_, err = authFunc(metadata.Pairs("authorization", "Bearer "+token))
+	if err == nil {
+		t.Fatalf("expected missing forwarded authorization to be rejected")
+	}
+}
+
+func TestGatewayClaims(t *testing.T) {
+	userInfo := base64.URLEncoding.EncodeToString([]byte(`{"sub":"testClient","scope":"/server.ServiceName/MethodName","tenant_id":"acme"}`))
+	gateway := &GatewayClaims{}
+	authResult, err := gateway.AuthFunc(metadata.Pairs(EndpointsUserInfoMetadataKey, userInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName || !reflect.DeepEqual(authResult.Permissions, []string{targetMethodName}) {
+		t.Fatalf("unexpected AuthResult %+v", authResult)
+	}
+
+	if authResult.StringClaim("tenant_id") != "acme" {
+		t.Fatalf("expected tenant_id claim, got %v", authResult.Claims)
+	}
+
+	_, err = gateway.AuthFunc(metadata.Pairs(EndpointsUserInfoMetadataKey, "not base64!"))
+	if err == nil {
+		t.Fatalf("expected invalid claims to be rejected")
+	}
+}
+
+func TestTrustedProxyClaims(t *testing.T) {
+	cert := testClientCertificate(t)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"testClient","scope":"/server.ServiceName/MethodName"}`))
+	md := metadata.Pairs("x-jwt-payload", payload)
+	ctx := tlsPeerContext(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	})
+
+	proxy := &TrustedProxyClaims{
+		TrustedPeers: []string{testSPIFFEID},
+		Claims:       GatewayClaims{MetadataKey: "x-jwt-payload"},
+	}
+	authResult, err := proxy.AuthFunc(ctx, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected %s, got %s", testClientName, authResult.ClientIdentifier)
+	}
+
+	proxy.TrustedPeers = []string{"spiffe://example.org/ns/istio-system/sa/ingress"}
+	_, err = proxy.AuthFunc(ctx, md)
+	if err == nil {
+		t.Fatalf("expected untrusted peer to be rejected")
+	}
+
+	_, err = proxy.AuthFunc(tlsPeerContext(tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}), md)
+	if err == nil {
+		t.Fatalf("expected unverified peer to be rejected")
+	}
+}
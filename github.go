@@ -0,0 +1,227 @@
+package grpcauth
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// gitHubAPIURL is the GitHub REST API used to check installation access tokens.
+	gitHubAPIURL = "https://api.github.com"
+
+	// gitHubInstallationTokenPrefix is the prefix GitHub puts on installation access tokens.
+	gitHubInstallationTokenPrefix = "ghs_"
+
+	// gitHubAppJWTMaxLifetime is the longest GitHub allows an App JWT to be valid for.
+	gitHubAppJWTMaxLifetime = 10 * time.Minute
+
+	// GitHub App JWTs must be signed with RS256.
+	signingMethod = "RS256"
+)
+
+// GitHubApp authenticates CI tooling and bots using their GitHub App identity.
+// Clients can present either a JWT signed with the App's private key or an installation access token.
+// App JWTs are verified offline against the App's public key and Audience, and the App ID becomes
+// AuthResult.ClientIdentifier.
+// Installation access tokens are opaque, so they are checked against the GitHub API to make sure they belong to the
+// App with AppSlug, and the account the App is installed on becomes AuthResult.ClientIdentifier.
+// See https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/about-authentication-with-a-github-app
+type GitHubApp struct {
+	// AppKeys maps the ID of each GitHub App allowed to authenticate to the public half of its private key.
+	AppKeys map[string]*rsa.PublicKey
+	// AppPermissions maps GitHub App IDs to the permissions they get when authenticating with an App JWT.
+	AppPermissions map[string][]string
+	// Audience must be in the aud claim of App JWTs. GitHub ignores aud, so it stops JWTs an App signed to call the
+	// GitHub API from being replayed to the server.
+	// App JWTs are rejected if it is empty.
+	Audience string
+	// AppSlug is the slug of the GitHub App, as in https://github.com/apps/<slug>, installation access tokens must
+	// belong to, so tokens for other Apps installed on the same account are rejected.
+	// Installation access tokens are rejected if it is empty.
+	AppSlug string
+	// InstallationPermissions maps the login of the account a GitHub App is installed on to the permissions
+	// installation access tokens get.
+	// Installation access tokens are rejected if it is nil.
+	InstallationPermissions map[string][]string
+	// APIURL is the GitHub REST API used to check installation access tokens, for GitHub Enterprise Server.
+	// It defaults to https://api.github.com.
+	APIURL *url.URL
+	// Client makes requests to the GitHub API. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// gitHubViewer is the response to a GraphQL query for the viewer's login.
+type gitHubViewer struct {
+	Data struct {
+		Viewer struct {
+			Login string `json:"login"`
+		} `json:"viewer"`
+	} `json:"data"`
+}
+
+// gitHubInstallationRepositories is the response from GET /installation/repositories.
+type gitHubInstallationRepositories struct {
+	Repositories []struct {
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repositories"`
+}
+
+// AuthFunc satisfies the AuthFunc interface so clients can use GitHub App credentials with a gRPC server.
+func (g *GitHubApp) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	tokenString, err := bearerToken(md)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(tokenString, gitHubInstallationTokenPrefix) {
+		return g.authenticateInstallation(tokenString)
+	}
+
+	return g.authenticateApp(tokenString)
+}
+
+func (g *GitHubApp) authenticateApp(tokenString string) (*AuthResult, error) {
+	if g.Audience == "" {
+		return nil, fmt.Errorf("GitHub App JWTs are not accepted")
+	}
+
+	var appID string
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: expected %s, got %v", signingMethod, token.Header["alg"])
+		}
+
+		claims := token.Claims.(jwt.MapClaims)
+		// GitHub Apps can use either their numeric App ID or their client ID as the issuer.
+		switch iss := claims["iss"].(type) {
+		case float64:
+			appID = strconv.FormatInt(int64(iss), 10)
+		case string:
+			appID = iss
+		}
+
+		key, ok := g.AppKeys[appID]
+		if !ok {
+			return nil, fmt.Errorf("unknown GitHub App: %v", claims["iss"])
+		}
+
+		return key, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if !verifyAudience(claims, g.Audience) {
+		return nil, fmt.Errorf("invalid audience, expected %s, got %v", g.Audience, claims["aud"])
+	}
+
+	exp, _ := claims["exp"].(float64)
+	iat, _ := claims["iat"].(float64)
+	if exp == 0 || iat == 0 {
+		return nil, fmt.Errorf("GitHub App JWTs must have exp and iat claims")
+	}
+
+	if time.Duration(exp-iat)*time.Second > gitHubAppJWTMaxLifetime {
+		return nil, fmt.Errorf("GitHub App JWTs must not be valid for more than %v", gitHubAppJWTMaxLifetime)
+	}
+
+	return &AuthResult{
+		ClientIdentifier: appID,
+		Timestamp:        time.Now(),
+		Permissions:      g.AppPermissions[appID],
+	}, nil
+}
+
+func (g *GitHubApp) authenticateInstallation(tokenString string) (*AuthResult, error) {
+	if g.InstallationPermissions == nil || g.AppSlug == "" {
+		return nil, fmt.Errorf("installation access tokens are not accepted")
+	}
+
+	apiURL := gitHubAPIURL
+	if g.APIURL != nil {
+		apiURL = strings.TrimSuffix(g.APIURL.String(), "/")
+	}
+
+	// Installation access tokens act as their App's bot account, which is the only way to tell which App they belong
+	// to. GitHub Enterprise Server serves GraphQL from /api/graphql rather than under the REST API's /api/v3.
+	var viewer gitHubViewer
+	query := strings.NewReader(`{"query":"query { viewer { login } }"}`)
+	err := g.do(http.MethodPost, strings.TrimSuffix(apiURL, "/v3")+"/graphql", query, tokenString, &viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if viewer.Data.Viewer.Login != g.AppSlug+"[bot]" {
+		return nil, fmt.Errorf("installation access token belongs to %s, not GitHub App %s", viewer.Data.Viewer.Login, g.AppSlug)
+	}
+
+	var repositories gitHubInstallationRepositories
+	err = g.do(http.MethodGet, apiURL+"/installation/repositories?per_page=1", nil, tokenString, &repositories)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(repositories.Repositories) == 0 {
+		return nil, fmt.Errorf("installation has no repositories")
+	}
+
+	owner := repositories.Repositories[0].Owner.Login
+	permissions, ok := g.InstallationPermissions[owner]
+	if !ok {
+		return nil, fmt.Errorf("installation on %s is not allowed", owner)
+	}
+
+	return &AuthResult{
+		ClientIdentifier: owner,
+		Timestamp:        time.Now(),
+		Permissions:      permissions,
+	}, nil
+}
+
+// do makes a request to the GitHub API with an installation access token and decodes the JSON response into v.
+func (g *GitHubApp) do(method, requestURL string, body io.Reader, tokenString string, v interface{}) error {
+	req, err := http.NewRequest(method, requestURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return errors.New(string(b))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,78 @@
+package grpcauth
+
+import (
+	"fmt"
+	"net/url"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// googleCertsURL is where Google publishes the keys it signs ID tokens with.
+	googleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+)
+
+var (
+	// googleIssuers are the iss claims Google puts in the ID tokens it signs.
+	googleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+)
+
+// GoogleServiceAccount authenticates GCP workloads presenting Google-signed ID tokens for a service account,
+// such as those from the metadata server or `gcloud auth print-identity-token`.
+// The service account's email address becomes AuthResult.ClientIdentifier.
+// See https://cloud.google.com/docs/authentication/token-types#id for more details.
+type GoogleServiceAccount struct {
+	// Audience is the aud claim the ID token was requested for, usually the URL of the service.
+	Audience string
+	// ServiceAccountPermissions maps the email of each service account allowed to call the server to its permissions.
+	// ID tokens don't carry scopes, so when it is nil any Google service account is accepted with no permissions.
+	ServiceAccountPermissions map[string][]string
+	// JWKSURL overrides where Google's signing keys are fetched from.
+	// It defaults to https://www.googleapis.com/oauth2/v3/certs.
+	JWKSURL *url.URL
+
+	JWTValidation
+}
+
+// AuthFunc satisfies the AuthFunc interface so clients can use Google ID tokens with a gRPC server.
+func (g *GoogleServiceAccount) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	jwksURL := g.JWKSURL
+	if jwksURL == nil {
+		jwksURL, _ = url.Parse(googleCertsURL)
+	}
+
+	claims, err := verifyBearerToken(md, jwksURL, g.Audience, g.JWTValidation)
+	if err != nil {
+		return nil, err
+	}
+
+	if !verifyIssuer(claims, googleIssuers) {
+		return nil, fmt.Errorf("invalid issuer, expected one of %v, got %v", googleIssuers, claims["iss"])
+	}
+
+	email, _ := claims["email"].(string)
+	if email == "" {
+		return nil, fmt.Errorf("ID token has no email claim, request it with the service account's email included")
+	}
+
+	if verified, _ := claims["email_verified"].(bool); !verified {
+		return nil, fmt.Errorf("email %s is not verified", email)
+	}
+
+	authResult, err := authResultFromClaims(claims, g.Leeway)
+	if err != nil {
+		return nil, err
+	}
+
+	authResult.ClientIdentifier = email
+	authResult.Permissions = nil
+	if g.ServiceAccountPermissions != nil {
+		permissions, ok := g.ServiceAccountPermissions[email]
+		if !ok {
+			return nil, fmt.Errorf("service account %s is not allowed", email)
+		}
+		authResult.Permissions = permissions
+	}
+
+	return authResult, nil
+}
@@ -0,0 +1,44 @@
+package grpcauth
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testServiceAccount = "caller@project.iam.gserviceaccount.com"
+
+func TestGoogleServiceAccountMapsEmail(t *testing.T) {
+	issuer := newTestIssuer(t)
+	google := &GoogleServiceAccount{
+		Audience: testAudience,
+		ServiceAccountPermissions: map[string][]string{
+			testServiceAccount: {targetMethodName},
+		},
+		JWKSURL: issuer.url(t),
+	}
+
+	claims := issuer.claims()
+	claims["iss"] = "https://accounts.google.com"
+	claims["sub"] = "113712633730990122473"
+	claims["email"] = testServiceAccount
+	claims["email_verified"] = true
+
+	authResult, err := google.AuthFunc(bearerMetadata(issuer.sign(t, claims)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testServiceAccount {
+		t.Fatalf("expected %v, got %v", testServiceAccount, authResult.ClientIdentifier)
+	}
+
+	if !reflect.DeepEqual(authResult.Permissions, []string{targetMethodName}) {
+		t.Fatalf("expected %v, got %v", []string{targetMethodName}, authResult.Permissions)
+	}
+
+	claims["email"] = "other@project.iam.gserviceaccount.com"
+	_, err = google.AuthFunc(bearerMetadata(issuer.sign(t, claims)))
+	if err == nil {
+		t.Fatalf("expected error with unknown service account")
+	}
+}
@@ -3,5 +3,17 @@
 // OAuth2 Client Credentials grant type.
 // grpcauth has authenticators for the following providers:
 // + auth0
-// + AWS Cognito.
+// + AWS Cognito
+// + Okta
+// + Azure AD (Microsoft Entra ID)
+// + Keycloak
+// + Google service account ID tokens
+// + GitHub Apps
+// + GCP Identity-Aware Proxy
+// + Cloudflare Access
+// + AWS Application Load Balancer OIDC authentication
+// + Kubernetes service accounts
+// + mutual TLS client certificates
+// + AWS IAM roles
+// + any OpenID Connect provider supporting discovery.
 package grpcauth
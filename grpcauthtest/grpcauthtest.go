@@ -0,0 +1,169 @@
+// Package grpcauthtest provides a fake grpcauth.Authority with scripted outcomes, so services can test how their
+// handlers behave for different clients without standing up an identity provider.
+// It also has a fake OAuth2 identity provider for testing grpcauth's authenticators end to end.
+package grpcauthtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/joncooperworks/grpcauth"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Outcome is what the fake Authority does with a request.
+// Requests fail with Err if it is set, are Unauthenticated if AuthResult is nil and are PermissionDenied if Denied
+// is true. Otherwise the handler is called with AuthResult in its context.
+type Outcome struct {
+	AuthResult *grpcauth.AuthResult
+	Denied     bool
+	Err        error
+}
+
+// Authenticated returns an Outcome that lets a client with clientIdentifier and permissions call the method.
+func Authenticated(clientIdentifier string, permissions ...string) Outcome {
+	return Outcome{AuthResult: &grpcauth.AuthResult{ClientIdentifier: clientIdentifier, Permissions: permissions}}
+}
+
+// Unauthenticated returns an Outcome that rejects the request as Unauthenticated.
+func Unauthenticated() Outcome {
+	return Outcome{}
+}
+
+// Denied returns an Outcome that authenticates a client with clientIdentifier and permissions, and rejects it as
+// PermissionDenied.
+func Denied(clientIdentifier string, permissions ...string) Outcome {
+	outcome := Authenticated(clientIdentifier, permissions...)
+	outcome.Denied = true
+	return outcome
+}
+
+// Failed returns an Outcome that rejects the request with err, like an Unavailable policy store.
+func Failed(err error) Outcome {
+	return Outcome{Err: err}
+}
+
+// Authority is a fake grpcauth.Authority that applies a scripted Outcome to each method and records the methods
+// whose handlers it called. It is safe for concurrent use.
+type Authority struct {
+	mu       sync.Mutex
+	fallback Outcome
+	outcomes map[string]Outcome
+	calls    []string
+}
+
+// NewAuthority returns an Authority that applies fallback to methods without an Outcome of their own.
+func NewAuthority(fallback Outcome) *Authority {
+	return &Authority{
+		fallback: fallback,
+		outcomes: map[string]Outcome{},
+	}
+}
+
+// On makes the Authority apply outcome to methodName, a full gRPC method name like `/server.ServiceName/MethodName`.
+func (a *Authority) On(methodName string, outcome Outcome) *Authority {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.outcomes[methodName] = outcome
+	return a
+}
+
+// Calls returns the methods whose handlers the Authority called, in order.
+func (a *Authority) Calls() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.calls...)
+}
+
+// UnaryServerInterceptor applies the method's Outcome before invoking the server handler.
+func (a *Authority) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.apply(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor applies the method's Outcome before invoking the stream handler.
+func (a *Authority) StreamServerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.apply(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	wrapped := middleware.WrapServerStream(stream)
+	wrapped.WrappedContext = ctx
+	return handler(srv, wrapped)
+}
+
+func (a *Authority) apply(ctx context.Context, methodName string) (context.Context, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	outcome, ok := a.outcomes[methodName]
+	if !ok {
+		outcome = a.fallback
+	}
+
+	if outcome.Err != nil {
+		return nil, outcome.Err
+	}
+
+	if outcome.AuthResult == nil {
+		st, _ := status.New(codes.Unauthenticated, grpcauth.UnauthenticatedError).WithDetails(&errdetails.ErrorInfo{
+			Reason: grpcauth.ReasonUnauthenticated,
+			Domain: grpcauth.ErrorDomain,
+		})
+		return nil, st.Err()
+	}
+
+	if outcome.Denied {
+		permissionDenied := &grpcauth.PermissionDeniedError{
+			ClientIdentifier:    outcome.AuthResult.ClientIdentifier,
+			PermissionRequested: methodName,
+			ClientPermissions:   outcome.AuthResult.Permissions,
+		}
+		return nil, permissionDenied.GRPCStatus().Err()
+	}
+
+	a.calls = append(a.calls, methodName)
+	return grpcauth.ContextWithAuthResult(ctx, outcome.AuthResult), nil
+}
+
+// AssertCode fails the test if err doesn't have the gRPC status code.
+func AssertCode(t testing.TB, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Fatalf("expected %v, got %v", code, err)
+	}
+}
+
+// AssertClient fails the test if ctx wasn't authenticated as clientIdentifier.
+func AssertClient(t testing.TB, ctx context.Context, clientIdentifier string) {
+	t.Helper()
+	authResult, err := grpcauth.GetAuthResult(ctx)
+	if err != nil {
+		t.Fatalf("expected %s to be authenticated: %v", clientIdentifier, err)
+	}
+
+	if authResult.ClientIdentifier != clientIdentifier {
+		t.Fatalf("expected %s to be authenticated, got %s", clientIdentifier, authResult.ClientIdentifier)
+	}
+}
+
+// AssertCalled fails the test if the Authority didn't call methodName's handler.
+func (a *Authority) AssertCalled(t testing.TB, methodName string) {
+	t.Helper()
+	for _, call := range a.Calls() {
+		if call == methodName {
+			return
+		}
+	}
+
+	t.Fatalf("expected %s to be called, got %v", methodName, a.Calls())
+}
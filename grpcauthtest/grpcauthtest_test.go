@@ -0,0 +1,66 @@
+package grpcauthtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/joncooperworks/grpcauth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	getThing    = "/server.ServiceName/GetThing"
+	deleteThing = "/server.ServiceName/DeleteThing"
+	listThings  = "/server.ServiceName/ListThings"
+)
+
+func TestAuthority(t *testing.T) {
+	var authority grpcauth.Authority = NewAuthority(Unauthenticated()).
+		On(getThing, Authenticated("reader", getThing)).
+		On(deleteThing, Denied("reader", getThing)).
+		On(listThings, Failed(status.Error(codes.Unavailable, "policy store down")))
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		AssertClient(t, ctx, "reader")
+		return req, nil
+	}
+	call := func(methodName string) error {
+		_, err := authority.UnaryServerInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: methodName}, handler)
+		return err
+	}
+
+	AssertCode(t, call(getThing), codes.OK)
+	AssertCode(t, call(deleteThing), codes.PermissionDenied)
+	AssertCode(t, call(listThings), codes.Unavailable)
+	AssertCode(t, call("/server.ServiceName/Other"), codes.Unauthenticated)
+
+	fake := authority.(*Authority)
+	fake.AssertCalled(t, getThing)
+	if len(fake.Calls()) != 1 {
+		t.Fatalf("expected only %s to be called, got %v", getThing, fake.Calls())
+	}
+
+	stream := &testServerStream{ctx: context.Background()}
+	err := NewAuthority(Failed(errors.New("boom"))).StreamServerInterceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: getThing}, nil)
+	if err == nil {
+		t.Fatalf("expected stream to fail")
+	}
+
+	err = authority.StreamServerInterceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: getThing}, func(srv interface{}, stream grpc.ServerStream) error {
+		AssertClient(t, stream.Context(), "reader")
+		return nil
+	})
+	AssertCode(t, err, codes.OK)
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,225 @@
+package grpcauthtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	idpKeyID = "grpcauthtest"
+
+	// idpJWKSPath and idpTokenPath are where the IdentityProvider serves its JWKS and token endpoint.
+	idpJWKSPath  = "/.well-known/jwks.json"
+	idpTokenPath = "/oauth/token"
+)
+
+// IdentityProvider is a fake OAuth2 and OpenID Connect provider running on an httptest.Server.
+// It serves a discovery document, a JWKS and a client credentials token endpoint, so grpcauth's authenticators
+// like OIDC, Auth0M2M and AWSCognitoM2M can be tested end to end against it.
+// Its URL is the iss claim of every token it issues. It is safe for concurrent use.
+type IdentityProvider struct {
+	Server *httptest.Server
+	// Audience is the aud claim of tokens requested without an audience parameter.
+	Audience string
+	// TokenLifetime is how long issued tokens are valid for. It defaults to an hour.
+	TokenLifetime time.Duration
+
+	key     *rsa.PrivateKey
+	mu      sync.Mutex
+	clients map[string]identityProviderClient
+}
+
+// identityProviderClient is an OAuth2 client registered with an IdentityProvider.
+type identityProviderClient struct {
+	secret string
+	scopes []string
+}
+
+// NewIdentityProvider starts an IdentityProvider that is shut down when the test finishes.
+func NewIdentityProvider(t testing.TB, audience string) *IdentityProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &IdentityProvider{
+		Audience: audience,
+		key:      key,
+		clients:  map[string]identityProviderClient{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.serveDiscovery)
+	mux.HandleFunc(idpJWKSPath, p.serveJWKS)
+	mux.HandleFunc(idpTokenPath, p.serveToken)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Server.Close)
+	return p
+}
+
+// URL returns the IdentityProvider's issuer URL.
+func (p *IdentityProvider) URL() *url.URL {
+	u, _ := url.Parse(p.Server.URL)
+	return u
+}
+
+// JWKSURL returns the URL of the IdentityProvider's JWKS.
+func (p *IdentityProvider) JWKSURL() *url.URL {
+	u := p.URL()
+	u.Path = idpJWKSPath
+	return u
+}
+
+// TokenURL returns the URL of the IdentityProvider's token endpoint.
+func (p *IdentityProvider) TokenURL() string {
+	return p.Server.URL + idpTokenPath
+}
+
+// AddClient registers an OAuth2 client that can get tokens with up to scopes using the client credentials grant.
+func (p *IdentityProvider) AddClient(clientID, clientSecret string, scopes ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clients[clientID] = identityProviderClient{secret: clientSecret, scopes: scopes}
+}
+
+// Claims returns valid claims for a token issued to subject with scopes, which tests can modify before calling Sign.
+func (p *IdentityProvider) Claims(subject string, scopes ...string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   p.Server.URL,
+		"sub":   subject,
+		"aud":   p.Audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(p.tokenLifetime()).Unix(),
+		"scope": strings.Join(scopes, " "),
+	}
+}
+
+// Sign returns a JWT containing claims signed by the IdentityProvider's key, for testing how servers handle tokens
+// the token endpoint wouldn't issue, like expired ones.
+func (p *IdentityProvider) Sign(t testing.TB, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := p.sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func (p *IdentityProvider) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = idpKeyID
+	return token.SignedString(p.key)
+}
+
+func (p *IdentityProvider) tokenLifetime() time.Duration {
+	if p.TokenLifetime == 0 {
+		return time.Hour
+	}
+
+	return p.TokenLifetime
+}
+
+func (p *IdentityProvider) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":         p.Server.URL,
+		"jwks_uri":       p.JWKSURL().String(),
+		"token_endpoint": p.TokenURL(),
+	})
+}
+
+func (p *IdentityProvider) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": idpKeyID,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+			},
+		},
+	})
+}
+
+// serveToken issues access tokens with the client credentials grant, authenticating clients with HTTP Basic
+// authentication or the client_id and client_secret form parameters.
+func (p *IdentityProvider) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.FormValue("grant_type") != "client_credentials" {
+		writeOAuth2Error(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.FormValue("client_id"), r.FormValue("client_secret")
+	}
+
+	p.mu.Lock()
+	client, ok := p.clients[clientID]
+	p.mu.Unlock()
+	if !ok || client.secret != clientSecret {
+		writeOAuth2Error(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+
+	scopes := client.scopes
+	if requested := strings.Fields(r.FormValue("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !contains(client.scopes, scope) {
+				writeOAuth2Error(w, http.StatusBadRequest, "invalid_scope")
+				return
+			}
+		}
+		scopes = requested
+	}
+
+	claims := p.Claims(clientID, scopes...)
+	if audience := r.FormValue("audience"); audience != "" {
+		claims["aud"] = audience
+	}
+
+	token, err := p.sign(claims)
+	if err != nil {
+		writeOAuth2Error(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(p.tokenLifetime() / time.Second),
+		"scope":        strings.Join(scopes, " "),
+	})
+}
+
+func writeOAuth2Error(w http.ResponseWriter, statusCode int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
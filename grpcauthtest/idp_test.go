@@ -0,0 +1,57 @@
+package grpcauthtest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joncooperworks/grpcauth"
+	"golang.org/x/oauth2/clientcredentials"
+	"google.golang.org/grpc/metadata"
+)
+
+const testAudience = "https://api.example.com"
+
+func TestIdentityProvider(t *testing.T) {
+	idp := NewIdentityProvider(t, testAudience)
+	idp.AddClient("billing", "s3cr3t", getThing, listThings)
+
+	config := &clientcredentials.Config{
+		ClientID:     "billing",
+		ClientSecret: "s3cr3t",
+		TokenURL:     idp.TokenURL(),
+		Scopes:       []string{getThing},
+	}
+	token, err := config.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oidc, err := grpcauth.NewOIDC(context.Background(), idp.URL(), testAudience)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authResult, err := oidc.AuthFunc(metadata.Pairs("authorization", "Bearer "+token.AccessToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != "billing" || !reflect.DeepEqual(authResult.Permissions, []string{getThing}) {
+		t.Fatalf("unexpected AuthResult %+v", authResult)
+	}
+
+	config.ClientSecret = "wrong"
+	_, err = config.Token(context.Background())
+	if err == nil {
+		t.Fatalf("expected invalid client secret to be rejected")
+	}
+
+	claims := idp.Claims("billing", getThing)
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	_, err = oidc.AuthFunc(metadata.Pairs("authorization", "Bearer "+idp.Sign(t, claims)))
+	if err == nil {
+		t.Fatalf("expected expired token to be rejected")
+	}
+}
@@ -0,0 +1,142 @@
+package grpcauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// GRPCAuthzPolicy is a policy in the JSON format of gRPC's built-in authorization, google.golang.org/grpc/authz, so
+// teams that have standardized on it can enforce the same policies with any grpcauth authenticator.
+// A rule's principals are matched against AuthResult.ClientIdentifier instead of the peer's certificate.
+// Requests matching a deny rule are denied, then requests matching an allow rule are allowed and everything else is
+// denied. See https://github.com/grpc/proposal/blob/master/A43-grpc-authorization-api.md for more details.
+type GRPCAuthzPolicy struct {
+	Name       string          `json:"name"`
+	DenyRules  []GRPCAuthzRule `json:"deny_rules"`
+	AllowRules []GRPCAuthzRule `json:"allow_rules"`
+}
+
+// GRPCAuthzRule matches requests from some clients to some methods.
+type GRPCAuthzRule struct {
+	Name   string `json:"name"`
+	Source struct {
+		// Principals match the client's identifier. A rule without principals matches every client.
+		Principals []string `json:"principals"`
+	} `json:"source"`
+	Request struct {
+		// Paths match the full gRPC method name. A rule without paths matches every method.
+		Paths []string `json:"paths"`
+		// Headers must all match the request's metadata.
+		Headers []GRPCAuthzHeader `json:"headers"`
+	} `json:"request"`
+}
+
+// GRPCAuthzHeader matches metadata with Key having any of Values.
+type GRPCAuthzHeader struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
+// ParseGRPCAuthzPolicy parses a policy in gRPC's authorization policy JSON format.
+func ParseGRPCAuthzPolicy(b []byte) (*GRPCAuthzPolicy, error) {
+	var policy GRPCAuthzPolicy
+	err := json.Unmarshal(b, &policy)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy.Name == "" {
+		return nil, fmt.Errorf("authorization policy has no name")
+	}
+
+	if len(policy.AllowRules) == 0 {
+		return nil, fmt.Errorf("authorization policy %s has no allow rules", policy.Name)
+	}
+
+	for _, rule := range append(policy.DenyRules, policy.AllowRules...) {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("authorization policy %s has a rule with no name", policy.Name)
+		}
+
+		for _, header := range rule.Request.Headers {
+			key := strings.ToLower(header.Key)
+			if strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-") || key == "host" {
+				return nil, fmt.Errorf("rule %s can't match the %s header", rule.Name, header.Key)
+			}
+		}
+	}
+
+	return &policy, nil
+}
+
+// AuthorizationFunc satisfies the AuthorizationFunc interface by evaluating the policy's deny rules, then its allow
+// rules.
+func (p *GRPCAuthzPolicy) AuthorizationFunc(ctx context.Context, authResult *AuthResult, methodName string) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, rule := range p.DenyRules {
+		if rule.matches(authResult, methodName, md) {
+			return false
+		}
+	}
+
+	for _, rule := range p.AllowRules {
+		if rule.matches(authResult, methodName, md) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *GRPCAuthzRule) matches(authResult *AuthResult, methodName string, md metadata.MD) bool {
+	if len(r.Source.Principals) > 0 && !matchesAnyAuthzPattern(r.Source.Principals, authResult.ClientIdentifier) {
+		return false
+	}
+
+	if len(r.Request.Paths) > 0 && !matchesAnyAuthzPattern(r.Request.Paths, methodName) {
+		return false
+	}
+
+	for _, header := range r.Request.Headers {
+		matched := false
+		for _, value := range md.Get(header.Key) {
+			if matchesAnyAuthzPattern(header.Values, value) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesAnyAuthzPattern reports whether value matches one of patterns, which are exact values, `*` for any
+// value, prefixes ending in `*` or suffixes starting with `*`.
+func matchesAnyAuthzPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		switch {
+		case pattern == "*":
+			return true
+		case strings.HasSuffix(pattern, "*"):
+			if strings.HasPrefix(value, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		case strings.HasPrefix(pattern, "*"):
+			if strings.HasSuffix(value, strings.TrimPrefix(pattern, "*")) {
+				return true
+			}
+		case pattern == value:
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,66 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const testGRPCAuthzPolicy = `{
+	"name": "authz",
+	"deny_rules": [
+		{
+			"name": "deny_suspended",
+			"source": {"principals": ["suspended-*"]}
+		}
+	],
+	"allow_rules": [
+		{
+			"name": "allow_service",
+			"source": {"principals": ["testClient"]},
+			"request": {"paths": ["/server.ServiceName/*"]}
+		},
+		{
+			"name": "allow_canary",
+			"request": {
+				"paths": ["/server.Canary/*"],
+				"headers": [{"key": "x-canary", "values": ["*-enabled"]}]
+			}
+		}
+	]
+}`
+
+func TestGRPCAuthzPolicy(t *testing.T) {
+	policy, err := ParseGRPCAuthzPolicy([]byte(testGRPCAuthzPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canary := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-canary", "canary-enabled"))
+	tests := []struct {
+		ctx              context.Context
+		clientIdentifier string
+		methodName       string
+		expected         bool
+	}{
+		{context.Background(), testClientName, targetMethodName, true},
+		{context.Background(), "otherClient", targetMethodName, false},
+		{context.Background(), testClientName, "/server.OtherService/MethodName", false},
+		{canary, "otherClient", "/server.Canary/Check", true},
+		{context.Background(), "otherClient", "/server.Canary/Check", false},
+		{canary, "suspended-client", "/server.Canary/Check", false},
+	}
+
+	for _, test := range tests {
+		authResult := &AuthResult{ClientIdentifier: test.clientIdentifier}
+		if policy.AuthorizationFunc(test.ctx, authResult, test.methodName) != test.expected {
+			t.Fatalf("expected %s calling %s to be allowed: %v", test.clientIdentifier, test.methodName, test.expected)
+		}
+	}
+
+	_, err = ParseGRPCAuthzPolicy([]byte(`{"name": "authz", "allow_rules": [{"name": "r", "request": {"headers": [{"key": ":path", "values": ["*"]}]}}]}`))
+	if err == nil {
+		t.Fatalf("expected pseudo headers to be rejected")
+	}
+}
@@ -0,0 +1,34 @@
+package grpcauth
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// hideMethod returns the error gRPC would send if methodName didn't exist when err rejects a call to one of the
+// Authority's hidden methods, so clients that can't call it can't tell it is there.
+// Other errors, like being rate limited, are returned unchanged since they don't depend on the method.
+func (a *authority) hideMethod(err error, methodName string) error {
+	if len(a.HiddenMethods) == 0 || !WildcardPermissions(a.HiddenMethods, methodName) {
+		return err
+	}
+
+	code := status.Code(err)
+	if code != codes.Unauthenticated && code != codes.PermissionDenied {
+		return err
+	}
+
+	if a.HiddenMethodCode == codes.NotFound {
+		return status.Error(codes.NotFound, "not found")
+	}
+
+	// Match the error grpc-go returns for methods that aren't registered.
+	service, method := methodName, ""
+	if i := strings.LastIndex(methodName, "/"); i > 0 {
+		service, method = strings.TrimPrefix(methodName[:i], "/"), methodName[i+1:]
+	}
+
+	return status.Errorf(codes.Unimplemented, "unknown method %s for service %s", method, service)
+}
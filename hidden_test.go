@@ -0,0 +1,47 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestHiddenMethods(t *testing.T) {
+	md := metadata.Pairs("authorization", "bearer words")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	var logged error
+	logger := LoggerFunc(func(ctx context.Context, event *AuthEvent) {
+		logged = event.Err
+	})
+
+	a := NewAuthority(alwaysAuthenticatedNoPermissions, nil, WithHiddenMethods(codes.Unimplemented, "/server.ServiceName/*"), WithLogger(logger)).(*authority)
+	_, err := a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if err.Error() != "rpc error: code = Unimplemented desc = unknown method MethodName for service server.ServiceName" {
+		t.Fatalf("expected the method to look unregistered, got %v", err)
+	}
+
+	if status.Code(logged) != codes.PermissionDenied {
+		t.Fatalf("expected the logger to get the real error, got %v", logged)
+	}
+
+	_, err = a.authenticateAndAuthorizeContext(ctx, "/server.OtherService/MethodName")
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected other methods to be denied normally, got %v", err)
+	}
+
+	a = NewAuthority(alwaysUnauthenticated, nil, WithHiddenMethods(codes.NotFound, targetMethodName)).(*authority)
+	_, err = a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected unauthenticated clients to get NotFound, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected other codes to panic")
+		}
+	}()
+	WithHiddenMethods(codes.PermissionDenied, targetMethodName)
+}
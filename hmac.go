@@ -0,0 +1,204 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// hmacScheme is the authorization scheme for HMAC signed requests.
+	hmacScheme = "HMAC-SHA256"
+
+	// defaultHMACWindow is how far a signed request's timestamp can be from the server's clock by default.
+	defaultHMACWindow = 5 * time.Minute
+)
+
+// HMACKey is a shared secret a client signs requests with.
+type HMACKey struct {
+	Secret           []byte
+	ClientIdentifier string
+	Permissions      []string
+}
+
+// HMAC authenticates clients that sign each request with a shared secret, for machine clients that can't use OAuth2.
+// Clients sign the gRPC method name, a timestamp and a random nonce, and send the signature in the authorization
+// metadata field:
+//
+//	authorization: HMAC-SHA256 Credential=<key id>, Timestamp=<unix seconds>, Nonce=<nonce>, Signature=<hex>
+//
+// The server rejects requests outside the freshness window and nonces it has already seen within it.
+// HMAC needs the method name, so it must be used with NewContextAuthority. See HMACCredentials for the client side.
+type HMAC struct {
+	// Keys maps key IDs to the secrets clients sign requests with.
+	Keys map[string]*HMACKey
+	// Window is how far a request's timestamp may be from the server's clock.
+	// It defaults to 5 minutes.
+	Window time.Duration
+	// Replay remembers nonces. It defaults to an InMemoryReplayStore, so use a shared store such as
+	// RedisReplayStore to reject requests replayed to other servers.
+	Replay ReplayStore
+
+	replay InMemoryReplayStore
+}
+
+// AuthFunc satisfies the ContextAuthFunc interface so clients can sign requests with a shared secret.
+func (h *HMAC) AuthFunc(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+	method, ok := grpc.Method(ctx)
+	if !ok {
+		return nil, fmt.Errorf("no gRPC method in context")
+	}
+
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected signature in 'authorization' metadata field")
+	}
+
+	params, err := parseHMACAuthorization(values[0])
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := h.Keys[params["Credential"]]
+	if !ok {
+		return nil, fmt.Errorf("unknown key: %s", params["Credential"])
+	}
+
+	signature, err := hex.DecodeString(params["Signature"])
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, nonce := params["Timestamp"], params["Nonce"]
+	expected := signHMAC(key.Secret, method, timestamp, nonce)
+	if !hmac.Equal(signature, expected) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	window := h.window()
+	signedAt := time.Unix(seconds, 0)
+	now := time.Now()
+	if signedAt.Before(now.Add(-window)) || signedAt.After(now.Add(window)) {
+		return nil, fmt.Errorf("request timestamp %v is outside the %v window", signedAt, window)
+	}
+
+	// Nonces only need to be remembered while their signature is fresh.
+	fresh, err := h.replayStore().Use(ctx, params["Credential"]+":"+nonce, signedAt.Add(window))
+	if err != nil {
+		return nil, fmt.Errorf("checking nonce: %v", err)
+	}
+
+	if !fresh {
+		return nil, fmt.Errorf("nonce has already been used")
+	}
+
+	return &AuthResult{
+		ClientIdentifier: key.ClientIdentifier,
+		Timestamp:        now,
+		Permissions:      key.Permissions,
+	}, nil
+}
+
+func (h *HMAC) window() time.Duration {
+	if h.Window == 0 {
+		return defaultHMACWindow
+	}
+	return h.Window
+}
+
+// replayStore returns the HMAC's ReplayStore, or its own InMemoryReplayStore if it doesn't have one.
+func (h *HMAC) replayStore() ReplayStore {
+	if h.Replay != nil {
+		return h.Replay
+	}
+	return &h.replay
+}
+
+// parseHMACAuthorization parses the comma separated key=value parameters of an HMAC-SHA256 authorization field.
+func parseHMACAuthorization(value string) (map[string]string, error) {
+	return parseSignatureParams(value, hmacScheme, "Credential", "Timestamp", "Nonce", "Signature")
+}
+
+// parseSignatureParams parses the comma separated key=value parameters of a signed request's authorization field,
+// such as `<scheme> Credential=<key id>, Timestamp=<unix seconds>, Signature=<signature>`.
+func parseSignatureParams(value, scheme string, required ...string) (map[string]string, error) {
+	if !strings.HasPrefix(value, scheme+" ") {
+		return nil, fmt.Errorf("expected %s authorization scheme", scheme)
+	}
+
+	params := map[string]string{}
+	for _, param := range strings.Split(strings.TrimPrefix(value, scheme+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid %s parameter: %s", scheme, param)
+		}
+		params[kv[0]] = kv[1]
+	}
+
+	for _, r := range required {
+		if params[r] == "" {
+			return nil, fmt.Errorf("missing %s parameter: %s", scheme, r)
+		}
+	}
+
+	return params, nil
+}
+
+// signHMAC signs the method, timestamp and nonce with secret.
+func signHMAC(secret []byte, method, timestamp, nonce string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(method + "\n" + timestamp + "\n" + nonce))
+	return mac.Sum(nil)
+}
+
+// HMACCredentials returns credentials.PerRPCCredentials that sign every request with a shared secret for servers using HMAC.
+// Use it with grpc.WithPerRPCCredentials.
+func HMACCredentials(keyID string, secret []byte) credentials.PerRPCCredentials {
+	return &hmacCredentials{keyID: keyID, secret: secret}
+}
+
+type hmacCredentials struct {
+	keyID  string
+	secret []byte
+}
+
+// GetRequestMetadata signs the method being called.
+func (h *hmacCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	info, ok := credentials.RequestInfoFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("no request info in context")
+	}
+
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := hex.EncodeToString(b)
+	signature := hex.EncodeToString(signHMAC(h.secret, info.Method, timestamp, nonce))
+	return map[string]string{
+		"authorization": fmt.Sprintf("%s Credential=%s, Timestamp=%s, Nonce=%s, Signature=%s", hmacScheme, h.keyID, timestamp, nonce, signature),
+	}, nil
+}
+
+// RequireTransportSecurity is true so signed requests can't be captured and raced to the server within the freshness window.
+func (h *hmacCredentials) RequireTransportSecurity() bool {
+	return true
+}
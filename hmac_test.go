@@ -0,0 +1,74 @@
+package grpcauth
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const testHMACKeyID = "webhook"
+
+var testHMACSecret = []byte("s3cr3t")
+
+// testServerTransportStream lets tests put a method name in a context the way the gRPC server does.
+type testServerTransportStream struct {
+	method  string
+	trailer metadata.MD
+}
+
+func (s *testServerTransportStream) Method() string                  { return s.method }
+func (s *testServerTransportStream) SetHeader(md metadata.MD) error  { return nil }
+func (s *testServerTransportStream) SendHeader(md metadata.MD) error { return nil }
+func (s *testServerTransportStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+func methodContext(method string) context.Context {
+	return grpc.NewContextWithServerTransportStream(context.Background(), &testServerTransportStream{method: method})
+}
+
+func hmacMetadata(method string, signedAt time.Time, nonce string) metadata.MD {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	signature := hex.EncodeToString(signHMAC(testHMACSecret, method, timestamp, nonce))
+	value := fmt.Sprintf("HMAC-SHA256 Credential=%s, Timestamp=%s, Nonce=%s, Signature=%s", testHMACKeyID, timestamp, nonce, signature)
+	return metadata.Pairs("authorization", value)
+}
+
+func TestHMACVerifiesSignedRequests(t *testing.T) {
+	h := &HMAC{
+		Keys: map[string]*HMACKey{
+			testHMACKeyID: {Secret: testHMACSecret, ClientIdentifier: testClientName, Permissions: []string{targetMethodName}},
+		},
+	}
+	ctx := methodContext(targetMethodName)
+
+	authResult, err := h.AuthFunc(ctx, hmacMetadata(targetMethodName, time.Now(), "nonce-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected %v, got %v", testClientName, authResult.ClientIdentifier)
+	}
+
+	tests := map[string]metadata.MD{
+		"replayed nonce":   hmacMetadata(targetMethodName, time.Now(), "nonce-1"),
+		"stale timestamp":  hmacMetadata(targetMethodName, time.Now().Add(-time.Hour), "nonce-2"),
+		"different method": hmacMetadata("/server.ServiceName/OtherMethod", time.Now(), "nonce-3"),
+		"bearer token":     metadata.Pairs("authorization", "Bearer words"),
+	}
+
+	for name, md := range tests {
+		_, err := h.AuthFunc(ctx, md)
+		if err == nil {
+			t.Fatalf("expected error with %s", name)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package grpcauth
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// HTTPMethodFunc maps an HTTP request to the full gRPC method name it is authorized as, like
+// `/server.ServiceName/GetThing`. It returns an empty string for requests that don't map to a method.
+type HTTPMethodFunc func(r *http.Request) string
+
+// RouteMethods returns an HTTPMethodFunc that maps routes like `GET /v1/things` to gRPC method names.
+// Routes ending in `*` match any path starting with the rest of the route, like `GET /v1/things/*`.
+func RouteMethods(routes map[string]string) HTTPMethodFunc {
+	return func(r *http.Request) string {
+		route := r.Method + " " + r.URL.Path
+		if methodName, ok := routes[route]; ok {
+			return methodName
+		}
+
+		// Prefer the longest matching pattern so specific routes win over broad ones.
+		var methodName string
+		longest := -1
+		for pattern, m := range routes {
+			if MatchMethod(pattern, route) && len(pattern) > longest {
+				methodName, longest = m, len(pattern)
+			}
+		}
+
+		return methodName
+	}
+}
+
+// HTTPMiddleware returns net/http middleware that authenticates and authorizes requests with authority, so REST
+// gateways in front of a gRPC server enforce the same policy as it does.
+// Request headers are passed to the Authority as metadata and the request's RemoteAddr as the peer, and methodFunc
+// decides which gRPC method each request is authorized as. Requests that don't map to a method are rejected with
+// 403 Forbidden.
+// Handlers can get the client's AuthResult from the request's context with GetAuthResult.
+// RequestAuthorizationFuncs need a gRPC request message, so they aren't run.
+func HTTPMiddleware(authority Authority, methodFunc HTTPMethodFunc) func(http.Handler) http.Handler {
+	challenge := challengeFor(authority)
+	return httpMiddleware(authority, methodFunc, func(w http.ResponseWriter, st *status.Status) {
+		setRetryAfter(w, st)
+		if st.Code() == codes.Unauthenticated && challenge != nil {
+			for _, value := range challenge.Values() {
+				w.Header().Add("WWW-Authenticate", value)
+			}
+		}
+		http.Error(w, st.Message(), httpStatusFromCode(st.Code()))
+	})
+}
+
+// httpMiddleware authorizes requests like HTTPMiddleware, rejecting them with writeError.
+func httpMiddleware(authority Authority, methodFunc HTTPMethodFunc, writeError func(w http.ResponseWriter, st *status.Status)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			methodName := methodFunc(r)
+			if methodName == "" {
+				writeError(w, status.New(codes.PermissionDenied, "no gRPC method for route"))
+				return
+			}
+
+			// The client's address is what peer based checks, like BruteForceProtection and Denylists, apply to.
+			ctx := peer.NewContext(r.Context(), httpPeer(r.RemoteAddr))
+			ctx = metadata.NewIncomingContext(ctx, metadataFromHeader(r.Header))
+			ctx, err := authenticateWith(authority, ctx, methodName)
+			if err != nil {
+				writeError(w, status.Convert(err))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authenticateWith returns a context with the client's AuthResult if authority lets it call methodName.
+// Authorities other than grpcauth's own are run as an interceptor to capture the context they pass to the handler.
+func authenticateWith(a Authority, ctx context.Context, methodName string) (context.Context, error) {
+	if internal, ok := a.(*authority); ok {
+		authCtx, err := internal.authenticateMethod(ctx, methodName)
+		if err != nil || internal.isUnauthenticatedMethod(methodName) {
+			return authCtx, err
+		}
+
+		err = internal.useQuota(authCtx, methodName)
+		if err != nil && !internal.shadowDenied(authCtx, methodName, err) {
+			return nil, err
+		}
+
+		return authCtx, nil
+	}
+
+	var authCtx context.Context
+	_, err := a.UnaryServerInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: methodName}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		authCtx = ctx
+		return nil, nil
+	})
+	return authCtx, err
+}
+
+// challengeFor returns the Challenge of one of grpcauth's Authorities, or nil.
+func challengeFor(a Authority) *Challenge {
+	if internal, ok := a.(*authority); ok {
+		return internal.Challenge
+	}
+	return nil
+}
+
+// httpPeer returns the gRPC peer for an HTTP client with remoteAddr, without an address if it isn't an IP address and
+// port.
+func httpPeer(remoteAddr string) *peer.Peer {
+	addrPort, err := netip.ParseAddrPort(remoteAddr)
+	if err != nil {
+		return &peer.Peer{}
+	}
+
+	return &peer.Peer{Addr: net.TCPAddrFromAddrPort(addrPort)}
+}
+
+// metadataFromHeader converts HTTP headers to gRPC metadata, which has lowercase keys.
+func metadataFromHeader(header http.Header) metadata.MD {
+	md := make(metadata.MD, len(header))
+	for key, values := range header {
+		key = strings.ToLower(key)
+		md[key] = append(md[key], values...)
+	}
+
+	return md
+}
+
+// setRetryAfter tells the client when to retry a request st rejected, if it says.
+func setRetryAfter(w http.ResponseWriter, st *status.Status) {
+	if delay, ok := RetryDelay(st.Err()); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	}
+}
+
+// httpStatusFromCode converts the gRPC status codes an Authority returns to HTTP status codes.
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.NotFound, codes.Unimplemented:
+		// Hidden methods look like routes that don't exist.
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
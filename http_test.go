@@ -0,0 +1,154 @@
+package grpcauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	authority := NewAuthority(func(md metadata.MD) (*AuthResult, error) {
+		if len(md.Get("authorization")) != 1 || md.Get("authorization")[0] != "Bearer words" {
+			return nil, ErrCredentialNotFound
+		}
+
+		return testPermissionedAuthResult, nil
+	}, nil)
+	methodFunc := RouteMethods(map[string]string{
+		"GET /v1/things/*":     targetMethodName,
+		"DELETE /v1/things/*":  "/server.ServiceName/DeleteThing",
+		"GET /v1/things/a/b/*": "/server.ServiceName/Nested",
+	})
+	handler := HTTPMiddleware(authority, methodFunc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := GetAuthResult(r.Context())
+		if err != nil {
+			t.Fatalf("expected request to be authenticated: %v", err)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method        string
+		path          string
+		authorization string
+		expected      int
+	}{
+		{http.MethodGet, "/v1/things/1", "Bearer words", http.StatusNoContent},
+		{http.MethodGet, "/v1/things/1", "", http.StatusUnauthorized},
+		{http.MethodDelete, "/v1/things/1", "Bearer words", http.StatusForbidden},
+		{http.MethodGet, "/v1/things/a/b/c", "Bearer words", http.StatusForbidden},
+		{http.MethodPost, "/v1/things", "Bearer words", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if test.authorization != "" {
+			r.Header.Set("Authorization", test.authorization)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != test.expected {
+			t.Fatalf("expected %s %s to return %d, got %d: %s", test.method, test.path, test.expected, w.Code, w.Body)
+		}
+	}
+}
+
+func TestHTTPMiddlewareRateLimited(t *testing.T) {
+	limiter := &RateLimiter{Limit: RateLimit{Rate: 0.5, Burst: 1}}
+	authority := NewAuthority(alwaysAuthenticatedAllPermissions, nil, WithRateLimiter(limiter))
+	methodFunc := RouteMethods(map[string]string{"GET /v1/things": targetMethodName})
+	handler := HTTPMiddleware(authority, methodFunc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, expected := range []int{http.StatusNoContent, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodGet, "/v1/things", nil)
+		r.Header.Set("Authorization", "Bearer words")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Fatalf("expected %d, got %d", expected, w.Code)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/things", nil)
+	r.Header.Set("Authorization", "Bearer words")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get("Retry-After") != "2" {
+		t.Fatalf("expected a Retry-After header, got %q", w.Header().Get("Retry-After"))
+	}
+}
+
+func TestHTTPMiddlewareHiddenMethods(t *testing.T) {
+	methodFunc := RouteMethods(map[string]string{"GET /v1/things": targetMethodName})
+	for _, code := range []codes.Code{codes.Unimplemented, codes.NotFound} {
+		authority := NewAuthority(alwaysAuthenticatedNoPermissions, nil, WithHiddenMethods(code, targetMethodName))
+		handler := HTTPMiddleware(authority, methodFunc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/v1/things", nil)
+		r.Header.Set("Authorization", "Bearer words")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected methods hidden with %v to return %d, got %d", code, http.StatusNotFound, w.Code)
+		}
+	}
+}
+
+func TestHTTPMiddlewareUsesRemoteAddr(t *testing.T) {
+	methodFunc := RouteMethods(map[string]string{"GET /v1/things": targetMethodName})
+	request := func(handler http.Handler, remoteAddr, authorization string) int {
+		r := httptest.NewRequest(http.MethodGet, "/v1/things", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		if md.Get("authorization")[0] != "Bearer words" {
+			return nil, ErrCredentialNotFound
+		}
+		return testPermissionedAuthResult, nil
+	}
+
+	tests := []struct {
+		name    string
+		options []Option
+		// fail is how many failed requests the first address makes first.
+		fail int
+	}{
+		{"ClientNetworks", []Option{WithClientNetworks((&Policy{Networks: map[string][]string{testClientName: {"192.0.2.2/32"}}}).ClientNetworks)}, 0},
+		{"Denylist", []Option{WithDenylist(&Denylist{MaxFailures: 2})}, 2},
+		{"BruteForce", []Option{WithBruteForceProtection(&BruteForceProtection{LockoutThreshold: 2})}, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := HTTPMiddleware(NewAuthority(authFunc, nil, test.options...), methodFunc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			for i := 0; i < test.fail; i++ {
+				request(handler, "192.0.2.1:1234", "Bearer bad")
+			}
+
+			if code := request(handler, "192.0.2.1:1234", "Bearer words"); code == http.StatusNoContent {
+				t.Fatalf("expected 192.0.2.1 to be rejected")
+			}
+
+			if code := request(handler, "192.0.2.2:1234", "Bearer words"); code != http.StatusNoContent {
+				t.Fatalf("expected 192.0.2.2 to be allowed, got %d", code)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package grpcauth
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// IAPMetadataKey is the metadata field Identity-Aware Proxy puts its signed assertion in.
+	// Pass it to WithMetadataKey when using IAP with an Authority.
+	IAPMetadataKey = "x-goog-iap-jwt-assertion"
+
+	// iapIssuer is the iss claim IAP puts in its assertions.
+	iapIssuer = "https://cloud.google.com/iap"
+
+	// iapKeysURL is where IAP publishes the ES256 keys it signs assertions with.
+	iapKeysURL = "https://www.gstatic.com/iap/verify/public_key-jwk"
+)
+
+// IAP authenticates requests from behind GCP's Identity-Aware Proxy by verifying the signed header IAP adds.
+// The authenticated user or service account's email becomes AuthResult.ClientIdentifier.
+// IAP asserts identity in the x-goog-iap-jwt-assertion header instead of authorization, so use it with
+// WithMetadataKey(IAPMetadataKey).
+// See https://cloud.google.com/iap/docs/signed-headers-howto for more details.
+type IAP struct {
+	// Audience identifies the protected backend.
+	// It is /projects/PROJECT_NUMBER/global/backendServices/SERVICE_ID for backend services and
+	// /projects/PROJECT_NUMBER/apps/PROJECT_ID for App Engine.
+	Audience string
+	// Permissions maps the email of each identity allowed to call the server to its permissions.
+	// IAP does its own authorization, so when it is nil every identity IAP lets through is accepted with no permissions.
+	Permissions map[string][]string
+	// JWKSURL overrides where IAP's signing keys are fetched from.
+	// It defaults to https://www.gstatic.com/iap/verify/public_key-jwk.
+	JWKSURL *url.URL
+
+	JWTValidation
+}
+
+// AuthFunc satisfies the AuthFunc interface so services behind IAP can trust the asserted identity.
+func (i *IAP) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	assertions := md.Get(IAPMetadataKey)
+	if len(assertions) != 1 {
+		return nil, fmt.Errorf("expected JWT in '%s' metadata field", IAPMetadataKey)
+	}
+
+	jwksURL := i.JWKSURL
+	if jwksURL == nil {
+		jwksURL, _ = url.Parse(iapKeysURL)
+	}
+
+	// IAP only signs assertions with ES256.
+	unverified, _, err := new(jwt.Parser).ParseUnverified(assertions[0], jwt.MapClaims{})
+	if err != nil {
+		return nil, err
+	}
+
+	if unverified.Method != jwt.SigningMethodES256 {
+		return nil, fmt.Errorf("unexpected signing method: expected ES256, got %v", unverified.Header["alg"])
+	}
+
+	claims, err := verifyJWT(assertions[0], jwksURL, i.Audience, i.JWTValidation)
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyIssuer(iapIssuer, true) {
+		return nil, fmt.Errorf("invalid issuer, expected %s, got %v", iapIssuer, claims["iss"])
+	}
+
+	email, _ := claims["email"].(string)
+	if email == "" {
+		return nil, fmt.Errorf("IAP assertion has no email claim")
+	}
+
+	var permissions []string
+	if i.Permissions != nil {
+		var ok bool
+		permissions, ok = i.Permissions[email]
+		if !ok {
+			return nil, fmt.Errorf("%s is not allowed", email)
+		}
+	}
+
+	return &AuthResult{
+		ClientIdentifier: email,
+		Timestamp:        time.Now(),
+		Permissions:      permissions,
+		Claims:           claims,
+		Expiry:           i.tokenExpiry(claims),
+	}, nil
+}
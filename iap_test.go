@@ -0,0 +1,72 @@
+package grpcauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+const testIAPAudience = "/projects/123456789/global/backendServices/987654321"
+
+func TestIAPVerifiesAssertion(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&jsonWebKeySet{
+			Keys: []jsonWebKey{
+				{
+					Kty: "EC",
+					Kid: testKeyID,
+					Alg: "ES256",
+					Crv: "P-256",
+					X:   base64.RawURLEncoding.EncodeToString(key.X.Bytes()),
+					Y:   base64.RawURLEncoding.EncodeToString(key.Y.Bytes()),
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	jwksURL, _ := url.Parse(server.URL)
+	iap := &IAP{Audience: testIAPAudience, JWKSURL: jwksURL}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss":   iapIssuer,
+		"aud":   testIAPAudience,
+		"sub":   "accounts.google.com:1234",
+		"email": "user@example.com",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	})
+	token.Header["kid"] = testKeyID
+	assertion, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authResult, err := iap.AuthFunc(metadata.Pairs(IAPMetadataKey, assertion))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != "user@example.com" {
+		t.Fatalf("expected user@example.com, got %v", authResult.ClientIdentifier)
+	}
+
+	_, err = iap.AuthFunc(metadata.Pairs("authorization", assertion))
+	if err == nil {
+		t.Fatalf("expected error without IAP assertion")
+	}
+}
@@ -0,0 +1,239 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// introspectionResponse is the response from an OAuth2 token introspection endpoint.
+// See https://datatracker.ietf.org/doc/html/rfc7662#section-2.2
+type introspectionResponse struct {
+	Active   bool        `json:"active"`
+	Scope    string      `json:"scope"`
+	ClientID string      `json:"client_id"`
+	Username string      `json:"username"`
+	Sub      string      `json:"sub"`
+	Exp      int64       `json:"exp"`
+	Aud      interface{} `json:"aud"`
+	Iss      string      `json:"iss"`
+
+	// claims is every member of the response, including ones that aren't standard.
+	claims map[string]interface{}
+}
+
+// introspectionCacheEntry is a cached active introspection response.
+type introspectionCacheEntry struct {
+	authResult *AuthResult
+	expiry     time.Time
+}
+
+// TokenIntrospection authenticates clients presenting opaque access tokens by asking the authorization server's
+// OAuth2 token introspection endpoint whether they are active, as described in RFC 7662.
+// The token's sub becomes AuthResult.ClientIdentifier, falling back to its client_id for client credentials tokens,
+// and its scopes become AuthResult.Permissions.
+// Active responses are cached by a hash of the token, so the endpoint isn't called on every request.
+// See https://datatracker.ietf.org/doc/html/rfc7662 for more details.
+type TokenIntrospection struct {
+	// URL is the authorization server's introspection endpoint.
+	URL *url.URL
+	// ClientID and ClientSecret authenticate the server to the introspection endpoint with HTTP Basic authentication.
+	ClientID     string
+	ClientSecret string
+	// Authenticate adds client authentication to introspection requests instead of ClientID and ClientSecret,
+	// for endpoints that expect a bearer token or other scheme.
+	Authenticate func(req *http.Request) error
+	// Audience, if set, must be in the token's aud.
+	Audience string
+	// CacheTTL is the longest an active response is cached for. Responses are never cached past the token's expiry.
+	// Caching is disabled when it is 0.
+	CacheTTL time.Duration
+	// Leeway is how long after its exp a token is still accepted, to allow for clock skew.
+	Leeway time.Duration
+	// Client makes introspection requests. It defaults to http.DefaultClient.
+	Client *http.Client
+
+	mu        sync.Mutex
+	cache     map[string]*introspectionCacheEntry
+	lastSweep time.Time
+}
+
+// AuthFunc satisfies the AuthFunc interface so clients can use opaque access tokens with a gRPC server.
+func (t *TokenIntrospection) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	return t.ContextAuthFunc(context.Background(), md)
+}
+
+// ContextAuthFunc satisfies the ContextAuthFunc interface like AuthFunc, but makes introspection requests with ctx,
+// so they are cancelled with the gRPC request and can be traced with TraceContextTransport.
+func (t *TokenIntrospection) ContextAuthFunc(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+	tokenString, err := bearerToken(md)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256([]byte(tokenString))
+	cacheKey := hex.EncodeToString(hash[:])
+	if authResult, ok := t.cached(cacheKey); ok {
+		return authResult, nil
+	}
+
+	introspection, err := t.introspect(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if !introspection.Active {
+		return nil, fmt.Errorf("token is not active")
+	}
+
+	// RFC 7662 leaves checking expiry to the authorization server, but check it anyway in case it's lenient.
+	now := time.Now()
+	var expiry time.Time
+	if introspection.Exp != 0 {
+		expiry = time.Unix(introspection.Exp, 0)
+		if !now.Before(expiry.Add(t.Leeway)) {
+			return nil, fmt.Errorf("token expired at %v", expiry)
+		}
+	}
+
+	if t.Audience != "" && !verifyAudience(map[string]interface{}{"aud": introspection.Aud}, t.Audience) {
+		return nil, fmt.Errorf("invalid audience, expected %s, got %v", t.Audience, introspection.Aud)
+	}
+
+	clientIdentifier := introspection.Sub
+	if clientIdentifier == "" {
+		clientIdentifier = introspection.ClientID
+	}
+
+	if clientIdentifier == "" {
+		return nil, fmt.Errorf("introspection response has no sub or client_id")
+	}
+
+	authResult := &AuthResult{
+		ClientIdentifier: clientIdentifier,
+		Timestamp:        now,
+		Permissions:      strings.Fields(introspection.Scope),
+		Claims:           introspection.claims,
+	}
+
+	// Tokens are accepted for Leeway after their exp, so the Authority has to accept them for as long.
+	if !expiry.IsZero() {
+		authResult.Expiry = expiry.Add(t.Leeway)
+	}
+
+	if t.CacheTTL > 0 {
+		cacheExpiry := now.Add(t.CacheTTL)
+		if !expiry.IsZero() && expiry.Before(cacheExpiry) {
+			cacheExpiry = expiry
+		}
+		t.store(cacheKey, &introspectionCacheEntry{authResult: authResult.clone(), expiry: cacheExpiry}, now)
+	}
+
+	return authResult, nil
+}
+
+// introspect asks the introspection endpoint about tokenString.
+func (t *TokenIntrospection) introspect(ctx context.Context, tokenString string) (*introspectionResponse, error) {
+	form := url.Values{}
+	form.Set("token", tokenString)
+	form.Set("token_type_hint", "access_token")
+	req, err := http.NewRequest(http.MethodPost, t.URL.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if t.Authenticate != nil {
+		err = t.Authenticate(req)
+		if err != nil {
+			return nil, err
+		}
+	} else if t.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(t.ClientID), url.QueryEscape(t.ClientSecret))
+	}
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthenticationUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, providerError(resp.StatusCode, string(b))
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var introspection introspectionResponse
+	err = json.Unmarshal(b, &introspection)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, &introspection.claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &introspection, nil
+}
+
+func (t *TokenIntrospection) cached(key string) (*AuthResult, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.cache[key]
+	if !ok || !time.Now().Before(entry.expiry) {
+		return nil, false
+	}
+
+	authResult := entry.authResult.clone()
+	authResult.Timestamp = time.Now()
+	return authResult, true
+}
+
+func (t *TokenIntrospection) store(key string, entry *introspectionCacheEntry, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sweep(now)
+	t.cache[key] = entry
+}
+
+// sweep forgets cached responses that have expired, at most once per CacheTTL.
+// It must be called with t.mu held.
+func (t *TokenIntrospection) sweep(now time.Time) {
+	if t.cache == nil {
+		t.cache = map[string]*introspectionCacheEntry{}
+	}
+
+	if now.Sub(t.lastSweep) < t.CacheTTL {
+		return
+	}
+
+	t.lastSweep = now
+	for key, entry := range t.cache {
+		if !now.Before(entry.expiry) {
+			delete(t.cache, key)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package grpcauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokenIntrospectionCachesActiveTokens(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != "resource-server" || clientSecret != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		response := &introspectionResponse{Active: false}
+		if r.FormValue("token") == "active-token" {
+			response = &introspectionResponse{
+				Active:   true,
+				ClientID: testClientName,
+				Scope:    targetMethodName,
+				Exp:      time.Now().Add(time.Hour).Unix(),
+			}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	introspectionURL, _ := url.Parse(server.URL)
+	introspection := &TokenIntrospection{
+		URL:          introspectionURL,
+		ClientID:     "resource-server",
+		ClientSecret: "s3cr3t",
+		CacheTTL:     time.Minute,
+	}
+
+	for i := 0; i < 2; i++ {
+		authResult, err := introspection.AuthFunc(bearerMetadata("active-token"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if authResult.ClientIdentifier != testClientName {
+			t.Fatalf("expected %v, got %v", testClientName, authResult.ClientIdentifier)
+		}
+
+		if !reflect.DeepEqual(authResult.Permissions, []string{targetMethodName}) {
+			t.Fatalf("expected %v, got %v", []string{targetMethodName}, authResult.Permissions)
+		}
+
+		if authResult.StringClaim("client_id") != testClientName {
+			t.Fatalf("expected client_id claim %v, got %v", testClientName, authResult.Claims["client_id"])
+		}
+
+		// Handlers changing their AuthResult mustn't change the cached response.
+		authResult.Permissions[0] = "/server.ServiceName/OtherMethod"
+		authResult.Claims["client_id"] = "other"
+	}
+
+	if requests != 1 {
+		t.Fatalf("expected 1 introspection request, got %d", requests)
+	}
+
+	_, err := introspection.AuthFunc(bearerMetadata("revoked-token"))
+	if err == nil {
+		t.Fatalf("expected error with inactive token")
+	}
+}
+
+func TestTokenIntrospectionSweepsOncePerCacheTTL(t *testing.T) {
+	introspection := &TokenIntrospection{CacheTTL: time.Minute}
+	now := time.Now()
+	introspection.store("active", &introspectionCacheEntry{authResult: &AuthResult{}, expiry: now.Add(time.Second)}, now)
+
+	introspection.store("other", &introspectionCacheEntry{authResult: &AuthResult{}, expiry: now.Add(time.Minute)}, now.Add(2*time.Second))
+	if _, ok := introspection.cache["active"]; !ok {
+		t.Fatalf("expected expired responses to be kept until the next sweep")
+	}
+
+	introspection.store("other", &introspectionCacheEntry{authResult: &AuthResult{}, expiry: now.Add(time.Minute)}, now.Add(time.Minute))
+	if _, ok := introspection.cache["active"]; ok {
+		t.Fatalf("expected expired responses to be forgotten once CacheTTL has passed")
+	}
+}
@@ -0,0 +1,192 @@
+package grpcauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// JWEKeyFunc returns the key to decrypt a JWE with, given its protected header, so keys can be chosen by kid.
+// It must return an *rsa.PrivateKey for the RSA-OAEP and RSA-OAEP-256 algorithms, or the content encryption key
+// as a []byte for dir.
+type JWEKeyFunc func(header map[string]interface{}) (interface{}, error)
+
+// isJWE reports whether tokenString is a JWE in compact serialization rather than a signed JWT.
+func isJWE(tokenString string) bool {
+	return strings.Count(tokenString, ".") == 4
+}
+
+// decryptJWE decrypts a JWE in compact serialization and returns its plaintext, which is the signed JWT for
+// nested tokens.
+// The RSA-OAEP, RSA-OAEP-256 and dir key management algorithms are supported with the A128GCM, A192GCM,
+// A256GCM, A128CBC-HS256, A192CBC-HS384 and A256CBC-HS512 content encryption algorithms.
+// See https://datatracker.ietf.org/doc/html/rfc7516
+func decryptJWE(compact string, keyFunc JWEKeyFunc) (string, error) {
+	parts := strings.Split(compact, ".")
+	if len(parts) != 5 {
+		return "", fmt.Errorf("JWE must have 5 parts, got %d", len(parts))
+	}
+
+	decoded := make([][]byte, 5)
+	for i, part := range parts {
+		b, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return "", err
+		}
+		decoded[i] = b
+	}
+	encryptedKey, iv, ciphertext, tag := decoded[1], decoded[2], decoded[3], decoded[4]
+
+	var header map[string]interface{}
+	err := json.Unmarshal(decoded[0], &header)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := header["zip"]; ok {
+		return "", fmt.Errorf("compressed JWEs are not supported")
+	}
+
+	key, err := keyFunc(header)
+	if err != nil {
+		return "", err
+	}
+
+	alg, _ := header["alg"].(string)
+	var cek []byte
+	switch alg {
+	case "RSA-OAEP", "RSA-OAEP-256":
+		privateKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("%s needs an *rsa.PrivateKey, got %T", alg, key)
+		}
+
+		var h hash.Hash = sha1.New()
+		if alg == "RSA-OAEP-256" {
+			h = sha256.New()
+		}
+
+		cek, err = rsa.DecryptOAEP(h, rand.Reader, privateKey, encryptedKey, nil)
+		if err != nil {
+			return "", err
+		}
+
+	case "dir":
+		var ok bool
+		cek, ok = key.([]byte)
+		if !ok {
+			return "", fmt.Errorf("dir needs a []byte key, got %T", key)
+		}
+
+		if len(encryptedKey) != 0 {
+			return "", fmt.Errorf("dir JWE must not have an encrypted key")
+		}
+
+	default:
+		return "", fmt.Errorf("unsupported JWE algorithm: %v", header["alg"])
+	}
+
+	// The additional authenticated data is the encoded protected header.
+	aad := []byte(parts[0])
+	enc, _ := header["enc"].(string)
+	plaintext, err := decryptJWEContent(enc, cek, iv, ciphertext, tag, aad)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// decryptJWEContent decrypts and authenticates a JWE's ciphertext with the content encryption key.
+func decryptJWEContent(enc string, cek, iv, ciphertext, tag, aad []byte) ([]byte, error) {
+	switch enc {
+	case "A128GCM", "A192GCM", "A256GCM":
+		if len(cek)*8 != jweKeySize(enc) {
+			return nil, fmt.Errorf("%s needs a %d bit key", enc, jweKeySize(enc))
+		}
+
+		block, err := aes.NewCipher(cek)
+		if err != nil {
+			return nil, err
+		}
+
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(iv) != gcm.NonceSize() {
+			return nil, fmt.Errorf("invalid JWE initialization vector")
+		}
+
+		return gcm.Open(nil, iv, append(ciphertext, tag...), aad)
+
+	case "A128CBC-HS256", "A192CBC-HS384", "A256CBC-HS512":
+		// See https://datatracker.ietf.org/doc/html/rfc7518#section-5.2
+		if len(cek)*8 != jweKeySize(enc) {
+			return nil, fmt.Errorf("%s needs a %d bit key", enc, jweKeySize(enc))
+		}
+
+		macKey, encKey := cek[:len(cek)/2], cek[len(cek)/2:]
+		h := map[string]func() hash.Hash{"A128CBC-HS256": sha256.New, "A192CBC-HS384": sha512.New384, "A256CBC-HS512": sha512.New}[enc]
+		al := make([]byte, 8)
+		binary.BigEndian.PutUint64(al, uint64(len(aad))*8)
+
+		mac := hmac.New(h, macKey)
+		mac.Write(aad)
+		mac.Write(iv)
+		mac.Write(ciphertext)
+		mac.Write(al)
+		if !hmac.Equal(mac.Sum(nil)[:len(macKey)], tag) {
+			return nil, fmt.Errorf("invalid JWE authentication tag")
+		}
+
+		block, err := aes.NewCipher(encKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(iv) != block.BlockSize() || len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+			return nil, fmt.Errorf("invalid JWE ciphertext")
+		}
+
+		plaintext := make([]byte, len(ciphertext))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+		padding := int(plaintext[len(plaintext)-1])
+		if padding == 0 || padding > block.BlockSize() {
+			return nil, fmt.Errorf("invalid JWE padding")
+		}
+		return plaintext[:len(plaintext)-padding], nil
+	}
+
+	return nil, fmt.Errorf("unsupported JWE encryption: %s", enc)
+}
+
+// jweKeySize returns the content encryption key size in bits for enc.
+func jweKeySize(enc string) int {
+	switch enc {
+	case "A128GCM":
+		return 128
+	case "A192GCM":
+		return 192
+	case "A256GCM", "A128CBC-HS256":
+		return 256
+	case "A192CBC-HS384":
+		return 384
+	case "A256CBC-HS512":
+		return 512
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package grpcauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// encryptJWE wraps plaintext in a compact JWE using RSA-OAEP-256 with A256GCM, or dir with A128CBC-HS256 when
+// key is a []byte.
+func encryptJWE(t *testing.T, plaintext string, key interface{}) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	var header string
+	var cek, encryptedKey []byte
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		header = encode([]byte(`{"alg":"RSA-OAEP-256","enc":"A256GCM","cty":"JWT"}`))
+		cek = make([]byte, 32)
+		rand.Read(cek)
+
+		var err error
+		encryptedKey, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, k, cek, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		block, _ := aes.NewCipher(cek)
+		gcm, _ := cipher.NewGCM(block)
+		iv := make([]byte, gcm.NonceSize())
+		rand.Read(iv)
+		sealed := gcm.Seal(nil, iv, []byte(plaintext), []byte(header))
+		ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+		return strings.Join([]string{header, encode(encryptedKey), encode(iv), encode(ciphertext), encode(tag)}, ".")
+
+	case []byte:
+		header = encode([]byte(`{"alg":"dir","enc":"A128CBC-HS256","cty":"JWT"}`))
+		macKey, encKey := k[:16], k[16:]
+		block, _ := aes.NewCipher(encKey)
+		iv := make([]byte, block.BlockSize())
+		rand.Read(iv)
+
+		padding := block.BlockSize() - len(plaintext)%block.BlockSize()
+		padded := append([]byte(plaintext), []byte(strings.Repeat(string(rune(padding)), padding))...)
+		ciphertext := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+		al := make([]byte, 8)
+		binary.BigEndian.PutUint64(al, uint64(len(header))*8)
+		mac := hmac.New(sha256.New, macKey)
+		mac.Write([]byte(header))
+		mac.Write(iv)
+		mac.Write(ciphertext)
+		mac.Write(al)
+		return strings.Join([]string{header, "", encode(iv), encode(ciphertext), encode(mac.Sum(nil)[:16])}, ".")
+	}
+
+	t.Fatalf("unsupported key %T", key)
+	return ""
+}
+
+func TestJWTValidatorDecryptsJWE(t *testing.T) {
+	issuer := newTestIssuer(t)
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/jwks"
+
+	decryptionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sharedKey := make([]byte, 32)
+	rand.Read(sharedKey)
+
+	validator := NewJWTValidator(jwksURL, issuer.server.URL, testAudience)
+	encrypted := map[string]string{
+		"RSA-OAEP-256": encryptJWE(t, issuer.sign(t, issuer.claims()), &decryptionKey.PublicKey),
+		"dir":          encryptJWE(t, issuer.sign(t, issuer.claims()), sharedKey),
+	}
+
+	for alg, token := range encrypted {
+		_, err := validator.AuthFunc(bearerMetadata(token))
+		if err == nil {
+			t.Fatalf("expected error with %s JWE without a DecryptionKey", alg)
+		}
+	}
+
+	validator.DecryptionKey = func(header map[string]interface{}) (interface{}, error) {
+		if header["alg"] == "dir" {
+			return sharedKey, nil
+		}
+		return decryptionKey, nil
+	}
+
+	for alg, token := range encrypted {
+		authResult, err := validator.AuthFunc(bearerMetadata(token))
+		if err != nil {
+			t.Fatalf("expected %s JWE to be accepted: %v", alg, err)
+		}
+
+		if authResult.ClientIdentifier != testClientName {
+			t.Fatalf("expected %v, got %v", testClientName, authResult.ClientIdentifier)
+		}
+	}
+
+	tampered := []byte(encrypted["dir"])
+	tampered[len(tampered)-30] ^= 1
+	_, err = validator.AuthFunc(bearerMetadata(string(tampered)))
+	if err == nil {
+		t.Fatalf("expected error with tampered JWE")
+	}
+}
@@ -0,0 +1,446 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+// jsonWebKeySet is a JWK Set as described in RFC 7517.
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// jsonWebKey is a single RSA or EC public key from a JWK Set.
+type jsonWebKey struct {
+	Kty string   `json:"kty"`
+	Kid string   `json:"kid"`
+	Use string   `json:"use"`
+	Alg string   `json:"alg"`
+	N   string   `json:"n"`
+	E   string   `json:"e"`
+	Crv string   `json:"crv"`
+	X   string   `json:"x"`
+	Y   string   `json:"y"`
+	X5c []string `json:"x5c"`
+}
+
+// publicKey converts the JWK into a key that can be used to verify a JWT signature.
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		nb, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		eb, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(nb),
+			E: int(new(big.Int).SetBytes(eb).Int64()),
+		}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %v", k.Crv)
+		}
+
+		xb, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		yb, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(xb),
+			Y:     new(big.Int).SetBytes(yb),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type: %v", k.Kty)
+}
+
+const (
+	// defaultJWKSCacheTTL is how long a JWKSCache keeps keys before fetching them again by default.
+	defaultJWKSCacheTTL = time.Hour
+
+	// defaultJWKSMinRefreshInterval is how often a JWKSCache can fetch the JWKS for unknown key IDs by default.
+	defaultJWKSMinRefreshInterval = 30 * time.Second
+)
+
+// cachedJWK is a public key from a JWKS and when it stopped being published.
+type cachedJWK struct {
+	key       interface{}
+	removedAt time.Time
+}
+
+// JWKSCache fetches the JSON Web Key Set published at URL and keeps its public keys in memory, so tokens can be
+// verified locally without calling the identity provider on every request.
+// Tokens signed with a key ID that isn't cached make it fetch the JWKS again, so key rotations are picked up
+// immediately, but no more often than MinRefreshInterval so clients can't use made up key IDs to flood the
+// identity provider.
+// Keys are fetched when a request needs them unless Start refreshes them in the background.
+// It is safe for concurrent use.
+type JWKSCache struct {
+	URL *url.URL
+	// TTL is how long keys are used before the JWKS is fetched again.
+	// It defaults to 1 hour.
+	TTL time.Duration
+	// MinRefreshInterval is the shortest time between fetches for unknown key IDs.
+	// It defaults to 30 seconds.
+	MinRefreshInterval time.Duration
+	// GracePeriod is how long keys are still trusted after they are removed from the JWKS, so tokens signed just
+	// before a rotation stay valid. Removed keys are dropped immediately when it is 0.
+	GracePeriod time.Duration
+	// Client fetches the JWKS, for proxies, custom CAs and timeouts. It defaults to http.DefaultClient.
+	Client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]*cachedJWK
+	etag        string
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	// shared is set on the caches sharedJWKSCache returns, which JWTValidators can swap for one using their Client.
+	shared bool
+}
+
+// Start fetches the JWKS, then refreshes it in the background shortly before TTL runs out until ctx is cancelled, so
+// requests don't wait for it to be fetched.
+// Refreshes are jittered so servers started together don't fetch together, and use the JWKS's ETag so unchanged
+// keys aren't downloaded again. Failed refreshes are retried after MinRefreshInterval.
+// The first fetch's error is returned, but the cache keeps refreshing even if it fails.
+func (c *JWKSCache) Start(ctx context.Context) error {
+	err := c.refreshInBackground()
+	go c.refreshLoop(ctx)
+	return err
+}
+
+func (c *JWKSCache) refreshLoop(ctx context.Context) {
+	for {
+		timer := time.NewTimer(c.untilNextRefresh())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			c.refreshInBackground()
+		}
+	}
+}
+
+// untilNextRefresh returns how long the background refresh should wait, up to a tenth of TTL before the keys expire.
+func (c *JWKSCache) untilNextRefresh() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.fetchedAt.IsZero() || c.attemptedAt.After(c.fetchedAt) {
+		return c.minRefreshInterval()
+	}
+
+	ttl := c.ttl()
+	jitter := time.Duration(rand.Int63n(int64(ttl/10) + 1))
+	wait := ttl - jitter - time.Since(c.fetchedAt)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// refreshInBackground refreshes the cached keys without holding c.mu while the JWKS is fetched, so requests can
+// keep using the current keys.
+func (c *JWKSCache) refreshInBackground() error {
+	c.mu.Lock()
+	etag := c.etag
+	c.mu.Unlock()
+
+	now := time.Now()
+	jwks, etag, err := c.fetch(etag)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attemptedAt = now
+	if err != nil {
+		return err
+	}
+
+	c.update(jwks, etag, now)
+	return nil
+}
+
+// Key returns the public key identified by kid, fetching the JWKS if the cached keys have expired or kid is unknown.
+func (c *JWKSCache) Key(kid string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keys == nil || time.Since(c.fetchedAt) > c.ttl() {
+		err := c.refresh()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cached, ok := c.keys[kid]
+	if !ok && time.Since(c.attemptedAt) >= c.minRefreshInterval() {
+		err := c.refresh()
+		if err != nil {
+			return nil, err
+		}
+		cached, ok = c.keys[kid]
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("key not found: %v", kid)
+	}
+
+	return cached.key, nil
+}
+
+func (c *JWKSCache) ttl() time.Duration {
+	if c.TTL == 0 {
+		return defaultJWKSCacheTTL
+	}
+	return c.TTL
+}
+
+func (c *JWKSCache) minRefreshInterval() time.Duration {
+	if c.MinRefreshInterval == 0 {
+		return defaultJWKSMinRefreshInterval
+	}
+	return c.MinRefreshInterval
+}
+
+// refresh replaces the cached keys with the current JWKS, keeping removed keys for the grace period.
+// Callers must hold c.mu.
+func (c *JWKSCache) refresh() error {
+	now := time.Now()
+	c.attemptedAt = now
+	jwks, etag, err := c.fetch(c.etag)
+	if err != nil {
+		return err
+	}
+
+	c.update(jwks, etag, now)
+	return nil
+}
+
+// fetch downloads the JWKS, returning nil keys if it hasn't changed since the response with etag.
+func (c *JWKSCache) fetch(etag string) (*jsonWebKeySet, string, error) {
+	req, err := http.NewRequest(http.MethodGet, c.URL.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrAuthenticationUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, etag, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, "", providerError(resp.StatusCode, string(b))
+	}
+
+	var jwks jsonWebKeySet
+	err = json.NewDecoder(resp.Body).Decode(&jwks)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &jwks, resp.Header.Get("ETag"), nil
+}
+
+// update replaces the cached keys with jwks, or keeps them if jwks is nil because they haven't changed.
+// Callers must hold c.mu.
+func (c *JWKSCache) update(jwks *jsonWebKeySet, etag string, now time.Time) {
+	c.etag = etag
+	c.fetchedAt = now
+	if jwks == nil {
+		return
+	}
+
+	keys := make(map[string]*cachedJWK, len(jwks.Keys))
+	for i := range jwks.Keys {
+		key, err := jwks.Keys[i].publicKey()
+		if err != nil {
+			// Skip keys we can't use for verification, such as encryption keys, instead of failing every token.
+			continue
+		}
+		keys[jwks.Keys[i].Kid] = &cachedJWK{key: key}
+	}
+
+	for kid, cached := range c.keys {
+		if _, ok := keys[kid]; ok {
+			continue
+		}
+
+		if cached.removedAt.IsZero() {
+			cached.removedAt = now
+		}
+
+		if now.Sub(cached.removedAt) < c.GracePeriod {
+			keys[kid] = cached
+		}
+	}
+
+	c.keys = keys
+}
+
+// jwksCacheKey identifies a shared JWKSCache. Caches aren't shared between clients, since they may reach the
+// identity provider through different proxies or trust different CAs.
+type jwksCacheKey struct {
+	url    string
+	client *http.Client
+}
+
+var (
+	jwksCachesMu sync.Mutex
+	jwksCaches   = map[jwksCacheKey]*JWKSCache{}
+)
+
+// sharedJWKSCache returns the process wide JWKSCache for jwksURL fetched with client, so the built in authenticators
+// share cached keys across requests.
+func sharedJWKSCache(jwksURL *url.URL, client *http.Client) *JWKSCache {
+	jwksCachesMu.Lock()
+	defer jwksCachesMu.Unlock()
+
+	key := jwksCacheKey{url: jwksURL.String(), client: client}
+	cache, ok := jwksCaches[key]
+	if !ok {
+		cache = &JWKSCache{URL: jwksURL, Client: client, shared: true}
+		jwksCaches[key] = cache
+	}
+	return cache
+}
+
+// keyFromJWKS returns the public key in the JWK Set identified by the token's kid header.
+func keyFromJWKS(keys *JWKSCache, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	return keys.Key(kid)
+}
+
+// verifyAsymmetricSigningMethod rejects tokens that aren't signed with RSA or ECDSA.
+// This keeps attackers from passing off HMAC or unsigned tokens as valid.
+func verifyAsymmetricSigningMethod(token *jwt.Token) error {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		return nil
+	}
+
+	return fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+}
+
+// verifyAudience checks that the aud claim, which may be a string or a list of strings, contains audience.
+func verifyAudience(claims jwt.MapClaims, audience string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// verifyIssuer checks that the iss claim is one of issuers.
+func verifyIssuer(claims jwt.MapClaims, issuers []string) bool {
+	iss, _ := claims["iss"].(string)
+	for _, issuer := range issuers {
+		if iss == issuer {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scopesFromClaims returns the OAuth2 scopes from the space delimited scope claim or the scp list claim.
+func scopesFromClaims(claims jwt.MapClaims) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+
+	return stringsFromClaim(claims["scp"])
+}
+
+// stringsFromClaim converts a claim that can be a list of strings or a single string into a []string.
+func stringsFromClaim(claim interface{}) []string {
+	switch c := claim.(type) {
+	case string:
+		return strings.Fields(c)
+	case []interface{}:
+		values := make([]string, 0, len(c))
+		for _, v := range c {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
+
+// bearerToken extracts the token from the authorization metadata field, removing the Bearer prefix if present.
+func bearerToken(md metadata.MD) (string, error) {
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return "", fmt.Errorf("expected JWT in 'authorization' metadata field")
+	}
+
+	tokenString := values[0]
+	if len(tokenString) > 7 && strings.EqualFold(tokenString[:7], "bearer ") {
+		tokenString = tokenString[7:]
+	}
+
+	return tokenString, nil
+}
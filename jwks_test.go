@@ -0,0 +1,142 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rotatingJWKS serves a JWKS whose keys can be rotated by tests.
+type rotatingJWKS struct {
+	mu       sync.Mutex
+	keys     map[string]*rsa.PrivateKey
+	requests int
+}
+
+func (r *rotatingJWKS) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests++
+
+	var jwks jsonWebKeySet
+	for kid, key := range r.keys {
+		jwks.Keys = append(jwks.Keys, jsonWebKey{
+			Kty: "RSA",
+			Kid: kid,
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(&jwks)
+}
+
+func (r *rotatingJWKS) rotate(keys map[string]*rsa.PrivateKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = keys
+}
+
+func TestJWKSCacheHandlesKeyRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := &rotatingJWKS{keys: map[string]*rsa.PrivateKey{"old": oldKey}}
+	server := httptest.NewServer(jwks)
+	defer server.Close()
+
+	jwksURL, _ := url.Parse(server.URL)
+	cache := &JWKSCache{URL: jwksURL, MinRefreshInterval: time.Nanosecond, GracePeriod: time.Hour}
+	_, err = cache.Key("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks.rotate(map[string]*rsa.PrivateKey{"new": newKey})
+	_, err = cache.Key("new")
+	if err != nil {
+		t.Fatalf("expected unknown kid to refetch the JWKS: %v", err)
+	}
+
+	_, err = cache.Key("old")
+	if err != nil {
+		t.Fatalf("expected removed key to be kept for the grace period: %v", err)
+	}
+
+	cache.MinRefreshInterval = time.Hour
+	requests := jwks.requests
+	for i := 0; i < 3; i++ {
+		_, err = cache.Key("made-up")
+		if err == nil {
+			t.Fatalf("expected error with unknown kid")
+		}
+	}
+
+	if jwks.requests != requests {
+		t.Fatalf("expected unknown kids not to refetch within MinRefreshInterval, got %d requests", jwks.requests-requests)
+	}
+}
+
+func TestJWKSCacheRefreshesInBackground(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := &rotatingJWKS{keys: map[string]*rsa.PrivateKey{"kid": key}}
+	var mu sync.Mutex
+	notModified := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			mu.Lock()
+			notModified++
+			mu.Unlock()
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		jwks.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	jwksURL, _ := url.Parse(server.URL)
+	cache := &JWKSCache{URL: jwksURL, TTL: 20 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = cache.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	mu.Lock()
+	revalidated := notModified
+	mu.Unlock()
+	// Looking up a key can fetch the JWKS, whose handler takes mu.
+	if jwks.requests != 1 || revalidated == 0 {
+		t.Fatalf("expected unchanged keys to be revalidated with their ETag, got %d downloads and %d revalidations", jwks.requests, revalidated)
+	}
+
+	_, err = cache.Key("kid")
+	if err != nil {
+		t.Fatalf("expected revalidated keys to be kept: %v", err)
+	}
+}
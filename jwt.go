@@ -0,0 +1,263 @@
+package grpcauth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+// JWTValidation holds the strict validation options shared by every JWT based authenticator in grpcauth.
+// The options only add checks on top of the ones the authenticator already makes.
+type JWTValidation struct {
+	// Audiences must all be in the token's aud claim.
+	Audiences []string
+	// Issuers, if set, are the only iss claims accepted.
+	Issuers []string
+	// Algorithms, if set, are the only alg headers accepted, such as RS256 or ES256.
+	// Tokens signed with HMAC or none are rejected regardless, since JWKS keys are public.
+	Algorithms []string
+	// RequiredClaims must be present in the token with a non-empty value.
+	RequiredClaims []string
+	// Leeway is how far the exp, nbf and iat claims can be off to allow for clock skew between the
+	// identity provider and the server.
+	Leeway time.Duration
+	// DecryptionKey returns the key to decrypt JWE wrapped tokens with, for identity providers that encrypt tokens
+	// containing sensitive claims. Encrypted tokens are rejected when it is nil.
+	DecryptionKey JWEKeyFunc
+	// Revocation, if set, is checked after the token is validated and revoked tokens are rejected.
+	Revocation RevocationChecker
+	// Client fetches the JWKS, for proxies, custom CAs and timeouts. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// verifySigningMethod rejects tokens that aren't signed with an asymmetric algorithm in Algorithms.
+func (o *JWTValidation) verifySigningMethod(token *jwt.Token) error {
+	err := verifyAsymmetricSigningMethod(token)
+	if err != nil {
+		return err
+	}
+
+	if len(o.Algorithms) == 0 {
+		return nil
+	}
+
+	alg := token.Method.Alg()
+	for _, allowed := range o.Algorithms {
+		if alg == allowed {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unexpected signing method: expected one of %v, got %v", o.Algorithms, alg)
+}
+
+// verifyTimes checks the exp, nbf and iat claims, allowing for Leeway.
+func (o *JWTValidation) verifyTimes(claims jwt.MapClaims, now time.Time) error {
+	leeway := int64(o.Leeway / time.Second)
+	if !claims.VerifyExpiresAt(now.Unix()-leeway, false) {
+		return fmt.Errorf("token is expired")
+	}
+
+	if !claims.VerifyNotBefore(now.Unix()+leeway, false) {
+		return fmt.Errorf("token is not valid yet")
+	}
+
+	if !claims.VerifyIssuedAt(now.Unix()+leeway, false) {
+		return fmt.Errorf("token used before issued")
+	}
+
+	return nil
+}
+
+// tokenExpiry returns when a token with claims stops being accepted: its exp claim plus Leeway, or the zero time if
+// it doesn't have one.
+func (o *JWTValidation) tokenExpiry(claims map[string]interface{}) time.Time {
+	exp, ok := timeClaim(claims, "exp")
+	if !ok {
+		return time.Time{}
+	}
+
+	return exp.Add(o.Leeway.Truncate(time.Second))
+}
+
+// verifyClaims checks the token's claims against the required audiences, allowed issuers and required claims.
+func (o *JWTValidation) verifyClaims(claims jwt.MapClaims) error {
+	for _, audience := range o.Audiences {
+		if !verifyAudience(claims, audience) {
+			return fmt.Errorf("invalid audience, expected %s, got %v", audience, claims["aud"])
+		}
+	}
+
+	if len(o.Issuers) > 0 && !verifyIssuer(claims, o.Issuers) {
+		return fmt.Errorf("invalid issuer, expected one of %v, got %v", o.Issuers, claims["iss"])
+	}
+
+	for _, claim := range o.RequiredClaims {
+		value, ok := claims[claim]
+		if !ok || value == nil || value == "" {
+			return fmt.Errorf("token has no %s claim", claim)
+		}
+	}
+
+	return nil
+}
+
+// JWTValidator verifies JWTs locally using keys from a cached JWKS, without calling the identity provider per request.
+// It checks the token's signature, exp and nbf claims, audience and issuer, then builds an AuthResult from
+// configurable claims.
+// The provider specific authenticators in grpcauth are built on top of it.
+type JWTValidator struct {
+	// Keys is where signing keys are looked up.
+	// A JWKSCache made by the caller is used as it is, with its own Client rather than JWTValidation.Client.
+	Keys *JWKSCache
+	// Issuer is the iss claim tokens must have.
+	// The issuer isn't checked when it is empty, so callers must check it themselves.
+	Issuer string
+	// Audience must be in the token's aud claim.
+	Audience string
+	// ClientIdentifierClaim is the claim used as AuthResult.ClientIdentifier.
+	// It defaults to sub.
+	ClientIdentifierClaim string
+	// PermissionsClaim is the claim used as AuthResult.Permissions, as a space delimited string or a list of strings.
+	// It defaults to the OAuth2 scope claim, or scp if there is no scope claim.
+	PermissionsClaim string
+
+	JWTValidation
+}
+
+// NewJWTValidator returns a JWTValidator that accepts tokens from issuer for audience, signed with keys from jwksURL.
+// Keys are cached and shared with every other JWTValidator using the same JWKS.
+func NewJWTValidator(jwksURL *url.URL, issuer, audience string) *JWTValidator {
+	return &JWTValidator{
+		Keys:     sharedJWKSCache(jwksURL, nil),
+		Issuer:   issuer,
+		Audience: audience,
+	}
+}
+
+// AuthFunc satisfies the AuthFunc interface so clients can present a JWT as a bearer token to a gRPC server.
+func (v *JWTValidator) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	tokenString, err := bearerToken(md)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := v.Validate(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.AuthResult(claims)
+}
+
+// Validate verifies tokenString and returns its claims.
+func (v *JWTValidator) Validate(tokenString string) (jwt.MapClaims, error) {
+	// Tokens are revoked by the hash of what clients present, even if it was encrypted.
+	presented := tokenString
+	if isJWE(tokenString) {
+		if v.DecryptionKey == nil {
+			return nil, fmt.Errorf("encrypted tokens are not accepted")
+		}
+
+		var err error
+		tokenString, err = decryptJWE(tokenString, v.DecryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The exp, nbf and iat claims are checked by verifyTimes so they can allow for Leeway.
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if err := v.verifySigningMethod(token); err != nil {
+			return nil, err
+		}
+
+		return keyFromJWKS(v.keys(), token)
+	})
+
+	if err != nil {
+		// The parser hides errors from fetching keys, so unwrap outages for CircuitBreakers.
+		if ve, ok := err.(*jwt.ValidationError); ok && errors.Is(ve.Inner, ErrAuthenticationUnavailable) {
+			return nil, ve.Inner
+		}
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	err = v.verifyTimes(claims, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !verifyAudience(claims, v.Audience) {
+		return nil, fmt.Errorf("invalid audience, expected %s, got %v", v.Audience, claims["aud"])
+	}
+
+	if v.Issuer != "" && !verifyIssuer(claims, []string{v.Issuer}) {
+		return nil, fmt.Errorf("invalid issuer, expected %v, got %v", v.Issuer, claims["iss"])
+	}
+
+	err = v.verifyClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.Revocation != nil {
+		revoked, err := v.Revocation.IsRevoked(revocationID(claims, presented))
+		if err != nil {
+			return nil, err
+		}
+
+		if revoked {
+			return nil, fmt.Errorf("token has been revoked")
+		}
+	}
+
+	return claims, nil
+}
+
+// keys returns the JWKSCache to look up signing keys in. A shared cache is swapped for the one shared by other
+// JWTValidators using the same JWKS and Client, but caches the caller made are used as they are.
+func (v *JWTValidator) keys() *JWKSCache {
+	if v.Client == nil || v.Keys.Client == v.Client || !v.Keys.shared {
+		return v.Keys
+	}
+	return sharedJWKSCache(v.Keys.URL, v.Client)
+}
+
+// AuthResult builds an AuthResult from a validated token's claims.
+func (v *JWTValidator) AuthResult(claims jwt.MapClaims) (*AuthResult, error) {
+	clientIdentifierClaim := v.ClientIdentifierClaim
+	if clientIdentifierClaim == "" {
+		clientIdentifierClaim = "sub"
+	}
+
+	clientIdentifier, ok := claims[clientIdentifierClaim].(string)
+	if !ok || clientIdentifier == "" {
+		return nil, fmt.Errorf("token has no %s claim", clientIdentifierClaim)
+	}
+
+	permissions := scopesFromClaims(claims)
+	if v.PermissionsClaim != "" {
+		permissions = stringsFromClaim(claims[v.PermissionsClaim])
+	}
+
+	return &AuthResult{
+		ClientIdentifier: clientIdentifier,
+		Timestamp:        time.Now(),
+		Permissions:      permissions,
+		Claims:           claims,
+		// Tokens are accepted for Leeway after their exp claim, so the Authority has to accept them for as long.
+		Expiry: v.tokenExpiry(claims),
+	}, nil
+}
@@ -0,0 +1,156 @@
+package grpcauth
+
+import (
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJWTValidatorCachesJWKS(t *testing.T) {
+	issuer := newTestIssuer(t)
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/jwks"
+	validator := NewJWTValidator(jwksURL, issuer.server.URL, testAudience)
+
+	for i := 0; i < 3; i++ {
+		_, err := validator.AuthFunc(bearerMetadata(issuer.sign(t, issuer.claims())))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if requests := atomic.LoadInt32(&issuer.jwksRequests); requests != 1 {
+		t.Fatalf("expected JWKS to be fetched once, got %d", requests)
+	}
+
+	notYetValid := issuer.claims()
+	notYetValid["nbf"] = time.Now().Add(time.Hour).Unix()
+	_, err := validator.AuthFunc(bearerMetadata(issuer.sign(t, notYetValid)))
+	if err == nil {
+		t.Fatalf("expected error with token that isn't valid yet")
+	}
+}
+
+func TestJWTValidatorUsesConfiguredClaims(t *testing.T) {
+	issuer := newTestIssuer(t)
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/jwks"
+	validator := NewJWTValidator(jwksURL, issuer.server.URL, testAudience)
+	validator.ClientIdentifierClaim = "client_id"
+	validator.PermissionsClaim = "permissions"
+
+	claims := issuer.claims()
+	claims["client_id"] = "billing"
+	claims["permissions"] = []interface{}{targetMethodName}
+	claims["tenant_id"] = "acme"
+	authResult, err := validator.AuthFunc(bearerMetadata(issuer.sign(t, claims)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != "billing" {
+		t.Fatalf("expected billing, got %v", authResult.ClientIdentifier)
+	}
+
+	if !reflect.DeepEqual(authResult.Permissions, []string{targetMethodName}) {
+		t.Fatalf("expected %v, got %v", []string{targetMethodName}, authResult.Permissions)
+	}
+
+	if authResult.StringClaim("tenant_id") != "acme" {
+		t.Fatalf("expected tenant_id claim acme, got %v", authResult.Claims["tenant_id"])
+	}
+
+	if exp := claims["exp"].(int64); authResult.Expiry.Unix() != exp {
+		t.Fatalf("expected the token to expire at %d, got %v", exp, authResult.Expiry)
+	}
+}
+
+func TestJWTValidationOptions(t *testing.T) {
+	issuer := newTestIssuer(t)
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/jwks"
+
+	tests := map[string]JWTValidation{
+		"disallowed algorithm": {Algorithms: []string{"ES256"}},
+		"missing audience":     {Audiences: []string{testAudience, "https://other.example.com"}},
+		"disallowed issuer":    {Issuers: []string{"https://other.example.com"}},
+		"missing claim":        {RequiredClaims: []string{"jti"}},
+	}
+	for name, validation := range tests {
+		validator := NewJWTValidator(jwksURL, "", testAudience)
+		validator.JWTValidation = validation
+		_, err := validator.AuthFunc(bearerMetadata(issuer.sign(t, issuer.claims())))
+		if err == nil {
+			t.Fatalf("expected error with %s", name)
+		}
+	}
+
+	validator := NewJWTValidator(jwksURL, "", testAudience)
+	validator.JWTValidation = JWTValidation{
+		Algorithms:     []string{"RS256"},
+		Audiences:      []string{testAudience},
+		Issuers:        []string{issuer.server.URL},
+		RequiredClaims: []string{"iat"},
+	}
+	_, err := validator.AuthFunc(bearerMetadata(issuer.sign(t, issuer.claims())))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJWTValidationLeeway(t *testing.T) {
+	issuer := newTestIssuer(t)
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/jwks"
+	validator := NewJWTValidator(jwksURL, "", testAudience)
+
+	justExpired := issuer.claims()
+	justExpired["exp"] = time.Now().Add(-10 * time.Second).Unix()
+	issuedAhead := issuer.claims()
+	issuedAhead["iat"] = time.Now().Add(10 * time.Second).Unix()
+	skewed := map[string]string{
+		"just expired": issuer.sign(t, justExpired),
+		"issued ahead": issuer.sign(t, issuedAhead),
+	}
+
+	for name, token := range skewed {
+		_, err := validator.AuthFunc(bearerMetadata(token))
+		if err == nil {
+			t.Fatalf("expected error with %s token without leeway", name)
+		}
+	}
+
+	validator.Leeway = time.Minute
+	for name, token := range skewed {
+		_, err := validator.AuthFunc(bearerMetadata(token))
+		if err != nil {
+			t.Fatalf("expected %s token to be accepted with leeway: %v", name, err)
+		}
+	}
+}
+
+func TestJWTValidatorUsesConfiguredKeys(t *testing.T) {
+	issuer := newTestIssuer(t)
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/jwks"
+	keys := &JWKSCache{URL: jwksURL, TTL: 2 * time.Hour, GracePeriod: time.Hour}
+	validator := &JWTValidator{
+		Keys:          keys,
+		Issuer:        issuer.server.URL,
+		Audience:      testAudience,
+		JWTValidation: JWTValidation{Client: &http.Client{}},
+	}
+
+	_, err := validator.AuthFunc(bearerMetadata(issuer.sign(t, issuer.claims())))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+	if keys.fetchedAt.IsZero() {
+		t.Fatalf("expected the configured JWKSCache to be used")
+	}
+}
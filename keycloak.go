@@ -0,0 +1,90 @@
+package grpcauth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+// Keycloak authenticates incoming gRPC requests carrying access tokens issued by a Keycloak realm.
+// Keycloak puts roles in the nested realm_access and resource_access claims, so Keycloak can map them into
+// AuthResult.Permissions alongside the token's scopes.
+// See https://www.keycloak.org/docs/latest/server_admin/#_service_accounts for more details.
+type Keycloak struct {
+	// URL is the base URL of the Keycloak server, such as https://keycloak.example.com.
+	URL      *url.URL
+	Realm    string
+	Audience string
+	// RealmRoles maps the roles in realm_access.roles into Permissions.
+	RealmRoles bool
+	// ClientRoles lists the clients whose roles in resource_access.<client>.roles will be mapped into Permissions.
+	ClientRoles []string
+	// QualifyClientRoles prefixes client roles with the client they belong to, such as "my-api:admin".
+	// This keeps roles with the same name in different clients from granting each other's permissions.
+	QualifyClientRoles bool
+
+	JWTValidation
+}
+
+// AuthFunc satisfies the AuthFunc interface so clients can use Keycloak with a gRPC server.
+func (k *Keycloak) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	issuer, err := url.Parse(fmt.Sprintf("%s/realms/%s", strings.TrimSuffix(k.URL.String(), "/"), k.Realm))
+	if err != nil {
+		return nil, err
+	}
+
+	jwksURL, err := url.Parse(issuer.String() + "/protocol/openid-connect/certs")
+	if err != nil {
+		return nil, err
+	}
+
+	oidc := &OIDC{
+		Issuer:        issuer,
+		Audience:      k.Audience,
+		JWKSURL:       jwksURL,
+		JWTValidation: k.JWTValidation,
+	}
+	claims, err := oidc.verify(md)
+	if err != nil {
+		return nil, err
+	}
+
+	authResult, err := authResultFromClaims(claims, k.Leeway)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keycloak puts the client ID of a service account in the azp claim.
+	// The sub claim is the ID of the service account user.
+	if azp, ok := claims["azp"].(string); ok && azp != "" {
+		authResult.ClientIdentifier = azp
+	}
+
+	authResult.Permissions = append(authResult.Permissions, k.roles(claims)...)
+	return authResult, nil
+}
+
+// roles returns the realm and client roles in the token that the Keycloak authenticator has been configured to map.
+func (k *Keycloak) roles(claims jwt.MapClaims) []string {
+	var roles []string
+	if k.RealmRoles {
+		realmAccess, _ := claims["realm_access"].(map[string]interface{})
+		roles = append(roles, stringsFromClaim(realmAccess["roles"])...)
+	}
+
+	resourceAccess, _ := claims["resource_access"].(map[string]interface{})
+	for _, client := range k.ClientRoles {
+		access, _ := resourceAccess[client].(map[string]interface{})
+		for _, role := range stringsFromClaim(access["roles"]) {
+			if k.QualifyClientRoles {
+				role = client + ":" + role
+			}
+			roles = append(roles, role)
+		}
+	}
+
+	return roles
+}
@@ -0,0 +1,68 @@
+package grpcauth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeycloakMapsRoles(t *testing.T) {
+	issuer := newTestIssuer(t)
+	claims := issuer.claims()
+	claims["iss"] = issuer.server.URL + "/realms/services"
+	claims["azp"] = "billing"
+	claims["realm_access"] = map[string]interface{}{
+		"roles": []string{"offline_access"},
+	}
+	claims["resource_access"] = map[string]interface{}{
+		"inventory": map[string]interface{}{"roles": []string{"reader"}},
+		"account":   map[string]interface{}{"roles": []string{"manage-account"}},
+	}
+	token := issuer.sign(t, claims)
+
+	tests := []struct {
+		keycloak *Keycloak
+		expected []string
+	}{
+		{
+			keycloak: &Keycloak{},
+			expected: []string{targetMethodName},
+		},
+		{
+			keycloak: &Keycloak{RealmRoles: true, ClientRoles: []string{"inventory"}},
+			expected: []string{targetMethodName, "offline_access", "reader"},
+		},
+		{
+			keycloak: &Keycloak{ClientRoles: []string{"inventory", "missing"}, QualifyClientRoles: true},
+			expected: []string{targetMethodName, "inventory:reader"},
+		},
+	}
+
+	for _, test := range tests {
+		test.keycloak.URL = issuer.url(t)
+		test.keycloak.Realm = "services"
+		test.keycloak.Audience = testAudience
+		authResult, err := test.keycloak.AuthFunc(bearerMetadata(token))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if authResult.ClientIdentifier != "billing" {
+			t.Fatalf("expected billing, got %v", authResult.ClientIdentifier)
+		}
+
+		if !reflect.DeepEqual(authResult.Permissions, test.expected) {
+			t.Fatalf("expected %v, got %v", test.expected, authResult.Permissions)
+		}
+	}
+}
+
+func TestKeycloakRejectsOtherRealms(t *testing.T) {
+	issuer := newTestIssuer(t)
+	claims := issuer.claims()
+	claims["iss"] = issuer.server.URL + "/realms/master"
+	keycloak := &Keycloak{URL: issuer.url(t), Realm: "services", Audience: testAudience}
+	_, err := keycloak.AuthFunc(bearerMetadata(issuer.sign(t, claims)))
+	if err == nil {
+		t.Fatalf("expected error with token from another realm")
+	}
+}
@@ -0,0 +1,51 @@
+package grpcauth
+
+import "sync"
+
+// KillSwitch lets an Authority deny every method at runtime, like during incident response when a credential leak
+// is suspected. Denied requests get Unavailable with a MaintenanceError.
+// Use it with WithKillSwitch. It is safe for concurrent use.
+type KillSwitch struct {
+	mu        sync.RWMutex
+	enabled   bool
+	reason    string
+	allowlist []string
+}
+
+// Enable denies every method except those matching allowlist, which can be method names or patterns matched with
+// MatchMethod. reason is sent to clients.
+func (k *KillSwitch) Enable(reason string, allowlist ...string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.enabled = true
+	k.reason = reason
+	k.allowlist = allowlist
+}
+
+// Disable lets requests through again.
+func (k *KillSwitch) Disable() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.enabled = false
+	k.reason = ""
+	k.allowlist = nil
+}
+
+// Enabled reports whether the kill switch is denying requests.
+func (k *KillSwitch) Enabled() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.enabled
+}
+
+// check returns an Unavailable status if the kill switch denies methodName.
+func (k *KillSwitch) check(methodName string) error {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if !k.enabled || WildcardPermissions(k.allowlist, methodName) {
+		return nil
+	}
+
+	maintenance := &MaintenanceError{Reason: k.reason, MethodName: methodName}
+	return maintenance.GRPCStatus().Err()
+}
@@ -0,0 +1,54 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestKillSwitch(t *testing.T) {
+	killSwitch := &KillSwitch{}
+	a := NewAuthority(alwaysAuthenticatedAllPermissions, nil, WithKillSwitch(killSwitch))
+	md := metadata.Pairs("authorization", "bearer words")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: targetMethodName}
+
+	_, err := a.UnaryServerInterceptor(ctx, nil, info, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	killSwitch.Enable("suspected credential leak", "/grpc.health.v1.Health/*")
+	_, err = a.UnaryServerInterceptor(ctx, nil, info, handler)
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected unavailable, got %v", err)
+	}
+
+	maintenanceError, ok := MaintenanceDetails(err)
+	if !ok {
+		t.Fatalf("expected a MaintenanceError in the details, got %v", err)
+	}
+
+	if maintenanceError.Reason != "suspected credential leak" {
+		t.Fatalf("unexpected reason %s", maintenanceError.Reason)
+	}
+
+	killSwitch.Enable("suspected credential leak", targetMethodName)
+	_, err = a.UnaryServerInterceptor(ctx, nil, info, handler)
+	if err != nil {
+		t.Fatalf("expected allowlisted method to be allowed: %v", err)
+	}
+
+	killSwitch.Disable()
+	_, err = a.UnaryServerInterceptor(ctx, nil, info, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,250 @@
+package grpcauth
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// kubernetesServiceAccountDir is where Kubernetes mounts a pod's service account credentials.
+	kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	// kubernetesServiceAccountPrefix is the prefix of a service account's username.
+	kubernetesServiceAccountPrefix = "system:serviceaccount:"
+)
+
+// KubernetesTokenReview authenticates in-cluster workloads presenting their service account token by asking the
+// Kubernetes API server to validate it with the TokenReview API.
+// The service account's namespace/name becomes AuthResult.ClientIdentifier and its groups become AuthResult.Permissions.
+// The server's own service account needs permission to create tokenreviews, such as from the system:auth-delegator ClusterRole.
+// See https://kubernetes.io/docs/reference/kubernetes-api/authentication-resources/token-review-v1/ for more details.
+type KubernetesTokenReview struct {
+	APIServerURL *url.URL
+	// TokenFile is the path to the token the server uses to call the API server.
+	// It is read on every request since projected service account tokens are rotated by the kubelet.
+	TokenFile string
+	// Audiences are the audiences the client's token must be valid for.
+	// The API server's audience is used when it is empty.
+	Audiences []string
+	// Client is used to call the API server and should trust the cluster's CA.
+	Client *http.Client
+}
+
+// NewInClusterTokenReview returns a KubernetesTokenReview that uses the pod's service account to call the API server.
+func NewInClusterTokenReview(audiences ...string) (*KubernetesTokenReview, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+	}
+
+	apiServerURL, err := url.Parse("https://" + net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+
+	caCert, err := ioutil.ReadFile(kubernetesServiceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no certificates found in cluster CA bundle")
+	}
+
+	return &KubernetesTokenReview{
+		APIServerURL: apiServerURL,
+		TokenFile:    kubernetesServiceAccountDir + "/token",
+		Audiences:    audiences,
+		Client: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		},
+	}, nil
+}
+
+// tokenReview is the subset of the authentication.k8s.io/v1 TokenReview grpcauth sends and reads.
+type tokenReview struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Spec       tokenReviewSpec   `json:"spec"`
+	Status     tokenReviewStatus `json:"status"`
+}
+
+type tokenReviewSpec struct {
+	Token     string   `json:"token"`
+	Audiences []string `json:"audiences,omitempty"`
+}
+
+type tokenReviewStatus struct {
+	Authenticated bool   `json:"authenticated"`
+	Error         string `json:"error"`
+	User          struct {
+		Username string   `json:"username"`
+		Groups   []string `json:"groups"`
+	} `json:"user"`
+}
+
+// AuthFunc satisfies the AuthFunc interface so in-cluster workloads can use their service account with a gRPC server.
+func (k *KubernetesTokenReview) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	tokenString, err := bearerToken(md)
+	if err != nil {
+		return nil, err
+	}
+
+	serverToken, err := ioutil.ReadFile(k.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(&tokenReview{
+		APIVersion: "authentication.k8s.io/v1",
+		Kind:       "TokenReview",
+		Spec: tokenReviewSpec{
+			Token:     tokenString,
+			Audiences: k.Audiences,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reviewURL := strings.TrimSuffix(k.APIServerURL.String(), "/") + "/apis/authentication.k8s.io/v1/tokenreviews"
+	req, err := http.NewRequest(http.MethodPost, reviewURL, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(serverToken)))
+
+	client := k.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, errors.New(string(b))
+	}
+
+	var review tokenReview
+	err = json.NewDecoder(resp.Body).Decode(&review)
+	if err != nil {
+		return nil, err
+	}
+
+	if !review.Status.Authenticated {
+		return nil, fmt.Errorf("token review failed: %s", review.Status.Error)
+	}
+
+	clientIdentifier, err := serviceAccountIdentifier(review.Status.User.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResult{
+		ClientIdentifier: clientIdentifier,
+		Timestamp:        time.Now(),
+		Permissions:      review.Status.User.Groups,
+	}, nil
+}
+
+// KubernetesServiceAccount authenticates in-cluster workloads presenting their service account token offline by
+// verifying it against the cluster's service account issuer JWKS, without calling the API server.
+// Unlike KubernetesTokenReview, it can't tell if the pod or service account a token is bound to has been deleted,
+// so it should only be used with short lived projected tokens.
+// See https://kubernetes.io/docs/tasks/configure-pod-container/configure-service-account/#service-account-issuer-discovery
+type KubernetesServiceAccount struct {
+	// Issuer is the cluster's --service-account-issuer.
+	Issuer   *url.URL
+	Audience string
+	// JWKSURL is where the cluster publishes its service account signing keys.
+	// It defaults to the issuer's /openid/v1/jwks.
+	JWKSURL *url.URL
+
+	JWTValidation
+}
+
+// AuthFunc satisfies the AuthFunc interface so in-cluster workloads can use their service account with a gRPC server.
+func (k *KubernetesServiceAccount) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	jwksURL := k.JWKSURL
+	if jwksURL == nil {
+		var err error
+		jwksURL, err = url.Parse(strings.TrimSuffix(k.Issuer.String(), "/") + "/openid/v1/jwks")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	oidc := &OIDC{
+		Issuer:        k.Issuer,
+		Audience:      k.Audience,
+		JWKSURL:       jwksURL,
+		JWTValidation: k.JWTValidation,
+	}
+	claims, err := oidc.verify(md)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	clientIdentifier, err := serviceAccountIdentifier(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResult{
+		ClientIdentifier: clientIdentifier,
+		Timestamp:        time.Now(),
+		Permissions:      serviceAccountGroups(claims),
+		Claims:           claims,
+		Expiry:           k.tokenExpiry(claims),
+	}, nil
+}
+
+// serviceAccountIdentifier converts a username like system:serviceaccount:namespace:name into namespace/name.
+func serviceAccountIdentifier(username string) (string, error) {
+	if !strings.HasPrefix(username, kubernetesServiceAccountPrefix) {
+		return "", fmt.Errorf("%s is not a service account", username)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(username, kubernetesServiceAccountPrefix), ":")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("invalid service account username: %s", username)
+	}
+
+	return parts[0] + "/" + parts[1], nil
+}
+
+// serviceAccountGroups returns the groups the API server would put a service account in, since they aren't in the token.
+func serviceAccountGroups(claims jwt.MapClaims) []string {
+	kubernetes, _ := claims["kubernetes.io"].(map[string]interface{})
+	namespace, _ := kubernetes["namespace"].(string)
+	return []string{
+		"system:serviceaccounts",
+		"system:serviceaccounts:" + namespace,
+		"system:authenticated",
+	}
+}
@@ -0,0 +1,99 @@
+package grpcauth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestKubernetesTokenReview(t *testing.T) {
+	const (
+		serverToken = "server-token"
+		clientToken = "client-token"
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/authentication.k8s.io/v1/tokenreviews" || r.Header.Get("Authorization") != "Bearer "+serverToken {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var review tokenReview
+		json.NewDecoder(r.Body).Decode(&review)
+		if review.Spec.Token == clientToken {
+			review.Status.Authenticated = true
+			review.Status.User.Username = "system:serviceaccount:payments:api"
+			review.Status.User.Groups = []string{"system:serviceaccounts", "system:serviceaccounts:payments"}
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(&review)
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	err := ioutil.WriteFile(tokenFile, []byte(serverToken+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apiServerURL, _ := url.Parse(server.URL)
+	kubernetes := &KubernetesTokenReview{APIServerURL: apiServerURL, TokenFile: tokenFile}
+	authResult, err := kubernetes.AuthFunc(bearerMetadata(clientToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != "payments/api" {
+		t.Fatalf("expected payments/api, got %v", authResult.ClientIdentifier)
+	}
+
+	expected := []string{"system:serviceaccounts", "system:serviceaccounts:payments"}
+	if !reflect.DeepEqual(authResult.Permissions, expected) {
+		t.Fatalf("expected %v, got %v", expected, authResult.Permissions)
+	}
+
+	_, err = kubernetes.AuthFunc(bearerMetadata("forged-token"))
+	if err == nil {
+		t.Fatalf("expected error with unauthenticated token")
+	}
+}
+
+func TestKubernetesServiceAccountOffline(t *testing.T) {
+	issuer := newTestIssuer(t)
+	kubernetes := &KubernetesServiceAccount{Issuer: issuer.url(t), Audience: testAudience}
+
+	claims := issuer.claims()
+	claims["sub"] = "system:serviceaccount:payments:api"
+	claims["kubernetes.io"] = map[string]interface{}{
+		"namespace":      "payments",
+		"serviceaccount": map[string]interface{}{"name": "api"},
+	}
+
+	authResult, err := kubernetes.AuthFunc(bearerMetadata(issuer.sign(t, claims)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != "payments/api" {
+		t.Fatalf("expected payments/api, got %v", authResult.ClientIdentifier)
+	}
+
+	expected := []string{"system:serviceaccounts", "system:serviceaccounts:payments", "system:authenticated"}
+	if !reflect.DeepEqual(authResult.Permissions, expected) {
+		t.Fatalf("expected %v, got %v", expected, authResult.Permissions)
+	}
+}
+
+func TestServiceAccountIdentifierRejectsUsers(t *testing.T) {
+	for _, username := range []string{"admin", "system:serviceaccount:payments", "system:serviceaccount::api"} {
+		_, err := serviceAccountIdentifier(username)
+		if err == nil {
+			t.Fatalf("expected error for %s", username)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The attribute keys every Logger in grpcauth uses, so events can be queried the same way whichever logger wrote them.
+const (
+	LogKeyClient        = "client"
+	LogKeyMethod        = "method"
+	LogKeyOutcome       = "outcome"
+	LogKeyLatency       = "latency"
+	LogKeyError         = "error"
+	LogKeyCause         = "cause"
+	LogKeyCorrelationID = "correlation_id"
+)
+
+// The outcomes of an AuthEvent.
+const (
+	OutcomeAllowed         = "allowed"
+	OutcomeUnauthenticated = "unauthenticated"
+	OutcomeDenied          = "denied"
+	OutcomeRateLimited     = "rate_limited"
+	OutcomeError           = "error"
+)
+
+// redactedToken replaces token material in logged errors.
+const redactedToken = "[REDACTED]"
+
+var (
+	// jwtPattern matches JWTs, and the first three parts of JWEs.
+	jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+
+	// credentialPattern matches credentials after an authorization scheme, including the comma separated key=value
+	// parameters of signature schemes like HMAC-SHA256.
+	credentialPattern = regexp.MustCompile(`(?i)\b(bearer|basic|dpop|macaroon|apikey|(?:aws4-)?hmac-sha256)\s+[A-Za-z0-9._~+/=-]+(?:,\s*[A-Za-z0-9-]+=[A-Za-z0-9._~+/=-]+)*`)
+)
+
+// AuthEvent is the outcome of authenticating a client and authorizing it to call a method.
+type AuthEvent struct {
+	MethodName string
+	// AuthResult is the client, or nil if it didn't authenticate.
+	AuthResult *AuthResult
+	// Err is the error the request was rejected with, or nil if it was allowed.
+	// In shadow mode, it is the error the request would have been rejected with.
+	Err error
+	// Cause is why the client failed to authenticate, such as an expired token.
+	// Clients only get Err, since the cause can describe the server's configuration.
+	Cause error
+	// Latency is how long authenticating and authorizing the request took.
+	Latency time.Duration
+	// CorrelationID is the ID in the error the client got when the Authority uses OpaqueErrors.
+	CorrelationID string
+}
+
+// ClientIdentifier returns the client's identifier, or an empty string if it didn't authenticate.
+func (e *AuthEvent) ClientIdentifier() string {
+	if e.AuthResult == nil {
+		return ""
+	}
+	return e.AuthResult.ClientIdentifier
+}
+
+// Outcome returns OutcomeAllowed, OutcomeUnauthenticated, OutcomeDenied, OutcomeRateLimited, or OutcomeError if
+// authorization failed.
+func (e *AuthEvent) Outcome() string {
+	switch status.Code(e.Err) {
+	case codes.OK:
+		return OutcomeAllowed
+	case codes.Unauthenticated:
+		return OutcomeUnauthenticated
+	case codes.PermissionDenied:
+		return OutcomeDenied
+	case codes.ResourceExhausted:
+		return OutcomeRateLimited
+	}
+
+	return OutcomeError
+}
+
+// RedactedError returns Err's message with token material removed, or an empty string if Err is nil.
+func (e *AuthEvent) RedactedError() string {
+	return redactError(e.Err)
+}
+
+// RedactedCause returns Cause's message with token material removed, or an empty string if Cause is nil.
+// Authenticators sometimes include the credential they rejected in their errors.
+func (e *AuthEvent) RedactedCause() string {
+	return redactError(e.Cause)
+}
+
+// RedactTokens replaces JWTs and credentials following an authorization scheme, such as `Bearer abc`, in s.
+func RedactTokens(s string) string {
+	s = jwtPattern.ReplaceAllString(s, redactedToken)
+	return credentialPattern.ReplaceAllString(s, "$1 "+redactedToken)
+}
+
+func redactError(err error) string {
+	if err == nil {
+		return ""
+	}
+	return RedactTokens(err.Error())
+}
+
+// Logger records every attempt to authenticate and authorize a request, so failed attempts can be audited and
+// alerted on. ctx has the client's AuthResult if it authenticated.
+// Loggers should write errors with RedactedError and RedactedCause so credentials don't end up in logs.
+type Logger interface {
+	LogAuth(ctx context.Context, event *AuthEvent)
+}
+
+// LoggerFunc satisfies the Logger interface with a function.
+type LoggerFunc func(ctx context.Context, event *AuthEvent)
+
+// LogAuth satisfies the Logger interface.
+func (f LoggerFunc) LogAuth(ctx context.Context, event *AuthEvent) {
+	f(ctx, event)
+}
+
+// StdLogger returns a Logger that writes events to logger as key=value pairs:
+//
+//	grpcauth: method=/server.ServiceName/MethodName client=testClient outcome=denied latency=1ms error="..." cause="..."
+//
+// logger defaults to the standard logger if it is nil.
+func StdLogger(logger *log.Logger) Logger {
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return LoggerFunc(func(ctx context.Context, event *AuthEvent) {
+		line := "grpcauth: " + LogKeyMethod + "=" + event.MethodName +
+			" " + LogKeyClient + "=" + event.ClientIdentifier() +
+			" " + LogKeyOutcome + "=" + event.Outcome() +
+			" " + LogKeyLatency + "=" + event.Latency.String()
+		if event.Err != nil {
+			line += " " + LogKeyError + "=" + strconv.Quote(event.RedactedError())
+		}
+		if event.Cause != nil {
+			line += " " + LogKeyCause + "=" + strconv.Quote(event.RedactedCause())
+		}
+		if event.CorrelationID != "" {
+			line += " " + LogKeyCorrelationID + "=" + event.CorrelationID
+		}
+		logger.Print(line)
+	})
+}
+
+// authenticationError is errUnauthorized with the reason the client failed to authenticate, for the Logger.
+// It is replaced with errUnauthorized, or Unavailable if an identity provider is down, before it is returned to the
+// client.
+type authenticationError struct {
+	cause error
+}
+
+func (e *authenticationError) Error() string {
+	return e.status().Error()
+}
+
+// status returns the error the client should get.
+func (e *authenticationError) status() error {
+	if errors.Is(e.cause, ErrAuthenticationUnavailable) {
+		return errAuthenticationUnavailable
+	}
+	return errUnauthorized
+}
+
+// logAuth reports a request's outcome to the Authority's Loggers, and returns the error that should be
+// returned to the client.
+func (a *authority) logAuth(ctx context.Context, methodName string, start time.Time, err error) error {
+	var cause error
+	if authErr, ok := err.(*authenticationError); ok {
+		cause = authErr.cause
+		err = authErr.status()
+	}
+
+	clientErr, correlationID := a.ErrorVerbosity.clientError(err, methodName)
+	if len(a.Loggers) > 0 {
+		authResult, _ := GetAuthResult(ctx)
+		event := &AuthEvent{
+			MethodName:    methodName,
+			AuthResult:    a.Pseudonymizer.pseudonymize(authResult),
+			Err:           err,
+			Cause:         cause,
+			Latency:       time.Since(start),
+			CorrelationID: correlationID,
+		}
+		for _, logger := range a.Loggers {
+			logger.LogAuth(ctx, event)
+		}
+	}
+
+	return a.hideMethod(clientErr, methodName)
+}
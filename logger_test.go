@@ -0,0 +1,108 @@
+package grpcauth
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthorityLogsAuthEvents(t *testing.T) {
+	var events []*AuthEvent
+	logger := LoggerFunc(func(ctx context.Context, event *AuthEvent) {
+		events = append(events, event)
+	})
+
+	md := metadata.Pairs("authorization", "bearer words")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	unauthenticated := NewAuthority(alwaysUnauthenticated, nil, WithLogger(logger)).(*authority)
+	_, err := unauthenticated.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if err != errUnauthorized {
+		t.Fatalf("expected the reason authentication failed not to be returned to the client, got %v", err)
+	}
+
+	denied := NewAuthority(alwaysAuthenticatedNoPermissions, nil, WithLogger(logger)).(*authority)
+	_, err = denied.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+
+	allowed := NewAuthority(alwaysAuthenticatedAllPermissions, nil, WithLogger(logger)).(*authority)
+	_, err = allowed.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected an event for every request, got %d", len(events))
+	}
+
+	if events[0].Err != errUnauthorized || events[0].Cause == nil || events[0].Cause.Error() != "unauthenticated" {
+		t.Fatalf("expected the reason authentication failed to be logged, got %+v", events[0])
+	}
+
+	if events[1].ClientIdentifier() != testClientName || status.Code(events[1].Err) != codes.PermissionDenied {
+		t.Fatalf("expected denied client to be logged, got %+v", events[1])
+	}
+
+	if events[2].ClientIdentifier() != testClientName || events[2].Err != nil || events[2].MethodName != targetMethodName {
+		t.Fatalf("expected allowed client to be logged, got %+v", events[2])
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	md := metadata.Pairs("authorization", "bearer words")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	a := NewAuthority(alwaysUnauthenticated, nil, WithLogger(StdLogger(log.New(&buf, "", 0)))).(*authority)
+	a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+
+	line := buf.String()
+	for _, expected := range []string{"method=" + targetMethodName, "outcome=unauthenticated", `cause="unauthenticated"`} {
+		if !strings.Contains(line, expected) {
+			t.Fatalf("expected %s in %q", expected, line)
+		}
+	}
+}
+
+func TestRedactTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	token := issuer.sign(t, issuer.claims())
+	redacted := RedactTokens("invalid token " + token + " in authorization: Bearer abc.def, Basic dXNlcjpwYXNz")
+	for _, secret := range []string{token, "abc.def", "dXNlcjpwYXNz"} {
+		if strings.Contains(redacted, secret) {
+			t.Fatalf("expected %s to be redacted from %q", secret, redacted)
+		}
+	}
+
+	if !strings.Contains(redacted, "Bearer [REDACTED]") {
+		t.Fatalf("expected the authorization scheme to be kept, got %q", redacted)
+	}
+	tests := []struct {
+		authorization string
+		secrets       []string
+		expected      string
+	}{
+		{"ApiKey gak_1234.secret", []string{"gak_1234.secret"}, "ApiKey [REDACTED]"},
+		{"HMAC-SHA256 Credential=key-1, Timestamp=1700000000, Nonce=abc, Signature=0a1b2c", []string{"key-1", "0a1b2c"}, "HMAC-SHA256 [REDACTED]"},
+		{"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240101/us-east-1/sts/aws4_request, SignedHeaders=host, Signature=5d672d79", []string{"AKIDEXAMPLE", "5d672d79"}, "AWS4-HMAC-SHA256 [REDACTED]"},
+	}
+
+	for _, test := range tests {
+		redacted := RedactTokens("invalid authorization: " + test.authorization + ", try again")
+		for _, secret := range test.secrets {
+			if strings.Contains(redacted, secret) {
+				t.Fatalf("expected %s to be redacted from %q", secret, redacted)
+			}
+		}
+
+		if redacted != "invalid authorization: "+test.expected+", try again" {
+			t.Fatalf("expected only the credentials to be redacted, got %q", redacted)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package grpcauth
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogrusLogger returns a Logger that writes events to logger with the LogKey fields, at Info level for allowed
+// requests and Warn level for rejected ones. Errors are redacted with RedactTokens.
+func LogrusLogger(logger logrus.FieldLogger) Logger {
+	return LoggerFunc(func(ctx context.Context, event *AuthEvent) {
+		fields := logrus.Fields{
+			LogKeyMethod:  event.MethodName,
+			LogKeyClient:  event.ClientIdentifier(),
+			LogKeyOutcome: event.Outcome(),
+			LogKeyLatency: event.Latency,
+		}
+		if event.Cause != nil {
+			fields[LogKeyCause] = event.RedactedCause()
+		}
+		if event.CorrelationID != "" {
+			fields[LogKeyCorrelationID] = event.CorrelationID
+		}
+		if event.Err == nil {
+			logger.WithFields(fields).Info("grpcauth")
+			return
+		}
+
+		fields[LogKeyError] = event.RedactedError()
+		logger.WithFields(fields).Warn("grpcauth")
+	})
+}
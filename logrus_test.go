@@ -0,0 +1,27 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLogrusLogger(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	LogrusLogger(logger).LogAuth(context.Background(), &AuthEvent{
+		MethodName: targetMethodName,
+		AuthResult: &AuthResult{ClientIdentifier: testClientName},
+		Err:        permissionDeniedStatus(&AuthResult{ClientIdentifier: testClientName}, targetMethodName),
+	})
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.WarnLevel {
+		t.Fatalf("expected denied request to be logged at warn level, got %v", entry)
+	}
+
+	if entry.Data[LogKeyClient] != testClientName || entry.Data[LogKeyOutcome] != OutcomeDenied {
+		t.Fatalf("expected client and outcome fields, got %v", entry.Data)
+	}
+}
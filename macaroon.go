@@ -0,0 +1,266 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// macaroonScheme is the authorization scheme clients send macaroons with.
+	macaroonScheme = "macaroon "
+
+	// macaroonKeyGenerator derives a macaroon's signing key from its root key the same way libmacaroons does.
+	macaroonKeyGenerator = "macaroons-key-generator"
+
+	// The first-party caveat conditions Macaroons understands.
+	macaroonMethodCaveat   = "method"
+	macaroonExpiresCaveat  = "expires"
+	macaroonClientIDCaveat = "client-id"
+)
+
+// Macaroon is a bearer credential that can be attenuated by anyone holding it, by adding caveats that restrict how it can be used.
+// Only first-party caveats are supported.
+// It is serialized using the libmacaroons v2 JSON format.
+// See https://research.google/pubs/pub41892/ for more details.
+type Macaroon struct {
+	Location  string
+	ID        string
+	Caveats   []string
+	Signature []byte
+}
+
+// macaroonJSON is the v2 JSON serialization of a macaroon.
+type macaroonJSON struct {
+	Version   int              `json:"v"`
+	Location  string           `json:"l,omitempty"`
+	ID        string           `json:"i"`
+	Caveats   []macaroonCaveat `json:"c,omitempty"`
+	Signature string           `json:"s64"`
+}
+
+type macaroonCaveat struct {
+	ID             string `json:"i"`
+	VerificationID string `json:"v64,omitempty"`
+	Location       string `json:"l,omitempty"`
+}
+
+// NewMacaroon mints a macaroon with no caveats, signed with rootKey.
+// The server must be able to look up rootKey from id when the macaroon is presented.
+func NewMacaroon(rootKey []byte, id, location string) *Macaroon {
+	return &Macaroon{
+		Location:  location,
+		ID:        id,
+		Signature: macaroonHMAC(macaroonHMAC([]byte(macaroonKeyGenerator), []byte(rootKey)), []byte(id)),
+	}
+}
+
+// AddFirstPartyCaveat restricts the macaroon with a caveat, such as "method = /server.ServiceName/MethodName",
+// "expires = 2030-01-01T00:00:00Z" or "client-id = billing".
+// Caveats can be added by anyone holding the macaroon, but never removed.
+func (m *Macaroon) AddFirstPartyCaveat(caveat string) {
+	m.Caveats = append(m.Caveats, caveat)
+	m.Signature = macaroonHMAC(m.Signature, []byte(caveat))
+}
+
+// Serialize encodes the macaroon so it can be sent as `authorization: Macaroon <serialized>`.
+func (m *Macaroon) Serialize() (string, error) {
+	encoded := macaroonJSON{
+		Version:   2,
+		Location:  m.Location,
+		ID:        m.ID,
+		Signature: base64.RawURLEncoding.EncodeToString(m.Signature),
+	}
+	for _, caveat := range m.Caveats {
+		encoded.Caveats = append(encoded.Caveats, macaroonCaveat{ID: caveat})
+	}
+
+	b, err := json.Marshal(&encoded)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// parseMacaroon decodes a macaroon serialized with Serialize.
+func parseMacaroon(serialized string) (*Macaroon, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(serialized, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded macaroonJSON
+	err = json.Unmarshal(b, &decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if decoded.Version != 2 {
+		return nil, fmt.Errorf("unsupported macaroon version: %d", decoded.Version)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(decoded.Signature, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Macaroon{Location: decoded.Location, ID: decoded.ID, Signature: signature}
+	for _, caveat := range decoded.Caveats {
+		if caveat.VerificationID != "" {
+			return nil, fmt.Errorf("third-party caveats are not supported")
+		}
+		m.Caveats = append(m.Caveats, caveat.ID)
+	}
+	return m, nil
+}
+
+// CaveatChecker checks a first-party caveat's value against the request.
+// It should return an error if the caveat isn't satisfied.
+type CaveatChecker func(ctx context.Context, value string) error
+
+// Macaroons authenticates clients presenting a macaroon in the authorization metadata field, such as
+// `authorization: Macaroon <serialized>`.
+// The signature chain is verified with the root key for the macaroon's ID, then every first-party caveat is checked
+// against the request. Caveats are written as "condition = value", and Macaroons understands:
+//
+//	method = /server.ServiceName/MethodName   the request must be for the method
+//	expires = 2030-01-01T00:00:00Z            the request must be made before the time
+//	client-id = billing                       the macaroon must belong to the client
+//
+// Every satisfied caveat becomes one of AuthResult.Permissions. Pair it with MacaroonMethodPermissions to require
+// a method caveat for every call.
+// Macaroons needs the method name, so it must be used with NewContextAuthority.
+type Macaroons struct {
+	// RootKey returns the root key the macaroon with id was minted with and the client it was minted for.
+	// Since holders can add caveats, the client identifier must come from the server and never from a caveat.
+	RootKey func(id string) (rootKey []byte, clientIdentifier string, err error)
+	// Checkers checks first-party caveat conditions beyond the ones Macaroons understands.
+	// Macaroons with caveats that no checker understands are rejected.
+	Checkers map[string]CaveatChecker
+}
+
+// AuthFunc satisfies the ContextAuthFunc interface so clients can use macaroons with a gRPC server.
+func (m *Macaroons) AuthFunc(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected macaroon in 'authorization' metadata field")
+	}
+
+	value := values[0]
+	if len(value) <= len(macaroonScheme) || !strings.EqualFold(value[:len(macaroonScheme)], macaroonScheme) {
+		return nil, fmt.Errorf("expected Macaroon authorization scheme")
+	}
+
+	macaroon, err := parseMacaroon(value[len(macaroonScheme):])
+	if err != nil {
+		return nil, err
+	}
+
+	rootKey, clientIdentifier, err := m.RootKey(macaroon.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := NewMacaroon(rootKey, macaroon.ID, macaroon.Location)
+	for _, caveat := range macaroon.Caveats {
+		expected.AddFirstPartyCaveat(caveat)
+	}
+
+	if !hmac.Equal(expected.Signature, macaroon.Signature) {
+		return nil, fmt.Errorf("invalid macaroon signature")
+	}
+
+	for _, caveat := range macaroon.Caveats {
+		condition, value, err := parseCaveat(caveat)
+		if err != nil {
+			return nil, err
+		}
+
+		err = m.checkCaveat(ctx, condition, value, clientIdentifier)
+		if err != nil {
+			return nil, fmt.Errorf("caveat %q not satisfied: %v", caveat, err)
+		}
+	}
+
+	return &AuthResult{
+		ClientIdentifier: clientIdentifier,
+		Timestamp:        time.Now(),
+		Permissions:      macaroon.Caveats,
+	}, nil
+}
+
+func (m *Macaroons) checkCaveat(ctx context.Context, condition, value, clientIdentifier string) error {
+	switch condition {
+	case macaroonClientIDCaveat:
+		if clientIdentifier != value {
+			return fmt.Errorf("macaroon belongs to %s", clientIdentifier)
+		}
+		return nil
+
+	case macaroonMethodCaveat:
+		method, ok := grpc.Method(ctx)
+		if !ok {
+			return fmt.Errorf("no gRPC method in context")
+		}
+
+		if method != value {
+			return fmt.Errorf("request is for %s", method)
+		}
+		return nil
+
+	case macaroonExpiresCaveat:
+		expires, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return err
+		}
+
+		if !time.Now().Before(expires) {
+			return fmt.Errorf("macaroon expired at %v", expires)
+		}
+		return nil
+	}
+
+	checker, ok := m.Checkers[condition]
+	if !ok {
+		return fmt.Errorf("unknown caveat condition: %s", condition)
+	}
+	return checker(ctx, value)
+}
+
+// MacaroonMethodPermissions is a PermissionFunc for Macaroons that only lets clients call methods their macaroon
+// has a method caveat for.
+// Macaroons without a method caveat can't call any method.
+func MacaroonMethodPermissions(permissions []string, methodName string) bool {
+	for _, permission := range permissions {
+		condition, value, err := parseCaveat(permission)
+		if err == nil && condition == macaroonMethodCaveat && value == methodName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseCaveat splits a "condition = value" caveat.
+func parseCaveat(caveat string) (string, string, error) {
+	parts := strings.SplitN(caveat, "=", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid caveat: %q", caveat)
+	}
+
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
+}
+
+func macaroonHMAC(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
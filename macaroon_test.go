@@ -0,0 +1,90 @@
+package grpcauth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+var testMacaroonRootKey = []byte("macaroon-root-key")
+
+func macaroonMetadata(t *testing.T, m *Macaroon) metadata.MD {
+	serialized, err := m.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metadata.Pairs("authorization", "Macaroon "+serialized)
+}
+
+func testMacaroons() *Macaroons {
+	return &Macaroons{
+		RootKey: func(id string) ([]byte, string, error) {
+			if id != "key-1" {
+				return nil, "", errors.New("unknown root key")
+			}
+			return testMacaroonRootKey, testClientName, nil
+		},
+	}
+}
+
+func TestMacaroonsVerifiesCaveats(t *testing.T) {
+	m := NewMacaroon(testMacaroonRootKey, "key-1", "https://api.example.com")
+	m.AddFirstPartyCaveat("client-id = " + testClientName)
+	m.AddFirstPartyCaveat("method = " + targetMethodName)
+	m.AddFirstPartyCaveat("expires = " + time.Now().Add(time.Hour).Format(time.RFC3339))
+
+	macaroons := testMacaroons()
+	authResult, err := macaroons.AuthFunc(methodContext(targetMethodName), macaroonMetadata(t, m))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected %v, got %v", testClientName, authResult.ClientIdentifier)
+	}
+
+	if !MacaroonMethodPermissions(authResult.Permissions, targetMethodName) {
+		t.Fatalf("expected method caveat to grant %v", targetMethodName)
+	}
+
+	_, err = macaroons.AuthFunc(methodContext("/server.ServiceName/OtherMethod"), macaroonMetadata(t, m))
+	if err == nil {
+		t.Fatalf("expected error calling a method outside the caveat")
+	}
+}
+
+func TestMacaroonsRejectsTamperedMacaroons(t *testing.T) {
+	macaroons := testMacaroons()
+	ctx := methodContext(targetMethodName)
+
+	m := NewMacaroon(testMacaroonRootKey, "key-1", "")
+	m.AddFirstPartyCaveat("method = /server.ServiceName/OtherMethod")
+	m.Caveats[0] = "method = " + targetMethodName
+	_, err := macaroons.AuthFunc(ctx, macaroonMetadata(t, m))
+	if err == nil {
+		t.Fatalf("expected error with tampered caveat")
+	}
+
+	expired := NewMacaroon(testMacaroonRootKey, "key-1", "")
+	expired.AddFirstPartyCaveat("expires = " + time.Now().Add(-time.Hour).Format(time.RFC3339))
+	_, err = macaroons.AuthFunc(ctx, macaroonMetadata(t, expired))
+	if err == nil {
+		t.Fatalf("expected error with expired macaroon")
+	}
+
+	impersonating := NewMacaroon(testMacaroonRootKey, "key-1", "")
+	impersonating.AddFirstPartyCaveat("client-id = admin")
+	_, err = macaroons.AuthFunc(ctx, macaroonMetadata(t, impersonating))
+	if err == nil {
+		t.Fatalf("expected error with client-id caveat for another client")
+	}
+
+	unknown := NewMacaroon(testMacaroonRootKey, "key-1", "")
+	unknown.AddFirstPartyCaveat("ip = 10.0.0.1")
+	_, err = macaroons.AuthFunc(ctx, macaroonMetadata(t, unknown))
+	if err == nil {
+		t.Fatalf("expected error with unknown caveat")
+	}
+}
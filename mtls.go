@@ -0,0 +1,89 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// MutualTLS authenticates clients using the certificate they presented during a mutual TLS handshake.
+// The certificate's first URI SAN (such as a SPIFFE ID), DNS SAN, email SAN, or Common Name, in that order,
+// becomes AuthResult.ClientIdentifier.
+// The gRPC server must be configured to require and verify client certificates, such as with
+// tls.RequireAndVerifyClientCert, and MutualTLS must be used with NewContextAuthority and WithoutMetadataKey.
+type MutualTLS struct {
+	// OrganizationalUnitPermissions maps the OUs in a certificate's subject to the permissions they grant.
+	OrganizationalUnitPermissions map[string][]string
+	// SANPermissions maps URI, DNS and email SANs to the permissions they grant.
+	SANPermissions map[string][]string
+}
+
+// AuthFunc satisfies the ContextAuthFunc interface so clients can use mutual TLS with a gRPC server.
+func (m *MutualTLS) AuthFunc(ctx context.Context, md metadata.MD) (*AuthResult, error) {
+	cert, err := verifiedPeerCertificate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sans := certificateSANs(cert)
+	clientIdentifier := cert.Subject.CommonName
+	if len(sans) > 0 {
+		clientIdentifier = sans[0]
+	}
+
+	if clientIdentifier == "" {
+		return nil, fmt.Errorf("client certificate has no SANs or Common Name")
+	}
+
+	var permissions []string
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		permissions = append(permissions, m.OrganizationalUnitPermissions[ou]...)
+	}
+
+	for _, san := range sans {
+		permissions = append(permissions, m.SANPermissions[san]...)
+	}
+
+	return &AuthResult{
+		ClientIdentifier: clientIdentifier,
+		Timestamp:        time.Now(),
+		Permissions:      permissions,
+	}, nil
+}
+
+// verifiedPeerCertificate returns the leaf certificate the gRPC peer presented if the TLS stack verified it.
+func verifiedPeerCertificate(ctx context.Context) (*x509.Certificate, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("no peer in context")
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, fmt.Errorf("peer did not connect over TLS")
+	}
+
+	// VerifiedChains is only populated when the server verified the client's certificate against its client CAs.
+	if len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil, fmt.Errorf("peer did not present a verified client certificate")
+	}
+
+	return tlsInfo.State.VerifiedChains[0][0], nil
+}
+
+// certificateSANs returns a certificate's URI, DNS and email SANs in that order.
+func certificateSANs(cert *x509.Certificate) []string {
+	var sans []string
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+
+	sans = append(sans, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	return sans
+}
@@ -0,0 +1,103 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const testSPIFFEID = "spiffe://example.org/ns/payments/sa/api"
+
+func testClientCertificate(t *testing.T) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spiffeID, _ := url.Parse(testSPIFFEID)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName:         "api",
+			OrganizationalUnit: []string{"payments"},
+		},
+		URIs:      []*url.URL{spiffeID},
+		NotBefore: time.Now().Add(-time.Minute),
+		NotAfter:  time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func tlsPeerContext(state tls.ConnectionState) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+	return metadata.NewIncomingContext(ctx, metadata.MD{})
+}
+
+func TestMutualTLSAuthenticatesVerifiedCertificates(t *testing.T) {
+	cert := testClientCertificate(t)
+	mtls := &MutualTLS{
+		OrganizationalUnitPermissions: map[string][]string{"payments": {"/payments.Ledger/Read"}},
+		SANPermissions:                map[string][]string{testSPIFFEID: {targetMethodName}},
+	}
+
+	ctx := tlsPeerContext(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	})
+	authority := NewContextAuthority(mtls.AuthFunc, nil, WithoutMetadataKey()).(*authority)
+	ctx, err := authority.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authResult, err := GetAuthResult(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testSPIFFEID {
+		t.Fatalf("expected %v, got %v", testSPIFFEID, authResult.ClientIdentifier)
+	}
+
+	expected := []string{"/payments.Ledger/Read", targetMethodName}
+	if !reflect.DeepEqual(authResult.Permissions, expected) {
+		t.Fatalf("expected %v, got %v", expected, authResult.Permissions)
+	}
+}
+
+func TestMutualTLSRejectsUnverifiedCertificates(t *testing.T) {
+	mtls := &MutualTLS{}
+	ctx := tlsPeerContext(tls.ConnectionState{PeerCertificates: []*x509.Certificate{testClientCertificate(t)}})
+	_, err := mtls.AuthFunc(ctx, metadata.MD{})
+	if err == nil {
+		t.Fatalf("expected error with unverified certificate")
+	}
+
+	_, err = mtls.AuthFunc(context.Background(), metadata.MD{})
+	if err == nil {
+		t.Fatalf("expected error without peer")
+	}
+}
@@ -0,0 +1,38 @@
+package grpcauth
+
+import (
+	"fmt"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc/metadata"
+)
+
+// MultiIssuer lets a single Authority trust JWTs from several issuers, such as an auth0 tenant, an AWS Cognito user
+// pool and an internal identity provider, so clients can be migrated between providers without running two servers.
+// Each token is routed to the AuthFunc for the issuer in its iss claim, which must verify the token itself.
+type MultiIssuer struct {
+	// Issuers maps each trusted iss claim to the AuthFunc that verifies its tokens.
+	Issuers map[string]AuthFunc
+}
+
+// AuthFunc satisfies the AuthFunc interface so clients can use tokens from any of the trusted issuers.
+func (m *MultiIssuer) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	tokenString, err := bearerToken(md)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token is only parsed to find its issuer here. Its signature is checked by the issuer's AuthFunc.
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return nil, err
+	}
+
+	iss, _ := token.Claims.(jwt.MapClaims)["iss"].(string)
+	authFunc, ok := m.Issuers[iss]
+	if !ok {
+		return nil, fmt.Errorf("untrusted issuer: %q", iss)
+	}
+
+	return authFunc(md)
+}
@@ -0,0 +1,40 @@
+package grpcauth
+
+import (
+	"testing"
+)
+
+func TestMultiIssuerRoutesByIssuer(t *testing.T) {
+	first, second := newTestIssuer(t), newTestIssuer(t)
+	firstJWKS, secondJWKS := first.url(t), second.url(t)
+	firstJWKS.Path, secondJWKS.Path = "/jwks", "/jwks"
+
+	multiIssuer := &MultiIssuer{
+		Issuers: map[string]AuthFunc{
+			first.server.URL:  NewJWTValidator(firstJWKS, first.server.URL, testAudience).AuthFunc,
+			second.server.URL: NewJWTValidator(secondJWKS, second.server.URL, testAudience).AuthFunc,
+		},
+	}
+
+	for _, issuer := range []*testIssuer{first, second} {
+		_, err := multiIssuer.AuthFunc(bearerMetadata(issuer.sign(t, issuer.claims())))
+		if err != nil {
+			t.Fatalf("expected token from %s to be accepted: %v", issuer.server.URL, err)
+		}
+	}
+
+	// A token claiming to be from one issuer but signed by the other must be rejected.
+	forged := first.claims()
+	forged["iss"] = second.server.URL
+	_, err := multiIssuer.AuthFunc(bearerMetadata(first.sign(t, forged)))
+	if err == nil {
+		t.Fatalf("expected error with token signed by another issuer")
+	}
+
+	untrusted := first.claims()
+	untrusted["iss"] = "https://evil.example.com"
+	_, err = multiIssuer.AuthFunc(bearerMetadata(first.sign(t, untrusted)))
+	if err == nil {
+		t.Fatalf("expected error with untrusted issuer")
+	}
+}
@@ -0,0 +1,106 @@
+package grpcauth
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errNetworkNotAllowed is returned to clients calling from outside their allowed networks.
+var errNetworkNotAllowed = status.Error(codes.PermissionDenied, "client is not allowed from this network")
+
+// ClientNetworksFunc returns the networks a client may call the server from, and false if it may call from anywhere.
+type ClientNetworksFunc func(clientIdentifier string) ([]netip.Prefix, bool)
+
+// ClientNetworks satisfies the ClientNetworksFunc interface with the policy's networks.
+// Clients without networks in the policy may call from anywhere.
+func (p *Policy) ClientNetworks(clientIdentifier string) ([]netip.Prefix, bool) {
+	networks, ok := p.Networks[clientIdentifier]
+	if !ok {
+		return nil, false
+	}
+
+	// Networks were checked by ParsePolicy, so invalid ones can only come from a Policy built in code.
+	prefixes, _ := parseNetworks(networks)
+	return prefixes, true
+}
+
+// ClientNetworks satisfies the ClientNetworksFunc interface using the policy currently in use.
+func (f *PolicyFile) ClientNetworks(clientIdentifier string) ([]netip.Prefix, bool) {
+	return f.Policy().ClientNetworks(clientIdentifier)
+}
+
+// parseNetworks parses CIDRs like 10.0.0.0/8, or single IP addresses.
+func parseNetworks(networks []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(networks))
+	for _, network := range networks {
+		if !strings.Contains(network, "/") {
+			addr, err := netip.ParseAddr(network)
+			if err != nil {
+				return nil, err
+			}
+
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return nil, err
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// validateNetworks returns an error naming the first client with an invalid network.
+func validateNetworks(networks map[string][]string) error {
+	clients := make([]string, 0, len(networks))
+	for client := range networks {
+		clients = append(clients, client)
+	}
+
+	sort.Strings(clients)
+	for _, client := range clients {
+		_, err := parseNetworks(networks[client])
+		if err != nil {
+			return fmt.Errorf("networks for %s: %v", client, err)
+		}
+	}
+
+	return nil
+}
+
+// checkNetwork returns errNetworkNotAllowed if the client with authResult has networks and the peer in ctx isn't
+// in one of them. Clients with networks are rejected if the peer's address isn't known.
+func (a *authority) checkNetwork(ctx context.Context, authResult *AuthResult) error {
+	if a.ClientNetworks == nil {
+		return nil
+	}
+
+	networks, restricted := a.ClientNetworks(authResult.ClientIdentifier)
+	if !restricted {
+		return nil
+	}
+
+	addr, err := netip.ParseAddr(PeerIP(ctx))
+	if err != nil {
+		return errNetworkNotAllowed
+	}
+
+	addr = addr.Unmap()
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return nil
+		}
+	}
+
+	return errNetworkNotAllowed
+}
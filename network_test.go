@@ -0,0 +1,67 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestPolicyClientNetworks(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`
+roles:
+  admin: ["/server.ServiceName/*"]
+networks:
+  testClient: ["10.0.0.0/8", "192.0.2.1", "2001:db8::/32"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	networks, restricted := policy.ClientNetworks(testClientName)
+	if !restricted || len(networks) != 3 || networks[1].String() != "192.0.2.1/32" {
+		t.Fatalf("unexpected networks %v", networks)
+	}
+
+	if _, restricted := policy.ClientNetworks("otherClient"); restricted {
+		t.Fatalf("expected clients without networks to be unrestricted")
+	}
+
+	_, err = ParsePolicy([]byte(`networks: {testClient: ["10.0.0.0/33"]}`))
+	if err == nil {
+		t.Fatalf("expected invalid networks to be rejected")
+	}
+}
+
+func TestAuthorityChecksClientNetworks(t *testing.T) {
+	policy := &Policy{Networks: map[string][]string{testClientName: {"10.0.0.0/8", "2001:db8::/32"}}}
+	a := NewAuthority(alwaysAuthenticatedAllPermissions, nil, WithClientNetworks(policy.ClientNetworks)).(*authority)
+
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"10.1.2.3", true},
+		{"::ffff:10.1.2.3", true},
+		{"2001:db8::1", true},
+		{"192.0.2.1", false},
+	}
+
+	for _, test := range tests {
+		_, err := a.authenticateAndAuthorizeContext(peerContext(test.ip), targetMethodName)
+		if (err == nil) != test.allowed {
+			t.Fatalf("expected %s to be allowed=%t, got %v", test.ip, test.allowed, err)
+		}
+	}
+
+	md := metadata.Pairs("authorization", "bearer words")
+	_, err := a.authenticateAndAuthorizeContext(metadata.NewIncomingContext(context.Background(), md), targetMethodName)
+	if err != errNetworkNotAllowed {
+		t.Fatalf("expected clients with networks to be rejected without a peer address, got %v", err)
+	}
+
+	explanation := a.Explain(peerContext("192.0.2.1"), md, targetMethodName)
+	if last := explanation.Steps[len(explanation.Steps)-1]; last.Check != CheckNetwork || last.Passed {
+		t.Fatalf("expected the network check to be explained, got %s", explanation)
+	}
+}
@@ -0,0 +1,155 @@
+package grpcauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// oidcDiscoveryPath is appended to an issuer URL to find its OpenID Provider Configuration.
+	// See https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderConfig
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+)
+
+// oidcProviderConfiguration is the subset of the OpenID Provider Metadata grpcauth needs.
+type oidcProviderConfiguration struct {
+	Issuer        string `json:"issuer"`
+	JWKSURI       string `json:"jwks_uri"`
+	TokenEndpoint string `json:"token_endpoint"`
+}
+
+// OIDC authenticates gRPC clients presenting access tokens from any standards compliant OpenID Connect provider.
+// It covers providers like Keycloak, Dex and Okta without needing a provider-specific authenticator.
+// Use NewOIDC to populate JWKSURL from the issuer's discovery document.
+type OIDC struct {
+	Issuer   *url.URL
+	Audience string
+	JWKSURL  *url.URL
+
+	JWTValidation
+}
+
+// NewOIDC performs OpenID Connect discovery against issuer and returns an OIDC authenticator that accepts
+// tokens issued by it for audience.
+// Discovery and JWKS requests use ctx's oauth2.HTTPClient if it has one, like the oauth2 package's token requests.
+func NewOIDC(ctx context.Context, issuer *url.URL, audience string) (*OIDC, error) {
+	client, _ := ctx.Value(oauth2.HTTPClient).(*http.Client)
+	config, err := discoverOIDC(ctx, client, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	jwksURL, err := url.Parse(config.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDC{
+		Issuer:        issuer,
+		Audience:      audience,
+		JWKSURL:       jwksURL,
+		JWTValidation: JWTValidation{Client: client},
+	}, nil
+}
+
+// AuthFunc satisfies the AuthFunc interface so clients can use an OpenID Connect provider with a gRPC server.
+func (o *OIDC) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	claims, err := o.verify(md)
+	if err != nil {
+		return nil, err
+	}
+
+	return authResultFromClaims(claims, o.Leeway)
+}
+
+// verify checks the bearer token's signature, expiry, issuer and audience and returns its claims.
+func (o *OIDC) verify(md metadata.MD) (jwt.MapClaims, error) {
+	claims, err := verifyBearerToken(md, o.JWKSURL, o.Audience, o.JWTValidation)
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyIssuer(o.Issuer.String(), true) {
+		return nil, fmt.Errorf("invalid issuer, expected %v, got %v", o.Issuer, claims["iss"])
+	}
+
+	return claims, nil
+}
+
+// verifyBearerToken checks the bearer token's signature against the JWKS, its expiry and audience and returns its claims.
+// Callers must verify the issuer.
+func verifyBearerToken(md metadata.MD, jwksURL *url.URL, audience string, validation JWTValidation) (jwt.MapClaims, error) {
+	tokenString, err := bearerToken(md)
+	if err != nil {
+		return nil, err
+	}
+
+	return verifyJWT(tokenString, jwksURL, audience, validation)
+}
+
+// verifyJWT checks tokenString's signature against the JWKS, its expiry and audience and returns its claims.
+// Callers must verify the issuer.
+func verifyJWT(tokenString string, jwksURL *url.URL, audience string, validation JWTValidation) (jwt.MapClaims, error) {
+	validator := NewJWTValidator(jwksURL, "", audience)
+	validator.JWTValidation = validation
+	return validator.Validate(tokenString)
+}
+
+// authResultFromClaims builds an AuthResult using the sub claim as the ClientIdentifier and the token's scopes as Permissions.
+// It expires leeway after the token's exp claim, as long as the token was accepted for.
+func authResultFromClaims(claims jwt.MapClaims, leeway time.Duration) (*AuthResult, error) {
+	return (&JWTValidator{JWTValidation: JWTValidation{Leeway: leeway}}).AuthResult(claims)
+}
+
+// discoverOIDC fetches the OpenID Provider Configuration for issuer with client, or http.DefaultClient if it is nil.
+func discoverOIDC(ctx context.Context, client *http.Client, issuer *url.URL) (*oidcProviderConfiguration, error) {
+	discoveryURL := strings.TrimSuffix(issuer.String(), "/") + oidcDiscoveryPath
+	req, err := http.NewRequest(http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, errors.New(string(b))
+	}
+
+	var config oidcProviderConfiguration
+	err = json.NewDecoder(resp.Body).Decode(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	// The issuer in the discovery document must match the one used to find it.
+	// See https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderConfigurationValidation
+	if config.Issuer != issuer.String() {
+		return nil, fmt.Errorf("issuer mismatch: expected %v, got %v", issuer, config.Issuer)
+	}
+
+	if config.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document for %v has no jwks_uri", issuer)
+	}
+
+	return &config, nil
+}
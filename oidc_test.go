@@ -0,0 +1,199 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	testKeyID    = "test-key"
+	testAudience = "https://api.example.com"
+)
+
+// testIssuer is an OpenID Connect provider serving a discovery document and JWKS for a single RSA key.
+type testIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// jwksRequests counts how many times the JWKS has been fetched.
+	jwksRequests int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &testIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc(oidcDiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&oidcProviderConfiguration{
+			Issuer:        issuer.server.URL,
+			JWKSURI:       issuer.server.URL + "/jwks",
+			TokenEndpoint: issuer.server.URL + "/token",
+		})
+	})
+	// Providers publish their JWKS at different paths, so serve it for everything but discovery.
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&issuer.jwksRequests, 1)
+		json.NewEncoder(w).Encode(&jsonWebKeySet{
+			Keys: []jsonWebKey{
+				{
+					Kty: "RSA",
+					Kid: testKeyID,
+					Use: "sig",
+					Alg: "RS256",
+					N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) url(t *testing.T) *url.URL {
+	u, err := url.Parse(i.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// sign returns a token signed by the issuer's key containing claims.
+func (i *testIssuer) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// claims returns a valid set of claims for the issuer that can be modified by tests.
+func (i *testIssuer) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   i.server.URL,
+		"sub":   testClientName,
+		"aud":   testAudience,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"scope": targetMethodName,
+	}
+}
+
+func bearerMetadata(token string) metadata.MD {
+	return metadata.Pairs("authorization", "Bearer "+token)
+}
+
+func TestOIDCAcceptsValidToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+	oidc, err := NewOIDC(context.Background(), issuer.url(t), testAudience)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authResult, err := oidc.AuthFunc(bearerMetadata(issuer.sign(t, issuer.claims())))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected %v, got %v", testClientName, authResult.ClientIdentifier)
+	}
+
+	if !reflect.DeepEqual(authResult.Permissions, []string{targetMethodName}) {
+		t.Fatalf("expected %v, got %v", []string{targetMethodName}, authResult.Permissions)
+	}
+}
+
+func TestOIDCRejectsInvalidTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	oidc, err := NewOIDC(context.Background(), issuer.url(t), testAudience)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrongAudience := issuer.claims()
+	wrongAudience["aud"] = "https://other.example.com"
+	wrongIssuer := issuer.claims()
+	wrongIssuer["iss"] = "https://evil.example.com"
+	expired := issuer.claims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, issuer.claims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := map[string]string{
+		"wrong audience": issuer.sign(t, wrongAudience),
+		"wrong issuer":   issuer.sign(t, wrongIssuer),
+		"expired":        issuer.sign(t, expired),
+		"unsigned":       unsigned,
+	}
+
+	for name, token := range tokens {
+		_, err := oidc.AuthFunc(bearerMetadata(token))
+		if err == nil {
+			t.Fatalf("expected error for %s token", name)
+		}
+	}
+}
+
+func TestNewOIDCRejectsIssuerMismatch(t *testing.T) {
+	issuer := newTestIssuer(t)
+	u := issuer.url(t)
+	u.Path = "/other"
+	_, err := NewOIDC(context.Background(), u, testAudience)
+	if err == nil {
+		t.Fatalf("expected error with mismatched issuer")
+	}
+}
+
+// countingTransport counts the requests made with it.
+type countingTransport struct {
+	requests int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.requests, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestOIDCUsesContextHTTPClient(t *testing.T) {
+	issuer := newTestIssuer(t)
+	transport := &countingTransport{}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
+	oidc, err := NewOIDC(ctx, issuer.url(t), testAudience)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = oidc.AuthFunc(bearerMetadata(issuer.sign(t, issuer.claims())))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests := atomic.LoadInt32(&transport.requests); requests != 2 {
+		t.Fatalf("expected discovery and the JWKS to be fetched with the context's client, got %d requests", requests)
+	}
+}
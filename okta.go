@@ -0,0 +1,102 @@
+package grpcauth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2/clientcredentials"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/oauth"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// OktaDefaultAuthorizationServer is the ID of the custom authorization server every Okta org comes with.
+	OktaDefaultAuthorizationServer = "default"
+
+	// oktaScopeClaim is the claim Okta puts an access token's scopes in.
+	oktaScopeClaim = "scp"
+)
+
+// OktaClientCredentials returns a grpc.DialOption that uses the client credentials flow with an Okta authorization server.
+// Callers can optionally pass the scopes they want for their client in the initial request to limit a client's privileges.
+func OktaClientCredentials(ctx context.Context, clientID, clientSecret string, orgURL *url.URL, authorizationServerID string, scopes ...string) grpc.DialOption {
+	config := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     oktaIssuer(orgURL, authorizationServerID) + "/v1/token",
+		Scopes:       scopes,
+	}
+	return grpc.WithPerRPCCredentials(oauth.TokenSource{TokenSource: clientCredentialsTokenSource(ctx, config)})
+}
+
+// Okta authenticates incoming gRPC requests carrying access tokens from an Okta custom authorization server.
+// See https://developer.okta.com/docs/guides/validate-access-tokens/ for more details.
+type Okta struct {
+	OrgURL                *url.URL
+	AuthorizationServerID string
+	Audience              string
+	// ScopeClaims are the claims that will be mapped into AuthResult.Permissions.
+	// Okta puts scopes in the scp claim, but custom claims added to the authorization server can be used as well.
+	// It defaults to scp.
+	ScopeClaims []string
+
+	JWTValidation
+}
+
+// AuthFunc satisfies the AuthFunc interface so clients can use Okta with a gRPC server.
+func (o *Okta) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	issuer, err := url.Parse(oktaIssuer(o.OrgURL, o.AuthorizationServerID))
+	if err != nil {
+		return nil, err
+	}
+
+	jwksURL, err := url.Parse(issuer.String() + "/v1/keys")
+	if err != nil {
+		return nil, err
+	}
+
+	oidc := &OIDC{
+		Issuer:        issuer,
+		Audience:      o.Audience,
+		JWKSURL:       jwksURL,
+		JWTValidation: o.JWTValidation,
+	}
+	claims, err := oidc.verify(md)
+	if err != nil {
+		return nil, err
+	}
+
+	authResult, err := authResultFromClaims(claims, o.Leeway)
+	if err != nil {
+		return nil, err
+	}
+
+	// Okta puts the client ID in the cid claim.
+	if cid, ok := claims["cid"].(string); ok && cid != "" {
+		authResult.ClientIdentifier = cid
+	}
+
+	scopeClaims := o.ScopeClaims
+	if len(scopeClaims) == 0 {
+		scopeClaims = []string{oktaScopeClaim}
+	}
+
+	var permissions []string
+	for _, claim := range scopeClaims {
+		permissions = append(permissions, stringsFromClaim(claims[claim])...)
+	}
+	authResult.Permissions = permissions
+	return authResult, nil
+}
+
+// oktaIssuer returns the issuer URL for an Okta authorization server.
+func oktaIssuer(orgURL *url.URL, authorizationServerID string) string {
+	if authorizationServerID == "" {
+		authorizationServerID = OktaDefaultAuthorizationServer
+	}
+
+	return fmt.Sprintf("%s/oauth2/%s", strings.TrimSuffix(orgURL.String(), "/"), authorizationServerID)
+}
@@ -0,0 +1,46 @@
+package grpcauth
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestOktaMapsClientIDAndScopeClaims(t *testing.T) {
+	issuer := newTestIssuer(t)
+	okta := &Okta{
+		OrgURL:      issuer.url(t),
+		Audience:    testAudience,
+		ScopeClaims: []string{"scp", "groups"},
+	}
+
+	claims := issuer.claims()
+	delete(claims, "scope")
+	claims["iss"] = oktaIssuer(issuer.url(t), OktaDefaultAuthorizationServer)
+	claims["sub"] = "0oa-subject"
+	claims["cid"] = testClientName
+	claims["scp"] = []string{targetMethodName}
+	claims["groups"] = []string{"admins"}
+
+	authResult, err := okta.AuthFunc(bearerMetadata(issuer.sign(t, claims)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected %v, got %v", testClientName, authResult.ClientIdentifier)
+	}
+
+	expected := []string{targetMethodName, "admins"}
+	if !reflect.DeepEqual(authResult.Permissions, expected) {
+		t.Fatalf("expected %v, got %v", expected, authResult.Permissions)
+	}
+}
+
+func TestOktaIssuer(t *testing.T) {
+	orgURL, _ := url.Parse("https://example.okta.com/")
+	const expected = "https://example.okta.com/oauth2/aus123"
+	if issuer := oktaIssuer(orgURL, "aus123"); issuer != expected {
+		t.Fatalf("expected %v, got %v", expected, issuer)
+	}
+}
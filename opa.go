@@ -0,0 +1,82 @@
+package grpcauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// opaDataResponse is the response from OPA's Data API.
+// See https://www.openpolicyagent.org/docs/latest/rest-api/#get-a-document-with-input
+type opaDataResponse struct {
+	Result *bool `json:"result"`
+}
+
+// OPA authorizes requests with an Open Policy Agent Rego policy, so authorization logic can be managed as
+// policy-as-code.
+// The policy is evaluated by querying an OPA server, usually a sidecar, or in process with Evaluate.
+// Its input is:
+//
+//	{
+//	  "client_identifier": "billing",
+//	  "permissions": ["billing:read"],
+//	  "method": "/server.ServiceName/MethodName"
+//	}
+//
+// and the rule must evaluate to true for the request to be allowed. Errors and undefined results deny the request.
+// Use it with WithAuthorizationFunc.
+type OPA struct {
+	// URL is the address of the OPA server, such as http://localhost:8181.
+	URL *url.URL
+	// Path is the path of the rule to evaluate, such as grpcauth/allow.
+	Path string
+	// Evaluate evaluates the policy in process instead of querying an OPA server, such as with a prepared
+	// github.com/open-policy-agent/opa/rego query.
+	Evaluate func(ctx context.Context, input map[string]interface{}) (bool, error)
+	// Client queries the OPA server. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// AuthorizationFunc satisfies the AuthorizationFunc interface by evaluating the policy for the request.
+// Requests are denied if the policy can't be evaluated.
+func (o *OPA) AuthorizationFunc(ctx context.Context, authResult *AuthResult, methodName string) bool {
+	allowed, err := o.CheckedAuthorizationFunc(ctx, authResult, methodName)
+	return err == nil && allowed
+}
+
+// CheckedAuthorizationFunc satisfies the CheckedAuthorizationFunc interface by evaluating the policy for the
+// request, so clients get Unavailable instead of PermissionDenied when OPA can't be reached.
+func (o *OPA) CheckedAuthorizationFunc(ctx context.Context, authResult *AuthResult, methodName string) (bool, error) {
+	allowed, err := o.evaluate(ctx, opaInput(authResult, methodName))
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrAuthorizationUnavailable, err)
+	}
+
+	return allowed, nil
+}
+
+// opaInput is the input document the policy is evaluated with.
+func opaInput(authResult *AuthResult, methodName string) map[string]interface{} {
+	return map[string]interface{}{
+		"client_identifier": authResult.ClientIdentifier,
+		"permissions":       authResult.Permissions,
+		"method":            methodName,
+	}
+}
+
+func (o *OPA) evaluate(ctx context.Context, input map[string]interface{}) (bool, error) {
+	if o.Evaluate != nil {
+		return o.Evaluate(ctx, input)
+	}
+
+	var data opaDataResponse
+	dataURL := strings.TrimSuffix(o.URL.String(), "/") + "/v1/data/" + strings.Trim(o.Path, "/")
+	err := postJSON(ctx, o.Client, dataURL, "", map[string]interface{}{"input": input}, &data)
+	if err != nil {
+		return false, err
+	}
+
+	return data.Result != nil && *data.Result, nil
+}
@@ -0,0 +1,48 @@
+package grpcauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestOPAQueriesDataAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/data/grpcauth/allow" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var body struct {
+			Input struct {
+				ClientIdentifier string `json:"client_identifier"`
+				Method           string `json:"method"`
+			} `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		// Stands in for a policy like: allow { input.client_identifier == "testClient" }
+		allowed := body.Input.ClientIdentifier == testClientName && body.Input.Method == targetMethodName
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": allowed})
+	}))
+	defer server.Close()
+
+	opaURL, _ := url.Parse(server.URL)
+	opa := &OPA{URL: opaURL, Path: "grpcauth/allow"}
+	ctx := context.Background()
+	if !opa.AuthorizationFunc(ctx, testUnpermissionedAuthResult, targetMethodName) {
+		t.Fatalf("expected policy to allow %s", targetMethodName)
+	}
+
+	if opa.AuthorizationFunc(ctx, testUnpermissionedAuthResult, "/server.ServiceName/OtherMethod") {
+		t.Fatalf("expected policy to deny other methods")
+	}
+
+	opa.Path = "grpcauth/missing"
+	if opa.AuthorizationFunc(ctx, testUnpermissionedAuthResult, targetMethodName) {
+		t.Fatalf("expected errors to deny the request")
+	}
+}
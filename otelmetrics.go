@@ -0,0 +1,47 @@
+package grpcauth
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// OTelRequestsMetric counts the requests an Authority authenticated and authorized, by method and outcome.
+	OTelRequestsMetric = "grpcauth.requests"
+
+	// OTelDurationMetric is how long authenticating and authorizing requests took in seconds, by method and outcome.
+	OTelDurationMetric = "grpcauth.duration"
+)
+
+// OTelMetrics returns a Logger that records every decision and how long it took with meter, for teams collecting
+// metrics with OpenTelemetry. Pass it to an Authority with WithLogger.
+// Metrics have the LogKeyMethod and LogKeyOutcome attributes, but not the client identifier, since every client
+// would make a new time series.
+func OTelMetrics(meter metric.Meter) (Logger, error) {
+	requests, err := meter.Int64Counter(OTelRequestsMetric,
+		metric.WithDescription("Requests authenticated and authorized by grpcauth."),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := meter.Float64Histogram(OTelDurationMetric,
+		metric.WithDescription("Time taken to authenticate and authorize requests."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoggerFunc(func(ctx context.Context, event *AuthEvent) {
+		attributes := metric.WithAttributes(
+			attribute.String(LogKeyMethod, event.MethodName),
+			attribute.String(LogKeyOutcome, event.Outcome()),
+		)
+		requests.Add(ctx, 1, attributes)
+		duration.Record(ctx, event.Latency.Seconds(), attributes)
+	}), nil
+}
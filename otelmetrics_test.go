@@ -0,0 +1,52 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestOTelMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	metrics, err := OTelMetrics(provider.Meter("grpcauth"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	a := NewAuthority(alwaysAuthenticatedNoPermissions, nil, WithLogger(metrics)).(*authority)
+	a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+
+	var rm metricdata.ResourceMetrics
+	err = reader.Collect(context.Background(), &rm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := map[string]bool{}
+	for _, scopeMetrics := range rm.ScopeMetrics {
+		for _, m := range scopeMetrics.Metrics {
+			found[m.Name] = true
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				continue
+			}
+
+			point := sum.DataPoints[0]
+			outcome, _ := point.Attributes.Value(attribute.Key(LogKeyOutcome))
+			if point.Value != 2 || outcome.AsString() != OutcomeDenied {
+				t.Fatalf("expected 2 denied requests, got %v %v", point.Value, outcome.AsString())
+			}
+		}
+	}
+
+	if !found[OTelRequestsMetric] || !found[OTelDurationMetric] {
+		t.Fatalf("expected request and duration metrics, got %v", found)
+	}
+}
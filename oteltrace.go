@@ -0,0 +1,100 @@
+package grpcauth
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// otelTracerName is the instrumentation name of the Authority's tracer.
+	otelTracerName = "github.com/joncooperworks/grpcauth"
+
+	// otelSpanName is the name of the span around authenticating and authorizing a request.
+	otelSpanName = "grpcauth.Authenticate"
+
+	// The attributes added to the request's span.
+	otelClientAttribute  = "grpcauth.client"
+	otelOutcomeAttribute = "grpcauth.outcome"
+)
+
+// WithTracerProvider makes the Authority trace authenticating and authorizing each request with a child span of the
+// request's span, so slow identity provider lookups show up in traces. The request's span is annotated with the
+// client's identifier and the decision.
+// ContextAuthFuncs get the child span's context, so HTTP requests they make with it and a client using
+// TraceContextTransport are traced too.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(a *authority) {
+		a.Tracer = provider.Tracer(otelTracerName)
+	}
+}
+
+// TraceContextTransport returns an http.RoundTripper that adds the trace context from each request's context to its
+// headers with the global OpenTelemetry propagator, so calls to identity providers join the request's trace.
+// base defaults to http.DefaultTransport.
+func TraceContextTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+		return base.RoundTrip(req)
+	})
+}
+
+// roundTripperFunc satisfies the http.RoundTripper interface with a function.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip satisfies the http.RoundTripper interface.
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// authSpan is the span around authenticating and authorizing a request, and the request's span it is a child of.
+type authSpan struct {
+	span          trace.Span
+	parent        trace.Span
+	pseudonymizer Pseudonymizer
+}
+
+// startSpan starts a span for authenticating and authorizing a request if the Authority has a Tracer.
+func (a *authority) startSpan(ctx context.Context, methodName string) (context.Context, *authSpan) {
+	if a.Tracer == nil {
+		return ctx, nil
+	}
+
+	parent := trace.SpanFromContext(ctx)
+	ctx, span := a.Tracer.Start(ctx, otelSpanName, trace.WithAttributes(attribute.String(LogKeyMethod, methodName)))
+	return ctx, &authSpan{span: span, parent: parent, pseudonymizer: a.Pseudonymizer}
+}
+
+// end records the decision on the span and the request's span, ends the span and returns ctx with the request's span,
+// so the handler's spans aren't children of the ended one.
+func (s *authSpan) end(ctx context.Context, err error) context.Context {
+	if s == nil {
+		return ctx
+	}
+
+	event := &AuthEvent{Err: err}
+	authResult, _ := GetAuthResult(ctx)
+	event.AuthResult = s.pseudonymizer.pseudonymize(authResult)
+	attributes := []attribute.KeyValue{
+		attribute.String(otelClientAttribute, event.ClientIdentifier()),
+		attribute.String(otelOutcomeAttribute, event.Outcome()),
+	}
+	s.span.SetAttributes(attributes...)
+	s.parent.SetAttributes(attributes...)
+	if err != nil {
+		s.span.SetStatus(otelcodes.Error, event.Outcome())
+	}
+	s.span.End()
+
+	return trace.ContextWithSpan(ctx, s.parent)
+}
@@ -0,0 +1,86 @@
+package grpcauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAuthorityTracesAuthentication(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "bearer words"))
+
+	a := NewAuthority(alwaysAuthenticatedAllPermissions, nil, WithTracerProvider(provider)).(*authority)
+	ctx, err := a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trace.SpanFromContext(ctx) != parent {
+		t.Fatalf("expected the handler's context to have the request's span")
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != otelSpanName {
+		t.Fatalf("expected an authentication span, got %v", spans)
+	}
+
+	if spans[0].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("expected the authentication span to be a child of the request's span")
+	}
+
+	expected := attribute.String(otelOutcomeAttribute, OutcomeAllowed)
+	for _, span := range spans {
+		found := false
+		for _, attr := range span.Attributes() {
+			found = found || attr == expected
+		}
+
+		if !found {
+			t.Fatalf("expected %s to have %v, got %v", span.Name(), expected, span.Attributes())
+		}
+	}
+}
+
+func TestTraceContextTransport(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	provider := sdktrace.NewTracerProvider()
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: TraceContextTransport(nil)}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if traceparent == "" {
+		t.Fatalf("expected trace context to be propagated")
+	}
+}
@@ -1,10 +1,46 @@
 package grpcauth
 
+import (
+	"context"
+	"strings"
+)
+
 // PermissionFunc determines if an authenticated client is authorized to access a particular gRPC method.
 // It allows users to override the default permission behaviour that requires a permission with the full gRPC
 // method name be sent over during authentication.
 type PermissionFunc func(permissions []string, methodName string) bool
 
+// AuthResultPermissionFunc determines if an authenticated client is authorized to access a particular gRPC method
+// from its whole AuthResult, so decisions can use the ClientIdentifier and Timestamp as well as its permissions.
+// Use it with WithAuthResultPermissionFunc, and AuthResultPermissions to adapt an existing PermissionFunc.
+type AuthResultPermissionFunc func(authResult *AuthResult, methodName string) bool
+
+// AuthResultPermissions adapts a PermissionFunc to an AuthResultPermissionFunc that checks the AuthResult's
+// permissions.
+func AuthResultPermissions(permissionFunc PermissionFunc) AuthResultPermissionFunc {
+	return func(authResult *AuthResult, methodName string) bool {
+		return permissionFunc(authResult.Permissions, methodName)
+	}
+}
+
+// AuthorizationFunc determines if an authenticated client is authorized to access a particular gRPC method.
+// Unlike a PermissionFunc, it sees the whole AuthResult and the request's context, so decisions can be made with the
+// client's identity or delegated to a policy engine. Use it with WithAuthorizationFunc.
+type AuthorizationFunc func(ctx context.Context, authResult *AuthResult, methodName string) bool
+
+// CheckedAuthorizationFunc is an AuthorizationFunc that can fail, so an unreachable policy store or a timeout isn't
+// reported to clients as a deliberate PermissionDenied.
+// Errors that are gRPC statuses are returned to the client as is, errors wrapping ErrAuthorizationUnavailable or
+// context.DeadlineExceeded become Unavailable and any other error becomes Internal.
+// Use it with WithCheckedAuthorizationFunc.
+type CheckedAuthorizationFunc func(ctx context.Context, authResult *AuthResult, methodName string) (bool, error)
+
+// RequestAuthorizationFunc determines if an authenticated client may send a request message, enabling attribute
+// based decisions like "a client may only query its own tenant ID".
+// It is called with the decoded request message for unary calls, and with every message the client sends on
+// streams. Use it with WithRequestAuthorizationFunc.
+type RequestAuthorizationFunc func(ctx context.Context, authResult *AuthResult, methodName string, req interface{}) bool
+
 // NoPermissions permits a gRPC client unlimited access to all methods on the server as long as they have no permissions.
 // It allows for servers that grant authenticated clients access to all methods on a gRPC server.
 // It will fail if a client has permissions.
@@ -15,6 +51,12 @@ func NoPermissions(permissions []string, methodName string) bool {
 	return true
 }
 
+// MethodPermissions allows a client to call a method only if one of its permissions is the full gRPC method name.
+// It is the default permission behaviour, exported so it can be combined with other PermissionFuncs.
+func MethodPermissions(permissions []string, methodName string) bool {
+	return defaultHasPermissions(permissions, methodName)
+}
+
 func defaultHasPermissions(permissions []string, methodName string) bool {
 	for _, permission := range permissions {
 		if permission == methodName {
@@ -24,3 +66,110 @@ func defaultHasPermissions(permissions []string, methodName string) bool {
 
 	return false
 }
+
+// WildcardPermissions allows a client to call a method if one of its permissions is the method name or a pattern
+// matching it, such as `/server.ServiceName/*` for every method on a service or `/server.*` for every service in
+// a package. See MatchMethod for how patterns are matched.
+func WildcardPermissions(permissions []string, methodName string) bool {
+	for _, permission := range permissions {
+		if MatchMethod(permission, methodName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchMethod reports whether a full gRPC method name like `/server.ServiceName/MethodName` matches pattern.
+// Patterns ending in `*` match any method name starting with the rest of the pattern, so `*` matches every method.
+// Any other pattern must be the exact method name.
+func MatchMethod(pattern, methodName string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(methodName, strings.TrimSuffix(pattern, "*"))
+	}
+
+	return pattern == methodName
+}
+
+// ImpliedPermissions wraps permissionFunc so a permission also grants every permission it implies, such as
+// `service:admin` implying `service:read` and `service:write`, and clients don't need to carry every leaf scope.
+// Implications are followed transitively. The default permission behaviour is wrapped if permissionFunc is nil.
+func ImpliedPermissions(implications map[string][]string, permissionFunc PermissionFunc) PermissionFunc {
+	if permissionFunc == nil {
+		permissionFunc = defaultHasPermissions
+	}
+
+	return func(permissions []string, methodName string) bool {
+		return permissionFunc(ExpandPermissions(implications, permissions), methodName)
+	}
+}
+
+// ExpandPermissions returns permissions along with every permission they imply.
+func ExpandPermissions(implications map[string][]string, permissions []string) []string {
+	seen := make(map[string]bool, len(permissions))
+	expanded := make([]string, 0, len(permissions))
+	pending := append([]string(nil), permissions...)
+	for len(pending) > 0 {
+		permission := pending[0]
+		pending = pending[1:]
+		if seen[permission] {
+			continue
+		}
+
+		seen[permission] = true
+		expanded = append(expanded, permission)
+		pending = append(pending, implications[permission]...)
+	}
+
+	return expanded
+}
+
+// AllOf allows a client to call a method only if every one of permissionFuncs allows it.
+func AllOf(permissionFuncs ...PermissionFunc) PermissionFunc {
+	return func(permissions []string, methodName string) bool {
+		for _, permissionFunc := range permissionFuncs {
+			if !permissionFunc(permissions, methodName) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// AnyOf allows a client to call a method if any one of permissionFuncs allows it, such as
+// `AnyOf(MethodPermissions, HasAnyPermission("admin"))`.
+func AnyOf(permissionFuncs ...PermissionFunc) PermissionFunc {
+	return func(permissions []string, methodName string) bool {
+		for _, permissionFunc := range permissionFuncs {
+			if permissionFunc(permissions, methodName) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Not allows a client to call a method only if permissionFunc doesn't allow it, so it can be combined with AllOf to
+// carve exceptions out of broader permissions.
+func Not(permissionFunc PermissionFunc) PermissionFunc {
+	return func(permissions []string, methodName string) bool {
+		return !permissionFunc(permissions, methodName)
+	}
+}
+
+// HasAnyPermission allows a client to call any method if it has one of required, such as an `admin` permission.
+func HasAnyPermission(required ...string) PermissionFunc {
+	return func(permissions []string, methodName string) bool {
+		for _, permission := range permissions {
+			for _, r := range required {
+				if permission == r {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+}
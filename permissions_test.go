@@ -0,0 +1,73 @@
+package grpcauth
+
+import (
+	"testing"
+)
+
+func TestWildcardPermissions(t *testing.T) {
+	tests := []struct {
+		permission string
+		expected   bool
+	}{
+		{targetMethodName, true},
+		{"/server.ServiceName/*", true},
+		{"/server.*", true},
+		{"*", true},
+		{"/server.ServiceName/OtherMethod", false},
+		{"/server.OtherService/*", false},
+		{"/server.ServiceName/Method", false},
+	}
+
+	for _, test := range tests {
+		if WildcardPermissions([]string{test.permission}, targetMethodName) != test.expected {
+			t.Fatalf("expected %s to match %s: %v", test.permission, targetMethodName, test.expected)
+		}
+	}
+}
+
+func TestImpliedPermissions(t *testing.T) {
+	implications := map[string][]string{
+		"service:admin": {"service:write"},
+		"service:write": {"service:read", targetMethodName},
+		// Cycles must not loop forever.
+		"service:read": {"service:admin"},
+	}
+
+	expanded := ExpandPermissions(implications, []string{"service:admin"})
+	if len(expanded) != 4 {
+		t.Fatalf("expected service:admin to expand to 4 permissions, got %v", expanded)
+	}
+
+	permissionFunc := ImpliedPermissions(implications, nil)
+	if !permissionFunc([]string{"service:admin"}, targetMethodName) {
+		t.Fatalf("expected service:admin to imply %s", targetMethodName)
+	}
+
+	if ImpliedPermissions(map[string][]string{"service:admin": {"service:read"}}, nil)([]string{"service:admin"}, targetMethodName) {
+		t.Fatalf("expected service:admin not to imply %s", targetMethodName)
+	}
+}
+
+func TestPermissionCombinators(t *testing.T) {
+	permissionFunc := AnyOf(MethodPermissions, HasAnyPermission("admin"))
+	if !permissionFunc([]string{targetMethodName}, targetMethodName) {
+		t.Fatalf("expected %s to be allowed by its method name", targetMethodName)
+	}
+
+	if !permissionFunc([]string{"admin"}, targetMethodName) {
+		t.Fatalf("expected admin to be allowed to call %s", targetMethodName)
+	}
+
+	if permissionFunc([]string{"user"}, targetMethodName) {
+		t.Fatalf("expected user not to be allowed to call %s", targetMethodName)
+	}
+
+	permissionFunc = AllOf(WildcardPermissions, Not(HasAnyPermission("suspended")))
+	if !permissionFunc([]string{"*"}, targetMethodName) {
+		t.Fatalf("expected * to be allowed to call %s", targetMethodName)
+	}
+
+	if permissionFunc([]string{"*", "suspended"}, targetMethodName) {
+		t.Fatalf("expected suspended clients not to be allowed to call %s", targetMethodName)
+	}
+}
@@ -0,0 +1,139 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// pkceCallbackPath is the path of the local redirect URI the authorization code is sent to.
+	pkceCallbackPath = "/callback"
+
+	// defaultPKCEListenAddress is the loopback address the local redirect URI listens on by default.
+	defaultPKCEListenAddress = "127.0.0.1:0"
+)
+
+// AuthorizationCodePKCE signs a user in with the authorization code flow and PKCE, receiving the code on a local
+// redirect URI, for desktop tools and developer utilities that call gRPC servers as the user instead of with client
+// credentials.
+// See https://datatracker.ietf.org/doc/html/rfc8252 and https://datatracker.ietf.org/doc/html/rfc7636 for more details.
+type AuthorizationCodePKCE struct {
+	// Config is the public client's ClientID, Endpoint and Scopes.
+	// Its RedirectURL is replaced with the local redirect URI, which must be allowed by the identity provider.
+	Config *oauth2.Config
+	// OpenURL sends the user to the authorization URL, usually by opening it in their browser.
+	OpenURL func(authorizationURL string) error
+	// ListenAddress is the loopback address the local redirect URI listens on.
+	// It defaults to 127.0.0.1 on a random port.
+	ListenAddress string
+}
+
+// Token signs the user in and returns their token, which includes a refresh token if the identity provider issued one.
+// It blocks until the identity provider redirects back to the local redirect URI or ctx is done.
+func (a *AuthorizationCodePKCE) Token(ctx context.Context) (*oauth2.Token, error) {
+	listenAddress := a.ListenAddress
+	if listenAddress == "" {
+		listenAddress = defaultPKCEListenAddress
+	}
+
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	config := *a.Config
+	config.RedirectURL = fmt.Sprintf("http://%s%s", listener.Addr(), pkceCallbackPath)
+
+	state, err := randomURLString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := randomURLString(32)
+	if err != nil {
+		return nil, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	type callback struct {
+		code string
+		err  error
+	}
+	callbacks := make(chan callback, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(pkceCallbackPath, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return
+		}
+
+		result := callback{code: query.Get("code")}
+		if e := query.Get("error"); e != "" {
+			result.err = fmt.Errorf("authorization failed: %s: %s", e, query.Get("error_description"))
+			http.Error(w, "Sign in failed. You can close this window.", http.StatusUnauthorized)
+		} else {
+			w.Write([]byte("Signed in. You can close this window."))
+		}
+
+		select {
+		case callbacks <- result:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authorizationURL := config.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+	err = a.OpenURL(authorizationURL)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-callbacks:
+		if result.err != nil {
+			return nil, result.err
+		}
+
+		return config.Exchange(ctx, result.code, oauth2.SetAuthURLParam("code_verifier", verifier))
+	}
+}
+
+// TokenSource signs the user in and returns an oauth2.TokenSource for their tokens, which uses the refresh token to
+// get new access tokens when they expire.
+// Use it with grpc.WithPerRPCCredentials and oauth.TokenSource, or TokenUnaryClientInterceptor and
+// TokenStreamClientInterceptor.
+func (a *AuthorizationCodePKCE) TokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	token, err := a.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.Config.TokenSource(ctx, token), nil
+}
+
+// randomURLString returns n random bytes encoded as unpadded base64url.
+func randomURLString(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
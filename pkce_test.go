@@ -0,0 +1,86 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestAuthorizationCodePKCE(t *testing.T) {
+	var challenge string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("code_challenge_method") != "S256" {
+			http.Error(w, "PKCE required", http.StatusBadRequest)
+			return
+		}
+		challenge = query.Get("code_challenge")
+
+		redirect, _ := url.Parse(query.Get("redirect_uri"))
+		redirect.RawQuery = url.Values{"code": {"code"}, "state": {query.Get("state")}}.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if r.FormValue("code") != "code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"words","token_type":"Bearer","refresh_token":"refresh","expires_in":3600}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	pkce := &AuthorizationCodePKCE{
+		Config: &oauth2.Config{
+			ClientID: testClientName,
+			Endpoint: oauth2.Endpoint{AuthURL: server.URL + "/authorize", TokenURL: server.URL + "/token"},
+		},
+		// Follow the redirects like the user's browser would.
+		OpenURL: func(authorizationURL string) error {
+			resp, err := http.Get(authorizationURL)
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		},
+	}
+
+	token, err := pkce.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken != "words" || token.RefreshToken != "refresh" {
+		t.Fatalf("expected access and refresh tokens, got %v", token)
+	}
+}
+
+func TestAuthorizationCodePKCEReportsErrors(t *testing.T) {
+	pkce := &AuthorizationCodePKCE{
+		Config: &oauth2.Config{ClientID: testClientName},
+		OpenURL: func(authorizationURL string) error {
+			u, _ := url.Parse(authorizationURL)
+			redirect := u.Query().Get("redirect_uri") + "?" + url.Values{"error": {"access_denied"}, "state": {u.Query().Get("state")}}.Encode()
+			resp, err := http.Get(redirect)
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		},
+	}
+
+	_, err := pkce.Token(context.Background())
+	if err == nil {
+		t.Fatalf("expected denied sign in to fail")
+	}
+}
@@ -0,0 +1,170 @@
+package grpcauth
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is a permission policy that can be loaded from a file.
+// It is RBAC with a list of methods every authenticated client may call.
+type Policy struct {
+	// Roles maps each role name to the methods it may call, as method names or patterns matched with MatchMethod.
+	Roles map[string][]string `json:"roles" yaml:"roles"`
+	// Exempt are method names or patterns any authenticated client may call, whatever its roles.
+	Exempt []string `json:"exempt" yaml:"exempt"`
+	// Networks maps client identifiers to the CIDRs or IP addresses they may call from.
+	// Clients without networks may call from anywhere. Use it with WithClientNetworks.
+	Networks map[string][]string `json:"networks" yaml:"networks"`
+}
+
+// PermissionFunc satisfies the PermissionFunc interface by checking the exemptions, then the client's roles.
+func (p *Policy) PermissionFunc(roles []string, methodName string) bool {
+	if WildcardPermissions(p.Exempt, methodName) {
+		return true
+	}
+
+	rbac := &RBAC{Roles: p.Roles}
+	return rbac.PermissionFunc(roles, methodName)
+}
+
+// ParsePolicy parses a Policy from YAML or JSON.
+func ParsePolicy(b []byte) (*Policy, error) {
+	// JSON is valid YAML, so one parser handles both.
+	var policy Policy
+	err := yaml.Unmarshal(b, &policy)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateNetworks(policy.Networks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// PolicyFile is a Policy loaded from a YAML or JSON file that can be reloaded without restarting the server.
+// If a reload fails, the last policy that loaded successfully keeps being used.
+// It is safe for concurrent use.
+type PolicyFile struct {
+	Path string
+	// OnReloadError is called when a reload triggered by Watch or ReloadOnSignal fails.
+	OnReloadError func(err error)
+
+	mu      sync.RWMutex
+	policy  *Policy
+	modTime time.Time
+}
+
+// LoadPolicyFile loads the policy at path.
+//
+//	roles:
+//	  admin: ["/server.ServiceName/*"]
+//	  reader: ["/server.ServiceName/GetThing"]
+//	exempt: ["/grpc.health.v1.Health/*"]
+//	networks:
+//	  batch-job: ["10.0.0.0/8"]
+func LoadPolicyFile(path string) (*PolicyFile, error) {
+	f := &PolicyFile{Path: path}
+	err := f.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Reload reads the policy file again and starts using it if it is valid.
+func (f *PolicyFile) Reload() error {
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return err
+	}
+
+	policy, err := ParsePolicy(b)
+	if err != nil {
+		return fmt.Errorf("invalid policy file %s: %v", f.Path, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.policy = policy
+	f.modTime = info.ModTime()
+	return nil
+}
+
+// Policy returns the policy currently in use.
+func (f *PolicyFile) Policy() *Policy {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.policy
+}
+
+// PermissionFunc satisfies the PermissionFunc interface using the policy currently in use.
+func (f *PolicyFile) PermissionFunc(roles []string, methodName string) bool {
+	return f.Policy().PermissionFunc(roles, methodName)
+}
+
+// Watch checks the policy file for changes every interval and reloads it when it is modified, until ctx is done.
+// It polls instead of using file system notifications, so it also sees Kubernetes ConfigMap updates, which replace
+// the file through a symlink.
+func (f *PolicyFile) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(f.Path)
+			if err != nil {
+				f.reloadFailed(err)
+				continue
+			}
+
+			f.mu.RLock()
+			modified := !info.ModTime().Equal(f.modTime)
+			f.mu.RUnlock()
+			if modified {
+				f.reloadFailed(f.Reload())
+			}
+		}
+	}
+}
+
+// ReloadOnSignal reloads the policy file whenever the process receives one of signals, such as syscall.SIGHUP,
+// until ctx is done.
+func (f *PolicyFile) ReloadOnSignal(ctx context.Context, signals ...os.Signal) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, signals...)
+	defer signal.Stop(c)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c:
+			f.reloadFailed(f.Reload())
+		}
+	}
+}
+
+func (f *PolicyFile) reloadFailed(err error) {
+	if err != nil && f.OnReloadError != nil {
+		f.OnReloadError(err)
+	}
+}
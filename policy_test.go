@@ -0,0 +1,67 @@
+package grpcauth
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPolicyFileReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	err := ioutil.WriteFile(path, []byte(`
+roles:
+  reader: ["/server.ServiceName/GetThing"]
+exempt: ["/grpc.health.v1.Health/*"]
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policyFile, err := LoadPolicyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !policyFile.PermissionFunc(nil, "/grpc.health.v1.Health/Check") {
+		t.Fatalf("expected exempt method to be allowed")
+	}
+
+	if policyFile.PermissionFunc([]string{"reader"}, targetMethodName) {
+		t.Fatalf("expected reader not to be allowed to call %s", targetMethodName)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go policyFile.Watch(ctx, 10*time.Millisecond)
+
+	// JSON policies work too, and the modification time must change for Watch to notice.
+	err = ioutil.WriteFile(path, []byte(`{"roles": {"reader": ["/server.ServiceName/*"]}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !policyFile.PermissionFunc([]string{"reader"}, targetMethodName) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected policy to be reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err = ioutil.WriteFile(path, []byte(`roles: [`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if policyFile.Reload() == nil {
+		t.Fatalf("expected error reloading invalid policy")
+	}
+
+	if !policyFile.PermissionFunc([]string{"reader"}, targetMethodName) {
+		t.Fatalf("expected last valid policy to be kept")
+	}
+}
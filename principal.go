@@ -0,0 +1,34 @@
+package grpcauth
+
+import (
+	"context"
+)
+
+// principalKeyName is the key an Authority's principal is stored under in the context.
+const principalKeyName = "principal"
+
+// PrincipalFunc converts an authenticated client's AuthResult into an application's own principal type, like a
+// User struct loaded from a database. Returning an error rejects the request as Unauthenticated.
+type PrincipalFunc[T any] func(ctx context.Context, authResult *AuthResult) (T, error)
+
+// WithPrincipal makes the Authority call principalFunc for every authenticated client and put the principal it
+// returns in the context, so handlers can get it with GetPrincipal instead of being limited to AuthResult's fields.
+func WithPrincipal[T any](principalFunc PrincipalFunc[T]) Option {
+	return func(a *authority) {
+		a.Principal = func(ctx context.Context, authResult *AuthResult) (interface{}, error) {
+			return principalFunc(ctx, authResult)
+		}
+	}
+}
+
+// GetPrincipal returns the principal attached to a context by an Authority created with WithPrincipal, and
+// ErrUnauthenticatedContext if the context has no principal of type T.
+func GetPrincipal[T any](ctx context.Context) (T, error) {
+	principal, ok := ctx.Value(authContextKey(principalKeyName)).(T)
+	if !ok {
+		var zero T
+		return zero, ErrUnauthenticatedContext
+	}
+
+	return principal, nil
+}
@@ -0,0 +1,62 @@
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type testPrincipal struct {
+	Name     string
+	TenantID string
+}
+
+func TestAuthorityAttachesPrincipal(t *testing.T) {
+	principalFunc := func(ctx context.Context, authResult *AuthResult) (*testPrincipal, error) {
+		if authResult.ClientIdentifier != testClientName {
+			return nil, errors.New("unknown client")
+		}
+
+		return &testPrincipal{Name: authResult.ClientIdentifier, TenantID: "acme"}, nil
+	}
+	a := NewAuthority(alwaysAuthenticatedAllPermissions, nil, WithPrincipal(principalFunc))
+
+	md := metadata.Pairs("authorization", "bearer words")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	info := &grpc.UnaryServerInfo{FullMethod: targetMethodName}
+	_, err := a.UnaryServerInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		principal, err := GetPrincipal[*testPrincipal](ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if principal.TenantID != "acme" {
+			t.Fatalf("expected tenant acme, got %s", principal.TenantID)
+		}
+
+		_, err = GetPrincipal[string](ctx)
+		if !errors.Is(err, ErrUnauthenticatedContext) {
+			t.Fatalf("expected principal of the wrong type to be rejected, got %v", err)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a = NewAuthority(func(md metadata.MD) (*AuthResult, error) {
+		return &AuthResult{ClientIdentifier: "otherClient"}, nil
+	}, NoPermissions, WithPrincipal(principalFunc))
+	_, err = a.UnaryServerInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected unauthenticated, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package grpcauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// pseudonymSize is how many bytes of the HMAC are kept in pseudonyms from HMACPseudonymizer.
+const pseudonymSize = 16
+
+// Pseudonymizer replaces a value that could identify a person, such as a client identifier or email claim, with a
+// pseudonym. It should always return the same pseudonym for the same value, so events stay correlatable.
+type Pseudonymizer func(value string) string
+
+// HMACPseudonymizer returns a Pseudonymizer that replaces values with a hex encoded HMAC-SHA256 of them under key.
+// Unlike a plain hash, the pseudonyms can't be reversed by hashing every likely identifier without the key.
+// Empty values are left empty.
+func HMACPseudonymizer(key []byte) Pseudonymizer {
+	return func(value string) string {
+		if value == "" {
+			return ""
+		}
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil)[:pseudonymSize])
+	}
+}
+
+// pseudonymize returns a copy of authResult with its client identifier and string claims replaced with pseudonyms,
+// or authResult if pseudonymizer is nil.
+func (p Pseudonymizer) pseudonymize(authResult *AuthResult) *AuthResult {
+	if p == nil || authResult == nil {
+		return authResult
+	}
+
+	pseudonymized := *authResult
+	pseudonymized.ClientIdentifier = p(authResult.ClientIdentifier)
+	if authResult.Claims != nil {
+		pseudonymized.Claims = make(map[string]interface{}, len(authResult.Claims))
+		for name, value := range authResult.Claims {
+			pseudonymized.Claims[name] = p.pseudonymizeClaim(value)
+		}
+	}
+
+	return &pseudonymized
+}
+
+// pseudonymizeClaim replaces strings in a claim's value with pseudonyms.
+func (p Pseudonymizer) pseudonymizeClaim(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return p(v)
+	case []string:
+		values := make([]string, len(v))
+		for i, s := range v {
+			values[i] = p(s)
+		}
+		return values
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, element := range v {
+			values[i] = p.pseudonymizeClaim(element)
+		}
+		return values
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(v))
+		for name, element := range v {
+			values[name] = p.pseudonymizeClaim(element)
+		}
+		return values
+	}
+
+	return value
+}
@@ -0,0 +1,77 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHMACPseudonymizer(t *testing.T) {
+	pseudonymizer := HMACPseudonymizer([]byte("key"))
+	pseudonym := pseudonymizer(testClientName)
+	if pseudonym == testClientName || len(pseudonym) != 2*pseudonymSize {
+		t.Fatalf("expected a pseudonym, got %s", pseudonym)
+	}
+
+	if pseudonymizer(testClientName) != pseudonym {
+		t.Fatalf("expected the same value to get the same pseudonym")
+	}
+
+	if HMACPseudonymizer([]byte("other key"))(testClientName) == pseudonym {
+		t.Fatalf("expected different keys to give different pseudonyms")
+	}
+
+	if pseudonymizer("") != "" {
+		t.Fatalf("expected empty values to stay empty")
+	}
+}
+
+func TestAuthorityPseudonymizesLoggedClients(t *testing.T) {
+	pseudonymizer := HMACPseudonymizer([]byte("key"))
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		return &AuthResult{
+			ClientIdentifier: testClientName,
+			Permissions:      []string{targetMethodName},
+			Claims: map[string]interface{}{
+				"email":  "client@example.com",
+				"groups": []interface{}{"admins"},
+				"exp":    float64(4102444800),
+			},
+		}, nil
+	}
+
+	var event *AuthEvent
+	logger := LoggerFunc(func(ctx context.Context, e *AuthEvent) {
+		event = e
+	})
+
+	a := NewAuthority(authFunc, nil, WithLogger(logger), WithPseudonymizer(pseudonymizer)).(*authority)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	ctx, err := a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authResult, _ := GetAuthResult(ctx)
+	if authResult.ClientIdentifier != testClientName {
+		t.Fatalf("expected the handler to get the real client, got %s", authResult.ClientIdentifier)
+	}
+
+	if event.ClientIdentifier() != pseudonymizer(testClientName) {
+		t.Fatalf("expected the logged client to be pseudonymized, got %s", event.ClientIdentifier())
+	}
+
+	if event.AuthResult.StringClaim("email") != pseudonymizer("client@example.com") {
+		t.Fatalf("expected string claims to be pseudonymized, got %v", event.AuthResult.Claims)
+	}
+
+	groups := event.AuthResult.Claims["groups"].([]interface{})
+	if groups[0] != pseudonymizer("admins") || event.AuthResult.Claims["exp"] != float64(4102444800) {
+		t.Fatalf("expected only strings in claims to be pseudonymized, got %v", event.AuthResult.Claims)
+	}
+
+	if authResult.StringClaim("email") != "client@example.com" {
+		t.Fatalf("expected the handler's claims to be left alone")
+	}
+}
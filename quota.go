@@ -0,0 +1,230 @@
+package grpcauth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// defaultRedisQuotaPrefix is put in front of quota counters in Redis by default.
+	defaultRedisQuotaPrefix = "grpcauth:quota:"
+	// quotaSweepInterval is how often an InMemoryQuotaCounter forgets counts that have expired.
+	quotaSweepInterval = time.Minute
+)
+
+// Quota limits how many calls each client can make, like the allowance in an API plan.
+// The Authority checks it after the client is authorized, so only calls that would have been served are counted.
+// Use it with WithQuota.
+type Quota interface {
+	// Use counts a call to methodName by the client with authResult, and returns a ResourceExhausted status if the
+	// client is over its quota. Other errors are returned to the client as Unavailable.
+	Use(ctx context.Context, authResult *AuthResult, methodName string) error
+}
+
+// QuotaFunc satisfies the Quota interface with a function.
+type QuotaFunc func(ctx context.Context, authResult *AuthResult, methodName string) error
+
+// Use satisfies the Quota interface.
+func (f QuotaFunc) Use(ctx context.Context, authResult *AuthResult, methodName string) error {
+	return f(ctx, authResult, methodName)
+}
+
+// QuotaLimit allows Calls calls in each Period.
+type QuotaLimit struct {
+	Calls  int64
+	Period time.Duration
+}
+
+// unlimited reports whether the limit doesn't restrict calls.
+func (l QuotaLimit) unlimited() bool {
+	return l.Calls <= 0 || l.Period <= 0
+}
+
+// QuotaCounter counts calls for a CallQuota.
+type QuotaCounter interface {
+	// Increment adds a call to key's count, which is forgotten at expiry, and returns the new count.
+	Increment(ctx context.Context, key string, expiry time.Time) (int64, error)
+}
+
+// CallQuota is a Quota that allows each client a number of calls in each period, counted in fixed windows that
+// start at multiples of the period since the Unix epoch.
+// A client's limit comes from Clients, then the Plans entry named by its PlanClaim, then Limit.
+type CallQuota struct {
+	// Limit applies to clients without a limit in Clients or Plans. Clients aren't limited if it is the zero value.
+	Limit QuotaLimit
+	// Clients overrides Limit for client identifiers.
+	Clients map[string]QuotaLimit
+	// PlanClaim is the claim naming the client's plan in Plans, like a tier claim added by the identity provider.
+	PlanClaim string
+	// Plans maps plan names to their limits.
+	Plans map[string]QuotaLimit
+	// Counter counts calls. Use a shared counter such as RedisQuotaCounter to count calls across servers.
+	// It defaults to an InMemoryQuotaCounter.
+	Counter QuotaCounter
+
+	once          sync.Once
+	memoryCounter *InMemoryQuotaCounter
+}
+
+// Use satisfies the Quota interface.
+func (q *CallQuota) Use(ctx context.Context, authResult *AuthResult, methodName string) error {
+	limit := q.limitFor(authResult)
+	if limit.unlimited() {
+		return nil
+	}
+
+	now := time.Now()
+	reset := now.Truncate(limit.Period).Add(limit.Period)
+	key := authResult.ClientIdentifier + ":" + strconv.FormatInt(reset.Unix(), 10)
+	count, err := q.counter().Increment(ctx, key, reset)
+	if err != nil {
+		return status.Error(codes.Unavailable, "quota unavailable")
+	}
+
+	if count <= limit.Calls {
+		return nil
+	}
+
+	return quotaExceededStatus(authResult.ClientIdentifier, limit, reset.Sub(now))
+}
+
+// limitFor returns the limit for the client with authResult.
+func (q *CallQuota) limitFor(authResult *AuthResult) QuotaLimit {
+	if limit, ok := q.Clients[authResult.ClientIdentifier]; ok {
+		return limit
+	}
+
+	if q.PlanClaim != "" {
+		if limit, ok := q.Plans[authResult.StringClaim(q.PlanClaim)]; ok {
+			return limit
+		}
+	}
+
+	return q.Limit
+}
+
+// counter returns the CallQuota's Counter, or an InMemoryQuotaCounter if it doesn't have one.
+func (q *CallQuota) counter() QuotaCounter {
+	if q.Counter != nil {
+		return q.Counter
+	}
+
+	q.once.Do(func() {
+		q.memoryCounter = &InMemoryQuotaCounter{}
+	})
+	return q.memoryCounter
+}
+
+// quotaExceededStatus returns a ResourceExhausted status for a client over limit, telling it to retry after wait.
+func quotaExceededStatus(clientIdentifier string, limit QuotaLimit, wait time.Duration) error {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("quota exceeded, retry after %s", wait))
+	st = withErrorInfo(st, ReasonQuotaExceeded, map[string]string{
+		"clientIdentifier": clientIdentifier,
+		"calls":            strconv.FormatInt(limit.Calls, 10),
+		"period":           limit.Period.String(),
+	})
+
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// quotaErrorStatus converts an error from a Quota to a gRPC status.
+func quotaErrorStatus(err error) error {
+	if st, ok := status.FromError(err); ok {
+		return st.Err()
+	}
+
+	return status.Error(codes.Unavailable, "quota unavailable")
+}
+
+// InMemoryQuotaCounter is a QuotaCounter for a single server. It is safe for concurrent use.
+type InMemoryQuotaCounter struct {
+	mu        sync.Mutex
+	counts    map[string]*quotaCount
+	lastSweep time.Time
+}
+
+// quotaCount is how many calls were counted for a key, and when the count is forgotten.
+type quotaCount struct {
+	calls  int64
+	expiry time.Time
+}
+
+// Increment satisfies the QuotaCounter interface.
+func (c *InMemoryQuotaCounter) Increment(ctx context.Context, key string, expiry time.Time) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.sweep(now)
+
+	count, ok := c.counts[key]
+	if !ok || !now.Before(count.expiry) {
+		count = &quotaCount{expiry: expiry}
+		c.counts[key] = count
+	}
+
+	count.calls++
+	return count.calls, nil
+}
+
+// sweep forgets counts that have expired, at most once per quotaSweepInterval.
+// It must be called with c.mu held.
+func (c *InMemoryQuotaCounter) sweep(now time.Time) {
+	if c.counts == nil {
+		c.counts = map[string]*quotaCount{}
+	}
+
+	if now.Sub(c.lastSweep) < quotaSweepInterval {
+		return
+	}
+
+	c.lastSweep = now
+	for key, count := range c.counts {
+		if !now.Before(count.expiry) {
+			delete(c.counts, key)
+		}
+	}
+}
+
+// RedisQuotaCounter is a QuotaCounter backed by Redis, so a client's quota applies across every server.
+type RedisQuotaCounter struct {
+	Client redis.Cmdable
+	// KeyPrefix is put in front of keys to make Redis keys.
+	// It defaults to grpcauth:quota:.
+	KeyPrefix string
+}
+
+// Increment satisfies the QuotaCounter interface.
+func (c *RedisQuotaCounter) Increment(ctx context.Context, key string, expiry time.Time) (int64, error) {
+	var calls *redis.IntCmd
+	_, err := c.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		calls = pipe.Incr(ctx, c.key(key))
+		pipe.ExpireAt(ctx, c.key(key), expiry)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return calls.Val(), nil
+}
+
+func (c *RedisQuotaCounter) key(key string) string {
+	if c.KeyPrefix == "" {
+		return defaultRedisQuotaPrefix + key
+	}
+	return c.KeyPrefix + key
+}
@@ -0,0 +1,118 @@
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestInMemoryQuotaCounter(t *testing.T) {
+	counter := &InMemoryQuotaCounter{}
+	ctx := context.Background()
+	for i := int64(1); i <= 2; i++ {
+		calls, err := counter.Increment(ctx, "key", time.Now().Add(time.Minute))
+		if err != nil || calls != i {
+			t.Fatalf("expected %d calls, got %d %v", i, calls, err)
+		}
+	}
+
+	counter.Increment(ctx, "expired", time.Now().Add(-time.Second))
+	calls, _ := counter.Increment(ctx, "expired", time.Now().Add(time.Minute))
+	if calls != 1 {
+		t.Fatalf("expected counts to be forgotten once they expire, got %d", calls)
+	}
+
+	now := time.Now()
+	counter.counts["old"] = &quotaCount{calls: 1, expiry: now.Add(-time.Second)}
+	counter.sweep(now)
+	if _, ok := counter.counts["old"]; !ok {
+		t.Fatalf("expected expired counts to be kept until the next sweep")
+	}
+
+	counter.sweep(now.Add(quotaSweepInterval))
+	if _, ok := counter.counts["old"]; ok {
+		t.Fatalf("expected expired counts to be forgotten once quotaSweepInterval has passed")
+	}
+}
+
+func TestCallQuotaLimits(t *testing.T) {
+	quota := &CallQuota{
+		Limit:     QuotaLimit{Calls: 1, Period: time.Hour},
+		Clients:   map[string]QuotaLimit{"internal": {}},
+		PlanClaim: "plan",
+		Plans:     map[string]QuotaLimit{"pro": {Calls: 3, Period: time.Hour}},
+	}
+
+	tests := []struct {
+		authResult *AuthResult
+		allowed    int
+	}{
+		{&AuthResult{ClientIdentifier: testClientName}, 1},
+		{&AuthResult{ClientIdentifier: "proClient", Claims: map[string]interface{}{"plan": "pro"}}, 3},
+		{&AuthResult{ClientIdentifier: "internal", Claims: map[string]interface{}{"plan": "pro"}}, 10},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 10; i++ {
+			err := quota.Use(context.Background(), test.authResult, targetMethodName)
+			if (err == nil) != (i < test.allowed) {
+				t.Fatalf("expected %s to be allowed %d calls, got %v on call %d", test.authResult.ClientIdentifier, test.allowed, err, i+1)
+			}
+		}
+	}
+}
+
+func TestAuthorityEnforcesQuota(t *testing.T) {
+	quota := &CallQuota{Limit: QuotaLimit{Calls: 1, Period: time.Hour}}
+	a := NewAuthority(alwaysAuthenticatedAllPermissions, nil,
+		WithQuota(quota),
+		WithRequestAuthorizationFunc(func(ctx context.Context, authResult *AuthResult, methodName string, req interface{}) bool {
+			return req != "forbidden"
+		}),
+	)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	call := func(a Authority, methodName string, req interface{}) error {
+		_, err := a.UnaryServerInterceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: methodName}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return req, nil
+		})
+		return err
+	}
+
+	err := call(a, "/server.ServiceName/OtherMethod", nil)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	err = call(a, targetMethodName, "forbidden")
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected the request to be denied, got %v", err)
+	}
+
+	err = call(a, targetMethodName, nil)
+	if err != nil {
+		t.Fatalf("expected denied calls not to use the quota, got %v", err)
+	}
+
+	err = call(a, targetMethodName, nil)
+	if _, ok := errorInfo(err, ReasonQuotaExceeded); !ok || status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected the client to be over its quota, got %v", err)
+	}
+
+	if delay, ok := RetryDelay(err); !ok || delay <= 0 || delay > time.Hour {
+		t.Fatalf("expected to retry when the period resets, got %v", delay)
+	}
+
+	failing := NewAuthority(alwaysAuthenticatedAllPermissions, nil, WithQuota(QuotaFunc(func(ctx context.Context, authResult *AuthResult, methodName string) error {
+		return errors.New("redis unavailable")
+	})))
+	err = call(failing, targetMethodName, nil)
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected calls to be rejected when the quota can't be checked, got %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package grpcauth
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// defaultRedisRateLimitPrefix is put in front of client identifiers in Redis by default.
+	defaultRedisRateLimitPrefix = "grpcauth:ratelimit:"
+	// rateLimitSweepInterval is how often an InMemoryRateLimitStore forgets buckets that have refilled.
+	rateLimitSweepInterval = time.Minute
+)
+
+// RateLimit is a token bucket: clients can make Burst requests at once, and the bucket refills at Rate requests
+// per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// unlimited reports whether the limit doesn't restrict requests.
+func (l RateLimit) unlimited() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+// RateLimitStore holds the token buckets for a RateLimiter.
+type RateLimitStore interface {
+	// Take takes a token from key's bucket under limit. If the bucket is empty, it returns how long until a token
+	// will be available, and zero otherwise.
+	Take(ctx context.Context, key string, limit RateLimit) (time.Duration, error)
+}
+
+// RateLimiter limits how many requests each authenticated client can make, so one noisy client can't starve the
+// rest. Use it with WithRateLimiter.
+type RateLimiter struct {
+	// Limit applies to clients without a limit in Clients. Clients aren't limited if it is the zero value.
+	Limit RateLimit
+	// Clients overrides Limit for client identifiers.
+	Clients map[string]RateLimit
+	// Peers limits requests from each IP address before they are authenticated, in TapHandle.
+	// Peers aren't limited if it is the zero value.
+	Peers RateLimit
+	// Store holds the token buckets. Use a shared store such as RedisRateLimitStore to limit clients across servers.
+	// It defaults to an InMemoryRateLimitStore.
+	Store RateLimitStore
+
+	once        sync.Once
+	memoryStore *InMemoryRateLimitStore
+}
+
+// limitFor returns the limit for clientIdentifier.
+func (r *RateLimiter) limitFor(clientIdentifier string) RateLimit {
+	if limit, ok := r.Clients[clientIdentifier]; ok {
+		return limit
+	}
+
+	return r.Limit
+}
+
+// store returns the RateLimiter's Store, or an InMemoryRateLimitStore if it doesn't have one.
+func (r *RateLimiter) store() RateLimitStore {
+	if r.Store != nil {
+		return r.Store
+	}
+
+	r.once.Do(func() {
+		r.memoryStore = &InMemoryRateLimitStore{}
+	})
+	return r.memoryStore
+}
+
+// Allow takes a token for the client with authResult, and returns a ResourceExhausted status with RetryInfo if it
+// has made too many requests.
+func (r *RateLimiter) Allow(ctx context.Context, authResult *AuthResult) error {
+	limit := r.limitFor(authResult.ClientIdentifier)
+	if limit.unlimited() {
+		return nil
+	}
+
+	return r.take(ctx, authResult.ClientIdentifier, limit)
+}
+
+// allowPeer takes a token for the peer with ip, and returns a ResourceExhausted status with RetryInfo if it has made
+// too many requests.
+func (r *RateLimiter) allowPeer(ctx context.Context, ip string) error {
+	if r.Peers.unlimited() || ip == "" {
+		return nil
+	}
+
+	return r.take(ctx, "peer:"+ip, r.Peers)
+}
+
+// take takes a token from key's bucket under limit.
+func (r *RateLimiter) take(ctx context.Context, key string, limit RateLimit) error {
+	wait, err := r.store().Take(ctx, key, limit)
+	if err != nil {
+		return status.Error(codes.Unavailable, "rate limit unavailable")
+	}
+
+	if wait <= 0 {
+		return nil
+	}
+
+	return resourceExhaustedStatus("rate limit exceeded", wait)
+}
+
+// resourceExhaustedStatus returns a ResourceExhausted status with message, telling the client to retry after wait.
+func resourceExhaustedStatus(message string, wait time.Duration) error {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("%s, retry after %s", message, wait))
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// RetryDelay returns how long a client should wait before retrying a request rejected by a RateLimiter or
+// BruteForceProtection, and false if err doesn't say.
+func RetryDelay(err error) (time.Duration, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		if retryInfo, ok := detail.(*errdetails.RetryInfo); ok {
+			return retryInfo.RetryDelay.AsDuration(), true
+		}
+	}
+
+	return 0, false
+}
+
+// InMemoryRateLimitStore is a RateLimitStore for a single server. It is safe for concurrent use.
+type InMemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket is how many tokens a client has left as of updated, and when it will be full again.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// Take satisfies the RateLimitStore interface.
+func (s *InMemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.updated).Seconds()
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+elapsed*limit.Rate)
+	bucket.updated = now
+	wait := time.Duration(0)
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+	} else {
+		wait = time.Duration((1 - bucket.tokens) / limit.Rate * float64(time.Second))
+	}
+
+	bucket.full = now.Add(time.Duration((float64(limit.Burst) - bucket.tokens) / limit.Rate * float64(time.Second)))
+	return wait, nil
+}
+
+// sweep forgets buckets that have refilled, which are the same as new ones, at most once per
+// rateLimitSweepInterval. It must be called with s.mu held.
+func (s *InMemoryRateLimitStore) sweep(now time.Time) {
+	if s.buckets == nil {
+		s.buckets = map[string]*tokenBucket{}
+	}
+
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+
+	s.lastSweep = now
+	for key, bucket := range s.buckets {
+		if !now.Before(bucket.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// redisTokenBucket takes a token from the bucket in KEYS[1] with rate ARGV[1] and burst ARGV[2], and returns how
+// many milliseconds until one is available if it is empty. It uses the Redis server's clock so every server agrees.
+var redisTokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + tonumber(time[2]) / 1000
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1]) or burst
+local updated = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) / 1000 * rate)
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000))
+return wait
+`)
+
+// RedisRateLimitStore is a RateLimitStore backed by Redis, so a client's limit applies across every server.
+// Buckets expire once they would be full again.
+type RedisRateLimitStore struct {
+	Client redis.Scripter
+	// KeyPrefix is put in front of client identifiers to make Redis keys.
+	// It defaults to grpcauth:ratelimit:.
+	KeyPrefix string
+}
+
+// Take satisfies the RateLimitStore interface.
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (time.Duration, error) {
+	wait, err := redisTokenBucket.Run(ctx, s.Client, []string{s.key(key)}, limit.Rate, limit.Burst).Int64()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+func (s *RedisRateLimitStore) key(key string) string {
+	if s.KeyPrefix == "" {
+		return defaultRedisRateLimitPrefix + key
+	}
+	return s.KeyPrefix + key
+}
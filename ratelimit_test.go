@@ -0,0 +1,112 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestInMemoryRateLimitStore(t *testing.T) {
+	store := &InMemoryRateLimitStore{}
+	limit := RateLimit{Rate: 1, Burst: 2}
+	for i := 0; i < 2; i++ {
+		wait, err := store.Take(context.Background(), testClientName, limit)
+		if err != nil || wait != 0 {
+			t.Fatalf("expected the burst to be allowed, got %v %v", wait, err)
+		}
+	}
+
+	wait, err := store.Take(context.Background(), testClientName, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wait <= 0 || wait > time.Second {
+		t.Fatalf("expected to wait up to a second for the next token, got %v", wait)
+	}
+
+	wait, _ = store.Take(context.Background(), "otherClient", limit)
+	if wait != 0 {
+		t.Fatalf("expected clients to have their own buckets")
+	}
+
+	store.buckets[testClientName].updated = time.Now().Add(-time.Second)
+	wait, _ = store.Take(context.Background(), testClientName, limit)
+	if wait != 0 {
+		t.Fatalf("expected the bucket to refill, got %v", wait)
+	}
+}
+
+func TestInMemoryRateLimitStoreSweepsFullBuckets(t *testing.T) {
+	store := &InMemoryRateLimitStore{}
+	limit := RateLimit{Rate: 1, Burst: 2}
+	store.Take(context.Background(), "peer:192.0.2.1", limit)
+	store.Take(context.Background(), testClientName, limit)
+	store.Take(context.Background(), testClientName, limit)
+
+	// The peer's bucket is full a second after its request, and the client's two seconds after its requests.
+	now := time.Now()
+	store.sweep(now.Add(time.Second + time.Millisecond))
+	if len(store.buckets) != 2 {
+		t.Fatalf("expected buckets to be kept until the next sweep, got %d", len(store.buckets))
+	}
+
+	store.sweep(now.Add(rateLimitSweepInterval))
+	if len(store.buckets) != 0 {
+		t.Fatalf("expected full buckets to be forgotten, got %d", len(store.buckets))
+	}
+
+	store.lastSweep = time.Time{}
+	store.Take(context.Background(), testClientName, limit)
+	store.sweep(time.Now())
+	if _, ok := store.buckets[testClientName]; !ok {
+		t.Fatalf("expected buckets that haven't refilled to be kept")
+	}
+}
+
+func TestAuthorityRateLimitsClients(t *testing.T) {
+	limiter := &RateLimiter{
+		Limit:   RateLimit{Rate: 0.001, Burst: 1},
+		Clients: map[string]RateLimit{"unlimitedClient": {}},
+	}
+
+	var events []*AuthEvent
+	logger := LoggerFunc(func(ctx context.Context, event *AuthEvent) {
+		events = append(events, event)
+	})
+
+	a := NewAuthority(alwaysAuthenticatedAllPermissions, nil, WithRateLimiter(limiter), WithLogger(logger)).(*authority)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	_, err := a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+
+	delay, ok := RetryDelay(err)
+	if !ok || delay <= 0 {
+		t.Fatalf("expected a retry delay, got %v", delay)
+	}
+
+	if events[1].Outcome() != OutcomeRateLimited {
+		t.Fatalf("expected the rate limited request to be logged, got %s", events[1].Outcome())
+	}
+
+	unlimited := NewAuthority(func(md metadata.MD) (*AuthResult, error) {
+		return &AuthResult{ClientIdentifier: "unlimitedClient", Permissions: []string{targetMethodName}}, nil
+	}, nil, WithRateLimiter(limiter)).(*authority)
+	for i := 0; i < 3; i++ {
+		_, err = unlimited.authenticateAndAuthorizeContext(ctx, targetMethodName)
+		if err != nil {
+			t.Fatalf("expected clients with a zero limit not to be limited, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package grpcauth
+
+// RBAC is role-based access control for a gRPC server.
+// It treats AuthResult.Permissions as role names and lets a client call a method if any of its roles grants it.
+type RBAC struct {
+	// Roles maps each role name to the methods it may call, as method names or patterns matched with MatchMethod.
+	Roles map[string][]string
+}
+
+// PermissionFunc satisfies the PermissionFunc interface by resolving the client's roles to the methods they grant.
+// Roles that aren't in Roles grant nothing.
+func (r *RBAC) PermissionFunc(roles []string, methodName string) bool {
+	for _, role := range roles {
+		if WildcardPermissions(r.Roles[role], methodName) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,33 @@
+package grpcauth
+
+import (
+	"testing"
+)
+
+func TestRBACResolvesRoles(t *testing.T) {
+	rbac := &RBAC{
+		Roles: map[string][]string{
+			"admin":  {"/server.ServiceName/*"},
+			"reader": {"/server.ServiceName/GetThing", "/server.ServiceName/ListThings"},
+		},
+	}
+
+	tests := []struct {
+		roles      []string
+		methodName string
+		expected   bool
+	}{
+		{[]string{"admin"}, targetMethodName, true},
+		{[]string{"reader"}, "/server.ServiceName/ListThings", true},
+		{[]string{"reader"}, targetMethodName, false},
+		{[]string{"reader", "admin"}, targetMethodName, true},
+		{[]string{"unknown"}, targetMethodName, false},
+		{[]string{targetMethodName}, targetMethodName, false},
+	}
+
+	for _, test := range tests {
+		if rbac.PermissionFunc(test.roles, test.methodName) != test.expected {
+			t.Fatalf("expected roles %v calling %s to be %v", test.roles, test.methodName, test.expected)
+		}
+	}
+}
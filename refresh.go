@@ -0,0 +1,144 @@
+package grpcauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrTokenNotFound is returned by a TokenStore that has no token, usually because the user hasn't signed in.
+	ErrTokenNotFound = errors.New("token not found")
+
+	// ErrRefreshTokenRevoked is returned when the identity provider rejects a refresh token, usually because it has
+	// expired or been revoked after a rotated refresh token was reused. The user must sign in again.
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+)
+
+// TokenStore persists a client's tokens, so long-running or repeatedly run clients don't need the user to sign in
+// again. Implementations must be safe for concurrent use.
+type TokenStore interface {
+	// Load returns the stored token, or ErrTokenNotFound if there isn't one.
+	Load() (*oauth2.Token, error)
+	// Save replaces the stored token.
+	Save(token *oauth2.Token) error
+	// Delete removes the stored token.
+	Delete() error
+}
+
+// MemoryTokenStore is a TokenStore that keeps the token in memory, for clients that don't need it to outlive the
+// process.
+type MemoryTokenStore struct {
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+// Load satisfies the TokenStore interface.
+func (m *MemoryTokenStore) Load() (*oauth2.Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.token == nil {
+		return nil, ErrTokenNotFound
+	}
+	return m.token, nil
+}
+
+// Save satisfies the TokenStore interface.
+func (m *MemoryTokenStore) Save(token *oauth2.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.token = token
+	return nil
+}
+
+// Delete satisfies the TokenStore interface.
+func (m *MemoryTokenStore) Delete() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.token = nil
+	return nil
+}
+
+// RefreshTokenSource returns an oauth2.TokenSource that gets access tokens from config with the refresh token in
+// store, such as one saved after signing in with AuthorizationCodePKCE.
+// Identity providers that rotate refresh tokens return a new one with each access token, and it is saved before the
+// access token is used since the old one won't work again. The store is read again before each refresh, so processes
+// sharing it use the latest refresh token instead of reusing a rotated one.
+// If the identity provider rejects the refresh token, which it does when a rotated refresh token is reused in case
+// it was stolen, the stored token is deleted and ErrRefreshTokenRevoked is returned.
+func RefreshTokenSource(ctx context.Context, config *oauth2.Config, store TokenStore) oauth2.TokenSource {
+	return &refreshTokenSource{ctx: ctx, config: config, store: store}
+}
+
+type refreshTokenSource struct {
+	ctx    context.Context
+	config *oauth2.Config
+	store  TokenStore
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+// Token satisfies the oauth2.TokenSource interface.
+func (r *refreshTokenSource) Token() (*oauth2.Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token.Valid() {
+		return r.token, nil
+	}
+
+	stored, err := r.store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	// Another process sharing the store may have refreshed it already.
+	if stored.Valid() {
+		r.token = stored
+		return stored, nil
+	}
+
+	if stored.RefreshToken == "" {
+		return nil, fmt.Errorf("stored token has no refresh token")
+	}
+
+	token, err := r.config.TokenSource(r.ctx, &oauth2.Token{RefreshToken: stored.RefreshToken}).Token()
+	if err != nil {
+		if isInvalidGrant(err) {
+			r.store.Delete()
+			return nil, fmt.Errorf("%w: %v", ErrRefreshTokenRevoked, err)
+		}
+		return nil, err
+	}
+
+	// Identity providers that don't rotate refresh tokens don't return one.
+	if token.RefreshToken == "" {
+		token.RefreshToken = stored.RefreshToken
+	}
+
+	err = r.store.Save(token)
+	if err != nil {
+		return nil, err
+	}
+
+	r.token = token
+	return token, nil
+}
+
+// isInvalidGrant reports whether err is a token endpoint's invalid_grant error.
+func isInvalidGrant(err error) bool {
+	var retrieveError *oauth2.RetrieveError
+	if !errors.As(err, &retrieveError) {
+		return false
+	}
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(retrieveError.Body, &body)
+	return body.Error == "invalid_grant"
+}
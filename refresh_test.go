@@ -0,0 +1,80 @@
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// newTestRotatingTokenServer returns a token endpoint that rotates refresh tokens, only accepting the latest one.
+// Access tokens expire immediately so every call to Token refreshes.
+func newTestRotatingTokenServer(t *testing.T) *httptest.Server {
+	latest, issued := "refresh0", 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != latest {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		issued++
+		latest = fmt.Sprintf("refresh%d", issued)
+		fmt.Fprintf(w, `{"access_token":"access%d","token_type":"Bearer","refresh_token":"%s","expires_in":1}`, issued, latest)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRefreshTokenSourceRotatesRefreshTokens(t *testing.T) {
+	server := newTestRotatingTokenServer(t)
+	config := &oauth2.Config{ClientID: testClientName, Endpoint: oauth2.Endpoint{TokenURL: server.URL}}
+	store := &MemoryTokenStore{}
+	store.Save(&oauth2.Token{AccessToken: "access0", RefreshToken: "refresh0", Expiry: time.Now().Add(-time.Minute)})
+
+	tokenSource := RefreshTokenSource(context.Background(), config, store)
+	for i := 1; i <= 2; i++ {
+		token, err := tokenSource.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if token.AccessToken != fmt.Sprintf("access%d", i) {
+			t.Fatalf("expected refreshed access token, got %v", token.AccessToken)
+		}
+
+		stored, _ := store.Load()
+		if stored.RefreshToken != fmt.Sprintf("refresh%d", i) {
+			t.Fatalf("expected rotated refresh token to be saved, got %v", stored.RefreshToken)
+		}
+	}
+}
+
+func TestRefreshTokenSourceDetectsReuse(t *testing.T) {
+	server := newTestRotatingTokenServer(t)
+	config := &oauth2.Config{ClientID: testClientName, Endpoint: oauth2.Endpoint{TokenURL: server.URL}}
+	expired := &oauth2.Token{AccessToken: "access0", RefreshToken: "refresh0", Expiry: time.Now().Add(-time.Minute)}
+
+	// Rotate refresh0 away, then present it again from a stale copy.
+	_, err := RefreshTokenSource(context.Background(), config, &MemoryTokenStore{token: expired}).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := &MemoryTokenStore{token: expired}
+	_, err = RefreshTokenSource(context.Background(), config, stale).Token()
+	if !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Fatalf("expected ErrRefreshTokenRevoked, got %v", err)
+	}
+
+	_, err = stale.Load()
+	if err != ErrTokenNotFound {
+		t.Fatalf("expected revoked token to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package grpcauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RelationshipCheck is a Zanzibar style check of whether user has relation to object, written as type:id strings
+// like user "client:billing", relation "call" and object "grpc_method:/server.ServiceName/MethodName".
+type RelationshipCheck struct {
+	User     string
+	Relation string
+	Object   string
+}
+
+// ErrInvalidRelationship is wrapped by RelationshipCheckers when a check can't be made because it is malformed, like
+// a SpiceDB object without a type, which is a configuration error rather than an outage.
+var ErrInvalidRelationship = errors.New("invalid relationship")
+
+// RelationshipChecker asks a relationship based authorization service like OpenFGA or SpiceDB whether a
+// relationship exists.
+type RelationshipChecker interface {
+	Check(ctx context.Context, check RelationshipCheck) (bool, error)
+}
+
+// relationshipCacheEntry is a cached check result.
+type relationshipCacheEntry struct {
+	allowed bool
+	expiry  time.Time
+}
+
+// relationshipCacheKeyName is the key a request's relationshipCache is stored under in the context.
+const relationshipCacheKeyName = "relationships"
+
+// relationshipCache holds the results of the checks made while handling one request.
+type relationshipCache struct {
+	mu      sync.Mutex
+	results map[RelationshipCheck]bool
+}
+
+// withRelationshipCache returns a context that caches relationship checks for the rest of the request.
+func withRelationshipCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, authContextKey(relationshipCacheKeyName), &relationshipCache{results: map[RelationshipCheck]bool{}})
+}
+
+// relationshipCacheFrom returns the request's relationshipCache, or nil if ctx doesn't have one.
+func relationshipCacheFrom(ctx context.Context) *relationshipCache {
+	cache, _ := ctx.Value(authContextKey(relationshipCacheKeyName)).(*relationshipCache)
+	return cache
+}
+
+func (c *relationshipCache) get(check RelationshipCheck) (bool, bool) {
+	if c == nil {
+		return false, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	allowed, ok := c.results[check]
+	return allowed, ok
+}
+
+func (c *relationshipCache) set(check RelationshipCheck, allowed bool) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results[check] = allowed
+}
+
+// RelationshipAuthorizer gates gRPC methods with fine-grained relationship checks against OpenFGA or SpiceDB.
+// Each request is turned into a RelationshipCheck, which is sent to the Checker. Failed checks deny the request.
+// Use it with WithAuthorizationFunc.
+// Check results are cached for the rest of the request, so handlers calling Check for the same relationship don't
+// make another call. Streams keep their results until they are authenticated again.
+type RelationshipAuthorizer struct {
+	Checker RelationshipChecker
+	// Relationship returns the check for a request.
+	// It defaults to checking the client has the call relation to the method, with
+	// user "client:<ClientIdentifier>" and object "grpc_method:<method>".
+	Relationship func(authResult *AuthResult, methodName string) RelationshipCheck
+	// CacheTTL is how long check results are shared between requests, so repeated calls don't each make a check.
+	// Results aren't shared between requests when it is 0, the default.
+	// Cached results keep allowing requests for up to CacheTTL after a relationship is deleted, so only set it if
+	// stale authorization decisions are acceptable.
+	CacheTTL time.Duration
+
+	mu        sync.Mutex
+	cache     map[RelationshipCheck]*relationshipCacheEntry
+	lastSweep time.Time
+}
+
+// AuthorizationFunc satisfies the AuthorizationFunc interface by checking the request's relationship.
+// Requests are denied if the check fails.
+func (r *RelationshipAuthorizer) AuthorizationFunc(ctx context.Context, authResult *AuthResult, methodName string) bool {
+	allowed, err := r.CheckedAuthorizationFunc(ctx, authResult, methodName)
+	return err == nil && allowed
+}
+
+// CheckedAuthorizationFunc satisfies the CheckedAuthorizationFunc interface by checking the request's relationship,
+// so clients get Unavailable instead of PermissionDenied when the Checker can't be reached.
+func (r *RelationshipAuthorizer) CheckedAuthorizationFunc(ctx context.Context, authResult *AuthResult, methodName string) (bool, error) {
+	return r.Check(ctx, r.relationship(authResult, methodName))
+}
+
+// Check asks the Checker whether a relationship exists, unless it was already checked while handling the request
+// in ctx. Handlers can use it for checks on the objects a request touches.
+// Errors reaching the Checker wrap ErrAuthorizationUnavailable, except for ones wrapping ErrInvalidRelationship.
+func (r *RelationshipAuthorizer) Check(ctx context.Context, check RelationshipCheck) (bool, error) {
+	requestCache := relationshipCacheFrom(ctx)
+	if allowed, ok := requestCache.get(check); ok {
+		return allowed, nil
+	}
+
+	if allowed, ok := r.cached(check); ok {
+		requestCache.set(check, allowed)
+		return allowed, nil
+	}
+
+	allowed, err := r.Checker.Check(ctx, check)
+	if errors.Is(err, ErrInvalidRelationship) {
+		return false, err
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrAuthorizationUnavailable, err)
+	}
+
+	requestCache.set(check, allowed)
+	if r.CacheTTL > 0 {
+		r.store(check, allowed)
+	}
+
+	return allowed, nil
+}
+
+func (r *RelationshipAuthorizer) relationship(authResult *AuthResult, methodName string) RelationshipCheck {
+	if r.Relationship != nil {
+		return r.Relationship(authResult, methodName)
+	}
+
+	return RelationshipCheck{
+		User:     "client:" + authResult.ClientIdentifier,
+		Relation: "call",
+		Object:   "grpc_method:" + methodName,
+	}
+}
+
+func (r *RelationshipAuthorizer) cached(check RelationshipCheck) (bool, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.cache[check]
+	if !ok || !time.Now().Before(entry.expiry) {
+		return false, false
+	}
+
+	return entry.allowed, true
+}
+
+func (r *RelationshipAuthorizer) store(check RelationshipCheck, allowed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cache == nil {
+		r.cache = map[RelationshipCheck]*relationshipCacheEntry{}
+	}
+
+	now := time.Now()
+	r.sweep(now)
+	r.cache[check] = &relationshipCacheEntry{allowed: allowed, expiry: now.Add(r.CacheTTL)}
+}
+
+// sweep forgets results that have expired, at most once per CacheTTL.
+// It must be called with r.mu held.
+func (r *RelationshipAuthorizer) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < r.CacheTTL {
+		return
+	}
+
+	r.lastSweep = now
+	for check, entry := range r.cache {
+		if !now.Before(entry.expiry) {
+			delete(r.cache, check)
+		}
+	}
+}
+
+// OpenFGA is a RelationshipChecker that uses the OpenFGA HTTP API.
+// See https://openfga.dev/api/service#/Relationship%20Queries/Check
+type OpenFGA struct {
+	// URL is the OpenFGA API's address, such as http://localhost:8080.
+	URL     *url.URL
+	StoreID string
+	// AuthorizationModelID pins checks to a model version. The latest model is used when it is empty.
+	AuthorizationModelID string
+	// APIToken is sent as a bearer token when set.
+	APIToken string
+	// Client makes check requests. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Check asks OpenFGA if the relationship exists.
+func (o *OpenFGA) Check(ctx context.Context, check RelationshipCheck) (bool, error) {
+	body := map[string]interface{}{
+		"tuple_key": map[string]string{
+			"user":     check.User,
+			"relation": check.Relation,
+			"object":   check.Object,
+		},
+	}
+	if o.AuthorizationModelID != "" {
+		body["authorization_model_id"] = o.AuthorizationModelID
+	}
+
+	var response struct {
+		Allowed bool `json:"allowed"`
+	}
+	checkURL := fmt.Sprintf("%s/stores/%s/check", strings.TrimSuffix(o.URL.String(), "/"), url.PathEscape(o.StoreID))
+	err := postJSON(ctx, o.Client, checkURL, o.APIToken, body, &response)
+	if err != nil {
+		return false, err
+	}
+
+	return response.Allowed, nil
+}
+
+// SpiceDB is a RelationshipChecker that uses the SpiceDB HTTP API.
+// RelationshipCheck.User and Object are split into SpiceDB's object type and ID at the first colon, and
+// Relation is the permission checked.
+// See https://authzed.com/docs/spicedb/api/http-api
+type SpiceDB struct {
+	// URL is the SpiceDB HTTP gateway's address, such as http://localhost:8443.
+	URL *url.URL
+	// Token is SpiceDB's preshared key.
+	Token string
+	// Client makes check requests. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Check asks SpiceDB if the subject has the permission on the resource.
+func (s *SpiceDB) Check(ctx context.Context, check RelationshipCheck) (bool, error) {
+	subjectType, subjectID, err := splitSpiceDBObject(check.User)
+	if err != nil {
+		return false, err
+	}
+
+	resourceType, resourceID, err := splitSpiceDBObject(check.Object)
+	if err != nil {
+		return false, err
+	}
+
+	body := map[string]interface{}{
+		"consistency": map[string]interface{}{"minimizeLatency": true},
+		"resource":    map[string]string{"objectType": resourceType, "objectId": resourceID},
+		"permission":  check.Relation,
+		"subject": map[string]interface{}{
+			"object": map[string]string{"objectType": subjectType, "objectId": subjectID},
+		},
+	}
+
+	var response struct {
+		Permissionship string `json:"permissionship"`
+	}
+	err = postJSON(ctx, s.Client, strings.TrimSuffix(s.URL.String(), "/")+"/v1/permissions/check", s.Token, body, &response)
+	if err != nil {
+		return false, err
+	}
+
+	return response.Permissionship == "PERMISSIONSHIP_HAS_PERMISSION", nil
+}
+
+// splitSpiceDBObject splits a type:id object reference.
+func splitSpiceDBObject(object string) (string, string, error) {
+	parts := strings.SplitN(object, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("%w: object must be type:id, got %q", ErrInvalidRelationship, object)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// postJSON posts body as JSON to endpoint with an optional bearer token and decodes the response into v.
+func postJSON(ctx context.Context, client *http.Client, endpoint, token string, body, v interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return errors.New(string(b))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
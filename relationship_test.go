@@ -0,0 +1,155 @@
+package grpcauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRelationshipAuthorizerWithOpenFGA(t *testing.T) {
+	checks := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks++
+		if r.URL.Path != "/stores/store-1/check" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var body struct {
+			TupleKey RelationshipCheck `json:"tuple_key"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		allowed := body.TupleKey == RelationshipCheck{User: "client:" + testClientName, Relation: "call", Object: "grpc_method:" + targetMethodName}
+		json.NewEncoder(w).Encode(map[string]bool{"allowed": allowed})
+	}))
+	defer server.Close()
+
+	fgaURL, _ := url.Parse(server.URL)
+	authorizer := &RelationshipAuthorizer{Checker: &OpenFGA{URL: fgaURL, StoreID: "store-1"}}
+
+	ctx := withRelationshipCache(context.Background())
+	for i := 0; i < 2; i++ {
+		if !authorizer.AuthorizationFunc(ctx, testUnpermissionedAuthResult, targetMethodName) {
+			t.Fatalf("expected relationship to allow %s", targetMethodName)
+		}
+	}
+
+	if checks != 1 {
+		t.Fatalf("expected check to be cached for the request, got %d checks", checks)
+	}
+
+	authorizer.AuthorizationFunc(context.Background(), testUnpermissionedAuthResult, targetMethodName)
+	if checks != 2 {
+		t.Fatalf("expected checks not to be shared between requests by default, got %d checks", checks)
+	}
+
+	authorizer.CacheTTL = time.Minute
+	for i := 0; i < 2; i++ {
+		authorizer.AuthorizationFunc(context.Background(), testUnpermissionedAuthResult, targetMethodName)
+	}
+
+	if checks != 3 {
+		t.Fatalf("expected checks to be shared between requests for CacheTTL, got %d checks", checks)
+	}
+
+	if authorizer.AuthorizationFunc(ctx, testUnpermissionedAuthResult, "/server.ServiceName/OtherMethod") {
+		t.Fatalf("expected relationship to deny other methods")
+	}
+}
+
+func TestAuthorityCachesRelationshipsForRequest(t *testing.T) {
+	checks := 0
+	authorizer := &RelationshipAuthorizer{Checker: relationshipCheckerFunc(func(ctx context.Context, check RelationshipCheck) (bool, error) {
+		checks++
+		return true, nil
+	})}
+	a := NewAuthority(alwaysAuthenticatedNoPermissions, nil, WithAuthorizationFunc(authorizer.AuthorizationFunc))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return authorizer.Check(ctx, authorizer.relationship(testUnpermissionedAuthResult, targetMethodName))
+	}
+	for i := 0; i < 2; i++ {
+		_, err := a.UnaryServerInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: targetMethodName}, handler)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if checks != 2 {
+		t.Fatalf("expected one check for each request, got %d", checks)
+	}
+}
+
+// relationshipCheckerFunc satisfies the RelationshipChecker interface with a function.
+type relationshipCheckerFunc func(ctx context.Context, check RelationshipCheck) (bool, error)
+
+func (f relationshipCheckerFunc) Check(ctx context.Context, check RelationshipCheck) (bool, error) {
+	return f(ctx, check)
+}
+
+func TestSpiceDBCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/permissions/check" || r.Header.Get("Authorization") != "Bearer preshared" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body struct {
+			Resource struct {
+				ObjectID string `json:"objectId"`
+			} `json:"resource"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		permissionship := "PERMISSIONSHIP_NO_PERMISSION"
+		if body.Resource.ObjectID == targetMethodName {
+			permissionship = "PERMISSIONSHIP_HAS_PERMISSION"
+		}
+		json.NewEncoder(w).Encode(map[string]string{"permissionship": permissionship})
+	}))
+	defer server.Close()
+
+	spiceDBURL, _ := url.Parse(server.URL)
+	authorizer := &RelationshipAuthorizer{Checker: &SpiceDB{URL: spiceDBURL, Token: "preshared"}}
+	ctx := context.Background()
+	if !authorizer.AuthorizationFunc(ctx, testUnpermissionedAuthResult, targetMethodName) {
+		t.Fatalf("expected SpiceDB to allow %s", targetMethodName)
+	}
+
+	if authorizer.AuthorizationFunc(ctx, testUnpermissionedAuthResult, "/server.ServiceName/OtherMethod") {
+		t.Fatalf("expected SpiceDB to deny other methods")
+	}
+}
+
+func TestSpiceDBRejectsInvalidRelationships(t *testing.T) {
+	spiceDBURL, _ := url.Parse("http://spicedb.invalid")
+	authorizer := &RelationshipAuthorizer{
+		Checker: &SpiceDB{URL: spiceDBURL},
+		Relationship: func(authResult *AuthResult, methodName string) RelationshipCheck {
+			return RelationshipCheck{User: "client:" + authResult.ClientIdentifier, Relation: "call", Object: methodName}
+		},
+	}
+
+	_, err := authorizer.CheckedAuthorizationFunc(context.Background(), testUnpermissionedAuthResult, targetMethodName)
+	if !errors.Is(err, ErrInvalidRelationship) || errors.Is(err, ErrAuthorizationUnavailable) {
+		t.Fatalf("expected a configuration error rather than an outage, got %v", err)
+	}
+
+	a := NewAuthority(alwaysAuthenticatedNoPermissions, nil, WithCheckedAuthorizationFunc(authorizer.CheckedAuthorizationFunc)).(*authority)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	_, err = a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected clients to get Internal, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package grpcauth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// defaultRedisReplayPrefix is put in front of nonces and jti values in Redis by default.
+	defaultRedisReplayPrefix = "grpcauth:replay:"
+	// replaySweepInterval is how often an InMemoryReplayStore forgets IDs that have expired.
+	replaySweepInterval = time.Minute
+)
+
+// ReplayStore remembers nonces and jti values while the requests they came with are fresh, so HMAC, DPoP and
+// ClientAssertion can reject requests that are replayed.
+type ReplayStore interface {
+	// Use records id until expiry and reports whether it hadn't been used before.
+	Use(ctx context.Context, id string, expiry time.Time) (bool, error)
+}
+
+// ReplayStoreFunc satisfies the ReplayStore interface with a function.
+type ReplayStoreFunc func(ctx context.Context, id string, expiry time.Time) (bool, error)
+
+// Use satisfies the ReplayStore interface.
+func (f ReplayStoreFunc) Use(ctx context.Context, id string, expiry time.Time) (bool, error) {
+	return f(ctx, id, expiry)
+}
+
+// InMemoryReplayStore is a ReplayStore for a single server. It is the default for HMAC, DPoP and ClientAssertion.
+// It is safe for concurrent use.
+type InMemoryReplayStore struct {
+	mu        sync.Mutex
+	used      map[string]time.Time
+	lastSweep time.Time
+}
+
+// Use satisfies the ReplayStore interface.
+func (s *InMemoryReplayStore) Use(ctx context.Context, id string, expiry time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.sweep(now)
+
+	if e, ok := s.used[id]; ok && !now.After(e) {
+		return false, nil
+	}
+
+	s.used[id] = expiry
+	return true, nil
+}
+
+// sweep forgets IDs that have expired, at most once per replaySweepInterval.
+// It must be called with s.mu held.
+func (s *InMemoryReplayStore) sweep(now time.Time) {
+	if s.used == nil {
+		s.used = map[string]time.Time{}
+	}
+
+	if now.Sub(s.lastSweep) < replaySweepInterval {
+		return
+	}
+
+	s.lastSweep = now
+	for id, expiry := range s.used {
+		if now.After(expiry) {
+			delete(s.used, id)
+		}
+	}
+}
+
+// RedisReplayStore is a ReplayStore backed by Redis, so a request replayed to another server is rejected too.
+type RedisReplayStore struct {
+	Client redis.Cmdable
+	// KeyPrefix is put in front of IDs to make Redis keys.
+	// It defaults to grpcauth:replay:.
+	KeyPrefix string
+}
+
+// Use satisfies the ReplayStore interface.
+func (s *RedisReplayStore) Use(ctx context.Context, id string, expiry time.Time) (bool, error) {
+	ttl := time.Until(expiry)
+	if ttl <= 0 {
+		// The request is no longer fresh, so it would be rejected anyway.
+		return true, nil
+	}
+
+	return s.Client.SetNX(ctx, s.key(id), 1, ttl).Result()
+}
+
+func (s *RedisReplayStore) key(id string) string {
+	if s.KeyPrefix == "" {
+		return defaultRedisReplayPrefix + id
+	}
+	return s.KeyPrefix + id
+}
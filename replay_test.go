@@ -0,0 +1,74 @@
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInMemoryReplayStore(t *testing.T) {
+	store := &InMemoryReplayStore{}
+	ctx := context.Background()
+	fresh, err := store.Use(ctx, "nonce", time.Now().Add(time.Minute))
+	if err != nil || !fresh {
+		t.Fatalf("expected a new nonce to be fresh, got %v %v", fresh, err)
+	}
+
+	fresh, _ = store.Use(ctx, "nonce", time.Now().Add(time.Minute))
+	if fresh {
+		t.Fatalf("expected a used nonce to be rejected")
+	}
+
+	store.Use(ctx, "expired", time.Now().Add(-time.Second))
+	fresh, _ = store.Use(ctx, "expired", time.Now().Add(time.Minute))
+	if !fresh {
+		t.Fatalf("expected nonces to be forgotten once they expire")
+	}
+}
+
+func TestInMemoryReplayStoreSweepsOncePerInterval(t *testing.T) {
+	store := &InMemoryReplayStore{}
+	now := time.Now()
+	store.sweep(now)
+	store.used["expired"] = now.Add(-time.Second)
+
+	store.sweep(now.Add(time.Second))
+	if _, ok := store.used["expired"]; !ok {
+		t.Fatalf("expected expired IDs to be kept until the next sweep")
+	}
+
+	store.sweep(now.Add(replaySweepInterval))
+	if _, ok := store.used["expired"]; ok {
+		t.Fatalf("expected expired IDs to be forgotten once replaySweepInterval has passed")
+	}
+}
+
+func TestHMACSharesReplayStore(t *testing.T) {
+	store := &InMemoryReplayStore{}
+	keys := map[string]*HMACKey{
+		testHMACKeyID: {Secret: testHMACSecret, ClientIdentifier: testClientName, Permissions: []string{targetMethodName}},
+	}
+	first := &HMAC{Keys: keys, Replay: store}
+	second := &HMAC{Keys: keys, Replay: store}
+	ctx := methodContext(targetMethodName)
+	md := hmacMetadata(targetMethodName, time.Now(), "nonce-1")
+
+	_, err := first.AuthFunc(ctx, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = second.AuthFunc(ctx, md)
+	if err == nil {
+		t.Fatalf("expected a request replayed to another server to be rejected")
+	}
+
+	failing := &HMAC{Keys: keys, Replay: ReplayStoreFunc(func(ctx context.Context, id string, expiry time.Time) (bool, error) {
+		return false, errors.New("redis unavailable")
+	})}
+	_, err = failing.AuthFunc(ctx, hmacMetadata(targetMethodName, time.Now(), "nonce-2"))
+	if err == nil {
+		t.Fatalf("expected requests to be rejected when nonces can't be checked")
+	}
+}
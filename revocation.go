@@ -0,0 +1,115 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultRedisRevocationPrefix is put in front of revoked token IDs in Redis by default.
+const defaultRedisRevocationPrefix = "grpcauth:revoked:"
+
+// RevocationChecker is consulted after a token is validated, so compromised credentials can be cut off before
+// their tokens expire.
+// Tokens are identified by their jti claim, or TokenHash for tokens without one.
+type RevocationChecker interface {
+	IsRevoked(id string) (bool, error)
+}
+
+// TokenHash returns the ID a token without a jti claim is revoked by.
+func TokenHash(tokenString string) string {
+	hash := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(hash[:])
+}
+
+// revocationID returns the ID a token is checked for revocation by.
+func revocationID(claims jwt.MapClaims, tokenString string) string {
+	if jti, ok := claims["jti"].(string); ok && jti != "" {
+		return jti
+	}
+
+	return TokenHash(tokenString)
+}
+
+// InMemoryRevocationList is a RevocationChecker for a single server.
+// It is safe for concurrent use.
+type InMemoryRevocationList struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+// NewInMemoryRevocationList returns an empty InMemoryRevocationList.
+func NewInMemoryRevocationList() *InMemoryRevocationList {
+	return &InMemoryRevocationList{revoked: map[string]time.Time{}}
+}
+
+// Revoke revokes the token with id until expiry, which should be when the token expires.
+// The token is revoked forever if expiry is zero.
+func (l *InMemoryRevocationList) Revoke(id string, expiry time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.revoked[id] = expiry
+}
+
+// IsRevoked reports whether the token with id has been revoked.
+func (l *InMemoryRevocationList) IsRevoked(id string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expiry, ok := l.revoked[id]
+	if !ok {
+		return false, nil
+	}
+
+	// Expired tokens are rejected by validation anyway, so there's no need to remember them.
+	if !expiry.IsZero() && time.Now().After(expiry) {
+		delete(l.revoked, id)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// RedisRevocationList is a RevocationChecker backed by Redis, so revocations apply to every server at once.
+type RedisRevocationList struct {
+	Client redis.Cmdable
+	// KeyPrefix is put in front of token IDs to make Redis keys.
+	// It defaults to grpcauth:revoked:.
+	KeyPrefix string
+}
+
+// Revoke revokes the token with id until expiry, which should be when the token expires.
+// The token is revoked forever if expiry is zero.
+func (l *RedisRevocationList) Revoke(ctx context.Context, id string, expiry time.Time) error {
+	var ttl time.Duration
+	if !expiry.IsZero() {
+		ttl = time.Until(expiry)
+		if ttl <= 0 {
+			return nil
+		}
+	}
+
+	return l.Client.Set(ctx, l.key(id), 1, ttl).Err()
+}
+
+// IsRevoked reports whether the token with id has been revoked.
+func (l *RedisRevocationList) IsRevoked(id string) (bool, error) {
+	n, err := l.Client.Exists(context.Background(), l.key(id)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (l *RedisRevocationList) key(id string) string {
+	if l.KeyPrefix == "" {
+		return defaultRedisRevocationPrefix + id
+	}
+	return l.KeyPrefix + id
+}
@@ -0,0 +1,38 @@
+package grpcauth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJWTValidatorRejectsRevokedTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	jwksURL := issuer.url(t)
+	jwksURL.Path = "/jwks"
+	revocations := NewInMemoryRevocationList()
+	validator := NewJWTValidator(jwksURL, issuer.server.URL, testAudience)
+	validator.Revocation = revocations
+
+	withJTI := issuer.claims()
+	withJTI["jti"] = "token-1"
+	tokens := map[string]string{
+		"jti":  issuer.sign(t, withJTI),
+		"hash": issuer.sign(t, issuer.claims()),
+	}
+
+	for name, token := range tokens {
+		_, err := validator.AuthFunc(bearerMetadata(token))
+		if err != nil {
+			t.Fatalf("expected %s token to be accepted before revocation: %v", name, err)
+		}
+	}
+
+	revocations.Revoke("token-1", time.Now().Add(time.Hour))
+	revocations.Revoke(TokenHash(tokens["hash"]), time.Time{})
+	for name, token := range tokens {
+		_, err := validator.AuthFunc(bearerMetadata(token))
+		if err == nil {
+			t.Fatalf("expected error with token revoked by %s", name)
+		}
+	}
+}
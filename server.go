@@ -0,0 +1,18 @@
+package grpcauth
+
+import (
+	"google.golang.org/grpc"
+)
+
+// ServerOptions returns grpc.ServerOptions that run authority's interceptors before extraUnary and extraStream, so
+// the other interceptors only ever see authenticated requests and can call GetAuthResult.
+//
+//	server := grpc.NewServer(grpcauth.ServerOptions(authority, []grpc.UnaryServerInterceptor{logging}, nil)...)
+func ServerOptions(authority Authority, extraUnary []grpc.UnaryServerInterceptor, extraStream []grpc.StreamServerInterceptor) []grpc.ServerOption {
+	unary := append([]grpc.UnaryServerInterceptor{authority.UnaryServerInterceptor}, extraUnary...)
+	stream := append([]grpc.StreamServerInterceptor{authority.StreamServerInterceptor}, extraStream...)
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+}
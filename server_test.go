@@ -0,0 +1,78 @@
+package grpcauth
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestHealthClient starts a gRPC server with options serving the health service and returns a client for it.
+func newTestHealthClient(t *testing.T, options ...grpc.ServerOption) healthpb.HealthClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(options...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestServerOptionsRunsAuthorityFirst(t *testing.T) {
+	authority := NewAuthority(alwaysAuthenticatedNoPermissions, NoPermissions)
+	extra := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		_, err := GetAuthResult(ctx)
+		if err != nil {
+			t.Fatalf("expected the Authority to run before other interceptors: %v", err)
+		}
+
+		return handler(ctx, req)
+	}
+
+	client := newTestHealthClient(t, ServerOptions(authority, []grpc.UnaryServerInterceptor{extra}, nil)...)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "bearer words")
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMiddlewareAuthFunc(t *testing.T) {
+	rbac := &RBAC{Roles: map[string][]string{"monitor": {"/grpc.health.v1.Health/*"}}}
+	authority := NewAuthority(func(md metadata.MD) (*AuthResult, error) {
+		return &AuthResult{ClientIdentifier: testClientName, Permissions: md.Get("roles")}, nil
+	}, rbac.PermissionFunc)
+
+	client := newTestHealthClient(t, grpc.UnaryInterceptor(auth.UnaryServerInterceptor(MiddlewareAuthFunc(authority))))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "bearer words", "roles", "monitor")
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "bearer words", "roles", "reader")
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+}
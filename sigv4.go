@@ -0,0 +1,365 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// awsIAMScheme is the authorization scheme for signed STS GetCallerIdentity requests.
+	awsIAMScheme = "aws4-sts "
+
+	// awsGetCallerIdentityBody is the only request body AWSIAM will forward to STS.
+	awsGetCallerIdentityBody = "Action=GetCallerIdentity&Version=2011-06-15"
+
+	// AWSIAMServerIDHeader is the signed header that binds a request to a single server, so a server that receives
+	// a signed request can't replay it to authenticate as the client somewhere else.
+	AWSIAMServerIDHeader = "X-Grpcauth-Server-Id"
+
+	// awsSignedRequestMaxAge is the longest AWSIAM will accept a signed request after it was signed.
+	// STS itself rejects signatures older than 15 minutes.
+	awsSignedRequestMaxAge = 15 * time.Minute
+
+	awsDateFormat = "20060102T150405Z"
+)
+
+// awsSignedRequest is a SigV4 signed STS GetCallerIdentity request sent by the client for the server to forward to STS.
+type awsSignedRequest struct {
+	Method  string              `json:"method"`
+	URL     string              `json:"url"`
+	Body    string              `json:"body"`
+	Headers map[string][]string `json:"headers"`
+}
+
+// awsGetCallerIdentityResponse is the STS GetCallerIdentity response.
+type awsGetCallerIdentityResponse struct {
+	Result struct {
+		Arn     string `xml:"Arn"`
+		UserID  string `xml:"UserId"`
+		Account string `xml:"Account"`
+	} `xml:"GetCallerIdentityResult"`
+}
+
+// AWSIAM authenticates AWS workloads with their IAM role, so they don't need Cognito client credentials.
+// Clients sign an STS GetCallerIdentity request with SigV4 and send it in the authorization metadata field instead
+// of sending the request to STS. The server checks the request is a GetCallerIdentity call to STS that is bound to it,
+// then forwards it to STS, which verifies the signature and returns the caller's ARN.
+// This is the same scheme HashiCorp Vault's AWS IAM auth method uses since the server never sees the client's secret key.
+// Assumed role ARNs are converted into the role's ARN to become AuthResult.ClientIdentifier.
+// See AWSIAMCredentials for the client side.
+type AWSIAM struct {
+	// ServerID must be signed into every request in the X-Grpcauth-Server-Id header.
+	ServerID string
+	// ARNPermissions maps the IAM ARNs allowed to call the server to their permissions.
+	ARNPermissions map[string][]string
+	// STSURL is where signed requests must be sent, for private or regional endpoints.
+	// It defaults to accepting https://sts.amazonaws.com and regional endpoints like https://sts.us-east-1.amazonaws.com.
+	STSURL *url.URL
+	// Client forwards signed requests to STS. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// AuthFunc satisfies the AuthFunc interface so AWS workloads can use their IAM role with a gRPC server.
+func (a *AWSIAM) AuthFunc(md metadata.MD) (*AuthResult, error) {
+	signed, err := awsSignedRequestFromMetadata(md)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.validate(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(signed.Method, signed.URL, strings.NewReader(signed.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range signed.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, errors.New(string(b))
+	}
+
+	var identity awsGetCallerIdentityResponse
+	err = xml.NewDecoder(resp.Body).Decode(&identity)
+	if err != nil {
+		return nil, err
+	}
+
+	arn := canonicalAWSARN(identity.Result.Arn)
+	permissions, ok := a.ARNPermissions[arn]
+	if !ok {
+		return nil, fmt.Errorf("%s is not allowed", arn)
+	}
+
+	return &AuthResult{
+		ClientIdentifier: arn,
+		Timestamp:        time.Now(),
+		Permissions:      permissions,
+	}, nil
+}
+
+// validate makes sure a signed request is safe to forward: it must be a GetCallerIdentity call to STS, bound to this
+// server and recently signed.
+func (a *AWSIAM) validate(signed *awsSignedRequest) error {
+	if signed.Method != http.MethodPost || signed.Body != awsGetCallerIdentityBody {
+		return fmt.Errorf("signed request must be an STS GetCallerIdentity call")
+	}
+
+	// Only the STS endpoint can be called, or the server could be used to send signed requests anywhere.
+	u, err := url.Parse(signed.URL)
+	if err != nil {
+		return err
+	}
+
+	if a.STSURL != nil {
+		if u.Scheme != a.STSURL.Scheme || u.Host != a.STSURL.Host {
+			return fmt.Errorf("signed request must be sent to %v", a.STSURL)
+		}
+	} else if u.Scheme != "https" || !isSTSHost(u.Host) {
+		return fmt.Errorf("signed request must be sent to STS, got %s", u.Host)
+	}
+
+	header := http.Header(signed.Headers)
+	if header.Get(AWSIAMServerIDHeader) != a.ServerID {
+		return fmt.Errorf("signed request is for server %q", header.Get(AWSIAMServerIDHeader))
+	}
+
+	if !strings.Contains(strings.ToLower(header.Get("Authorization")), strings.ToLower(AWSIAMServerIDHeader)) {
+		return fmt.Errorf("%s must be a signed header", AWSIAMServerIDHeader)
+	}
+
+	signedAt, err := time.Parse(awsDateFormat, header.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+
+	if time.Since(signedAt) > awsSignedRequestMaxAge {
+		return fmt.Errorf("signed request is older than %v", awsSignedRequestMaxAge)
+	}
+
+	return nil
+}
+
+// isSTSHost reports whether host is the global or a regional STS endpoint.
+func isSTSHost(host string) bool {
+	if host == "sts.amazonaws.com" {
+		return true
+	}
+
+	parts := strings.Split(host, ".")
+	return len(parts) == 4 && parts[0] == "sts" && parts[2] == "amazonaws" && parts[3] == "com"
+}
+
+// canonicalAWSARN converts an assumed role ARN like arn:aws:sts::123456789012:assumed-role/role-name/session into
+// the role's ARN arn:aws:iam::123456789012:role/role-name, since the session name is chosen by the caller.
+func canonicalAWSARN(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[2] != "sts" || !strings.HasPrefix(parts[5], "assumed-role/") {
+		return arn
+	}
+
+	resource := strings.Split(parts[5], "/")
+	return fmt.Sprintf("%s:%s:iam::%s:role/%s", parts[0], parts[1], parts[4], resource[1])
+}
+
+// awsSignedRequestFromMetadata decodes the signed request from an authorization metadata field.
+func awsSignedRequestFromMetadata(md metadata.MD) (*awsSignedRequest, error) {
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected signed request in 'authorization' metadata field")
+	}
+
+	value := values[0]
+	if len(value) <= len(awsIAMScheme) || !strings.EqualFold(value[:len(awsIAMScheme)], awsIAMScheme) {
+		return nil, fmt.Errorf("expected AWS4-STS authorization scheme")
+	}
+
+	b, err := base64.StdEncoding.DecodeString(value[len(awsIAMScheme):])
+	if err != nil {
+		return nil, err
+	}
+
+	var signed awsSignedRequest
+	err = json.Unmarshal(b, &signed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &signed, nil
+}
+
+// AWSCredentials are the temporary or long term credentials of an IAM principal.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AWSCredentialsFunc returns the current AWS credentials, such as from the AWS SDK's credential chain.
+type AWSCredentialsFunc func(ctx context.Context) (*AWSCredentials, error)
+
+// AWSIAMCredentials returns credentials.PerRPCCredentials that sign an STS GetCallerIdentity request for each RPC,
+// for servers using AWSIAM.
+// Use it with grpc.WithPerRPCCredentials.
+func AWSIAMCredentials(credentialsFunc AWSCredentialsFunc, region, serverID string) credentials.PerRPCCredentials {
+	return &awsIAMCredentials{credentialsFunc: credentialsFunc, region: region, serverID: serverID}
+}
+
+type awsIAMCredentials struct {
+	credentialsFunc AWSCredentialsFunc
+	region          string
+	serverID        string
+	stsURL          string
+}
+
+// GetRequestMetadata signs an STS GetCallerIdentity request.
+func (a *awsIAMCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	creds, err := a.credentialsFunc(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stsURL := a.stsURL
+	if stsURL == "" {
+		stsURL = fmt.Sprintf("https://sts.%s.amazonaws.com/", a.region)
+	}
+
+	signed, err := signGetCallerIdentity(creds, a.region, stsURL, a.serverID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"authorization": "AWS4-STS " + base64.StdEncoding.EncodeToString(b),
+	}, nil
+}
+
+// RequireTransportSecurity is true since the signed request can be forwarded to STS by anyone who sees it.
+func (a *awsIAMCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// signGetCallerIdentity signs an STS GetCallerIdentity request using AWS Signature Version 4.
+// See https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+func signGetCallerIdentity(creds *AWSCredentials, region, stsURL, serverID string, now time.Time) (*awsSignedRequest, error) {
+	u, err := url.Parse(stsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	amzDate := now.UTC().Format(awsDateFormat)
+	date := amzDate[:8]
+	headers := map[string]string{
+		"content-type":                        "application/x-www-form-urlencoded; charset=utf-8",
+		"host":                                u.Host,
+		"x-amz-date":                          amzDate,
+		strings.ToLower(AWSIAMServerIDHeader): serverID,
+	}
+	if creds.SessionToken != "" {
+		headers["x-amz-security-token"] = creds.SessionToken
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost,
+		path,
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(awsGetCallerIdentityBody),
+	}, "\n")
+
+	scope := date + "/" + region + "/sts/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex(canonicalRequest)}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "sts")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	signed := &awsSignedRequest{
+		Method:  http.MethodPost,
+		URL:     u.String(),
+		Body:    awsGetCallerIdentityBody,
+		Headers: map[string][]string{},
+	}
+	for _, name := range names {
+		if name == "host" {
+			continue
+		}
+		signed.Headers[http.CanonicalHeaderKey(name)] = []string{headers[name]}
+	}
+	signed.Headers["Authorization"] = []string{fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature,
+	)}
+	return signed, nil
+}
+
+func sha256Hex(s string) string {
+	hash := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
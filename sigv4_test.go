@@ -0,0 +1,101 @@
+package grpcauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	testAWSServerID = "payments.example.com"
+	testAWSRoleARN  = "arn:aws:iam::123456789012:role/payments-api"
+)
+
+func awsIAMMetadata(t *testing.T, signed *awsSignedRequest) metadata.MD {
+	b, err := json.Marshal(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metadata.Pairs("authorization", "AWS4-STS "+base64.StdEncoding.EncodeToString(b))
+}
+
+func TestAWSIAMForwardsSignedRequestsToSTS(t *testing.T) {
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != awsGetCallerIdentityBody || !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`<GetCallerIdentityResponse><GetCallerIdentityResult>
+			<Arn>arn:aws:sts::123456789012:assumed-role/payments-api/i-0123456789</Arn>
+			<UserId>AROAEXAMPLE:i-0123456789</UserId>
+			<Account>123456789012</Account>
+		</GetCallerIdentityResult></GetCallerIdentityResponse>`))
+	}))
+	defer sts.Close()
+
+	stsURL, _ := url.Parse(sts.URL)
+	awsIAM := &AWSIAM{
+		ServerID:       testAWSServerID,
+		ARNPermissions: map[string][]string{testAWSRoleARN: {targetMethodName}},
+		STSURL:         stsURL,
+	}
+
+	creds := &AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signed, err := signGetCallerIdentity(creds, "us-east-1", sts.URL+"/", testAWSServerID, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authResult, err := awsIAM.AuthFunc(awsIAMMetadata(t, signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authResult.ClientIdentifier != testAWSRoleARN {
+		t.Fatalf("expected %v, got %v", testAWSRoleARN, authResult.ClientIdentifier)
+	}
+
+	otherServer, err := signGetCallerIdentity(creds, "us-east-1", sts.URL+"/", "other.example.com", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := signGetCallerIdentity(creds, "us-east-1", sts.URL+"/", testAWSServerID, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	elsewhere, err := signGetCallerIdentity(creds, "us-east-1", "https://attacker.example.com/", testAWSServerID, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, signed := range map[string]*awsSignedRequest{"other server": otherServer, "stale": stale, "elsewhere": elsewhere} {
+		_, err := awsIAM.AuthFunc(awsIAMMetadata(t, signed))
+		if err == nil {
+			t.Fatalf("expected error with %s request", name)
+		}
+	}
+}
+
+func TestIsSTSHost(t *testing.T) {
+	for host, expected := range map[string]bool{
+		"sts.amazonaws.com":           true,
+		"sts.eu-west-1.amazonaws.com": true,
+		"sts.amazonaws.com.evil.com":  false,
+		"s3.us-east-1.amazonaws.com":  false,
+	} {
+		if isSTSHost(host) != expected {
+			t.Fatalf("expected isSTSHost(%s) to be %v", host, expected)
+		}
+	}
+}
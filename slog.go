@@ -0,0 +1,39 @@
+//go:build go1.21
+
+package grpcauth
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger returns a Logger that writes events to logger with the LogKey attributes, at Info level for allowed
+// requests and Warn level for rejected ones. Errors are redacted with RedactTokens.
+// logger defaults to slog.Default() if it is nil.
+func SlogLogger(logger *slog.Logger) Logger {
+	return LoggerFunc(func(ctx context.Context, event *AuthEvent) {
+		l := logger
+		if l == nil {
+			l = slog.Default()
+		}
+
+		level := slog.LevelInfo
+		attrs := []slog.Attr{
+			slog.String(LogKeyMethod, event.MethodName),
+			slog.String(LogKeyClient, event.ClientIdentifier()),
+			slog.String(LogKeyOutcome, event.Outcome()),
+			slog.Duration(LogKeyLatency, event.Latency),
+		}
+		if event.Err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String(LogKeyError, event.RedactedError()))
+		}
+		if event.Cause != nil {
+			attrs = append(attrs, slog.String(LogKeyCause, event.RedactedCause()))
+		}
+		if event.CorrelationID != "" {
+			attrs = append(attrs, slog.String(LogKeyCorrelationID, event.CorrelationID))
+		}
+		l.LogAttrs(ctx, level, "grpcauth", attrs...)
+	})
+}
@@ -0,0 +1,36 @@
+//go:build go1.21
+
+package grpcauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := SlogLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	logger.LogAuth(context.Background(), &AuthEvent{
+		MethodName: targetMethodName,
+		Err:        errUnauthorized,
+		Cause:      errors.New("invalid token: Bearer words"),
+	})
+
+	var record map[string]interface{}
+	err := json.Unmarshal(buf.Bytes(), &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if record["level"] != "WARN" || record[LogKeyMethod] != targetMethodName || record[LogKeyOutcome] != OutcomeUnauthenticated {
+		t.Fatalf("expected rejected request to be logged, got %v", record)
+	}
+
+	if record[LogKeyCause] != "invalid token: Bearer [REDACTED]" {
+		t.Fatalf("expected token to be redacted, got %v", record[LogKeyCause])
+	}
+}
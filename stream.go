@@ -0,0 +1,200 @@
+package grpcauth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errCredentialsExpired closes streams that outlive the client's credentials.
+var errCredentialsExpired = withErrorInfo(status.New(codes.Unauthenticated, "credentials expired"), ReasonCredentialsExpired, nil).Err()
+
+// authorizingServerStream checks every message a client sends on a stream with the Authority's
+// RequestAuthorizationFunc, authenticates the stream again as often as the Authority's WithStreamReauthentication
+// settings require, and closes it when the client's credentials expire.
+type authorizingServerStream struct {
+	grpc.ServerStream
+	authority  *authority
+	methodName string
+	// base is the stream's context with the metadata it was opened with, and no AuthResult.
+	base context.Context
+	// cancel cancels base, and every context derived from it, when the stream is closed.
+	cancel context.CancelFunc
+
+	mu sync.Mutex
+	// ctx is the stream's context with the client's latest AuthResult.
+	ctx context.Context
+	// authenticatedAt is when the stream was last authenticated.
+	authenticatedAt time.Time
+	// received counts the messages received since the stream was last authenticated.
+	received int
+	// reauthenticating is set while the stream is being authenticated again.
+	reauthenticating bool
+	// expiry closes the stream when the client's latest credentials expire.
+	expiry *time.Timer
+	// err is the error the stream was closed with. The stream stays closed once it is set.
+	err error
+}
+
+func newAuthorizingServerStream(a *authority, stream grpc.ServerStream, base context.Context, cancel context.CancelFunc, ctx context.Context, methodName string) *authorizingServerStream {
+	s := &authorizingServerStream{
+		ServerStream:    stream,
+		authority:       a,
+		methodName:      methodName,
+		base:            base,
+		cancel:          cancel,
+		ctx:             ctx,
+		authenticatedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduleExpiry()
+	return s
+}
+
+// Context returns the stream's context with the client's latest AuthResult.
+func (s *authorizingServerStream) Context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ctx
+}
+
+// RecvMsg receives a message and returns PermissionDenied if the client isn't allowed to send it, or the error the
+// stream was closed with.
+func (s *authorizingServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err != nil {
+		return err
+	}
+
+	err = s.reauthenticate(1)
+	if err != nil {
+		return err
+	}
+
+	if s.authority.AuthorizeRequest == nil {
+		return nil
+	}
+
+	ctx := s.Context()
+	err = s.authority.authorizeRequest(ctx, s.methodName, m)
+	if err != nil && !s.authority.shadowDenied(ctx, s.methodName, err) {
+		return err
+	}
+
+	return nil
+}
+
+// SendMsg sends a message unless the stream has been closed.
+func (s *authorizingServerStream) SendMsg(m interface{}) error {
+	err := s.reauthenticate(0)
+	if err != nil {
+		return err
+	}
+
+	return s.ServerStream.SendMsg(m)
+}
+
+// reauthenticate counts received messages and authenticates the stream again with the metadata it was opened with
+// if the Authority's interval has passed or it has received enough messages.
+// The AuthFunc is called without holding s.mu, so a slow identity provider doesn't block the stream's other
+// goroutines, and only one call authenticates the stream at a time.
+func (s *authorizingServerStream) reauthenticate(received int) error {
+	s.mu.Lock()
+	if s.err != nil {
+		defer s.mu.Unlock()
+		return s.err
+	}
+
+	s.received += received
+	now := time.Now()
+	intervalPassed := s.authority.ReauthInterval > 0 && now.Sub(s.authenticatedAt) >= s.authority.ReauthInterval
+	messagesReached := s.authority.ReauthMessages > 0 && s.received >= s.authority.ReauthMessages
+	if (!intervalPassed && !messagesReached) || s.reauthenticating {
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.reauthenticating = true
+	s.mu.Unlock()
+
+	ctx, err := s.authority.authenticateMethod(s.base, s.methodName)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reauthenticating = false
+	// The stream may have been closed, such as by its credentials expiring, while it was being authenticated.
+	if s.err != nil {
+		return s.err
+	}
+
+	if err != nil {
+		s.close(err)
+		return err
+	}
+
+	s.ctx = ctx
+	s.authenticatedAt = now
+	s.received = 0
+	s.scheduleExpiry()
+	return nil
+}
+
+// scheduleExpiry closes the stream when the credentials in its latest AuthResult expire.
+// Callers must hold s.mu.
+func (s *authorizingServerStream) scheduleExpiry() {
+	if s.expiry != nil {
+		s.expiry.Stop()
+		s.expiry = nil
+	}
+
+	authResult, _ := GetAuthResult(s.ctx)
+	if !s.authority.expires(authResult) {
+		return
+	}
+
+	expiry, _, _ := s.authority.credentialsExpiry(authResult)
+	s.expiry = time.AfterFunc(time.Until(expiry), s.expire)
+}
+
+// expire closes the stream because the client's credentials expired, or reports it in shadow mode.
+func (s *authorizingServerStream) expire() {
+	ctx := s.Context()
+	if s.authority.shadowDenied(ctx, s.methodName, errCredentialsExpired) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.close(errCredentialsExpired)
+	}
+}
+
+// close records the error the stream was closed with and cancels the handler's context.
+// Callers must hold s.mu.
+func (s *authorizingServerStream) close(err error) {
+	s.err = err
+	s.cancel()
+}
+
+// closed returns the error the stream was closed with, and nil if it is still open.
+func (s *authorizingServerStream) closed() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// stop stops the stream's expiry timer once the handler returns.
+func (s *authorizingServerStream) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.expiry != nil {
+		s.expiry.Stop()
+	}
+}
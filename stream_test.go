@@ -0,0 +1,173 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestStreamReauthenticationEveryNMessages(t *testing.T) {
+	calls := 0
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		calls++
+		if calls > 2 {
+			return nil, ErrCredentialNotFound
+		}
+		return alwaysAuthenticatedAllPermissions(md)
+	}
+	a := NewAuthority(authFunc, nil, WithStreamReauthentication(0, 2))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	stream := &testServerStream{ctx: ctx, messages: []string{"1", "2", "3", "4"}}
+	received := 0
+	err := a.StreamServerInterceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: targetMethodName}, func(srv interface{}, stream grpc.ServerStream) error {
+		if _, err := GetAuthResult(stream.Context()); err != nil {
+			t.Fatalf("expected the stream's context to have an AuthResult: %v", err)
+		}
+
+		for {
+			var m string
+			if err := stream.RecvMsg(&m); err != nil {
+				return err
+			}
+			received++
+		}
+	})
+
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected the stream to be closed once its credentials are rejected, got %v", err)
+	}
+
+	if received != 3 || calls != 3 {
+		t.Fatalf("expected the stream to be authenticated again every 2 messages, got %d messages and %d calls", received, calls)
+	}
+}
+
+func TestStreamReauthenticationInterval(t *testing.T) {
+	permissions := []string{targetMethodName}
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		return &AuthResult{ClientIdentifier: testClientName, Permissions: permissions}, nil
+	}
+	a := NewAuthority(authFunc, nil, WithStreamReauthentication(10*time.Millisecond, 0))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	stream := &testServerStream{ctx: ctx}
+	err := a.StreamServerInterceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: targetMethodName}, func(srv interface{}, stream grpc.ServerStream) error {
+		if err := stream.SendMsg("before"); err != nil {
+			t.Fatalf("expected messages to be sent before the interval: %v", err)
+		}
+
+		permissions = nil
+		time.Sleep(20 * time.Millisecond)
+		if err := stream.SendMsg("after"); err == nil {
+			t.Fatalf("expected messages not to be sent once the client's permissions are revoked")
+		}
+
+		return stream.SendMsg("again")
+	})
+
+	if status.Code(err) != codes.PermissionDenied || len(stream.sent) != 1 {
+		t.Fatalf("expected the stream to stay closed with permission denied, got %v after %d messages", err, len(stream.sent))
+	}
+}
+
+func TestStreamClosedWhenCredentialsExpire(t *testing.T) {
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		return &AuthResult{ClientIdentifier: testClientName, Permissions: []string{targetMethodName}, Expiry: time.Now().Add(20 * time.Millisecond)}, nil
+	}
+	a := NewAuthority(authFunc, nil)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	stream := &testServerStream{ctx: ctx}
+	err := a.StreamServerInterceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: targetMethodName}, func(srv interface{}, stream grpc.ServerStream) error {
+		if err := stream.SendMsg("before"); err != nil {
+			t.Fatalf("expected messages to be sent before the credentials expire: %v", err)
+		}
+
+		select {
+		case <-stream.Context().Done():
+		case <-time.After(time.Second):
+			t.Fatalf("expected the handler's context to be cancelled when the credentials expire")
+		}
+
+		if err := stream.SendMsg("after"); err == nil {
+			t.Fatalf("expected messages not to be sent once the credentials expire")
+		}
+		return stream.Context().Err()
+	})
+
+	if _, ok := errorInfo(err, ReasonCredentialsExpired); !ok || status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected the stream to be closed because its credentials expired, got %v", err)
+	}
+}
+
+func TestStreamExpiryInShadowMode(t *testing.T) {
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		return &AuthResult{ClientIdentifier: testClientName, Permissions: []string{targetMethodName}, Expiry: time.Now().Add(10 * time.Millisecond)}, nil
+	}
+	denied := make(chan error, 1)
+	a := NewAuthority(authFunc, nil, WithShadowMode(func(ctx context.Context, methodName string, err error) {
+		denied <- err
+	}))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	err := a.StreamServerInterceptor(nil, &testServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: targetMethodName}, func(srv interface{}, stream grpc.ServerStream) error {
+		if err := <-denied; err != errCredentialsExpired {
+			t.Fatalf("expected the expiry to be reported, got %v", err)
+		}
+		return stream.SendMsg("after")
+	})
+
+	if err != nil {
+		t.Fatalf("expected streams not to be closed in shadow mode, got %v", err)
+	}
+}
+
+func TestStreamReauthenticationDoesNotHoldLock(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	calls := 0
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		calls++
+		if calls == 2 {
+			close(started)
+			<-release
+		}
+		return alwaysAuthenticatedAllPermissions(md)
+	}
+	a := NewAuthority(authFunc, nil, WithStreamReauthentication(0, 1))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	stream := &testServerStream{ctx: ctx, messages: []string{"1"}}
+	err := a.StreamServerInterceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: targetMethodName}, func(srv interface{}, stream grpc.ServerStream) error {
+		received := make(chan error)
+		go func() {
+			var m string
+			received <- stream.RecvMsg(&m)
+		}()
+
+		<-started
+		contextReturned := make(chan struct{})
+		go func() {
+			stream.Context()
+			close(contextReturned)
+		}()
+
+		select {
+		case <-contextReturned:
+		case <-time.After(time.Second):
+			t.Errorf("expected the stream's context to be available while it is authenticated again")
+		}
+
+		close(release)
+		return <-received
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
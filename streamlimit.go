@@ -0,0 +1,76 @@
+package grpcauth
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errTooManyStreams is returned to clients that already have as many streams open as their limit allows.
+var errTooManyStreams = status.Error(codes.ResourceExhausted, "too many open streams")
+
+// StreamLimiter limits how many streams each authenticated client can have open at once, so one client can't open
+// thousands of streams and tie up the server's memory and goroutines. Use it with WithStreamLimiter.
+// Streams are counted for each server. It is safe for concurrent use.
+type StreamLimiter struct {
+	// Limit applies to clients without a limit in Clients. Clients aren't limited if it is 0.
+	Limit int
+	// Clients overrides Limit for client identifiers.
+	Clients map[string]int
+
+	mu   sync.Mutex
+	open map[string]int
+}
+
+// Open returns how many streams clientIdentifier has open.
+func (l *StreamLimiter) Open(clientIdentifier string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.open[clientIdentifier]
+}
+
+// limitFor returns the limit for clientIdentifier.
+func (l *StreamLimiter) limitFor(clientIdentifier string) int {
+	if limit, ok := l.Clients[clientIdentifier]; ok {
+		return limit
+	}
+
+	return l.Limit
+}
+
+// acquire counts a stream opened by the client with authResult, and returns ResourceExhausted if it already has as
+// many open as its limit allows. Streams that were counted must be released when they close.
+// It allows every stream if l is nil or the client isn't authenticated.
+func (l *StreamLimiter) acquire(authResult *AuthResult) error {
+	if l == nil || authResult == nil {
+		return nil
+	}
+
+	limit := l.limitFor(authResult.ClientIdentifier)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit > 0 && l.open[authResult.ClientIdentifier] >= limit {
+		return errTooManyStreams
+	}
+
+	if l.open == nil {
+		l.open = map[string]int{}
+	}
+	l.open[authResult.ClientIdentifier]++
+	return nil
+}
+
+// release stops counting a stream acquired by the client with authResult.
+func (l *StreamLimiter) release(authResult *AuthResult) {
+	if l == nil || authResult == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.open[authResult.ClientIdentifier]--
+	if l.open[authResult.ClientIdentifier] <= 0 {
+		delete(l.open, authResult.ClientIdentifier)
+	}
+}
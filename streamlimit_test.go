@@ -0,0 +1,44 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestStreamLimiter(t *testing.T) {
+	limiter := &StreamLimiter{Limit: 1, Clients: map[string]int{"batch-job": 0}}
+	a := NewAuthority(alwaysAuthenticatedAllPermissions, nil, WithStreamLimiter(limiter))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer words"))
+	info := &grpc.StreamServerInfo{FullMethod: targetMethodName}
+	err := a.StreamServerInterceptor(nil, &testServerStream{ctx: ctx}, info, func(srv interface{}, stream grpc.ServerStream) error {
+		if open := limiter.Open(testClientName); open != 1 {
+			t.Fatalf("expected the stream to be counted, got %d open", open)
+		}
+
+		return a.StreamServerInterceptor(nil, &testServerStream{ctx: ctx}, info, func(srv interface{}, stream grpc.ServerStream) error {
+			t.Fatalf("expected a second stream to be rejected")
+			return nil
+		})
+	})
+
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected resource exhausted, got %v", err)
+	}
+
+	if open := limiter.Open(testClientName); open != 0 {
+		t.Fatalf("expected closed streams to stop being counted, got %d open", open)
+	}
+
+	for i := 0; i < 3; i++ {
+		err := limiter.acquire(&AuthResult{ClientIdentifier: "batch-job"})
+		if err != nil {
+			t.Fatalf("expected clients with a limit of 0 to be unlimited, got %v", err)
+		}
+	}
+}
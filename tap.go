@@ -0,0 +1,62 @@
+package grpcauth
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/tap"
+)
+
+// TapHandle returns a tap.ServerInHandle that rejects requests the Authority would reject without authenticating
+// them, before the server creates a stream or decodes any messages, so floods of unauthenticated requests are cheap.
+// It checks the KillSwitch, the Denylist's peers, BruteForceProtection, RateLimiter.Peers, and that the credentials'
+// metadata is present and within limits.
+// Requests it rejects aren't given to Loggers. Authorities in shadow mode and ones not from grpcauth reject nothing.
+// Install it with grpc.InTapHandle alongside the authority's interceptors.
+func TapHandle(a Authority) tap.ServerInHandle {
+	internal, ok := a.(*authority)
+	if !ok || internal.OnShadowDenial != nil {
+		return func(ctx context.Context, info *tap.Info) (context.Context, error) {
+			return ctx, nil
+		}
+	}
+
+	return internal.tapHandle
+}
+
+func (a *authority) tapHandle(ctx context.Context, info *tap.Info) (context.Context, error) {
+	if a.KillSwitch != nil {
+		err := a.KillSwitch.check(info.FullMethodName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if a.isUnauthenticatedMethod(info.FullMethodName) {
+		return ctx, nil
+	}
+
+	peerIP := PeerIP(ctx)
+	if err := a.Denylist.check(DenylistPeer, peerIP); err != nil {
+		return nil, err
+	}
+
+	if err := a.BruteForce.check(a.BruteForce.key(ctx, peerIP)); err != nil {
+		return nil, err
+	}
+
+	if a.RateLimiter != nil {
+		err := a.RateLimiter.allowPeer(ctx, peerIP)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The transport puts the request's headers in ctx before calling tap handles.
+	md, _ := metadata.FromIncomingContext(ctx)
+	if !a.withinMetadataLimits(md) || (!a.SkipMetadataKey && !validateIncomingMetadata(md, a.metadataKey())) {
+		return nil, a.hideMethod(errUnauthorized, info.FullMethodName)
+	}
+
+	return ctx, nil
+}
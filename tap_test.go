@@ -0,0 +1,47 @@
+package grpcauth
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTapHandle(t *testing.T) {
+	var calls int32
+	authFunc := func(md metadata.MD) (*AuthResult, error) {
+		atomic.AddInt32(&calls, 1)
+		return &AuthResult{ClientIdentifier: testClientName, Permissions: []string{"/grpc.health.v1.Health/Check"}}, nil
+	}
+
+	limiter := &RateLimiter{Peers: RateLimit{Rate: 0.001, Burst: 2}}
+	authority := NewAuthority(authFunc, nil, WithRateLimiter(limiter))
+	client := newTestHealthClient(t, append(ServerOptions(authority, nil, nil), grpc.InTapHandle(TapHandle(authority)))...)
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.Unauthenticated || calls != 0 {
+		t.Fatalf("expected requests without credentials to be rejected before authenticating, got %v after %d calls", err, calls)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "bearer words")
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.ResourceExhausted || calls != 1 {
+		t.Fatalf("expected the peer to be rate limited before authenticating, got %v after %d calls", err, calls)
+	}
+
+	shadow := NewAuthority(authFunc, nil, WithShadowMode(func(ctx context.Context, methodName string, err error) {}))
+	_, err = TapHandle(shadow)(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("expected authorities in shadow mode not to reject anything, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// TLSClientAuth authenticates a client to token endpoints with mutual TLS instead of a client secret, for identity
+// providers that support the tls_client_auth or self_signed_tls_client_auth methods and disallow shared secrets.
+// Identity providers that bind tokens to the certificate issue tokens that only work over connections using it.
+// See https://datatracker.ietf.org/doc/html/rfc8705 for more details.
+type TLSClientAuth struct {
+	ClientID string
+	// TLSConfig holds the client's certificate, and the root CAs the token endpoint is verified with.
+	// Each provider can have its own.
+	TLSConfig *tls.Config
+}
+
+// HTTPClient returns an http.Client that presents the client's certificate.
+func (t *TLSClientAuth) HTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = t.TLSConfig
+	return &http.Client{Transport: transport}
+}
+
+// Context returns a context that makes OAuth2 token requests made with it, such as those from the client credential
+// helpers, present the client's certificate.
+func (t *TLSClientAuth) Context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, t.HTTPClient())
+}
+
+// TokenSource returns an oauth2.TokenSource that uses the client credentials flow at tokenURL, authenticating with the
+// client's certificate. Tokens are cached and refreshed before they expire.
+func (t *TLSClientAuth) TokenSource(ctx context.Context, tokenURL string, endpointParams url.Values, scopes ...string) oauth2.TokenSource {
+	config := &clientcredentials.Config{
+		ClientID:       t.ClientID,
+		TokenURL:       tokenURL,
+		Scopes:         scopes,
+		EndpointParams: endpointParams,
+		// The client is identified by client_id and authenticated by the TLS handshake, so there is no secret to send.
+		AuthStyle: oauth2.AuthStyleInParams,
+	}
+	return clientCredentialsTokenSource(t.Context(ctx), config)
+}
@@ -0,0 +1,79 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTLSClientAuthTokenSource(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: testClientName},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) != 1 || r.TLS.PeerCertificates[0].Subject.CommonName != testClientName {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+
+		if r.FormValue("client_id") != testClientName {
+			http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"words","token_type":"Bearer","expires_in":3600}`))
+	}))
+	// self_signed_tls_client_auth: the server checks the certificate it has registered for the client.
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	tlsClientAuth := &TLSClientAuth{
+		ClientID: testClientName,
+		TLSConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		},
+	}
+
+	token, err := tlsClientAuth.TokenSource(context.Background(), server.URL, nil).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken != "words" {
+		t.Fatalf("expected access token, got %v", token.AccessToken)
+	}
+
+	withoutCertificate := &TLSClientAuth{ClientID: testClientName, TLSConfig: &tls.Config{RootCAs: roots}}
+	_, err = withoutCertificate.TokenSource(context.Background(), server.URL, nil).Token()
+	if err == nil {
+		t.Fatalf("expected token request without a client certificate to fail")
+	}
+}
@@ -0,0 +1,126 @@
+package grpcauth
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	// DefaultTokenRefreshWindow is how long before a token expires the client credential helpers start refreshing it.
+	DefaultTokenRefreshWindow = time.Minute
+
+	// tokenRefreshRetryInterval is how long a failed background refresh waits before the next one, while the cached
+	// token is still valid.
+	tokenRefreshRetryInterval = 5 * time.Second
+)
+
+// RefreshingTokenSource returns an oauth2.TokenSource that caches tokens from tokenSource and refreshes them in the
+// background between refreshBefore and twice refreshBefore before they expire, so callers keep using a valid token
+// instead of blocking on the token endpoint at expiry.
+// The refresh time is jittered so many clients started together don't refresh together, and concurrent callers share
+// a single request to tokenSource.
+// If a background refresh fails, the cached token is used until it expires.
+// tokenSource should fetch a new token every time it is called, unlike the oauth2.ReuseTokenSource returned by
+// clientcredentials.Config.TokenSource.
+func RefreshingTokenSource(tokenSource oauth2.TokenSource, refreshBefore time.Duration) oauth2.TokenSource {
+	return &refreshingTokenSource{source: tokenSource, refreshBefore: refreshBefore}
+}
+
+type refreshingTokenSource struct {
+	source        oauth2.TokenSource
+	refreshBefore time.Duration
+
+	mu        sync.Mutex
+	token     *oauth2.Token
+	refreshAt time.Time
+	inflight  *tokenFetch
+}
+
+// tokenFetch is a request to the token endpoint shared by every caller that needs a token while it is in flight.
+type tokenFetch struct {
+	done  chan struct{}
+	token *oauth2.Token
+	err   error
+}
+
+// Token satisfies the oauth2.TokenSource interface.
+func (r *refreshingTokenSource) Token() (*oauth2.Token, error) {
+	r.mu.Lock()
+	if r.token.Valid() {
+		token := r.token
+		if !r.refreshAt.IsZero() && time.Now().After(r.refreshAt) {
+			r.fetch()
+		}
+		r.mu.Unlock()
+		return token, nil
+	}
+
+	fetch := r.fetch()
+	r.mu.Unlock()
+	<-fetch.done
+	return fetch.token, fetch.err
+}
+
+// fetch starts a request to the token endpoint unless one is already in flight. It must be called with r.mu held.
+func (r *refreshingTokenSource) fetch() *tokenFetch {
+	if r.inflight != nil {
+		return r.inflight
+	}
+
+	fetch := &tokenFetch{done: make(chan struct{})}
+	r.inflight = fetch
+	go func() {
+		fetch.token, fetch.err = r.source.Token()
+
+		r.mu.Lock()
+		if fetch.err == nil {
+			r.token = fetch.token
+			r.refreshAt = r.nextRefresh(fetch.token)
+		} else {
+			r.refreshAt = time.Now().Add(tokenRefreshRetryInterval)
+		}
+		r.inflight = nil
+		r.mu.Unlock()
+		close(fetch.done)
+	}()
+
+	return fetch
+}
+
+// nextRefresh picks a random time to refresh token in the window before it expires.
+func (r *refreshingTokenSource) nextRefresh(token *oauth2.Token) time.Time {
+	if token.Expiry.IsZero() {
+		return time.Time{}
+	}
+
+	// Short-lived tokens are refreshed in the last half of their lifetime so they aren't refreshed on every call.
+	window := r.refreshBefore
+	if lifetime := time.Until(token.Expiry); window > lifetime/4 {
+		window = lifetime / 4
+	}
+	if window <= 0 {
+		return token.Expiry
+	}
+
+	return token.Expiry.Add(-window - time.Duration(rand.Int63n(int64(window))))
+}
+
+// tokenSourceFunc satisfies the oauth2.TokenSource interface with a function.
+type tokenSourceFunc func() (*oauth2.Token, error)
+
+// Token satisfies the oauth2.TokenSource interface.
+func (f tokenSourceFunc) Token() (*oauth2.Token, error) {
+	return f()
+}
+
+// clientCredentialsTokenSource gets tokens with config, refreshing them before they expire.
+func clientCredentialsTokenSource(ctx context.Context, config *clientcredentials.Config) oauth2.TokenSource {
+	return RefreshingTokenSource(tokenSourceFunc(func() (*oauth2.Token, error) {
+		return config.Token(ctx)
+	}), DefaultTokenRefreshWindow)
+}
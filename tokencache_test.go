@@ -0,0 +1,92 @@
+package grpcauth
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestRefreshingTokenSourceSharesFetches(t *testing.T) {
+	var fetches int32
+	release := make(chan struct{})
+	source := tokenSourceFunc(func() (*oauth2.Token, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return &oauth2.Token{AccessToken: "words", Expiry: time.Now().Add(time.Hour)}, nil
+	})
+
+	tokenSource := RefreshingTokenSource(source, time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := tokenSource.Token()
+			if err != nil || token.AccessToken != "words" {
+				t.Errorf("expected shared token, got %v, %v", token, err)
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Fatalf("expected concurrent callers to share one fetch, got %d", fetches)
+	}
+
+	_, err := tokenSource.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fetches != 1 {
+		t.Fatalf("expected cached token to be reused, got %d fetches", fetches)
+	}
+}
+
+func TestRefreshingTokenSourceRefreshesBeforeExpiry(t *testing.T) {
+	var fetches int32
+	source := tokenSourceFunc(func() (*oauth2.Token, error) {
+		n := atomic.AddInt32(&fetches, 1)
+		return &oauth2.Token{AccessToken: fmt.Sprint(n), Expiry: time.Now().Add(time.Duration(n) * time.Hour)}, nil
+	})
+
+	tokenSource := RefreshingTokenSource(source, time.Minute).(*refreshingTokenSource)
+	token, err := tokenSource.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken != "1" {
+		t.Fatalf("expected first token, got %v", token.AccessToken)
+	}
+
+	refreshAt := time.Until(tokenSource.refreshAt)
+	if refreshAt < 57*time.Minute || refreshAt > 59*time.Minute {
+		t.Fatalf("expected refresh one to two minutes before expiry, got %v", refreshAt)
+	}
+
+	// Move into the refresh window.
+	tokenSource.mu.Lock()
+	tokenSource.refreshAt = time.Now().Add(-time.Second)
+	tokenSource.mu.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for token.AccessToken == "1" {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected token to be refreshed in the background")
+		}
+
+		token, err = tokenSource.Token()
+		if err != nil {
+			t.Fatalf("expected cached token while refreshing, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
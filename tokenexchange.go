@@ -0,0 +1,135 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/url"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// TokenExchangeGrantType is the grant_type for token exchange requests.
+	TokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// AccessTokenType is the token type identifier for OAuth2 access tokens.
+	AccessTokenType = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// TokenExchange exchanges the access tokens clients call a server with for tokens scoped to a downstream service, so
+// handlers can call other services as the client without forwarding tokens that are valid for more than they need.
+// Exchanged tokens are cached until they expire.
+// See https://datatracker.ietf.org/doc/html/rfc8693 for more details.
+type TokenExchange struct {
+	// TokenURL is the identity provider's token endpoint.
+	TokenURL string
+	// ClientID and ClientSecret authenticate the server to the token endpoint.
+	ClientID     string
+	ClientSecret string
+	// Audience is the downstream service the exchanged tokens are for.
+	Audience string
+	// Scopes, if set, are the scopes requested for exchanged tokens.
+	Scopes []string
+
+	mu     sync.Mutex
+	tokens map[[sha256.Size]byte]*oauth2.Token
+}
+
+// Exchange returns a token for the downstream service in exchange for subjectToken.
+// Token requests use ctx's oauth2.HTTPClient, so TLSClientAuth.Context can be used to authenticate with mutual TLS.
+func (e *TokenExchange) Exchange(ctx context.Context, subjectToken string) (*oauth2.Token, error) {
+	key := sha256.Sum256([]byte(subjectToken))
+	e.mu.Lock()
+	token := e.tokens[key]
+	e.mu.Unlock()
+	if token.Valid() {
+		return token, nil
+	}
+
+	params := url.Values{}
+	params.Set("grant_type", TokenExchangeGrantType)
+	params.Set("subject_token", subjectToken)
+	params.Set("subject_token_type", AccessTokenType)
+	params.Set("requested_token_type", AccessTokenType)
+	if e.Audience != "" {
+		params.Set("audience", e.Audience)
+	}
+	config := &clientcredentials.Config{
+		ClientID:       e.ClientID,
+		ClientSecret:   e.ClientSecret,
+		TokenURL:       e.TokenURL,
+		Scopes:         e.Scopes,
+		EndpointParams: params,
+	}
+	token, err := config.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.tokens == nil {
+		e.tokens = map[[sha256.Size]byte]*oauth2.Token{}
+	}
+	for k, t := range e.tokens {
+		if !t.Valid() {
+			delete(e.tokens, k)
+		}
+	}
+	e.tokens[key] = token
+	return token, nil
+}
+
+// UnaryClientInterceptor returns a grpc.UnaryClientInterceptor that exchanges the access token of the client
+// authenticated in ctx for a downstream token, and sends it with outgoing calls made with ctx from a handler.
+// Calls made without an authenticated client fail with Unauthenticated, so they can't go out without credentials.
+func (e *TokenExchange) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := e.contextWithExchangedToken(ctx)
+		if err != nil {
+			return err
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a grpc.StreamClientInterceptor that sends exchanged tokens with outgoing streams,
+// like UnaryClientInterceptor.
+func (e *TokenExchange) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := e.contextWithExchangedToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// contextWithExchangedToken exchanges the bearer token the authenticated client called the server with.
+func (e *TokenExchange) contextWithExchangedToken(ctx context.Context) (context.Context, error) {
+	_, err := GetAuthResult(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "no authenticated client to exchange a token for")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	subjectToken, err := bearerToken(md)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "no access token to exchange: %v", err)
+	}
+
+	token, err := e.Exchange(ctx, subjectToken)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "unable to exchange access token: %v", err)
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token.AccessToken), nil
+}
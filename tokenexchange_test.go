@@ -0,0 +1,63 @@
+package grpcauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenExchangeClientInterceptor(t *testing.T) {
+	var exchanges int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != TokenExchangeGrantType ||
+			r.FormValue("subject_token") != "incoming" ||
+			r.FormValue("subject_token_type") != AccessTokenType ||
+			r.FormValue("audience") != testAudience {
+			http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+			return
+		}
+
+		atomic.AddInt32(&exchanges, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"downstream","issued_token_type":"urn:ietf:params:oauth:token-type:access_token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	tokenExchange := &TokenExchange{TokenURL: server.URL, ClientID: "server", ClientSecret: "secret", Audience: testAudience}
+	var authorization []string
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		authorization = md.Get("authorization")
+		return nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), bearerMetadata("incoming"))
+	ctx = ContextWithAuthResult(ctx, &AuthResult{ClientIdentifier: testClientName})
+	for i := 0; i < 2; i++ {
+		err := tokenExchange.UnaryClientInterceptor()(ctx, targetMethodName, nil, nil, nil, invoker)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(authorization) != 1 || authorization[0] != "Bearer downstream" {
+			t.Fatalf("expected exchanged token in outgoing metadata, got %v", authorization)
+		}
+	}
+
+	if exchanges != 1 {
+		t.Fatalf("expected exchanged token to be cached, got %d exchanges", exchanges)
+	}
+
+	unauthenticated := metadata.NewIncomingContext(context.Background(), bearerMetadata("incoming"))
+	err := tokenExchange.UnaryClientInterceptor()(unauthenticated, targetMethodName, nil, nil, nil, invoker)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without an authenticated client, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package grpcauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/oauth2"
+)
+
+// KeyringTokenStore is a TokenStore that keeps the token in the OS keyring: the macOS Keychain, the Secret Service on
+// Linux or the Windows Credential Manager, so CLI and desktop clients don't leave tokens in plain text on disk.
+type KeyringTokenStore struct {
+	// Service is the name the token is stored under, such as the client's name.
+	Service string
+	// User is the account the token is stored for, such as the server's address.
+	User string
+}
+
+// Load satisfies the TokenStore interface.
+func (k *KeyringTokenStore) Load() (*oauth2.Token, error) {
+	secret, err := keyring.Get(k.Service, k.User)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var token oauth2.Token
+	err = json.Unmarshal([]byte(secret), &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Save satisfies the TokenStore interface.
+func (k *KeyringTokenStore) Save(token *oauth2.Token) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	return keyring.Set(k.Service, k.User, string(b))
+}
+
+// Delete satisfies the TokenStore interface.
+func (k *KeyringTokenStore) Delete() error {
+	err := keyring.Delete(k.Service, k.User)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// EncryptedFileTokenStore is a TokenStore that keeps the token in a file encrypted with AES-256-GCM, for clients
+// running where there is no OS keyring, such as containers and CI.
+// The file is only readable by its owner, and is replaced atomically so a crash can't leave a partial token.
+type EncryptedFileTokenStore struct {
+	Path string
+	// Key is the 32 byte AES-256 key the file is encrypted with.
+	Key []byte
+
+	mu sync.Mutex
+}
+
+// Load satisfies the TokenStore interface.
+func (e *EncryptedFileTokenStore) Load() (*oauth2.Token, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b, err := ioutil.ReadFile(e.Path)
+	if os.IsNotExist(err) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := e.aead()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) < aead.NonceSize() {
+		return nil, fmt.Errorf("token file %s is too short", e.Path)
+	}
+
+	// The path is authenticated so a token file can't be swapped for another one encrypted with the same key.
+	plaintext, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], []byte(e.Path))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt token file %s: %v", e.Path, err)
+	}
+
+	var token oauth2.Token
+	err = json.Unmarshal(plaintext, &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Save satisfies the TokenStore interface.
+func (e *EncryptedFileTokenStore) Save(token *oauth2.Token) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	aead, err := e.aead()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(e.Path), filepath.Base(e.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	// TempFile creates files only readable by their owner.
+	_, err = f.Write(aead.Seal(nonce, nonce, plaintext, []byte(e.Path)))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), e.Path)
+}
+
+// Delete satisfies the TokenStore interface.
+func (e *EncryptedFileTokenStore) Delete() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	err := os.Remove(e.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (e *EncryptedFileTokenStore) aead() (cipher.AEAD, error) {
+	if len(e.Key) != 32 {
+		return nil, fmt.Errorf("token file key must be 32 bytes, got %d", len(e.Key))
+	}
+
+	block, err := aes.NewCipher(e.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
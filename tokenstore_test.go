@@ -0,0 +1,83 @@
+package grpcauth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/oauth2"
+)
+
+// testTokenStore checks that store saves, loads and deletes tokens.
+func testTokenStore(t *testing.T, store TokenStore) {
+	_, err := store.Load()
+	if err != ErrTokenNotFound {
+		t.Fatalf("expected ErrTokenNotFound from an empty store, got %v", err)
+	}
+
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	err = store.Save(&oauth2.Token{AccessToken: "words", RefreshToken: "refresh", TokenType: "Bearer", Expiry: expiry})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken != "words" || token.RefreshToken != "refresh" || !token.Expiry.Equal(expiry) {
+		t.Fatalf("expected saved token, got %v", token)
+	}
+
+	err = store.Delete()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Load()
+	if err != ErrTokenNotFound {
+		t.Fatalf("expected ErrTokenNotFound after delete, got %v", err)
+	}
+}
+
+func TestKeyringTokenStore(t *testing.T) {
+	keyring.MockInit()
+	testTokenStore(t, &KeyringTokenStore{Service: "grpcauth", User: testClientName})
+}
+
+func TestEncryptedFileTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	key := bytes.Repeat([]byte{1}, 32)
+	testTokenStore(t, &EncryptedFileTokenStore{Path: path, Key: key})
+
+	store := &EncryptedFileTokenStore{Path: path, Key: key}
+	err := store.Save(&oauth2.Token{AccessToken: "words"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected token file to only be readable by its owner, got %v", info.Mode())
+	}
+
+	b, _ := ioutil.ReadFile(path)
+	if bytes.Contains(b, []byte("words")) {
+		t.Fatalf("expected token file to be encrypted")
+	}
+
+	wrongKey := &EncryptedFileTokenStore{Path: path, Key: bytes.Repeat([]byte{2}, 32)}
+	_, err = wrongKey.Load()
+	if err == nil {
+		t.Fatalf("expected token file not to decrypt with the wrong key")
+	}
+}
@@ -0,0 +1,77 @@
+package grpcauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultTwirpPrefix is the path prefix Twirp servers are mounted on by default.
+const defaultTwirpPrefix = "/twirp"
+
+// TwirpMethods returns an HTTPMethodFunc that maps Twirp routes like `/twirp/server.ServiceName/MethodName` to the
+// gRPC method name `/server.ServiceName/MethodName`, so Twirp and gRPC servers can share a permission policy.
+// prefix is the path prefix the Twirp server is mounted on, and defaults to `/twirp`.
+func TwirpMethods(prefix string) HTTPMethodFunc {
+	if prefix == "" {
+		prefix = defaultTwirpPrefix
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(r *http.Request) string {
+		// Twirp only accepts POST requests.
+		if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, prefix+"/") {
+			return ""
+		}
+
+		methodName := strings.TrimPrefix(r.URL.Path, prefix)
+		if strings.Count(methodName, "/") != 2 {
+			return ""
+		}
+
+		return methodName
+	}
+}
+
+// TwirpMiddleware returns net/http middleware that authorizes requests to a Twirp server mounted on prefix with
+// authority, like HTTPMiddleware with TwirpMethods, and rejects requests with Twirp JSON errors.
+// See https://twitchtv.github.io/twirp/docs/spec_v7.html#error-codes for more details.
+func TwirpMiddleware(authority Authority, prefix string) func(http.Handler) http.Handler {
+	return httpMiddleware(authority, TwirpMethods(prefix), writeTwirpError)
+}
+
+// twirpError is the JSON body of a Twirp error response.
+type twirpError struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// writeTwirpError writes st as a Twirp error.
+func writeTwirpError(w http.ResponseWriter, st *status.Status) {
+	code, httpStatus := "internal", http.StatusInternalServerError
+	switch st.Code() {
+	case codes.Unauthenticated:
+		code, httpStatus = "unauthenticated", http.StatusUnauthorized
+	case codes.PermissionDenied:
+		code, httpStatus = "permission_denied", http.StatusForbidden
+	case codes.Unavailable:
+		code, httpStatus = "unavailable", http.StatusServiceUnavailable
+	case codes.InvalidArgument:
+		code, httpStatus = "invalid_argument", http.StatusBadRequest
+	case codes.ResourceExhausted:
+		code, httpStatus = "resource_exhausted", http.StatusTooManyRequests
+	case codes.NotFound:
+		code, httpStatus = "not_found", http.StatusNotFound
+	case codes.Unimplemented:
+		// Hidden methods look like routes the Twirp server doesn't have.
+		code, httpStatus = "bad_route", http.StatusNotFound
+	}
+
+	setRetryAfter(w, st)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(&twirpError{Code: code, Msg: st.Message()})
+}
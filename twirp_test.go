@@ -0,0 +1,122 @@
+package grpcauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestTwirpMiddleware(t *testing.T) {
+	handler := TwirpMiddleware(NewAuthority(alwaysAuthenticatedAllPermissions, nil), "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method   string
+		path     string
+		expected int
+		code     string
+	}{
+		{http.MethodPost, "/twirp" + targetMethodName, http.StatusOK, ""},
+		{http.MethodPost, "/twirp/server.ServiceName/OtherMethod", http.StatusForbidden, "permission_denied"},
+		{http.MethodGet, "/twirp" + targetMethodName, http.StatusForbidden, "permission_denied"},
+		{http.MethodPost, "/other" + targetMethodName, http.StatusForbidden, "permission_denied"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		r.Header.Set("Authorization", "bearer words")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != test.expected {
+			t.Fatalf("expected %s %s to return %d, got %d", test.method, test.path, test.expected, w.Code)
+		}
+
+		if test.code == "" {
+			continue
+		}
+
+		var twirpErr twirpError
+		err := json.NewDecoder(w.Body).Decode(&twirpErr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if twirpErr.Code != test.code {
+			t.Fatalf("expected Twirp error %s, got %s", test.code, twirpErr.Code)
+		}
+	}
+}
+
+func TestTwirpMiddlewareHiddenMethods(t *testing.T) {
+	tests := []struct {
+		code     codes.Code
+		expected string
+	}{
+		{codes.Unimplemented, "bad_route"},
+		{codes.NotFound, "not_found"},
+	}
+
+	for _, test := range tests {
+		authority := NewAuthority(alwaysAuthenticatedNoPermissions, nil, WithHiddenMethods(test.code, targetMethodName))
+		handler := TwirpMiddleware(authority, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		r := httptest.NewRequest(http.MethodPost, "/twirp"+targetMethodName, nil)
+		r.Header.Set("Authorization", "bearer words")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected methods hidden with %v to return %d, got %d", test.code, http.StatusNotFound, w.Code)
+		}
+
+		var twirpErr twirpError
+		err := json.NewDecoder(w.Body).Decode(&twirpErr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if twirpErr.Code != test.expected {
+			t.Fatalf("expected Twirp error %s, got %s", test.expected, twirpErr.Code)
+		}
+	}
+}
+
+func TestTwirpMiddlewareRateLimited(t *testing.T) {
+	limiter := &RateLimiter{Limit: RateLimit{Rate: 0.5, Burst: 1}}
+	handler := TwirpMiddleware(NewAuthority(alwaysAuthenticatedAllPermissions, nil, WithRateLimiter(limiter)), "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, expected := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodPost, "/twirp"+targetMethodName, nil)
+		r.Header.Set("Authorization", "bearer words")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Fatalf("expected %d, got %d", expected, w.Code)
+		}
+
+		if expected == http.StatusOK {
+			continue
+		}
+
+		if w.Header().Get("Retry-After") != "2" {
+			t.Fatalf("expected a Retry-After header, got %q", w.Header().Get("Retry-After"))
+		}
+
+		var twirpErr twirpError
+		err := json.NewDecoder(w.Body).Decode(&twirpErr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if twirpErr.Code != "resource_exhausted" {
+			t.Fatalf("expected Twirp error resource_exhausted, got %s", twirpErr.Code)
+		}
+	}
+}
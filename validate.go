@@ -0,0 +1,105 @@
+package grpcauth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// ServerMethods returns the full name of every method registered on server, like
+// `/server.ServiceName/MethodName`. Call it after registering services, and before serving.
+func ServerMethods(server *grpc.Server) []string {
+	var methods []string
+	for serviceName, info := range server.GetServiceInfo() {
+		for _, method := range info.Methods {
+			methods = append(methods, "/"+serviceName+"/"+method.Name)
+		}
+	}
+
+	sort.Strings(methods)
+	return methods
+}
+
+// ServiceDescMethods returns the full name of every method in descs, for validating policies without a *grpc.Server.
+func ServiceDescMethods(descs ...*grpc.ServiceDesc) []string {
+	var methods []string
+	for _, desc := range descs {
+		for _, method := range desc.Methods {
+			methods = append(methods, "/"+desc.ServiceName+"/"+method.MethodName)
+		}
+
+		for _, stream := range desc.Streams {
+			methods = append(methods, "/"+desc.ServiceName+"/"+stream.StreamName)
+		}
+	}
+
+	sort.Strings(methods)
+	return methods
+}
+
+// ValidateMethodPatterns returns an error naming every one of patterns that doesn't match any of methods, so typos
+// in permissions are reported at startup instead of silently never matching.
+// Patterns are matched with MatchMethod.
+func ValidateMethodPatterns(patterns []string, methods []string) error {
+	var unmatched []string
+	for _, pattern := range patterns {
+		if !matchesAnyMethod(pattern, methods) {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+
+	if len(unmatched) > 0 {
+		return fmt.Errorf("patterns match no registered method: %s", strings.Join(unmatched, ", "))
+	}
+
+	return nil
+}
+
+// Validate returns an error naming every role's method pattern that doesn't match any of methods.
+func (r *RBAC) Validate(methods []string) error {
+	return validateRoles(r.Roles, methods)
+}
+
+// Validate returns an error naming every role and exempt method pattern that doesn't match any of methods.
+func (p *Policy) Validate(methods []string) error {
+	err := validateRoles(p.Roles, methods)
+	if err != nil {
+		return err
+	}
+
+	err = ValidateMethodPatterns(p.Exempt, methods)
+	if err != nil {
+		return fmt.Errorf("exempt: %v", err)
+	}
+
+	return nil
+}
+
+func validateRoles(roles map[string][]string, methods []string) error {
+	roleNames := make([]string, 0, len(roles))
+	for role := range roles {
+		roleNames = append(roleNames, role)
+	}
+
+	sort.Strings(roleNames)
+	for _, role := range roleNames {
+		err := ValidateMethodPatterns(roles[role], methods)
+		if err != nil {
+			return fmt.Errorf("role %s: %v", role, err)
+		}
+	}
+
+	return nil
+}
+
+func matchesAnyMethod(pattern string, methods []string) bool {
+	for _, method := range methods {
+		if MatchMethod(pattern, method) {
+			return true
+		}
+	}
+
+	return false
+}
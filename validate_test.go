@@ -0,0 +1,52 @@
+package grpcauth
+
+import (
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+var testServiceDesc = &grpc.ServiceDesc{
+	ServiceName: "server.ServiceName",
+	Methods:     []grpc.MethodDesc{{MethodName: "MethodName"}},
+	Streams:     []grpc.StreamDesc{{StreamName: "StreamName"}},
+}
+
+func TestValidateMethodPatterns(t *testing.T) {
+	methods := ServiceDescMethods(testServiceDesc)
+	if len(methods) != 2 || methods[0] != targetMethodName || methods[1] != "/server.ServiceName/StreamName" {
+		t.Fatalf("unexpected methods %v", methods)
+	}
+
+	err := ValidateMethodPatterns([]string{targetMethodName, "/server.ServiceName/*", "/server.*"}, methods)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ValidateMethodPatterns([]string{"/server.ServiceName/MethodNmae"}, methods)
+	if err == nil {
+		t.Fatalf("expected typo to be reported")
+	}
+
+	policy := &Policy{
+		Roles:  map[string][]string{"admin": {"/server.ServiceName/*"}},
+		Exempt: []string{"/grpc.health.v1.Health/*"},
+	}
+	if policy.Validate(methods) == nil {
+		t.Fatalf("expected unregistered exempt service to be reported")
+	}
+
+	rbac := &RBAC{Roles: policy.Roles}
+	if err := rbac.Validate(methods); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerMethods(t *testing.T) {
+	server := grpc.NewServer()
+	server.RegisterService(testServiceDesc, nil)
+	methods := ServerMethods(server)
+	if len(methods) != 2 || methods[0] != targetMethodName {
+		t.Fatalf("unexpected methods %v", methods)
+	}
+}
@@ -0,0 +1,53 @@
+package grpcauth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorVerbosity controls how much an Authority tells clients about why they were denied.
+// Loggers always get the full error.
+type ErrorVerbosity int
+
+const (
+	// VerboseErrors returns a PermissionDeniedError with the client's identifier and permissions.
+	// It is the default, and helps clients debug, but tells anyone holding stolen credentials what they can call.
+	VerboseErrors ErrorVerbosity = iota
+	// MinimalErrors only tells clients which method they were denied.
+	MinimalErrors
+	// OpaqueErrors only gives clients a correlation ID, which is in the AuthEvent the Loggers get.
+	OpaqueErrors
+)
+
+// clientError returns the error a client denied with err should get, and the correlation ID it contains if errors
+// are opaque. Errors other than PermissionDenied are returned unchanged.
+func (v ErrorVerbosity) clientError(err error, methodName string) (error, string) {
+	if v == VerboseErrors || status.Code(err) != codes.PermissionDenied {
+		return err, ""
+	}
+
+	if v == MinimalErrors {
+		return denials.minimal(methodName), ""
+	}
+
+	correlationID := newCorrelationID()
+	st := status.Newf(codes.PermissionDenied, "permission denied, correlation id %s", correlationID)
+	detailed, err := withErrorInfo(st, ReasonPermissionDenied, nil).WithDetails(&errdetails.RequestInfo{RequestId: correlationID})
+	if err != nil {
+		return st.Err(), correlationID
+	}
+
+	return detailed.Err(), correlationID
+}
+
+// newCorrelationID returns a random ID for matching an opaque error to the server's logs.
+func newCorrelationID() string {
+	b := make([]byte, 16)
+	// crypto/rand only fails if the OS can't provide randomness, and a predictable ID doesn't weaken anything.
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
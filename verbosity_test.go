@@ -0,0 +1,54 @@
+package grpcauth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorVerbosity(t *testing.T) {
+	md := metadata.Pairs("authorization", "bearer words")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	tests := []struct {
+		verbosity ErrorVerbosity
+		leaks     bool
+	}{
+		{VerboseErrors, true},
+		{MinimalErrors, false},
+		{OpaqueErrors, false},
+	}
+
+	for _, test := range tests {
+		var event *AuthEvent
+		logger := LoggerFunc(func(ctx context.Context, e *AuthEvent) {
+			event = e
+		})
+		a := NewAuthority(alwaysAuthenticatedNoPermissions, nil, WithErrorVerbosity(test.verbosity), WithLogger(logger)).(*authority)
+		_, err := a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("expected PermissionDenied, got %v", err)
+		}
+
+		if leaks := strings.Contains(err.Error(), testClientName); leaks != test.leaks {
+			t.Fatalf("verbosity %d: expected the client to be in the error=%t, got %v", test.verbosity, test.leaks, err)
+		}
+
+		if !strings.Contains(event.Err.Error(), testClientName) {
+			t.Fatalf("expected the logger to get the full error, got %v", event.Err)
+		}
+
+		if test.verbosity == OpaqueErrors && (event.CorrelationID == "" || !strings.Contains(err.Error(), event.CorrelationID)) {
+			t.Fatalf("expected the error to have the logged correlation ID %q, got %v", event.CorrelationID, err)
+		}
+	}
+
+	a := NewAuthority(alwaysUnauthenticated, nil, WithErrorVerbosity(OpaqueErrors)).(*authority)
+	_, err := a.authenticateAndAuthorizeContext(ctx, targetMethodName)
+	if err != errUnauthorized {
+		t.Fatalf("expected other errors to be unchanged, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package grpcauth
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// ZapLogger returns a Logger that writes events to logger with the LogKey fields, at Info level for allowed requests
+// and Warn level for rejected ones. Errors are redacted with RedactTokens.
+func ZapLogger(logger *zap.Logger) Logger {
+	return LoggerFunc(func(ctx context.Context, event *AuthEvent) {
+		fields := []zap.Field{
+			zap.String(LogKeyMethod, event.MethodName),
+			zap.String(LogKeyClient, event.ClientIdentifier()),
+			zap.String(LogKeyOutcome, event.Outcome()),
+			zap.Duration(LogKeyLatency, event.Latency),
+		}
+		if event.Cause != nil {
+			fields = append(fields, zap.String(LogKeyCause, event.RedactedCause()))
+		}
+		if event.CorrelationID != "" {
+			fields = append(fields, zap.String(LogKeyCorrelationID, event.CorrelationID))
+		}
+		if event.Err == nil {
+			logger.Info("grpcauth", fields...)
+			return
+		}
+
+		fields = append(fields, zap.String(LogKeyError, event.RedactedError()))
+		logger.Warn("grpcauth", fields...)
+	})
+}
@@ -0,0 +1,29 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := ZapLogger(zap.New(core))
+	logger.LogAuth(context.Background(), &AuthEvent{
+		MethodName: targetMethodName,
+		AuthResult: &AuthResult{ClientIdentifier: testClientName},
+	})
+
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].Level != zapcore.InfoLevel {
+		t.Fatalf("expected allowed request to be logged at info level, got %v", entries)
+	}
+
+	fields := entries[0].ContextMap()
+	if fields[LogKeyClient] != testClientName || fields[LogKeyOutcome] != OutcomeAllowed {
+		t.Fatalf("expected client and outcome fields, got %v", fields)
+	}
+}